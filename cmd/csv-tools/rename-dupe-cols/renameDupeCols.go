@@ -1,26 +1,33 @@
 package main
 
 import (
-	"bufio"
 	"encoding/csv"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
 	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
 	"github.com/charmbracelet/log"
 )
 
 // main is the entry point of the program.
 func main() {
-	log.SetLevel(log.DebugLevel)
 	startTime := time.Now()
 
 	processingErr := ErrMsg{Code: Success}
+	var report RunReport
+	reportFormatPtr := flag.String("report", "", "Write a run report in the given format (json)")
+	reportFilePtr := flag.String("report-file", "", "Write the run report to this file instead of stdout")
 	defer func() {
+		report.Finish(startTime, processingErr.Code)
+		if reportErr := WriteReport(report, *reportFormatPtr, *reportFilePtr); reportErr != nil {
+			log.Error("Failed to write run report", "error", reportErr)
+		}
 		log.Debug(
 			"DONE!",
 			"time", time.Since(startTime),
@@ -28,27 +35,38 @@ func main() {
 		processingErr.Exit()
 	}()
 	filePathPtr := flag.String("path", "", "CSV file path")
+	bomPtr := flag.String("bom", BOMPreserve, "How to handle a UTF-8 byte order mark: 'preserve' keeps the one the file has, 'strip' removes it, 'add' writes one (as Excel expects)")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
 	flag.Parse()
-	pipeInput, _ := os.Stdin.Stat()
-
-	if pipeInput.Mode()&os.ModeNamedPipe != 0 {
-		reader := bufio.NewReader(os.Stdin)
-		input, inputErr := reader.ReadString('\n')
-		if inputErr != nil {
-			processingErr = ErrMsg{Err: inputErr, Code: ErrStdin}
+	if logErr := logging.Setup(); logErr != nil {
+		processingErr = ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+		return
+	}
+	if !ValidReportFormat(*reportFormatPtr) {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("unsupported report format '%s'", *reportFormatPtr),
+			Code: ErrInvalidArgs,
 		}
-		processingErr = processCSV(strings.TrimSpace(input))
-	} else if *filePathPtr != "" {
-		processingErr = processCSV(*filePathPtr)
-	} else {
+		return
+	}
+	if !ValidBOMMode(*bomPtr) {
 		processingErr = ErrMsg{
-			Err:  fmt.Errorf("no CSV path provided from pipe nor --path flag"),
-			Code: ErrNoInput,
+			Err:  fmt.Errorf("unknown --bom '%s', expected one of %s", *bomPtr, strings.Join(BOMModes, ", ")),
+			Code: ErrInvalidArgs,
 		}
+		return
 	}
+	path, inputErr := ResolveFile(*filePathPtr, flag.Args())
+	if inputErr != nil {
+		processingErr = ToErrMsg(inputErr, ErrNoInput)
+		return
+	}
+	processingErr = processCSV(path, *bomPtr, &report)
 }
 
-func processCSV(path string) ErrMsg {
+func processCSV(path, bom string, report *RunReport) ErrMsg {
+	report.File = path
 	if exists, _ := PathExists(path); !exists {
 		return ErrMsg{Err: fmt.Errorf("file '%s' does not exist", path), Code: ErrNoFile}
 	}
@@ -58,34 +76,19 @@ func processCSV(path string) ErrMsg {
 			Code: ErrInvalidFileType,
 		}
 	}
-	tempFile, ioErr := readWriteCsv(path)
-	if ioErr != nil {
+	if ioErr := readWriteCsv(path, bom, report); ioErr != nil {
 		return ErrMsg{Err: ioErr, Code: ErrReadWrite}
 	}
-	removeErr := os.Remove(path)
-	if removeErr != nil {
-		return ErrMsg{Err: removeErr, Code: ErrWriteFile}
-	}
-	log.Info(
-		"Removed original file",
-		"file", path,
-	)
-	moveErr := MoveFile(tempFile, path)
-	if moveErr != nil {
-		return ErrMsg{Err: moveErr, Code: ErrMoveFile}
-	}
-	log.Info(
-		"Successfully amended file",
-		"original", filepath.Base(path),
-		"amended", filepath.Base(tempFile),
-	)
+	log.Info("Successfully amended file", "file", path)
 	return ErrMsg{Code: Success}
 }
 
-func readWriteCsv(path string) (string, error) {
+// readWriteCsv rewrites the CSV file at path through an AtomicFile, so the file is left as it was if reading
+// or writing fails part way through.
+func readWriteCsv(path, bom string, report *RunReport) (err error) {
 	originalCsv, readErr := os.Open(path)
 	if readErr != nil {
-		return "", readErr
+		return readErr
 	}
 	defer func(originalCsv *os.File) {
 		err := originalCsv.Close()
@@ -93,39 +96,60 @@ func readWriteCsv(path string) (string, error) {
 			log.Error(err)
 		}
 	}(originalCsv)
-	tempCsv, tempErr := os.CreateTemp("", fmt.Sprintf("*_%s", filepath.Base(path)))
-	if tempErr != nil {
-		return tempCsv.Name(), tempErr
+	output, createErr := CreateAtomic(path)
+	if createErr != nil {
+		return createErr
 	}
-	defer func(tempCsv *os.File) {
-		err := tempCsv.Close()
+	defer func() {
 		if err != nil {
-			log.Error(err)
+			output.Abort()
 		}
-	}(tempCsv)
-	log.Info("Created temp file", "file", tempCsv.Name())
+	}()
 
-	reader := csv.NewReader(originalCsv)
-	writer := csv.NewWriter(tempCsv)
-	defer writer.Flush()
+	input, hadBOM, bomErr := SkipBOM(originalCsv)
+	if bomErr != nil {
+		return bomErr
+	}
+	if bomErr = WriteBOM(output, bom, hadBOM); bomErr != nil {
+		return bomErr
+	}
+	reader := csv.NewReader(input)
+	reader.FieldsPerRecord = -1
+	writer := csv.NewWriter(output)
 
-	log.Info("ORIGINAL", "file", originalCsv.Name())
-	log.Info("AMENDED", "file", tempCsv.Name())
 	lineCount := 0
 	for {
-		record, err := reader.Read()
-		if err != nil {
+		record, readErr := reader.Read()
+		if errors.Is(readErr, io.EOF) {
 			break
+		} else if readErr != nil {
+			return readErr
+		}
+		if lineCount > 0 {
+			report.RowsRead++
 		}
 		if lineCount == 0 {
+			original := append([]string(nil), record...)
 			record = RenameDuplicates(record, true)
+			for i := range record {
+				if record[i] != original[i] {
+					report.HeadersRenamed++
+				}
+			}
 		}
 		writeErr := writer.Write(record)
 		if writeErr != nil {
-			return tempCsv.Name(), writeErr
+			return writeErr
+		}
+		if lineCount > 0 {
+			report.RowsWritten++
 		}
 		lineCount++
 	}
+	writer.Flush()
+	if flushErr := writer.Error(); flushErr != nil {
+		return flushErr
+	}
 	log.Info("Renamed duplicate columns successfully")
-	return tempCsv.Name(), nil
+	return output.Commit()
 }
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
+	"github.com/charmbracelet/log"
+)
+
+const (
+	actionAdd   = "add"
+	actionStrip = "strip"
+)
+
+// indexHeaders are the headers, lowercased and without spaces, underscores, dashes or dots, that mark the first
+// column as a row number when stripping without --name; pandas writes an index as "" or "Unnamed: 0".
+var indexHeaders = map[string]bool{
+	"": true, "#": true, "no": true, "idx": true, "index": true, "row": true, "rowno": true, "rownum": true,
+	"rownumber": true, "unnamed:0": true,
+}
+
+// options holds the command line settings controlling how the row-number column is handled.
+type options struct {
+	Action string
+	Name   string
+	Start  int
+	BOM    string
+}
+
+func main() {
+	startTime := time.Now()
+
+	processingErr := ErrMsg{Code: Success}
+	var report RunReport
+	reportFormatPtr := flag.String("report", "", "Write a run report in the given format (json)")
+	reportFilePtr := flag.String("report-file", "", "Write the run report to this file instead of stdout")
+	defer func() {
+		report.Finish(startTime, processingErr.Code)
+		if reportErr := WriteReport(report, *reportFormatPtr, *reportFilePtr); reportErr != nil {
+			log.Error("Failed to write run report", "error", reportErr)
+		}
+		log.Debug(
+			"DONE!",
+			"time", time.Since(startTime),
+		)
+		processingErr.Exit()
+	}()
+	var opts options
+	filePathPtr := flag.String("path", "", "CSV file path")
+	flag.StringVar(&opts.Action, "action", "", "Whether to 'strip' an existing row-number column or 'add' one (required)")
+	flag.StringVar(&opts.Name, "name", "", "Header of the row-number column (strip defaults to the first column if it holds row numbers, add defaults to 'RowNumber')")
+	flag.IntVar(&opts.Start, "start", 1, "The number given to the first data row when adding a row-number column")
+	flag.StringVar(&opts.BOM, "bom", BOMPreserve, "How to handle a UTF-8 byte order mark: 'preserve' keeps the one the file has, 'strip' removes it, 'add' writes one (as Excel expects)")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if logErr := logging.Setup(); logErr != nil {
+		processingErr = ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+		return
+	}
+
+	if !ValidReportFormat(*reportFormatPtr) {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("unsupported report format '%s'", *reportFormatPtr),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if !ValidBOMMode(opts.BOM) {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("unknown --bom '%s', expected one of %s", opts.BOM, strings.Join(BOMModes, ", ")),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if opts.Action == "" {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("--action is required, expected '%s' or '%s'", actionAdd, actionStrip),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if opts.Action != actionAdd && opts.Action != actionStrip {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("unknown action '%s', expected '%s' or '%s'", opts.Action, actionAdd, actionStrip),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if opts.Action == actionAdd && opts.Name == "" {
+		opts.Name = "RowNumber"
+	}
+	path, inputErr := ResolveFile(*filePathPtr, flag.Args())
+	if inputErr != nil {
+		processingErr = ToErrMsg(inputErr, ErrNoInput)
+		return
+	}
+	processingErr = processCSV(path, opts, &report)
+}
+
+func processCSV(path string, opts options, report *RunReport) ErrMsg {
+	report.File = path
+	if exists, _ := PathExists(path); !exists {
+		return ErrMsg{Err: fmt.Errorf("file '%s' does not exist", path), Code: ErrNoFile}
+	}
+	if !CheckExtension(path, ".csv") {
+		return ErrMsg{
+			Err:  fmt.Errorf("file '%s' is not a CSV file", path),
+			Code: ErrInvalidFileType,
+		}
+	}
+	if ioErr := readWriteCsv(path, opts, report); ioErr != nil {
+		return *ioErr
+	}
+	log.Info("Successfully amended file", "file", path)
+	return ErrMsg{Code: Success}
+}
+
+// readWriteCsv rewrites the CSV file at path through an AtomicFile, so the file is left as it was if reading
+// or writing fails part way through.
+// Stripping without --name removes the first column only if its header looks like an index or its values count
+// up one row at a time; otherwise the file is left as it was, as the column is most likely data.
+func readWriteCsv(path string, opts options, report *RunReport) (errMsg *ErrMsg) {
+	originalCsv, readErr := os.Open(path)
+	if readErr != nil {
+		return &ErrMsg{Err: readErr, Code: ErrReadFile}
+	}
+	defer func(originalCsv *os.File) {
+		err := originalCsv.Close()
+		if err != nil {
+			log.Error(err)
+		}
+	}(originalCsv)
+	output, createErr := CreateAtomic(path)
+	if createErr != nil {
+		return &ErrMsg{Err: createErr, Code: ErrWriteFile}
+	}
+	defer func() {
+		if errMsg != nil {
+			output.Abort()
+		}
+	}()
+
+	input, hadBOM, bomErr := SkipBOM(originalCsv)
+	if bomErr != nil {
+		return &ErrMsg{Err: bomErr, Code: ErrReadFile}
+	}
+	if bomErr = WriteBOM(output, opts.BOM, hadBOM); bomErr != nil {
+		return &ErrMsg{Err: bomErr, Code: ErrWriteFile}
+	}
+	reader := csv.NewReader(input)
+	reader.FieldsPerRecord = -1
+	writer := csv.NewWriter(output)
+
+	stripIndex := -1
+	// checkNumbers is set when the first column is to be stripped only if it holds row numbers
+	checkNumbers := false
+	previous := 0
+	lineCount := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return &ErrMsg{Err: err, Code: ErrParse}
+		}
+		if lineCount > 0 {
+			report.RowsRead++
+		}
+		if lineCount == 0 && opts.Action == actionStrip {
+			stripIndex = findIndexColumn(record, opts.Name)
+			if stripIndex < 0 {
+				return &ErrMsg{
+					Err:  fmt.Errorf("column '%s' not found in '%s'", opts.Name, path),
+					Code: ErrInvalidArgs,
+				}
+			}
+			checkNumbers = opts.Name == "" && !looksLikeIndexHeader(record[stripIndex])
+			log.Info("Stripping row-number column", "column", record[stripIndex], "index", stripIndex)
+		} else if checkNumbers {
+			number, numberErr := strconv.Atoi(strings.TrimSpace(record[0]))
+			if numberErr != nil || (lineCount > 1 && number != previous+1) {
+				return &ErrMsg{
+					Err: fmt.Errorf("the first column of '%s' does not hold row numbers ('%s' in row %d), name the column to strip with --name",
+						path, record[0], lineCount),
+					Code: ErrInvalidArgs,
+				}
+			}
+			previous = number
+		}
+		var newRecord []string
+		switch opts.Action {
+		case actionStrip:
+			newRecord = removeField(record, stripIndex)
+		case actionAdd:
+			number := opts.Name
+			if lineCount > 0 {
+				number = strconv.Itoa(opts.Start + lineCount - 1)
+			}
+			newRecord = append([]string{number}, record...)
+		}
+		writeErr := writer.Write(newRecord)
+		if writeErr != nil {
+			return &ErrMsg{Err: writeErr, Code: ErrReadWrite}
+		}
+		if lineCount > 0 {
+			report.RowsWritten++
+		}
+		lineCount++
+	}
+	writer.Flush()
+	if flushErr := writer.Error(); flushErr != nil {
+		return &ErrMsg{Err: flushErr, Code: ErrWriteFile}
+	}
+	log.Info("Updated row-number column successfully", "action", opts.Action, "lines", lineCount)
+	if commitErr := output.Commit(); commitErr != nil {
+		return &ErrMsg{Err: commitErr, Code: ErrWriteFile}
+	}
+	return nil
+}
+
+// findIndexColumn returns the position of the column headed name, or the first column if name is empty.
+// It returns -1 if the header is empty or no column matches.
+func findIndexColumn(header []string, name string) int {
+	if len(header) == 0 {
+		return -1
+	}
+	if name == "" {
+		return 0
+	}
+	for i, column := range header {
+		if strings.TrimSpace(column) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// looksLikeIndexHeader reports whether header is one of indexHeaders once normalised.
+func looksLikeIndexHeader(header string) bool {
+	normalised := strings.ToLower(strings.TrimSpace(header))
+	normalised = strings.NewReplacer(" ", "", "_", "", "-", "", ".", "").Replace(normalised)
+	return indexHeaders[normalised]
+}
+
+// removeField returns a copy of record without the field at index, leaving short records untouched.
+func removeField(record []string, index int) []string {
+	if index < 0 || index >= len(record) {
+		return record
+	}
+	newRecord := make([]string, 0, len(record)-1)
+	newRecord = append(newRecord, record[:index]...)
+	return append(newRecord, record[index+1:]...)
+}
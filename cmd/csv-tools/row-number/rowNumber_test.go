@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "GoTools/pkg/helpers"
+)
+
+func TestProcessCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		opts    options
+		want    string
+		wantErr int
+	}{
+		{
+			name:  "Add",
+			input: "Name\nann\nbob\n",
+			opts:  options{Action: actionAdd, Name: "Row", Start: 1},
+			want:  "Row,Name\n1,ann\n2,bob\n",
+		},
+		{
+			name:  "AddFromZero",
+			input: "Name\nann\n",
+			opts:  options{Action: actionAdd, Name: "#", Start: 0},
+			want:  "#,Name\n0,ann\n",
+		},
+		{
+			name:  "StripFirst",
+			input: "Row,Name\n1,ann\n2,bob\n",
+			opts:  options{Action: actionStrip},
+			want:  "Name\nann\nbob\n",
+		},
+		{
+			name:  "StripFirstIndexHeader",
+			input: "Unnamed: 0,Name\n7,ann\n3,bob\n",
+			opts:  options{Action: actionStrip},
+			want:  "Name\nann\nbob\n",
+		},
+		{
+			name:  "StripFirstSequential",
+			input: "Key,Name\n0,ann\n1,bob\n",
+			opts:  options{Action: actionStrip},
+			want:  "Name\nann\nbob\n",
+		},
+		{
+			name:    "StripFirstNotSequential",
+			input:   "Age,Name\n30,ann\n25,bob\n",
+			opts:    options{Action: actionStrip},
+			want:    "Age,Name\n30,ann\n25,bob\n",
+			wantErr: ErrInvalidArgs,
+		},
+		{
+			name:    "StripFirstNotNumbers",
+			input:   "Name,Age\nann,30\n",
+			opts:    options{Action: actionStrip},
+			want:    "Name,Age\nann,30\n",
+			wantErr: ErrInvalidArgs,
+		},
+		{
+			name:  "StripNamed",
+			input: "Name,Row\nann,1\nbob\n",
+			opts:  options{Action: actionStrip, Name: "Row"},
+			want:  "Name\nann\nbob\n",
+		},
+		{
+			name:    "StripMissing",
+			input:   "Name\nann\n",
+			opts:    options{Action: actionStrip, Name: "Row"},
+			want:    "Name\nann\n",
+			wantErr: ErrInvalidArgs,
+		},
+		{
+			name:    "Malformed",
+			input:   "Name\n\"ann\n",
+			opts:    options{Action: actionAdd, Name: "Row", Start: 1},
+			want:    "Name\n\"ann\n",
+			wantErr: ErrParse,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data.csv")
+			if err := os.WriteFile(path, []byte(test.input), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			report := RunReport{}
+			if errMsg := processCSV(path, test.opts, &report); errMsg.Code != test.wantErr {
+				t.Fatalf("processCSV() = %v, want code %d", errMsg, test.wantErr)
+			}
+			if got, _ := os.ReadFile(path); string(got) != test.want {
+				t.Errorf("file = %q, want %q", got, test.want)
+			}
+			if rows := strings.Count(test.want, "\n") - 1; test.wantErr == 0 && (report.RowsRead != rows || report.RowsWritten != rows) {
+				t.Errorf("report rows read/written = %d/%d, want %d/%d", report.RowsRead, report.RowsWritten, rows, rows)
+			}
+		})
+	}
+}
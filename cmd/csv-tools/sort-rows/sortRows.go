@@ -0,0 +1,270 @@
+// This program sorts the data rows of a CSV file in place, optionally removing duplicate rows.
+// Files larger than --max-memory are sorted in chunks that are spilled to disk and merged,
+// so it works on machines with limited RAM and on files bigger than memory.
+package main
+
+import (
+	"cmp"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
+	"github.com/charmbracelet/log"
+)
+
+// options holds the command line settings controlling the sort.
+type options struct {
+	Columns   []string
+	Numeric   bool
+	Reverse   bool
+	Dedupe    bool
+	MaxMemory int64
+	TempDir   string
+	BOM       string
+}
+
+func main() {
+	startTime := time.Now()
+
+	processingErr := ErrMsg{Code: Success}
+	var report RunReport
+	reportFormatPtr := flag.String("report", "", "Write a run report in the given format (json)")
+	reportFilePtr := flag.String("report-file", "", "Write the run report to this file instead of stdout")
+	defer func() {
+		report.Finish(startTime, processingErr.Code)
+		if reportErr := WriteReport(report, *reportFormatPtr, *reportFilePtr); reportErr != nil {
+			log.Error("Failed to write run report", "error", reportErr)
+		}
+		log.Debug(
+			"DONE!",
+			"time", time.Since(startTime),
+		)
+		processingErr.Exit()
+	}()
+	var opts options
+	filePathPtr := flag.String("path", "", "CSV file path")
+	columnsPtr := flag.String("columns", "", "Comma-separated list of column headers to sort by (defaults to the whole row)")
+	maxMemoryPtr := flag.String("max-memory", "256MB", "Approximate memory limit before sorted chunks are spilled to disk (0 disables spilling)")
+	flag.BoolVar(&opts.Numeric, "numeric", false, "Compare sort keys numerically, sorting values that are not numbers lexically after the numbers")
+	flag.BoolVar(&opts.Reverse, "reverse", false, "Sort in descending order")
+	flag.BoolVar(&opts.Dedupe, "dedupe", false, "Remove duplicate rows while sorting")
+	flag.StringVar(&opts.BOM, "bom", BOMPreserve, "How to handle a UTF-8 byte order mark: 'preserve' keeps the one the file has, 'strip' removes it, 'add' writes one (as Excel expects)")
+	flag.StringVar(&opts.TempDir, "temp-dir", "", "Directory for spill files (defaults to the OS temp directory)")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if logErr := logging.Setup(); logErr != nil {
+		processingErr = ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+		return
+	}
+
+	if !ValidReportFormat(*reportFormatPtr) {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("unsupported report format '%s'", *reportFormatPtr),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if !ValidBOMMode(opts.BOM) {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("unknown --bom '%s', expected one of %s", opts.BOM, strings.Join(BOMModes, ", ")),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	maxMemory, sizeErr := ParseByteSize(*maxMemoryPtr)
+	if sizeErr != nil {
+		processingErr = ErrMsg{Err: sizeErr, Code: ErrInvalidArgs}
+		return
+	}
+	opts.MaxMemory = maxMemory
+	for _, column := range strings.Split(*columnsPtr, ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			opts.Columns = append(opts.Columns, column)
+		}
+	}
+	path, inputErr := ResolveFile(*filePathPtr, flag.Args())
+	if inputErr != nil {
+		processingErr = ToErrMsg(inputErr, ErrNoInput)
+		return
+	}
+	processingErr = processCSV(path, opts, &report)
+}
+
+func processCSV(path string, opts options, report *RunReport) ErrMsg {
+	report.File = path
+	if exists, _ := PathExists(path); !exists {
+		return ErrMsg{Err: fmt.Errorf("file '%s' does not exist", path), Code: ErrNoFile}
+	}
+	if !CheckExtension(path, ".csv") {
+		return ErrMsg{
+			Err:  fmt.Errorf("file '%s' is not a CSV file", path),
+			Code: ErrInvalidFileType,
+		}
+	}
+	if ioErr := readWriteCsv(path, opts, report); ioErr != nil {
+		return *ioErr
+	}
+	log.Info("Successfully amended file", "file", path)
+	return ErrMsg{Code: Success}
+}
+
+// readWriteCsv rewrites the CSV file at path through an AtomicFile, so the file is left as it was if reading
+// or writing fails part way through.
+func readWriteCsv(path string, opts options, report *RunReport) (errMsg *ErrMsg) {
+	originalCsv, readErr := os.Open(path)
+	if readErr != nil {
+		return &ErrMsg{Err: readErr, Code: ErrReadFile}
+	}
+	defer func(originalCsv *os.File) {
+		err := originalCsv.Close()
+		if err != nil {
+			log.Error(err)
+		}
+	}(originalCsv)
+	output, createErr := CreateAtomic(path)
+	if createErr != nil {
+		return &ErrMsg{Err: createErr, Code: ErrWriteFile}
+	}
+	defer func() {
+		if errMsg != nil {
+			output.Abort()
+		}
+	}()
+
+	input, hadBOM, bomErr := SkipBOM(originalCsv)
+	if bomErr != nil {
+		return &ErrMsg{Err: bomErr, Code: ErrReadFile}
+	}
+	if bomErr = WriteBOM(output, opts.BOM, hadBOM); bomErr != nil {
+		return &ErrMsg{Err: bomErr, Code: ErrWriteFile}
+	}
+	reader := csv.NewReader(input)
+	reader.FieldsPerRecord = -1
+	writer := csv.NewWriter(output)
+
+	header, headerErr := reader.Read()
+	if headerErr != nil {
+		return &ErrMsg{Err: headerErr, Code: ErrReadFile}
+	}
+	less, keyErr := buildComparator(header, opts)
+	if keyErr != nil {
+		return &ErrMsg{Err: keyErr, Code: ErrInvalidArgs}
+	}
+	if writeErr := writer.Write(header); writeErr != nil {
+		return &ErrMsg{Err: writeErr, Code: ErrReadWrite}
+	}
+
+	sorter := &chunkSorter{less: less, maxMemory: opts.MaxMemory, tempDir: opts.TempDir}
+	defer sorter.Cleanup()
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return &ErrMsg{Err: err, Code: ErrParse}
+		}
+		report.RowsRead++
+		if addErr := sorter.Add(record); addErr != nil {
+			return &ErrMsg{Err: addErr, Code: ErrWriteFile}
+		}
+	}
+
+	var previous []string
+	duplicates := 0
+	eachErr := sorter.Each(func(record []string) error {
+		if opts.Dedupe && previous != nil && slices.Equal(previous, record) {
+			duplicates++
+			return nil
+		}
+		previous = record
+		report.RowsWritten++
+		return writer.Write(record)
+	})
+	if eachErr != nil {
+		return &ErrMsg{Err: eachErr, Code: ErrReadWrite}
+	}
+	writer.Flush()
+	if flushErr := writer.Error(); flushErr != nil {
+		return &ErrMsg{Err: flushErr, Code: ErrWriteFile}
+	}
+	log.Info(
+		"Sorted rows successfully",
+		"rows", report.RowsRead,
+		"spills", len(sorter.spills),
+		"duplicates removed", duplicates,
+	)
+	if commitErr := output.Commit(); commitErr != nil {
+		return &ErrMsg{Err: commitErr, Code: ErrWriteFile}
+	}
+	return nil
+}
+
+// buildComparator returns an ordering over records using the requested key columns.
+// Ties on the keys are broken by comparing the whole record, so identical rows always end up adjacent.
+func buildComparator(header []string, opts options) (func(a, b []string) bool, error) {
+	var keys []int
+	for _, column := range opts.Columns {
+		index := slices.IndexFunc(header, func(name string) bool { return strings.TrimSpace(name) == column })
+		if index < 0 {
+			return nil, fmt.Errorf("column '%s' not found", column)
+		}
+		keys = append(keys, index)
+	}
+	less := func(a, b []string) bool {
+		for _, key := range keys {
+			if cmp := compareFields(field(a, key), field(b, key), opts.Numeric); cmp != 0 {
+				return (cmp < 0) != opts.Reverse
+			}
+		}
+		if cmp := slices.Compare(a, b); cmp != 0 {
+			return (cmp < 0) != opts.Reverse
+		}
+		return false
+	}
+	return less, nil
+}
+
+// field returns the value at index, or an empty string for short records.
+func field(record []string, index int) string {
+	if index < len(record) {
+		return record[index]
+	}
+	return ""
+}
+
+// compareFields compares two values lexically, or numerically if requested. Comparing numerically, the values
+// that are not numbers form one group after the numbers and are compared lexically among themselves,
+// so the order is the same whichever rows are compared first.
+func compareFields(a, b string, numeric bool) int {
+	if numeric {
+		aNum, aOK := parseNumber(a)
+		bNum, bOK := parseNumber(b)
+		switch {
+		case aOK && bOK:
+			return cmp.Compare(aNum, bNum)
+		case aOK:
+			return -1
+		case bOK:
+			return 1
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// parseNumber returns the number value holds, ignoring surrounding spaces; NaN does not count as a number,
+// as it is not ordered.
+func parseNumber(value string) (float64, bool) {
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	return number, err == nil && !math.IsNaN(number)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	. "GoTools/pkg/helpers"
+)
+
+func TestProcessCSV(t *testing.T) {
+	const input = "Name,Score\nbob,10\nann,9\ncy,100\nann,9\n"
+	tests := []struct {
+		name    string
+		opts    options
+		want    string
+		wantErr int
+	}{
+		{
+			name: "WholeRow",
+			want: "Name,Score\nann,9\nann,9\nbob,10\ncy,100\n",
+		},
+		{
+			name: "Lexical",
+			opts: options{Columns: []string{"Score"}},
+			want: "Name,Score\nbob,10\ncy,100\nann,9\nann,9\n",
+		},
+		{
+			name: "Numeric",
+			opts: options{Columns: []string{"Score"}, Numeric: true},
+			want: "Name,Score\nann,9\nann,9\nbob,10\ncy,100\n",
+		},
+		{
+			name: "ReverseDedupe",
+			opts: options{Columns: []string{"Score"}, Numeric: true, Reverse: true, Dedupe: true},
+			want: "Name,Score\ncy,100\nbob,10\nann,9\n",
+		},
+		{
+			name: "Spilled",
+			opts: options{Columns: []string{"Name"}, MaxMemory: 1, Dedupe: true},
+			want: "Name,Score\nann,9\nbob,10\ncy,100\n",
+		},
+		{
+			name:    "UnknownColumn",
+			opts:    options{Columns: []string{"Age"}},
+			want:    input,
+			wantErr: ErrInvalidArgs,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "data.csv")
+			if err := os.WriteFile(path, []byte(input), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			test.opts.TempDir = dir
+			if errMsg := processCSV(path, test.opts, &RunReport{}); errMsg.Code != test.wantErr {
+				t.Fatalf("processCSV() = %v, want code %d", errMsg, test.wantErr)
+			}
+			if got, _ := os.ReadFile(path); string(got) != test.want {
+				t.Errorf("sorted file = %q, want %q", got, test.want)
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+				t.Errorf("%d files left in the directory, want only the CSV file", len(entries))
+			}
+		})
+	}
+}
+
+func TestCompareFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		numeric bool
+		values  []string
+		want    []string
+	}{
+		{
+			name:   "Lexical",
+			values: []string{"10", "b", "2", "a"},
+			want:   []string{"10", "2", "a", "b"},
+		},
+		{
+			name:    "Numeric",
+			numeric: true,
+			values:  []string{"10", " 3", "2.5", "-1"},
+			want:    []string{"-1", "2.5", " 3", "10"},
+		},
+		{
+			name:    "NumericMixed",
+			numeric: true,
+			values:  []string{"b", "10", "NaN", "x1", "2", "", "a"},
+			want:    []string{"2", "10", "", "NaN", "a", "b", "x1"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := slices.Clone(test.values)
+			slices.SortFunc(got, func(a, b string) int {
+				return compareFields(a, b, test.numeric)
+			})
+			if !slices.Equal(got, test.want) {
+				t.Errorf("sorted = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
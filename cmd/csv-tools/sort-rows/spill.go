@@ -0,0 +1,189 @@
+package main
+
+import (
+	"container/heap"
+	"encoding/csv"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/charmbracelet/log"
+)
+
+// recordOverhead approximates the bookkeeping cost of holding a single field in memory,
+// on top of the bytes of the field itself.
+const recordOverhead = 16
+
+// chunkSorter accumulates records in memory and spills them to sorted temporary CSV files
+// whenever the estimated size exceeds maxMemory. A maxMemory of 0 disables spilling.
+type chunkSorter struct {
+	less       func(a, b []string) bool
+	maxMemory  int64
+	tempDir    string
+	records    [][]string
+	usedMemory int64
+	spills     []string
+}
+
+// recordSize estimates the memory used by a record.
+func recordSize(record []string) int64 {
+	size := int64(recordOverhead)
+	for _, field := range record {
+		size += int64(len(field)) + recordOverhead
+	}
+	return size
+}
+
+// Add buffers a record, spilling the current chunk to disk first if it would exceed the memory limit.
+func (c *chunkSorter) Add(record []string) error {
+	size := recordSize(record)
+	if c.maxMemory > 0 && c.usedMemory+size > c.maxMemory && len(c.records) > 0 {
+		if err := c.spill(); err != nil {
+			return err
+		}
+	}
+	c.records = append(c.records, record)
+	c.usedMemory += size
+	return nil
+}
+
+// spill sorts the buffered records and writes them to a new temporary file.
+func (c *chunkSorter) spill() error {
+	sort.Slice(c.records, func(i, j int) bool { return c.less(c.records[i], c.records[j]) })
+	spillFile, createErr := os.CreateTemp(c.tempDir, "sort-rows-*.csv")
+	if createErr != nil {
+		return createErr
+	}
+	writer := csv.NewWriter(spillFile)
+	if writeErr := writer.WriteAll(c.records); writeErr != nil {
+		_ = spillFile.Close()
+		return writeErr
+	}
+	if closeErr := spillFile.Close(); closeErr != nil {
+		return closeErr
+	}
+	log.Debug("Spilled sorted chunk to disk", "file", spillFile.Name(), "records", len(c.records))
+	c.spills = append(c.spills, spillFile.Name())
+	c.records = nil
+	c.usedMemory = 0
+	return nil
+}
+
+// Each calls fn with every buffered record in sorted order, merging any spill files with the in-memory chunk.
+func (c *chunkSorter) Each(fn func(record []string) error) error {
+	sort.Slice(c.records, func(i, j int) bool { return c.less(c.records[i], c.records[j]) })
+	if len(c.spills) == 0 {
+		for _, record := range c.records {
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	merge := &mergeHeap{less: c.less}
+	var files []*os.File
+	defer func() {
+		for _, file := range files {
+			if err := file.Close(); err != nil {
+				log.Error(err)
+			}
+		}
+	}()
+	for _, spill := range c.spills {
+		file, openErr := os.Open(spill)
+		if openErr != nil {
+			return openErr
+		}
+		files = append(files, file)
+		source := &mergeSource{reader: csv.NewReader(file)}
+		source.reader.FieldsPerRecord = -1
+		if err := merge.push(source); err != nil {
+			return err
+		}
+	}
+	if len(c.records) > 0 {
+		if err := merge.push(&mergeSource{records: c.records}); err != nil {
+			return err
+		}
+	}
+	for merge.Len() > 0 {
+		source := merge.sources[0]
+		if err := fn(source.current); err != nil {
+			return err
+		}
+		more, nextErr := source.next()
+		if nextErr != nil {
+			return nextErr
+		}
+		if more {
+			heap.Fix(merge, 0)
+		} else {
+			heap.Pop(merge)
+		}
+	}
+	return nil
+}
+
+// Cleanup removes any spill files created while sorting.
+func (c *chunkSorter) Cleanup() {
+	for _, spill := range c.spills {
+		if err := os.Remove(spill); err != nil {
+			log.Error("Failed to remove spill file", "file", spill, "error", err)
+		}
+	}
+	c.spills = nil
+}
+
+// mergeSource yields sorted records either from a spill file or from an in-memory chunk.
+type mergeSource struct {
+	reader  *csv.Reader
+	records [][]string
+	current []string
+}
+
+// next advances the source, returning false once it is exhausted.
+func (s *mergeSource) next() (bool, error) {
+	if s.reader == nil {
+		if len(s.records) == 0 {
+			return false, nil
+		}
+		s.current, s.records = s.records[0], s.records[1:]
+		return true, nil
+	}
+	record, err := s.reader.Read()
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	s.current = record
+	return true, nil
+}
+
+// mergeHeap orders merge sources by their current record, implementing heap.Interface.
+type mergeHeap struct {
+	less    func(a, b []string) bool
+	sources []*mergeSource
+}
+
+func (h *mergeHeap) Len() int           { return len(h.sources) }
+func (h *mergeHeap) Less(i, j int) bool { return h.less(h.sources[i].current, h.sources[j].current) }
+func (h *mergeHeap) Swap(i, j int)      { h.sources[i], h.sources[j] = h.sources[j], h.sources[i] }
+func (h *mergeHeap) Push(x any)         { h.sources = append(h.sources, x.(*mergeSource)) }
+func (h *mergeHeap) Pop() any {
+	last := h.sources[len(h.sources)-1]
+	h.sources = h.sources[:len(h.sources)-1]
+	return last
+}
+
+// push primes a source with its first record and adds it to the heap if it is not empty.
+func (h *mergeHeap) push(source *mergeSource) error {
+	more, err := source.next()
+	if err != nil || !more {
+		return err
+	}
+	heap.Push(h, source)
+	return nil
+}
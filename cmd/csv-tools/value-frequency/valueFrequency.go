@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
+	"github.com/charmbracelet/log"
+)
+
+// valueCount is a single distinct value of a column along with the number of times it occurred.
+type valueCount struct {
+	Value string
+	Count int
+}
+
+func main() {
+	startTime := time.Now()
+
+	processingErr := ErrMsg{Code: Success}
+	var report RunReport
+	reportFormatPtr := flag.String("report", "", "Write a run report in the given format (json)")
+	reportFilePtr := flag.String("report-file", "", "Write the run report to this file (required with --report)")
+	defer func() {
+		report.Finish(startTime, processingErr.Code)
+		if reportErr := WriteReport(report, *reportFormatPtr, *reportFilePtr); reportErr != nil {
+			log.Error("Failed to write run report", "error", reportErr)
+		}
+		log.Debug(
+			"DONE!",
+			"time", time.Since(startTime),
+		)
+		processingErr.Exit()
+	}()
+	filePathPtr := flag.String("path", "", "CSV file path")
+	columnsPtr := flag.String("columns", "", "Comma-separated list of column headers to count values for")
+	limitPtr := flag.Int("limit", 0, "Only print the N most frequent values per column (0 prints all)")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if logErr := logging.Setup(); logErr != nil {
+		processingErr = ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+		return
+	}
+
+	if !ValidReportFormat(*reportFormatPtr) {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("unsupported report format '%s'", *reportFormatPtr),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if len(*reportFormatPtr) > 0 && (*reportFilePtr == "" || *reportFilePtr == "-") {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("frequencies are written to stdout, so --report needs --report-file"),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	columns := splitColumns(*columnsPtr)
+	if len(columns) == 0 {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("no columns provided via --columns flag"),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	path, inputErr := ResolveFile(*filePathPtr, flag.Args())
+	if inputErr != nil {
+		processingErr = ToErrMsg(inputErr, ErrNoInput)
+		return
+	}
+	processingErr = processCSV(path, columns, *limitPtr, &report)
+}
+
+// splitColumns splits a comma-separated list of column headers, discarding surrounding whitespace and blanks.
+func splitColumns(list string) []string {
+	var columns []string
+	for _, column := range strings.Split(list, ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+func processCSV(path string, columns []string, limit int, report *RunReport) ErrMsg {
+	report.File = path
+	if exists, _ := PathExists(path); !exists {
+		return ErrMsg{Err: fmt.Errorf("file '%s' does not exist", path), Code: ErrNoFile}
+	}
+	if !CheckExtension(path, ".csv") {
+		return ErrMsg{
+			Err:  fmt.Errorf("file '%s' is not a CSV file", path),
+			Code: ErrInvalidFileType,
+		}
+	}
+	frequencies, countErr := countValues(path, columns, report)
+	if countErr != nil {
+		return *countErr
+	}
+	writer := bufio.NewWriter(os.Stdout)
+	for _, column := range columns {
+		printFrequencies(writer, column, frequencies[column], limit)
+	}
+	if flushErr := writer.Flush(); flushErr != nil {
+		return ErrMsg{Err: flushErr, Code: ErrStdout}
+	}
+	return ErrMsg{Code: Success}
+}
+
+// countValues reads the CSV file at path and tallies the distinct values of each requested column.
+// The returned map is keyed by column header, and each slice is ordered by descending count, then by value.
+func countValues(path string, columns []string, report *RunReport) (map[string][]valueCount, *ErrMsg) {
+	csvFile, readErr := os.Open(path)
+	if readErr != nil {
+		return nil, &ErrMsg{Err: readErr, Code: ErrReadFile}
+	}
+	defer func(csvFile *os.File) {
+		err := csvFile.Close()
+		if err != nil {
+			log.Error(err)
+		}
+	}(csvFile)
+
+	reader := csv.NewReader(csvFile)
+	reader.FieldsPerRecord = -1
+	header, headerErr := reader.Read()
+	if headerErr != nil {
+		return nil, &ErrMsg{Err: headerErr, Code: ErrReadFile}
+	}
+	indexes := make(map[string]int, len(columns))
+	for _, column := range columns {
+		index := indexOf(header, column)
+		if index < 0 {
+			return nil, &ErrMsg{
+				Err:  fmt.Errorf("column '%s' not found in '%s'", column, path),
+				Code: ErrInvalidArgs,
+			}
+		}
+		indexes[column] = index
+	}
+
+	counts := make(map[string]map[string]int, len(columns))
+	for _, column := range columns {
+		counts[column] = make(map[string]int)
+	}
+	lineCount := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, &ErrMsg{Err: err, Code: ErrParse}
+		}
+		for column, index := range indexes {
+			var value string
+			if index < len(record) {
+				value = record[index]
+			}
+			counts[column][value]++
+		}
+		report.RowsRead++
+		lineCount++
+	}
+	log.Info("Counted values successfully", "file", path, "lines", lineCount)
+
+	frequencies := make(map[string][]valueCount, len(columns))
+	for column, valueCounts := range counts {
+		sorted := make([]valueCount, 0, len(valueCounts))
+		for value, count := range valueCounts {
+			sorted = append(sorted, valueCount{Value: value, Count: count})
+		}
+		sort.Slice(sorted, func(i, j int) bool {
+			if sorted[i].Count != sorted[j].Count {
+				return sorted[i].Count > sorted[j].Count
+			}
+			return sorted[i].Value < sorted[j].Value
+		})
+		frequencies[column] = sorted
+	}
+	return frequencies, nil
+}
+
+// indexOf returns the index of the first header matching column, or -1 if there is none.
+func indexOf(header []string, column string) int {
+	for i, name := range header {
+		if strings.TrimSpace(name) == column {
+			return i
+		}
+	}
+	return -1
+}
+
+// printFrequencies writes the distinct values of a column and their counts, limited to the top N if limit > 0.
+func printFrequencies(writer io.Writer, column string, frequencies []valueCount, limit int) {
+	_, _ = fmt.Fprintf(writer, "%s (%d distinct)\n", column, len(frequencies))
+	if limit > 0 && limit < len(frequencies) {
+		frequencies = frequencies[:limit]
+	}
+	for _, frequency := range frequencies {
+		value := frequency.Value
+		if value == "" {
+			value = "(blank)"
+		}
+		_, _ = fmt.Fprintf(writer, "\t%d\t%s\n", frequency.Count, value)
+	}
+}
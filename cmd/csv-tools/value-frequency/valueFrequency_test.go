@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	. "GoTools/pkg/helpers"
+)
+
+func TestSplitColumns(t *testing.T) {
+	tests := []struct {
+		list string
+		want []string
+	}{
+		{"", nil},
+		{"a", []string{"a"}},
+		{" a , b ,,c ", []string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		if got := splitColumns(tt.list); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitColumns(%q) = %q, want %q", tt.list, got, tt.want)
+		}
+	}
+}
+
+func TestCountValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		columns []string
+		want    map[string][]valueCount
+		wantErr int
+	}{
+		{
+			name:    "OrderedByCountThenValue",
+			input:   "Name,City\nann,Oslo\nbob,Rome\ncat,Oslo\ndan,Bern\n",
+			columns: []string{"City"},
+			want:    map[string][]valueCount{"City": {{"Oslo", 2}, {"Bern", 1}, {"Rome", 1}}},
+		},
+		{
+			name:    "ShortRowsCountAsBlank",
+			input:   "Name,City\nann,Oslo\nbob\n",
+			columns: []string{"City", "Name"},
+			want: map[string][]valueCount{
+				"City": {{"", 1}, {"Oslo", 1}},
+				"Name": {{"ann", 1}, {"bob", 1}},
+			},
+		},
+		{
+			name:    "HeaderWhitespaceIgnored",
+			input:   " City \nOslo\n",
+			columns: []string{"City"},
+			want:    map[string][]valueCount{"City": {{"Oslo", 1}}},
+		},
+		{
+			name:    "MissingColumn",
+			input:   "Name\nann\n",
+			columns: []string{"City"},
+			wantErr: ErrInvalidArgs,
+		},
+		{
+			name:    "Empty",
+			input:   "",
+			columns: []string{"City"},
+			wantErr: ErrReadFile,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "in.csv")
+			if err := os.WriteFile(path, []byte(tt.input), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, errMsg := countValues(path, tt.columns, &RunReport{})
+			if tt.wantErr != 0 {
+				if errMsg == nil || errMsg.Code != tt.wantErr {
+					t.Fatalf("countValues() error = %v, want code %d", errMsg, tt.wantErr)
+				}
+				return
+			}
+			if errMsg != nil {
+				t.Fatalf("countValues() error = %v", errMsg)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("countValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintFrequencies(t *testing.T) {
+	var buf bytes.Buffer
+	printFrequencies(&buf, "City", []valueCount{{"Oslo", 2}, {"", 1}, {"Rome", 1}}, 2)
+	want := "City (3 distinct)\n\t2\tOslo\n\t1\t(blank)\n"
+	if buf.String() != want {
+		t.Errorf("printFrequencies() = %q, want %q", buf.String(), want)
+	}
+}
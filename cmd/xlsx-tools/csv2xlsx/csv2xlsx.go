@@ -0,0 +1,192 @@
+// This program converts CSV files into a .xlsx workbook with one worksheet per file, named after the file.
+// The sheets are formatted for reading: a bold, frozen header row, columns sized to their contents and
+// numbers and dates stored as typed cells, and optionally an Excel table. Each of these can be turned off.
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
+	"GoTools/pkg/xlsxwriter"
+	"github.com/charmbracelet/log"
+	"github.com/xuri/excelize/v2"
+)
+
+// options holds the command line settings controlling the conversion.
+type options struct {
+	Inputs    []string
+	Output    string
+	Force     bool
+	Delimiter rune
+	Format    xlsxwriter.Options
+}
+
+func main() {
+	startTime := time.Now()
+
+	processingErr := ErrMsg{Code: Success}
+	defer func() {
+		log.Debug(
+			"DONE!",
+			"time", time.Since(startTime),
+		)
+		processingErr.Exit()
+	}()
+	opts, argErr := getInput()
+	if argErr != nil {
+		processingErr = *argErr
+		return
+	}
+	processingErr = convertFiles(opts)
+}
+
+// getInput parses the command line flags. The CSV files are given with --path or as arguments,
+// falling back to paths piped through standard input.
+func getInput() (options, *ErrMsg) {
+	opts := options{Format: xlsxwriter.DefaultOptions()}
+	var filePath, delimiter string
+	flag.StringVar(&filePath, "path", "", "The path to the CSV file to convert")
+	flag.StringVar(&opts.Output, "output", "", "The path of the workbook to write (defaults to the first CSV file's path with a .xlsx extension)")
+	flag.StringVar(&opts.Output, "o", "", "Shorthand for --output")
+	flag.BoolVar(&opts.Force, "force", false, "Overwrite the output workbook if it already exists")
+	flag.StringVar(&delimiter, "delimiter", ",", "The field delimiter of the CSV files (use \\t for tabs)")
+	flag.BoolVar(&opts.Format.BoldHeader, "bold-header", opts.Format.BoldHeader, "Write the header row in bold")
+	flag.BoolVar(&opts.Format.FreezeHeader, "freeze-header", opts.Format.FreezeHeader, "Freeze the header row so it stays visible while scrolling")
+	flag.BoolVar(&opts.Format.AutoWidth, "auto-width", opts.Format.AutoWidth, "Size each column to fit its contents")
+	flag.BoolVar(&opts.Format.InferTypes, "infer-types", opts.Format.InferTypes, "Write numbers, booleans and dates as typed cells rather than text")
+	flag.BoolVar(&opts.Format.Table, "table", opts.Format.Table, "Format each sheet as an Excel table with filter buttons")
+	flag.StringVar(&opts.Format.TableStyle, "table-style", opts.Format.TableStyle, "The Excel table style used by --table")
+	flag.StringVar(&opts.Format.DateFormat, "date-format", opts.Format.DateFormat, "The Excel number format of inferred dates")
+	flag.StringVar(&opts.Format.DateTimeFormat, "datetime-format", opts.Format.DateTimeFormat, "The Excel number format of inferred dates with a time of day")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if logErr := logging.Setup(); logErr != nil {
+		return opts, &ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+	}
+
+	if delimiter == `\t` {
+		delimiter = "\t"
+	}
+	if utf8.RuneCountInString(delimiter) != 1 {
+		return opts, &ErrMsg{Err: fmt.Errorf("delimiter must be a single character, got '%s'", delimiter), Code: ErrInvalidArgs}
+	}
+	opts.Delimiter, _ = utf8.DecodeRuneInString(delimiter)
+
+	inputs, inputErr := ResolveInputs(filePath, flag.Args())
+	if inputErr != nil {
+		errMsg := ToErrMsg(inputErr, ErrNoInput)
+		return opts, &errMsg
+	}
+	for _, input := range inputs {
+		// Each file's name names its sheet, and the first names the workbook, so standard input has no place here
+		if input.IsStdin() {
+			return opts, &ErrMsg{Err: errors.New("CSV data cannot be read from standard input, give the path of a file"), Code: ErrInvalidArgs}
+		}
+		opts.Inputs = append(opts.Inputs, input.Path)
+	}
+	if len(opts.Output) == 0 {
+		opts.Output = strings.TrimSuffix(opts.Inputs[0], filepath.Ext(opts.Inputs[0])) + ".xlsx"
+	}
+	return opts, nil
+}
+
+func convertFiles(opts options) ErrMsg {
+	for _, input := range opts.Inputs {
+		if exists, _ := PathExists(input); !exists {
+			return ErrMsg{Err: fmt.Errorf("file '%s' does not exist", input), Code: ErrNoFile}
+		}
+		if !CheckExtension(input, ".csv") && !CheckExtension(input, ".tsv") && !CheckExtension(input, ".txt") {
+			return ErrMsg{Err: fmt.Errorf("file '%s' is not a CSV file", input), Code: ErrInvalidFileType}
+		}
+	}
+	if exists, _ := PathExists(opts.Output); exists && !opts.Force {
+		return ErrMsg{Err: fmt.Errorf("output file '%s' already exists, use --force to overwrite it", opts.Output), Code: ErrWriteFile}
+	}
+
+	file := excelize.NewFile()
+	defer func(file *excelize.File) {
+		_ = file.Close()
+	}(file)
+	var used []string
+	for index, input := range opts.Inputs {
+		sheet := xlsxwriter.SheetName(strings.TrimSuffix(filepath.Base(input), filepath.Ext(input)), used)
+		used = append(used, sheet)
+		if index == 0 {
+			if err := file.SetSheetName(file.GetSheetName(0), sheet); err != nil {
+				return ErrMsg{Err: err, Code: ErrWriteFile}
+			}
+		} else if _, err := file.NewSheet(sheet); err != nil {
+			return ErrMsg{Err: err, Code: ErrWriteFile}
+		}
+		rowCount, convertErr := convertFile(file, sheet, input, opts)
+		if convertErr != nil {
+			var parseErr *csv.ParseError
+			if errors.As(convertErr, &parseErr) {
+				return ErrMsg{Err: fmt.Errorf("%s: %w", input, convertErr), Code: ErrParse}
+			}
+			return ErrMsg{Err: fmt.Errorf("%s: %w", input, convertErr), Code: ErrReadWrite}
+		}
+		log.Info("Wrote sheet", "file", input, "sheet", sheet, "rows", rowCount)
+	}
+	if saveErr := file.SaveAs(opts.Output); saveErr != nil {
+		return ErrMsg{Err: saveErr, Code: ErrWriteFile}
+	}
+	log.Info("Wrote workbook", "file", opts.Output)
+	return ErrMsg{Code: Success}
+}
+
+// convertFile writes the records of a CSV file to a worksheet, treating the first record as the header,
+// and returns the number of data rows written.
+func convertFile(file *excelize.File, sheet, path string, opts options) (int, error) {
+	csvFile, openErr := os.Open(path)
+	if openErr != nil {
+		return 0, openErr
+	}
+	defer func(csvFile *os.File) {
+		_ = csvFile.Close()
+	}(csvFile)
+
+	reader := csv.NewReader(bufio.NewReader(csvFile))
+	reader.Comma = opts.Delimiter
+	reader.FieldsPerRecord = -1
+	writer, writerErr := xlsxwriter.NewSheetWriter(file, sheet, opts.Format)
+	if writerErr != nil {
+		return 0, writerErr
+	}
+	rowCount := 0
+	headerWritten := false
+	for {
+		record, readErr := reader.Read()
+		if errors.Is(readErr, io.EOF) {
+			break
+		} else if readErr != nil {
+			return rowCount, readErr
+		}
+		if !headerWritten {
+			// Strip the byte order mark Excel writes at the start of UTF-8 CSV files.
+			record[0] = strings.TrimPrefix(record[0], "\uFEFF")
+			if err := writer.WriteHeader(record); err != nil {
+				return rowCount, err
+			}
+			headerWritten = true
+			continue
+		}
+		if err := writer.WriteRow(record); err != nil {
+			return rowCount, err
+		}
+		rowCount++
+	}
+	return rowCount, writer.Close()
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/xlsxwriter"
+	"github.com/xuri/excelize/v2"
+)
+
+// writeTestFile writes content to dir/name, creating dir if needed, and returns its path.
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readWorkbook returns the rows of every sheet of the workbook at path, keyed by sheet name.
+func readWorkbook(t *testing.T, path string) map[string][][]string {
+	t.Helper()
+	file, err := excelize.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	sheets := make(map[string][][]string)
+	for _, sheet := range file.GetSheetList() {
+		if sheets[sheet], err = file.GetRows(sheet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return sheets
+}
+
+func TestConvertFiles(t *testing.T) {
+	dir := t.TempDir()
+	people := writeTestFile(t, dir, "people.csv", "\ufeffName,Age\nann,30\nbob\n")
+	otherPeople := writeTestFile(t, filepath.Join(dir, "other"), "people.csv", "Name\ncy\n")
+	tabbed := writeTestFile(t, dir, "tabbed.tsv", "Name\tCity\nann\tOslo, NO\n")
+	malformed := writeTestFile(t, dir, "malformed.csv", "Name\n\"ann\n")
+	text := writeTestFile(t, dir, "notes.md", "Name\nann\n")
+	tests := []struct {
+		name      string
+		inputs    []string
+		delimiter rune
+		want      map[string][][]string
+		wantCode  int
+	}{
+		{
+			name:     "Single File",
+			inputs:   []string{people},
+			want:     map[string][][]string{"people": {{"Name", "Age"}, {"ann", "30"}, {"bob"}}},
+			wantCode: Success,
+		},
+		{
+			name:   "Same Base Name",
+			inputs: []string{people, otherPeople},
+			want: map[string][][]string{
+				"people":   {{"Name", "Age"}, {"ann", "30"}, {"bob"}},
+				"people_2": {{"Name"}, {"cy"}},
+			},
+			wantCode: Success,
+		},
+		{
+			name:      "Delimiter",
+			inputs:    []string{tabbed},
+			delimiter: '\t',
+			want:      map[string][][]string{"tabbed": {{"Name", "City"}, {"ann", "Oslo, NO"}}},
+			wantCode:  Success,
+		},
+		{
+			name:     "Malformed",
+			inputs:   []string{malformed},
+			wantCode: ErrParse,
+		},
+		{
+			name:     "Not A CSV File",
+			inputs:   []string{text},
+			wantCode: ErrInvalidFileType,
+		},
+		{
+			name:     "Missing File",
+			inputs:   []string{filepath.Join(dir, "missing.csv")},
+			wantCode: ErrNoFile,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := options{
+				Inputs:    tt.inputs,
+				Output:    filepath.Join(t.TempDir(), "book.xlsx"),
+				Delimiter: ',',
+				Format:    xlsxwriter.DefaultOptions(),
+			}
+			if tt.delimiter != 0 {
+				opts.Delimiter = tt.delimiter
+			}
+			if got := convertFiles(opts); got.Code != tt.wantCode {
+				t.Fatalf("convertFiles() = %v, want code %d", got, tt.wantCode)
+			}
+			if tt.wantCode != Success {
+				if exists, _ := PathExists(opts.Output); exists {
+					t.Errorf("convertFiles() failed but wrote %s", opts.Output)
+				}
+				return
+			}
+			if got := readWorkbook(t, opts.Output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("workbook = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertFilesExistingOutput(t *testing.T) {
+	dir := t.TempDir()
+	input := writeTestFile(t, dir, "people.csv", "Name\nann\n")
+	output := writeTestFile(t, dir, "people.xlsx", "keep")
+	opts := options{Inputs: []string{input}, Output: output, Delimiter: ',', Format: xlsxwriter.DefaultOptions()}
+	if got := convertFiles(opts); got.Code != ErrWriteFile {
+		t.Fatalf("convertFiles() = %v, want code %d", got, ErrWriteFile)
+	}
+	opts.Force = true
+	if got := convertFiles(opts); got.Code != Success {
+		t.Fatalf("convertFiles() with Force = %v, want success", got)
+	}
+	if got := readWorkbook(t, output); !reflect.DeepEqual(got, map[string][][]string{"people": {{"Name"}, {"ann"}}}) {
+		t.Errorf("workbook = %q", got)
+	}
+}
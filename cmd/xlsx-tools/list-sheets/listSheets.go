@@ -0,0 +1,191 @@
+// This program prints the worksheets of a .xlsx file along with their visibility, dimensions and row counts,
+// so scripts can discover which sheet to pass to parse-xml's --sheet flag.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
+	"github.com/xuri/excelize/v2"
+)
+
+// SheetInfo describes a single worksheet of a workbook.
+type SheetInfo struct {
+	Index      int    `json:"index"`
+	Name       string `json:"name"`
+	Visibility string `json:"visibility"`
+	Dimension  string `json:"dimension"`
+	Rows       int    `json:"rows"`
+	Active     bool   `json:"active"`
+}
+
+func main() {
+	processingErr := ErrMsg{Code: Success}
+	defer func() {
+		processingErr.Exit()
+	}()
+	var filePath string
+	var asJSON bool
+	flag.StringVar(&filePath, "path", "", "The path to the .xlsx file to inspect")
+	flag.BoolVar(&asJSON, "json", false, "Print the sheet list as JSON")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if logErr := logging.Setup(); logErr != nil {
+		processingErr = ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+		return
+	}
+
+	path, inputErr := ResolveFile(filePath, flag.Args())
+	if inputErr != nil {
+		processingErr = ToErrMsg(inputErr, ErrNoInput)
+		return
+	}
+	filePath = path
+	if exists, _ := PathExists(filePath); !exists {
+		processingErr = ErrMsg{Err: fmt.Errorf("file '%s' does not exist", filePath), Code: ErrNoFile}
+		return
+	}
+	if !IsWorkbookFile(filePath, false) {
+		processingErr = ErrMsg{Err: fmt.Errorf("file '%s' is not an Excel workbook", filePath), Code: ErrInvalidFileType}
+		return
+	}
+
+	sheets, listErr := listSheets(filePath)
+	if listErr != nil {
+		processingErr = ErrMsg{Err: listErr, Code: ErrParse}
+		return
+	}
+	var writeErr error
+	if asJSON {
+		writeErr = printJSON(os.Stdout, sheets)
+	} else {
+		writeErr = printText(os.Stdout, sheets)
+	}
+	if writeErr != nil {
+		processingErr = ErrMsg{Err: writeErr, Code: ErrStdout}
+	}
+}
+
+// listSheets opens the workbook at path and collects the details of each worksheet in workbook order.
+func listSheets(path string) (sheets []SheetInfo, err error) {
+	file, openErr := excelize.OpenFile(path)
+	if openErr != nil {
+		return nil, openErr
+	}
+	defer func(file *excelize.File) {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}(file)
+
+	activeSheet := file.GetActiveSheetIndex()
+	for index, name := range file.GetSheetList() {
+		rowCount, dimension, countErr := scanSheet(file, name)
+		if countErr != nil {
+			return nil, fmt.Errorf("sheet '%s': %w", name, countErr)
+		}
+		if dimension == "" {
+			// Fall back to the stored dimension for sheets without any values.
+			dimension, _ = file.GetSheetDimension(name)
+		}
+		sheets = append(sheets, SheetInfo{
+			Index:      index,
+			Name:       name,
+			Visibility: sheetVisibility(file, name),
+			Dimension:  dimension,
+			Rows:       rowCount,
+			Active:     index == activeSheet,
+		})
+	}
+	return sheets, nil
+}
+
+// sheetVisibility returns the state of a worksheet: visible, hidden or veryHidden.
+func sheetVisibility(file *excelize.File, name string) string {
+	if file.WorkBook != nil {
+		for _, sheet := range file.WorkBook.Sheets.Sheet {
+			if strings.EqualFold(sheet.Name, name) && sheet.State != "" {
+				return sheet.State
+			}
+		}
+	}
+	return "visible"
+}
+
+// scanSheet counts the rows of a worksheet that contain at least one non-empty cell,
+// and returns the range spanned by those cells (e.g. "A1:F20"), which is empty if the sheet has no values.
+// The range is computed rather than read from the sheet, as the stored dimension is often stale.
+func scanSheet(file *excelize.File, name string) (count int, dimension string, err error) {
+	rows, rowsErr := file.Rows(name)
+	if rowsErr != nil {
+		return 0, "", rowsErr
+	}
+	defer func(rows *excelize.Rows) {
+		_ = rows.Close()
+	}(rows)
+	var firstRow, firstColumn, lastRow, lastColumn, rowIndex int
+	for rows.Next() {
+		rowIndex++
+		columns, colErr := rows.Columns()
+		if colErr != nil {
+			return count, "", colErr
+		}
+		used := false
+		for columnIndex, value := range columns {
+			if value == "" {
+				continue
+			}
+			if !used {
+				used = true
+				count++
+				if firstRow == 0 {
+					firstRow = rowIndex
+				}
+				if firstColumn == 0 || columnIndex+1 < firstColumn {
+					firstColumn = columnIndex + 1
+				}
+			}
+			lastColumn = max(lastColumn, columnIndex+1)
+		}
+		if used {
+			lastRow = rowIndex
+		}
+	}
+	if count == 0 {
+		return 0, "", rows.Error()
+	}
+	firstCell, _ := excelize.CoordinatesToCellName(firstColumn, firstRow)
+	lastCell, _ := excelize.CoordinatesToCellName(lastColumn, lastRow)
+	return count, firstCell + ":" + lastCell, rows.Error()
+}
+
+// printText writes the sheet list as an aligned table.
+func printText(w io.Writer, sheets []SheetInfo) error {
+	writer := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(writer, "INDEX\tNAME\tVISIBILITY\tDIMENSION\tROWS\tACTIVE")
+	for _, sheet := range sheets {
+		_, _ = fmt.Fprintf(
+			writer, "%d\t%s\t%s\t%s\t%d\t%t\n",
+			sheet.Index, sheet.Name, sheet.Visibility, sheet.Dimension, sheet.Rows, sheet.Active,
+		)
+	}
+	return writer.Flush()
+}
+
+// printJSON writes the sheet list as a JSON array.
+func printJSON(w io.Writer, sheets []SheetInfo) error {
+	if sheets == nil {
+		sheets = []SheetInfo{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sheets)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// createTestWorkbook saves a workbook with a Data sheet whose values start at B2, an empty sheet and a hidden
+// sheet, making the hidden one active, and returns its path.
+func createTestWorkbook(t *testing.T) string {
+	t.Helper()
+	file := excelize.NewFile()
+	defer func() {
+		_ = file.Close()
+	}()
+	if err := file.SetSheetName("Sheet1", "Data"); err != nil {
+		t.Fatal(err)
+	}
+	for cell, value := range map[string]any{"B2": "Name", "C2": "Age", "B3": "ann", "D5": 30} {
+		if err := file.SetCellValue("Data", cell, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := file.NewSheet("Empty"); err != nil {
+		t.Fatal(err)
+	}
+	hidden, err := file.NewSheet("Hidden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = file.SetCellValue("Hidden", "A1", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err = file.SetSheetVisible("Hidden", false); err != nil {
+		t.Fatal(err)
+	}
+	file.SetActiveSheet(hidden)
+	path := filepath.Join(t.TempDir(), "book.xlsx")
+	if err = file.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestListSheets(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		want    []SheetInfo
+		wantErr bool
+	}{
+		{
+			name: "Workbook",
+			path: createTestWorkbook(t),
+			want: []SheetInfo{
+				{Index: 0, Name: "Data", Visibility: "visible", Dimension: "B2:D5", Rows: 3},
+				{Index: 1, Name: "Empty", Visibility: "visible", Dimension: "A1", Rows: 0},
+				{Index: 2, Name: "Hidden", Visibility: "hidden", Dimension: "A1:A1", Rows: 1, Active: true},
+			},
+		},
+		{
+			name:    "Missing File",
+			path:    filepath.Join(t.TempDir(), "missing.xlsx"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := listSheets(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("listSheets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("listSheets() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintSheets(t *testing.T) {
+	sheets := []SheetInfo{{Index: 0, Name: "Data", Visibility: "visible", Dimension: "A1:B2", Rows: 2, Active: true}}
+	tests := []struct {
+		name   string
+		print  func(*bytes.Buffer, []SheetInfo) error
+		sheets []SheetInfo
+		want   string
+	}{
+		{
+			name:   "Text",
+			print:  func(buf *bytes.Buffer, sheets []SheetInfo) error { return printText(buf, sheets) },
+			sheets: sheets,
+			want:   "INDEX  NAME  VISIBILITY  DIMENSION  ROWS  ACTIVE\n0      Data  visible     A1:B2      2     true\n",
+		},
+		{
+			name:   "JSON",
+			print:  func(buf *bytes.Buffer, sheets []SheetInfo) error { return printJSON(buf, sheets) },
+			sheets: sheets,
+			want: `[
+  {
+    "index": 0,
+    "name": "Data",
+    "visibility": "visible",
+    "dimension": "A1:B2",
+    "rows": 2,
+    "active": true
+  }
+]
+`,
+		},
+		{
+			name:  "Empty JSON",
+			print: func(buf *bytes.Buffer, sheets []SheetInfo) error { return printJSON(buf, sheets) },
+			want:  "[]\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.print(&buf, tt.sheets); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("output = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
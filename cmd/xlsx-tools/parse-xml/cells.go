@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	. "GoTools/pkg/helpers"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	valuesFormatted = "formatted"
+	valuesRaw       = "raw"
+
+	formulasCalculated = "calculated"
+	formulasRaw        = "raw"
+	formulasEvaluate   = "evaluate"
+)
+
+// formulaModes lists the values accepted by the --formulas flag.
+var formulaModes = []string{formulasCalculated, formulasRaw, formulasEvaluate}
+
+const (
+	xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+	xsNamespace  = "http://www.w3.org/2001/XMLSchema"
+
+	xsString   = "xs:string"
+	xsDouble   = "xs:double"
+	xsBoolean  = "xs:boolean"
+	xsDateTime = "xs:dateTime"
+
+	// isoDateTime is the layout used for typed date values, as expected by xs:dateTime.
+	isoDateTime = "2006-01-02T15:04:05"
+)
+
+// builtInDateFormats holds the built-in number format IDs that Excel renders as dates or times.
+var builtInDateFormats = map[int]bool{
+	14: true, 15: true, 16: true, 17: true, 18: true, 19: true, 20: true, 21: true, 22: true,
+	27: true, 28: true, 29: true, 30: true, 31: true, 32: true, 33: true, 34: true, 35: true, 36: true,
+	45: true, 46: true, 47: true, 50: true, 51: true, 52: true, 53: true, 54: true, 55: true,
+	56: true, 57: true, 58: true,
+}
+
+// numFmtLiterals matches the quoted text, escaped characters and bracketed sections of a number format,
+// which must be ignored when looking for date and time tokens.
+var numFmtLiterals = regexp.MustCompile(`"[^"]*"|\\.|\[[^]]*]`)
+
+// typedNamespaces returns the namespace declarations needed on the root element when columns carry xsi:type.
+func typedNamespaces() []xml.Attr {
+	return []xml.Attr{
+		{Name: xml.Name{Local: "xmlns:xsi"}, Value: xsiNamespace},
+		{Name: xml.Name{Local: "xmlns:xs"}, Value: xsNamespace},
+	}
+}
+
+// cellInspector looks up the metadata of individual cells in a worksheet.
+type cellInspector struct {
+	file       *excelize.File
+	sheet      string
+	date1904   bool
+	dateStyles map[int]bool
+	// typed renders values to suit their XML Schema type, e.g. booleans as true/false and numbers unformatted.
+	typed bool
+	// rawNumbers emits the stored value of numeric cells rather than their displayed text.
+	rawNumbers bool
+	// dateLayout is the Go time layout used for date and time values.
+	dateLayout string
+	// formulas selects whether formula cells yield their cached value, their formula text or a fresh evaluation.
+	formulas string
+	// hyperlinks enables looking up the link target of each cell.
+	hyperlinks bool
+	// comments maps cell references to their comment text, and is nil unless comments are requested.
+	comments map[string]string
+}
+
+// needsInspector checks if any of the options require per-cell metadata from the sheet.
+func needsInspector(opts options) bool {
+	return opts.Typed || opts.WithSchema || len(opts.DateFormat) > 0 || opts.Hyperlinks || opts.Comments ||
+		(len(opts.Formulas) > 0 && opts.Formulas != formulasCalculated)
+}
+
+// newCellInspector creates a cellInspector for the named worksheet of file.
+// Typed output always uses ISO-8601 dates, as xs:dateTime requires; otherwise opts.DateFormat applies.
+func newCellInspector(file *excelize.File, sheet string, opts options) *cellInspector {
+	inspector := &cellInspector{
+		file:       file,
+		sheet:      sheet,
+		dateStyles: make(map[int]bool),
+		typed:      opts.Typed || opts.WithSchema,
+		rawNumbers: opts.Typed || opts.WithSchema || opts.Values == valuesRaw,
+		dateLayout: time.DateTime,
+		formulas:   opts.Formulas,
+		hyperlinks: opts.Hyperlinks,
+	}
+	if opts.Comments {
+		inspector.comments = make(map[string]string)
+		comments, _ := file.GetComments(sheet)
+		for _, comment := range comments {
+			inspector.comments[comment.Cell] = commentText(comment)
+		}
+	}
+	if inspector.typed {
+		inspector.dateLayout = isoDateTime
+	} else if len(opts.DateFormat) > 0 {
+		inspector.dateLayout = opts.DateFormat
+	}
+	if props, propsErr := file.GetWorkbookProps(); propsErr == nil && props.Date1904 != nil {
+		inspector.date1904 = *props.Date1904
+	}
+	return inspector
+}
+
+// typedValue determines the XML Schema type of the cell at the given column and row,
+// and returns it along with the value rendered to suit that type.
+// Date cells are rendered with the inspector's date layout, whether they are stored as serial numbers or text.
+// Blank cells have no type, and cells that cannot be typed fall back to xs:string.
+func (c *cellInspector) typedValue(column, row int, cellValue string) (xsiType, value string) {
+	cell, cellErr := excelize.CoordinatesToCellName(column, row)
+	if cellErr != nil {
+		if len(cellValue) == 0 {
+			return "", ""
+		}
+		return xsString, ConvertDateFormat(cellValue, c.dateLayout)
+	}
+	if len(c.formulas) > 0 && c.formulas != formulasCalculated {
+		if formula, _ := c.file.GetCellFormula(c.sheet, cell); len(formula) > 0 {
+			return c.formulaValue(cell, formula, cellValue)
+		}
+	}
+	if len(cellValue) == 0 {
+		return "", ""
+	}
+	cellType, _ := c.file.GetCellType(c.sheet, cell)
+	switch cellType {
+	case excelize.CellTypeBool:
+		if !c.typed {
+			return xsBoolean, cellValue
+		}
+		raw := c.rawValue(cell, cellValue)
+		return xsBoolean, strconv.FormatBool(raw == "1" || strings.EqualFold(raw, "true"))
+	case excelize.CellTypeDate:
+		raw := c.rawValue(cell, cellValue)
+		if date, parseErr := time.Parse(time.RFC3339, raw); parseErr == nil {
+			return xsDateTime, date.Format(c.dateLayout)
+		}
+		return xsDateTime, ConvertDateFormat(cellValue, c.dateLayout)
+	case excelize.CellTypeNumber, excelize.CellTypeUnset, excelize.CellTypeFormula:
+		raw := c.rawValue(cell, cellValue)
+		number, parseErr := strconv.ParseFloat(raw, 64)
+		if parseErr != nil {
+			break
+		}
+		if c.isDateCell(cell) {
+			if date, dateErr := excelize.ExcelDateToTime(number, c.date1904); dateErr == nil {
+				return xsDateTime, date.Format(c.dateLayout)
+			}
+		}
+		if c.rawNumbers {
+			return xsDouble, raw
+		}
+		return xsDouble, cellValue
+	}
+	return xsString, ConvertDateFormat(cellValue, c.dateLayout)
+}
+
+// annotations returns the hyperlink target and comment text of the cell at the given column and row,
+// for whichever of the two the inspector was asked to collect.
+func (c *cellInspector) annotations(column, row int) (href, comment string) {
+	cell, cellErr := excelize.CoordinatesToCellName(column, row)
+	if cellErr != nil {
+		return "", ""
+	}
+	if c.hyperlinks {
+		if linked, target, linkErr := c.file.GetCellHyperLink(c.sheet, cell); linkErr == nil && linked {
+			href = target
+		}
+	}
+	if c.comments != nil {
+		comment = c.comments[cell]
+	}
+	return href, comment
+}
+
+// commentText returns the plain text of a comment, joining its rich text runs if it has no plain text.
+func commentText(comment excelize.Comment) string {
+	if len(comment.Text) > 0 || len(comment.Paragraph) == 0 {
+		return comment.Text
+	}
+	var text strings.Builder
+	for _, run := range comment.Paragraph {
+		text.WriteString(run.Text)
+	}
+	return text.String()
+}
+
+// formulaValue renders a formula cell according to the formulas mode: the formula text prefixed with "=",
+// or the result of evaluating the formula, falling back to the cached value if evaluation fails.
+func (c *cellInspector) formulaValue(cell, formula, cachedValue string) (xsiType, value string) {
+	if c.formulas == formulasRaw {
+		return xsString, "=" + strings.TrimPrefix(formula, "=")
+	}
+	result, calcErr := c.file.CalcCellValue(c.sheet, cell, excelize.Options{RawCellValue: c.rawNumbers})
+	if calcErr != nil {
+		result = cachedValue
+	}
+	if len(result) == 0 {
+		return "", ""
+	}
+	if _, parseErr := strconv.ParseFloat(result, 64); parseErr == nil {
+		return xsDouble, result
+	}
+	if c.typed && (result == "TRUE" || result == "FALSE") {
+		return xsBoolean, strings.ToLower(result)
+	}
+	return xsString, result
+}
+
+// rawValue returns the stored value of a cell without number formatting applied,
+// falling back to the formatted value if it cannot be read.
+func (c *cellInspector) rawValue(cell, formatted string) string {
+	raw, rawErr := c.file.GetCellValue(c.sheet, cell, excelize.Options{RawCellValue: true})
+	if rawErr != nil {
+		return formatted
+	}
+	return raw
+}
+
+// isDateCell checks if the number format of a cell renders it as a date or time.
+func (c *cellInspector) isDateCell(cell string) bool {
+	styleID, styleErr := c.file.GetCellStyle(c.sheet, cell)
+	if styleErr != nil {
+		return false
+	}
+	if isDate, cached := c.dateStyles[styleID]; cached {
+		return isDate
+	}
+	isDate := false
+	if style, getErr := c.file.GetStyle(styleID); getErr == nil && style != nil {
+		if style.CustomNumFmt != nil {
+			isDate = isDateFormat(*style.CustomNumFmt)
+		} else {
+			isDate = builtInDateFormats[style.NumFmt]
+		}
+	}
+	c.dateStyles[styleID] = isDate
+	return isDate
+}
+
+// isDateFormat checks if a custom number format contains date or time tokens outside of literals.
+func isDateFormat(format string) bool {
+	format = strings.ToLower(numFmtLiterals.ReplaceAllString(format, ""))
+	return strings.ContainsAny(format, "ydhs")
+}
+
+// dateLayoutTokens maps the date tokens familiar from Excel and .NET format strings, each a letter repeated
+// a number of times, to Go layout elements. Go has no unpadded 24-hour element, so "H" is written as "15".
+var dateLayoutTokens = map[string]string{
+	"yyyy": "2006", "yy": "06",
+	"MMMM": "January", "MMM": "Jan", "MM": "01", "M": "1",
+	"dddd": "Monday", "ddd": "Mon", "dd": "02", "d": "2",
+	"HH": "15", "H": "15", "hh": "03", "h": "3",
+	"mm": "04", "m": "4", "ss": "05", "s": "5", "tt": "PM",
+}
+
+// toGoLayout converts a date format such as "yyyy-MM-dd HH:mm" into a Go time layout.
+// Formats that already contain Go layout elements (the reference year 2006) are returned unchanged.
+// Characters other than letters are kept as they are; a run of letters that is not one of dateLayoutTokens
+// is an error, as it would otherwise be written into every date as it stands.
+func toGoLayout(format string) (string, error) {
+	if len(format) == 0 || strings.Contains(format, "2006") || strings.Contains(format, "06") {
+		return format, nil
+	}
+	var layout strings.Builder
+	for i := 0; i < len(format); {
+		letter := format[i]
+		if !('a' <= letter && letter <= 'z' || 'A' <= letter && letter <= 'Z') {
+			layout.WriteByte(letter)
+			i++
+			continue
+		}
+		end := i + 1
+		for end < len(format) && format[end] == letter {
+			end++
+		}
+		element, ok := dateLayoutTokens[format[i:end]]
+		if !ok {
+			return "", fmt.Errorf("unknown element '%s' in date format '%s', give a Go layout such as 2006-01-02T15:04 to write letters as they are",
+				format[i:end], format)
+		}
+		layout.WriteString(element)
+		i = end
+	}
+	return layout.String(), nil
+}
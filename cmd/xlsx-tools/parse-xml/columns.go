@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	. "GoTools/pkg/helpers"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	headerCaseNone   = "none"
+	headerCasePascal = "pascal"
+	headerCaseCamel  = "camel"
+	headerCaseSnake  = "snake"
+)
+
+// headerCases lists the values accepted by the --header-case flag.
+var headerCases = []string{headerCaseNone, headerCasePascal, headerCaseCamel, headerCaseSnake}
+
+// splitList splits a comma-separated flag value, discarding surrounding whitespace and blanks.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+const (
+	dupeSuffix = "suffix"
+	dupeError  = "error"
+	dupeDrop   = "drop"
+)
+
+// dupeStrategies lists the values accepted by the --dupe-strategy flag.
+var dupeStrategies = []string{dupeSuffix, dupeError, dupeDrop}
+
+// duplicateHeaders returns each non-blank header that appears more than once, in the order of its first repeat.
+func duplicateHeaders(headers []string) []string {
+	seen := make(map[string]int)
+	var duplicates []string
+	for _, header := range headers {
+		if len(strings.TrimSpace(header)) == 0 {
+			continue
+		}
+		if seen[header]++; seen[header] == 2 {
+			duplicates = append(duplicates, header)
+		}
+	}
+	return duplicates
+}
+
+// firstOccurrences returns the indexes of headers without the later repeats of any non-blank header.
+func firstOccurrences(headers []string) []int {
+	seen := make(map[string]bool)
+	var keep []int
+	for columnIndex, header := range headers {
+		if len(strings.TrimSpace(header)) > 0 && seen[header] {
+			continue
+		}
+		seen[header] = true
+		keep = append(keep, columnIndex)
+	}
+	return keep
+}
+
+const (
+	blankHeadersNone   = "none"
+	blankHeadersLetter = "letter"
+	blankHeadersIndex  = "index"
+)
+
+// blankHeaderModes lists the values accepted by the --blank-headers flag.
+var blankHeaderModes = []string{blankHeadersNone, blankHeadersLetter, blankHeadersIndex}
+
+// fillBlankHeaders names each blank header after its column, so that it still makes a valid element name:
+// "letter" uses the sheet's column letter, counted from firstColumn, and "index" uses Column<N>, counted from one.
+// Headers are left as they are for "none".
+func fillBlankHeaders(headers []string, mode string, firstColumn int) {
+	if mode != blankHeadersLetter && mode != blankHeadersIndex {
+		return
+	}
+	for headerIndex, header := range headers {
+		if len(strings.TrimSpace(header)) > 0 {
+			continue
+		}
+		if mode == blankHeadersIndex {
+			headers[headerIndex] = fmt.Sprintf("Column%d", headerIndex+1)
+		} else if name, err := excelize.ColumnNumberToName(firstColumn + headerIndex); err == nil {
+			headers[headerIndex] = name
+		}
+	}
+}
+
+// applyHeaderCase converts header to the naming convention given by style, leaving it as it is for "none".
+// Words are split at spaces, punctuation and changes of case, so the result needs no further cleaning.
+func applyHeaderCase(header, style string) string {
+	switch style {
+	case headerCasePascal:
+		return ToPascalCase(header)
+	case headerCaseCamel:
+		return ToCamelCase(header)
+	case headerCaseSnake:
+		return ToSnakeCase(header)
+	default:
+		return header
+	}
+}
+
+// selectColumns keeps only the columns of dataTable at the given indexes, in the given order.
+func selectColumns(dataTable *DataTable, keep []int) {
+	if len(keep) == len(dataTable.Headers) && slices.IsSorted(keep) {
+		return
+	}
+	headers := make([]string, 0, len(keep))
+	sourceHeaders := make([]string, 0, len(keep))
+	for _, columnIndex := range keep {
+		headers = append(headers, dataTable.Headers[columnIndex])
+		sourceHeaders = append(sourceHeaders, dataTable.sourceHeaders[columnIndex])
+	}
+	dataTable.Headers = headers
+	dataTable.sourceHeaders = sourceHeaders
+	for rowIndex, dataRow := range dataTable.Rows {
+		dataTable.Rows[rowIndex] = selectRowColumns(dataRow, keep)
+	}
+}
+
+// selectRowColumns returns dataRow with only the columns at the given indexes, in the given order.
+func selectRowColumns(dataRow DataRow, keep []int) DataRow {
+	columns := make([]DataColumn, 0, len(keep))
+	for _, columnIndex := range keep {
+		if columnIndex < len(dataRow.Columns) {
+			columns = append(columns, dataRow.Columns[columnIndex])
+		}
+	}
+	dataRow.Columns = columns
+	return dataRow
+}
+
+// dropBlankColumns removes the columns of dataTable that have an empty header and no data in any row.
+func dropBlankColumns(dataTable *DataTable) {
+	var keep []int
+	for columnIndex := range dataTable.Headers {
+		blankHeader := len(strings.TrimSpace(dataTable.sourceHeaders[columnIndex])) == 0
+		if !blankHeader || !isBlankColumn(dataTable.Rows, columnIndex) {
+			keep = append(keep, columnIndex)
+		}
+	}
+	selectColumns(dataTable, keep)
+}
+
+// isBlankColumn checks if the cell at columnIndex is empty or whitespace in every row.
+func isBlankColumn(rows []DataRow, columnIndex int) bool {
+	for _, dataRow := range rows {
+		if columnIndex < len(dataRow.Columns) && len(strings.TrimSpace(dataRow.Columns[columnIndex].Value)) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// filterColumns keeps the columns named in include (or all columns if include is empty),
+// then removes those named in exclude. Columns keep their order in the sheet.
+// Names are matched against either the header as written in the sheet or its cleaned element name,
+// and an error is returned for any name that matches no column, so typos are not silently ignored.
+func filterColumns(dataTable *DataTable, include, exclude []string) error {
+	keep, keepErr := keptColumns(dataTable, include, exclude)
+	if keepErr != nil {
+		return keepErr
+	}
+	selectColumns(dataTable, keep)
+	return nil
+}
+
+// keptColumns returns the indexes of the columns of dataTable that filterColumns would keep,
+// looking only at its headers so it can be used before any rows have been read.
+func keptColumns(dataTable *DataTable, include, exclude []string) ([]int, error) {
+	matches := func(columnIndex int, names []string) bool {
+		return slices.Contains(names, strings.TrimSpace(dataTable.sourceHeaders[columnIndex])) ||
+			slices.Contains(names, dataTable.Headers[columnIndex])
+	}
+	for _, name := range append(slices.Clone(include), exclude...) {
+		found := slices.ContainsFunc(dataTable.Headers, func(header string) bool { return header == name }) ||
+			slices.ContainsFunc(dataTable.sourceHeaders, func(header string) bool { return strings.TrimSpace(header) == name })
+		if !found {
+			return nil, fmt.Errorf("column '%s' not found", name)
+		}
+	}
+	var keep []int
+	for columnIndex := range dataTable.Headers {
+		if len(include) > 0 && !matches(columnIndex, include) {
+			continue
+		}
+		if matches(columnIndex, exclude) {
+			continue
+		}
+		keep = append(keep, columnIndex)
+	}
+	return keep, nil
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	. "GoTools/pkg/helpers"
+)
+
+const (
+	formatXML  = "xml"
+	formatJSON = "json"
+	formatCSV  = "csv"
+	formatYAML = "yaml"
+)
+
+const (
+	modeElements   = "elements"
+	modeAttributes = "attributes"
+)
+
+const (
+	emptyCellsOmit  = "omit"
+	emptyCellsEmpty = "empty"
+	emptyCellsNil   = "nil"
+)
+
+// emptyCellModes lists the values accepted by the --empty-cells flag.
+var emptyCellModes = []string{emptyCellsOmit, emptyCellsEmpty, emptyCellsNil}
+
+// xmlModes lists the values accepted by the --mode flag.
+var xmlModes = []string{modeElements, modeAttributes}
+
+// outputFormats lists the values accepted by the --format flag.
+var outputFormats = []string{formatXML, formatJSON, formatCSV, formatYAML}
+
+// yamlPlainScalar matches values that can be written as a plain YAML scalar without quoting.
+var yamlPlainScalar = regexp.MustCompile(`^[A-Za-z0-9_./][A-Za-z0-9 _./-]*$`)
+
+// xmlElementName matches names that can be used for the root and row elements.
+var xmlElementName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// yamlReserved holds plain scalars that YAML would read as booleans or nulls rather than strings.
+var yamlReserved = []string{"~", "null", "true", "false", "yes", "no", "on", "off", "y", "n"}
+
+// yamlDecimal matches the numbers written as plain scalars, which YAML reads back as the same number.
+var yamlDecimal = regexp.MustCompile(`^(0|[1-9][0-9]*)(\.[0-9]+)?$`)
+
+// yamlResolved matches the other plain scalars that YAML 1.1 or 1.2 would read as a number or a timestamp
+// rather than a string: leading zeros, exponents, digit separators, hexadecimal, octal and binary integers,
+// infinities, NaN and dates.
+var yamlResolved = regexp.MustCompile(`^(?:[-+]?(?:[0-9][0-9_]*(?:\.[0-9_]*)?|\.[0-9_]+)(?:[eE][-+]?[0-9]+)?|` +
+	`0[xob][0-9A-Fa-f_]+|[-+]?\.(?:inf|Inf|INF)|\.(?:nan|NaN|NAN)|[0-9]{4}-[0-9]{1,2}-[0-9]{1,2}.*)$`)
+
+// validFormat checks if format is one of the supported output formats.
+func validFormat(format string) bool {
+	return slices.Contains(outputFormats, format)
+}
+
+// marshalDocument encodes the parsed tables in the format selected by opts.Format.
+// A single table is emitted on its own, while --all-sheets wraps the tables in a workbook-level document.
+func marshalDocument(tables []DataTable, opts options) ([]byte, error) {
+	switch opts.Format {
+	case formatJSON:
+		return marshalJSON(tables, opts.multiSheet())
+	case formatCSV:
+		return marshalCSV(tables, opts.multiSheet(), opts.Sanitize)
+	case formatYAML:
+		return marshalYAML(tables, opts.multiSheet()), nil
+	default:
+		return marshalXML(tables, opts)
+	}
+}
+
+// marshalXML encodes the tables as a DataTable document, or as a DataSet document if several sheets are selected.
+// The root element takes its name from opts.RootElement when one was given.
+// The namespaces used by the document are declared on the root element, as listed by rootNamespaces.
+func marshalXML(tables []DataTable, opts options) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	layout := newXMLLayout(opts)
+	root := xml.StartElement{Name: xml.Name{Local: layout.qualify(rootElementName(opts))}}
+	rootAttrs := rootNamespaces(opts)
+	if opts.multiSheet() {
+		if len(layout.prefix) > 0 {
+			tables = slices.Clone(tables)
+			for i := range tables {
+				tables[i].XMLName = xml.Name{Local: layout.qualify("Table")}
+			}
+		}
+		if err := encoder.EncodeElement(DataSet{Attrs: rootAttrs, Tables: tables}, root); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	table := tables[0]
+	table.Attrs = append(rootAttrs, table.Attrs...)
+	if opts.WithSchema {
+		table.Schema = buildSchema(table, rootElementName(opts), rowElementName(opts), opts.Namespace)
+	}
+	if err := encoder.EncodeElement(table, root); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// rootElementName returns the name of the document's root element: opts.RootElement if set,
+// otherwise DataSet for --all-sheets and DataTable for a single sheet.
+func rootElementName(opts options) string {
+	if len(opts.RootElement) > 0 {
+		return opts.RootElement
+	} else if opts.multiSheet() {
+		return "DataSet"
+	}
+	return "DataTable"
+}
+
+// namespaceAttrs returns the declaration of the document namespace selected by --namespace, bound to
+// --namespace-prefix if one was given and as the default namespace otherwise.
+func namespaceAttrs(opts options) []xml.Attr {
+	if len(opts.Namespace) == 0 {
+		return nil
+	}
+	name := "xmlns"
+	if len(opts.NamespacePrefix) > 0 {
+		name += ":" + opts.NamespacePrefix
+	}
+	return []xml.Attr{{Name: xml.Name{Local: name}, Value: opts.Namespace}}
+}
+
+// rootNamespaces returns the namespace declarations for the root element: the document namespace if any,
+// the namespaces used by xsi:type when opts.Typed is set, and the xsi namespace for xsi:nil with --empty-cells nil.
+func rootNamespaces(opts options) []xml.Attr {
+	attrs := namespaceAttrs(opts)
+	if opts.Typed {
+		attrs = append(attrs, typedNamespaces()...)
+	} else if emptyCellsMode(opts) == emptyCellsNil {
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "xmlns:xsi"}, Value: xsiNamespace})
+	}
+	return attrs
+}
+
+// emptyCellsMode returns how blank cells are written: opts.EmptyCells if set, otherwise omitted when an
+// inline schema is written, so typed columns read back as nulls rather than failing to parse, and empty elsewhere.
+func emptyCellsMode(opts options) string {
+	if len(opts.EmptyCells) > 0 {
+		return opts.EmptyCells
+	} else if opts.WithSchema {
+		return emptyCellsOmit
+	}
+	return emptyCellsEmpty
+}
+
+// rowElementName returns the name of the element wrapping each row, opts.RowElement if set or Row otherwise.
+func rowElementName(opts options) string {
+	if len(opts.RowElement) > 0 {
+		return opts.RowElement
+	}
+	return "Row"
+}
+
+// validElementName checks if name can be used as an XML element name without any escaping.
+func validElementName(name string) bool {
+	return xmlElementName.MatchString(name) && !strings.HasPrefix(strings.ToLower(name), "xml")
+}
+
+// xmlLayout describes how rows are written as XML. It is shared by every row read from a sheet.
+type xmlLayout struct {
+	// attributes emits the columns as attributes of the row element rather than as child elements.
+	attributes bool
+	// element is the name of the row element.
+	element string
+	// prefix is the namespace prefix added to every element name, if any.
+	prefix string
+	// emptyCells is how blank cells are written, one of emptyCellModes.
+	emptyCells string
+}
+
+// newXMLLayout returns the layout selected by the --mode, --row-element, --namespace-prefix and --empty-cells flags.
+func newXMLLayout(opts options) *xmlLayout {
+	return &xmlLayout{
+		attributes: opts.Mode == modeAttributes,
+		element:    rowElementName(opts),
+		prefix:     opts.NamespacePrefix,
+		emptyCells: emptyCellsMode(opts),
+	}
+}
+
+// qualify adds the layout's namespace prefix, if any, to an element name.
+func (l *xmlLayout) qualify(name string) string {
+	if l == nil || len(l.prefix) == 0 {
+		return name
+	}
+	return l.prefix + ":" + name
+}
+
+// dataRowElements has the layout of DataRow without its MarshalXML method, so it encodes with the default rules.
+type dataRowElements DataRow
+
+// MarshalXML encodes the row with one child element per column, or with one attribute per column in
+// attributes mode, using the element name and namespace prefix of the row's layout if it has one.
+// Blank cells are omitted, left empty or marked xsi:nil according to the layout.
+// Hyperlinks and comments become "<column>_href" and "<column>_comment" attributes in attributes mode.
+func (r DataRow) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if r.layout != nil {
+		start.Name = xml.Name{Local: r.layout.qualify(r.layout.element)}
+	}
+	if r.layout == nil || !r.layout.attributes {
+		if r.layout != nil && (len(r.layout.prefix) > 0 || r.layout.emptyCells != emptyCellsEmpty) {
+			columns := make([]DataColumn, 0, len(r.Columns))
+			for _, column := range r.Columns {
+				if len(column.Value) == 0 {
+					if r.layout.emptyCells == emptyCellsOmit {
+						continue
+					} else if r.layout.emptyCells == emptyCellsNil {
+						column.Nil = "true"
+					}
+				}
+				column.XMLName.Local = r.layout.qualify(column.XMLName.Local)
+				columns = append(columns, column)
+			}
+			r.Columns = columns
+		}
+		return e.EncodeElement(dataRowElements(r), start)
+	}
+	for _, column := range r.Columns {
+		if len(column.Value) == 0 && r.layout.emptyCells == emptyCellsOmit {
+			continue
+		}
+		name := column.XMLName.Local
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: name}, Value: column.Value})
+		if len(column.Href) > 0 {
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: name + "_href"}, Value: column.Href})
+		}
+		if len(column.Comment) > 0 {
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: name + "_comment"}, Value: column.Comment})
+		}
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	return e.EncodeToken(start.End())
+}
+
+// marshalJSON encodes the tables as an array of row objects, or as an object of such arrays keyed by
+// sheet name if allSheets is set.
+func marshalJSON(tables []DataTable, allSheets bool) ([]byte, error) {
+	if allSheets {
+		return json.MarshalIndent(jsonDataSet(tables), "", "  ")
+	}
+	return json.MarshalIndent(tables[0], "", "  ")
+}
+
+// marshalCSV encodes a single table as CSV, with the cleaned headers as the first record.
+// CSV cannot hold more than one sheet, so allSheets is rejected. With sanitize set, values that a
+// spreadsheet program would evaluate as a formula are quoted with SanitizeFormula.
+func marshalCSV(tables []DataTable, allSheets, sanitize bool) ([]byte, error) {
+	if allSheets {
+		return nil, errors.New("the csv format cannot hold multiple sheets, select a single sheet instead")
+	}
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	table := tables[0]
+	if len(table.Headers) > 0 {
+		headers := table.Headers
+		if sanitize {
+			headers = make([]string, len(table.Headers))
+			for i, header := range table.Headers {
+				headers[i] = SanitizeFormula(header)
+			}
+		}
+		if err := writer.Write(headers); err != nil {
+			return nil, err
+		}
+	}
+	for _, row := range table.Rows {
+		record := make([]string, len(row.Columns))
+		for i, column := range row.Columns {
+			record[i] = column.Value
+			if sanitize {
+				record[i] = SanitizeFormula(column.Value)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// marshalYAML encodes the tables as a list of maps per row, or as a map of such lists keyed by
+// sheet name if allSheets is set. Column order is preserved within each map.
+func marshalYAML(tables []DataTable, allSheets bool) []byte {
+	var buf bytes.Buffer
+	if !allSheets {
+		writeYAMLRows(&buf, tables[0].Rows, "")
+		return buf.Bytes()
+	}
+	for _, table := range tables {
+		buf.WriteString(yamlScalar(table.Name))
+		buf.WriteString(":")
+		if len(table.Rows) == 0 {
+			buf.WriteString(" []\n")
+			continue
+		}
+		buf.WriteString("\n")
+		writeYAMLRows(&buf, table.Rows, "  ")
+	}
+	return buf.Bytes()
+}
+
+// writeYAMLRows writes rows as a YAML block sequence of mappings, each line prefixed by indent.
+func writeYAMLRows(buf *bytes.Buffer, rows []DataRow, indent string) {
+	if len(rows) == 0 {
+		buf.WriteString(indent + "[]\n")
+		return
+	}
+	for _, row := range rows {
+		if len(row.Columns) == 0 {
+			buf.WriteString(indent + "- {}\n")
+			continue
+		}
+		for i, column := range row.Columns {
+			if i == 0 {
+				buf.WriteString(indent + "- ")
+			} else {
+				buf.WriteString(indent + "  ")
+			}
+			buf.WriteString(yamlScalar(column.XMLName.Local))
+			buf.WriteString(": ")
+			buf.WriteString(yamlScalar(column.Value))
+			buf.WriteString("\n")
+		}
+	}
+}
+
+// yamlScalar returns value as a plain YAML scalar where that round-trips safely, and as a double-quoted scalar
+// otherwise (empty values, reserved words, special characters, and values YAML would read as anything but
+// a string, other than plain decimal numbers).
+func yamlScalar(value string) string {
+	plain := yamlDecimal.MatchString(value) ||
+		yamlPlainScalar.MatchString(value) &&
+			!strings.HasSuffix(value, " ") &&
+			!slices.Contains(yamlReserved, strings.ToLower(value)) &&
+			!yamlResolved.MatchString(value)
+	if plain {
+		return value
+	}
+	return strconv.Quote(value)
+}
+
+// jsonDataSet is a list of tables encoded as a single JSON object keyed by table name.
+type jsonDataSet []DataTable
+
+// MarshalJSON encodes the tables as an object, preserving the workbook's sheet order.
+func (d jsonDataSet) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, table := range d {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, keyErr := json.Marshal(table.Name)
+		if keyErr != nil {
+			return nil, keyErr
+		}
+		value, valueErr := json.Marshal(table)
+		if valueErr != nil {
+			return nil, valueErr
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// MarshalJSON encodes the table as an array of row objects, using an empty array when there are no rows.
+func (t DataTable) MarshalJSON() ([]byte, error) {
+	rows := t.Rows
+	if rows == nil {
+		rows = []DataRow{}
+	}
+	return json.Marshal(rows)
+}
+
+// MarshalJSON encodes the row as an object keyed by column name, preserving the column order of the sheet.
+// Hyperlinks and comments are added as "<column>_href" and "<column>_comment" keys after their column.
+func (r DataRow) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	first := true
+	writeMember := func(name, value string) error {
+		key, keyErr := json.Marshal(name)
+		if keyErr != nil {
+			return keyErr
+		}
+		encoded, valueErr := json.Marshal(value)
+		if valueErr != nil {
+			return valueErr
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(encoded)
+		return nil
+	}
+	for _, column := range r.Columns {
+		if err := writeMember(column.XMLName.Local, column.Value); err != nil {
+			return nil, err
+		}
+		if len(column.Href) > 0 {
+			if err := writeMember(column.XMLName.Local+"_href", column.Href); err != nil {
+				return nil, err
+			}
+		}
+		if len(column.Comment) > 0 {
+			if err := writeMember(column.XMLName.Local+"_comment", column.Comment); err != nil {
+				return nil, err
+			}
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
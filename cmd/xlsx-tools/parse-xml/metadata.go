@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// workbookMetadata describes a workbook rather than its data, for recording where an ingested payload came from.
+type workbookMetadata struct {
+	XMLName        xml.Name           `xml:"Workbook" json:"-"`
+	Title          string             `xml:"Title,omitempty" json:"title,omitempty"`
+	Subject        string             `xml:"Subject,omitempty" json:"subject,omitempty"`
+	Creator        string             `xml:"Creator,omitempty" json:"creator,omitempty"`
+	LastModifiedBy string             `xml:"LastModifiedBy,omitempty" json:"lastModifiedBy,omitempty"`
+	Created        string             `xml:"Created,omitempty" json:"created,omitempty"`
+	Modified       string             `xml:"Modified,omitempty" json:"modified,omitempty"`
+	Keywords       string             `xml:"Keywords,omitempty" json:"keywords,omitempty"`
+	Category       string             `xml:"Category,omitempty" json:"category,omitempty"`
+	Description    string             `xml:"Description,omitempty" json:"description,omitempty"`
+	Revision       string             `xml:"Revision,omitempty" json:"revision,omitempty"`
+	Company        string             `xml:"Company,omitempty" json:"company,omitempty"`
+	Application    string             `xml:"Application,omitempty" json:"application,omitempty"`
+	AppVersion     string             `xml:"AppVersion,omitempty" json:"appVersion,omitempty"`
+	Sheets         []sheetMetadata    `xml:"Sheets>Sheet" json:"sheets"`
+	DefinedNames   []definedNameEntry `xml:"DefinedNames>DefinedName" json:"definedNames"`
+}
+
+// sheetMetadata describes one worksheet and the Excel tables it holds.
+type sheetMetadata struct {
+	Name      string   `xml:"name,attr" json:"name"`
+	Index     int      `xml:"index,attr" json:"index"`
+	Visible   bool     `xml:"visible,attr" json:"visible"`
+	Dimension string   `xml:"dimension,attr,omitempty" json:"dimension,omitempty"`
+	Tables    []string `xml:"Table,omitempty" json:"tables,omitempty"`
+}
+
+// definedNameEntry is a named range or formula; an empty scope means it belongs to the whole workbook.
+type definedNameEntry struct {
+	Name     string `xml:"name,attr" json:"name"`
+	Scope    string `xml:"scope,attr,omitempty" json:"scope,omitempty"`
+	RefersTo string `xml:"refersTo,attr" json:"refersTo"`
+	Comment  string `xml:"comment,attr,omitempty" json:"comment,omitempty"`
+}
+
+// readMetadata collects the document properties, worksheets and defined names of file.
+// Workbooks without core or app properties, as written by some tools, simply leave those fields empty.
+func readMetadata(file *excelize.File) (workbookMetadata, error) {
+	var metadata workbookMetadata
+	if docProps, err := file.GetDocProps(); err == nil {
+		metadata.Title, metadata.Subject = docProps.Title, docProps.Subject
+		metadata.Creator, metadata.LastModifiedBy = docProps.Creator, docProps.LastModifiedBy
+		metadata.Created, metadata.Modified = docProps.Created, docProps.Modified
+		metadata.Keywords, metadata.Category = docProps.Keywords, docProps.Category
+		metadata.Description, metadata.Revision = docProps.Description, docProps.Revision
+	}
+	if appProps, err := file.GetAppProps(); err == nil {
+		metadata.Company, metadata.Application, metadata.AppVersion = appProps.Company, appProps.Application, appProps.AppVersion
+	}
+	for index, sheetName := range file.GetSheetList() {
+		sheet := sheetMetadata{Name: sheetName, Index: index}
+		var err error
+		if sheet.Visible, err = file.GetSheetVisible(sheetName); err != nil {
+			return metadata, fmt.Errorf("sheet '%s': %w", sheetName, err)
+		}
+		if sheet.Dimension, err = file.GetSheetDimension(sheetName); err != nil {
+			return metadata, fmt.Errorf("sheet '%s': %w", sheetName, err)
+		}
+		tables, tablesErr := file.GetTables(sheetName)
+		if tablesErr != nil {
+			return metadata, fmt.Errorf("sheet '%s': %w", sheetName, tablesErr)
+		}
+		for _, table := range tables {
+			sheet.Tables = append(sheet.Tables, table.Name)
+		}
+		metadata.Sheets = append(metadata.Sheets, sheet)
+	}
+	for _, name := range file.GetDefinedName() {
+		scope := name.Scope
+		if scope == "Workbook" {
+			scope = ""
+		}
+		metadata.DefinedNames = append(metadata.DefinedNames, definedNameEntry{
+			Name:     name.Name,
+			Scope:    scope,
+			RefersTo: name.RefersTo,
+			Comment:  name.Comment,
+		})
+	}
+	return metadata, nil
+}
+
+// writeMetadata writes the metadata of file to w as an XML Workbook document, or as JSON for --format json.
+func writeMetadata(file *excelize.File, opts options, w io.Writer) error {
+	metadata, readErr := readMetadata(file)
+	if readErr != nil {
+		return readErr
+	}
+	marshal := xml.MarshalIndent
+	if opts.Format == formatJSON {
+		marshal = json.MarshalIndent
+	}
+	marshalled, marshalErr := marshal(metadata, "", "  ")
+	if marshalErr != nil {
+		return marshalErr
+	}
+	_, writeErr := w.Write(marshalled)
+	return writeErr
+}
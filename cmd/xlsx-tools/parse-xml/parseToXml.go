@@ -3,55 +3,201 @@
 package main
 
 import (
-	"bufio"
 	"encoding/xml"
 	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"os"
+	"regexp"
+	"slices"
 	"strings"
 
 	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
+	"github.com/charmbracelet/log"
 	"github.com/xuri/excelize/v2"
 )
 
 type DataColumn struct {
 	XMLName xml.Name
+	Type    string `xml:"xsi:type,attr,omitempty"`
+	Href    string `xml:"href,attr,omitempty"`
+	Comment string `xml:"comment,attr,omitempty"`
+	Nil     string `xml:"xsi:nil,attr,omitempty"`
 	Value   string `xml:",chardata"`
+	// kind is the detected XML Schema type of the value, kept even when xsi:type attributes are not emitted.
+	kind string
 }
 
 type DataRow struct {
 	Columns []DataColumn `xml:",any"`
+	// layout controls how the row is written as XML; the defaults are used when it is nil.
+	layout *xmlLayout
 }
 
 type DataTable struct {
-	Rows []DataRow `xml:"Row"`
+	XMLName xml.Name
+	Name    string     `xml:"name,attr,omitempty"`
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Schema  *xsdSchema `xml:"xs:schema,omitempty"`
+	Headers []string   `xml:"-"`
+	Rows    []DataRow  `xml:"Row"`
+	// sourceHeaders holds the header cells as they appear in the sheet, before renaming and cleaning.
+	sourceHeaders []string
+}
+
+// DataSet nests one Table element per worksheet, so .NET can load a whole workbook with a single DataSet.ReadXml call.
+type DataSet struct {
+	XMLName xml.Name    `xml:"DataSet"`
+	Attrs   []xml.Attr  `xml:",any,attr"`
+	Tables  []DataTable `xml:"Table"`
+}
+
+// options holds the command line settings controlling how the workbook is parsed.
+type options struct {
+	FilePath        string
+	Sheets          []string
+	SheetPattern    string
+	Table           string
+	SheetIndex      int
+	AllSheets       bool
+	Format          string
+	Output          string
+	Force           bool
+	Range           string
+	HeaderRow       int
+	Offset          int
+	Limit           int
+	SkipBlank       bool
+	DropBlank       bool
+	Columns         []string
+	Exclude         []string
+	Typed           bool
+	WithSchema      bool
+	Values          string
+	DateFormat      string
+	Formulas        string
+	Hyperlinks      bool
+	Comments        bool
+	FillMerged      bool
+	MergeMark       string
+	Strict          bool
+	Verbose         bool
+	Stdin           bool
+	Mode            string
+	RootElement     string
+	RowElement      string
+	TableName       string
+	Namespace       string
+	NamespacePrefix string
+	HeaderCase      string
+	DupeStrategy    string
+	BlankHeaders    string
+	Replacement     string
+	Transliterate   bool
+	EmptyCells      string
+	Sanitize        bool
+	StrictRows      bool
+	Metadata        bool
+	BOM             string
+}
+
+// multiSheet reports whether the options select more than one worksheet, whose tables are then
+// written together as a DataSet document. A --sheet-pattern always does, however many sheets it matches,
+// so the shape of the output does not depend on the workbook.
+func (o options) multiSheet() bool {
+	return o.AllSheets || len(o.Sheets) > 1 || len(o.SheetPattern) > 0
 }
 
-// getInput retrieves user input for the file path and sheet name.
-// It uses command line flags to get the user input, and falls back to standard input if no arguments are provided.
-// The function trims any leading/trailing whitespace from the file path.
-// It returns the file path, sheet name, and any input error encountered.
-func getInput() (filePath, sheetName string, inputErr error) {
-	flag.StringVar(&filePath, "path", "", "The path to the .xlsx file to parse")
-	flag.StringVar(&sheetName, "sheet", "", "The name of the worksheet to parse")
+// sheetList collects the values of a flag that may be repeated, also accepting comma-separated lists.
+type sheetList []string
+
+func (l *sheetList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *sheetList) Set(value string) error {
+	*l = append(*l, splitList(value)...)
+	return nil
+}
+
+// getInput retrieves user input for the file path, sheet name and parsing options.
+// The file path is given by --path or as an argument, and falls back to a path piped through standard input.
+// A path of "-" reads the workbook itself from standard input, as --stdin does.
+// It returns the parsing options and any input error encountered.
+func getInput() (opts options, inputErr error) {
+	flag.StringVar(&opts.FilePath, "path", "", "The path to the .xlsx file to parse, or - to read the workbook from standard input")
+	flag.Var((*sheetList)(&opts.Sheets), "sheet", "The name of a worksheet to parse; repeat it, or give a comma-separated list, to parse several into a DataSet document")
+	flag.StringVar(&opts.SheetPattern, "sheet-pattern", "", "Parse every worksheet whose name matches this regular expression, e.g. '^Data_', into a DataSet document")
+	flag.StringVar(&opts.Table, "table", "", "Parse the Excel table (ListObject) with this name, using its header row and data range, instead of a whole sheet")
+	flag.IntVar(&opts.SheetIndex, "sheet-index", -1, "The zero-based position of the worksheet to parse, as an alternative to --sheet")
+	flag.BoolVar(&opts.AllSheets, "all-sheets", false, "Parse every worksheet into a DataSet document with one Table per sheet")
+	flag.StringVar(&opts.Format, "format", formatXML, "The output format: "+strings.Join(outputFormats, ", "))
+	flag.StringVar(&opts.Output, "output", "", "Write the output to this file instead of stdout")
+	flag.StringVar(&opts.Output, "o", "", "Shorthand for --output")
+	flag.BoolVar(&opts.Force, "force", false, "Overwrite the --output file if it already exists")
+	flag.StringVar(&opts.Range, "range", "", "Restrict parsing to a cell range such as A1:F500, whose first row is the header")
+	flag.IntVar(&opts.HeaderRow, "header-row", 1, "The one-based row holding the headers (counted from the start of --range if given); rows above it are ignored")
+	flag.IntVar(&opts.Offset, "offset", 0, "Skip this many data rows of each sheet before emitting any")
+	flag.IntVar(&opts.Limit, "limit", 0, "Emit at most this many data rows of each sheet, stopping the read there (0 emits every row)")
+	flag.BoolVar(&opts.SkipBlank, "skip-blank-rows", false, "Drop data rows whose every cell is empty")
+	flag.BoolVar(&opts.DropBlank, "drop-blank-columns", false, "Remove columns that have an empty header and no data")
+	var columns, exclude string
+	flag.StringVar(&columns, "columns", "", "Comma-separated list of headers to include, all others are left out")
+	flag.StringVar(&exclude, "exclude-columns", "", "Comma-separated list of headers to leave out")
+	flag.StringVar(&opts.Mode, "mode", modeElements, "Emit each column of a row as child 'elements' or as 'attributes' of the row element")
+	flag.StringVar(&opts.RootElement, "root-element", "", "The name of the document's root element (defaults to DataTable, or DataSet with --all-sheets)")
+	flag.StringVar(&opts.RowElement, "row-element", "Row", "The name of the element wrapping each row")
+	flag.StringVar(&opts.TableName, "table-name", "", "Add a name attribute with this value to the DataTable root element")
+	flag.StringVar(&opts.HeaderCase, "header-case", headerCaseNone, "Convert headers to a naming convention before use as element names: "+strings.Join(headerCases, ", "))
+	flag.StringVar(&opts.DupeStrategy, "dupe-strategy", dupeSuffix, "How to handle duplicate headers: 'suffix' renames them, 'error' fails, 'drop' keeps only the first column")
+	flag.StringVar(&opts.BlankHeaders, "blank-headers", blankHeadersNone, "How to name columns with a blank header: 'none' leaves them blank, 'letter' uses the column letter (A, B, C), 'index' uses Column<N>")
+	flag.StringVar(&opts.Replacement, "invalid-char-replacement", "", "Replace characters that are invalid in element names with this token instead of removing them")
+	flag.BoolVar(&opts.Transliterate, "transliterate", false, "Spell out symbols such as $ and % as words and strip accents in headers before cleaning them")
+	flag.StringVar(&opts.EmptyCells, "empty-cells", "", "How blank cells are written in XML: 'omit' leaves them out, 'empty' writes an empty element, 'nil' marks it xsi:nil=\"true\" (defaults to omit with --with-schema, empty otherwise)")
+	flag.StringVar(&opts.Namespace, "namespace", "", "Place the document's elements in this XML namespace, declared on the root element")
+	flag.StringVar(&opts.NamespacePrefix, "namespace-prefix", "", "Bind --namespace to this prefix and qualify every element with it, instead of using a default namespace")
+	flag.BoolVar(&opts.Typed, "typed", false, "Detect cell types and annotate each column element with an xsi:type attribute")
+	flag.BoolVar(&opts.WithSchema, "with-schema", false, "Emit an inline XML Schema before the data for DataSet.ReadXml(XmlReadMode.ReadSchema)")
+	flag.StringVar(&opts.Values, "values", valuesFormatted, "Emit cells using their 'formatted' (displayed) value or their 'raw' stored value")
+	flag.StringVar(&opts.DateFormat, "date-format", "", "Layout for date values, e.g. yyyy-MM-dd or a Go layout such as 2006-01-02 (defaults to yyyy-MM-dd HH:mm:ss)")
+	flag.StringVar(&opts.Formulas, "formulas", formulasCalculated, "How to emit formula cells: "+strings.Join(formulaModes, ", "))
+	flag.BoolVar(&opts.Hyperlinks, "hyperlinks", false, "Include each cell's hyperlink target as an href attribute (or <column>_href key in JSON)")
+	flag.BoolVar(&opts.Comments, "comments", false, "Include each cell's comment as a comment attribute (or <column>_comment key in JSON)")
+	flag.BoolVar(&opts.FillMerged, "fill-merged", false, "Propagate the value of merged cells to every cell they cover")
+	flag.StringVar(&opts.MergeMark, "merge-marker", "", "Fill the cells covered by a merged range with this marker instead of the merged value")
+	flag.BoolVar(&opts.StrictRows, "strict", false, "Fail on data rows with values beyond the last header instead of dropping those values with a warning")
+	flag.BoolVar(&opts.Strict, "strict-extension", false, "Only accept files with the .xlsx extension, rejecting .xlsm, .xltx and .xltm workbooks")
+	flag.BoolVar(&opts.Stdin, "stdin", false, "Read the workbook itself from standard input instead of a file path")
+	flag.BoolVar(&opts.Sanitize, "sanitize-formulas", false, "With --format csv, prefix values starting with =, +, -, @, tab or carriage return with a quote to guard against CSV injection")
+	flag.StringVar(&opts.BOM, "bom", BOMPreserve, "With --format xml or csv, 'add' starts the output with a UTF-8 byte order mark, as Excel needs to read a CSV file as UTF-8; 'strip' and 'preserve' write none, as a workbook has none to keep")
+	flag.BoolVar(&opts.Metadata, "metadata", false, "Emit the workbook's properties (author, created and modified times), sheet list and defined names instead of its data, as XML or JSON")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 
-	if len(filePath) > 0 {
-		filePath = strings.TrimSpace(filePath)
-	} else {
-		pipeInput, pipeErr := os.Stdin.Stat()
-		if pipeErr != nil {
-			inputErr = pipeErr
-		}
-		if pipeInput.Mode()&os.ModeNamedPipe != 0 {
-			reader := bufio.NewReader(os.Stdin)
-			input, bufferErr := reader.ReadString('\n')
-			if bufferErr != nil {
-				inputErr = bufferErr
-			} else {
-				filePath = strings.TrimSpace(input)
-			}
+	if opts.DateFormat, inputErr = toGoLayout(opts.DateFormat); inputErr != nil {
+		inputErr = ErrMsg{Err: inputErr, Code: ErrInvalidArgs}
+		return
+	}
+	opts.Columns = splitList(columns)
+	opts.Exclude = splitList(exclude)
+	if opts.Stdin {
+		// Standard input holds the workbook, so it cannot also hold its path
+		if len(strings.TrimSpace(opts.FilePath)) > 0 || flag.NArg() > 0 {
+			inputErr = ErrMsg{Err: errors.New("--stdin cannot be used with --path or a file argument"), Code: ErrInvalidArgs}
 		}
+		return
+	}
+	input, inputErr := ResolveInput(opts.FilePath, flag.Args())
+	if inputErr != nil {
+		return
+	}
+	if input.IsStdin() {
+		opts.Stdin, opts.FilePath = true, ""
+	} else {
+		opts.FilePath = input.Path
 	}
 	return
 }
@@ -61,80 +207,502 @@ func main() {
 	defer func() {
 		processingErr.Exit()
 	}()
-	filePath, sheetName, inputErr := getInput()
+	opts, inputErr := getInput()
+	// --verbose also logs periodic progress while large sheets are parsed; the document is the only thing
+	// ever written to stdout, whatever the logging flags
+	if logErr := logging.Setup(); logErr != nil {
+		processingErr = ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+		return
+	}
+	opts.Verbose = logging.Verbose()
+	filePath := opts.FilePath
+	if !validFormat(opts.Format) {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("unsupported output format '%s'", opts.Format),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if opts.Sanitize && opts.Format != formatCSV {
+		processingErr = ErrMsg{
+			Err:  errors.New("--sanitize-formulas only applies to --format csv"),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if !ValidBOMMode(opts.BOM) {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("unknown --bom '%s', expected one of %s", opts.BOM, strings.Join(BOMModes, ", ")),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if opts.BOM == BOMAdd && opts.Format != formatXML && opts.Format != formatCSV {
+		processingErr = ErrMsg{
+			Err:  errors.New("--bom add only applies to --format xml or csv"),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if opts.Metadata && opts.Format != formatXML && opts.Format != formatJSON {
+		processingErr = ErrMsg{
+			Err:  errors.New("--metadata can only be written as --format xml or json"),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if (len(opts.Sheets) > 0 || len(opts.SheetPattern) > 0) && opts.SheetIndex >= 0 {
+		processingErr = ErrMsg{
+			Err:  errors.New("--sheet-index cannot be used with --sheet or --sheet-pattern"),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if opts.AllSheets && (len(opts.Sheets) > 0 || len(opts.SheetPattern) > 0 || opts.SheetIndex >= 0) {
+		processingErr = ErrMsg{
+			Err:  errors.New("--all-sheets cannot be used with --sheet, --sheet-pattern or --sheet-index"),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if len(opts.Table) > 0 && (opts.multiSheet() || len(opts.Sheets) > 0 || opts.SheetIndex >= 0 || len(opts.Range) > 0) {
+		processingErr = ErrMsg{
+			Err:  errors.New("--table selects its own sheet and range, so it cannot be used with --sheet, --sheet-pattern, --sheet-index, --all-sheets or --range"),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if _, patternErr := regexp.Compile(opts.SheetPattern); patternErr != nil {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("invalid --sheet-pattern '%s': %w", opts.SheetPattern, patternErr),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if len(opts.Output) > 0 && !opts.Force {
+		if exists, _ := PathExists(opts.Output); exists {
+			processingErr = ErrMsg{
+				Err:  fmt.Errorf("output file '%s' already exists, use --force to overwrite it", opts.Output),
+				Code: ErrWriteFile,
+			}
+			return
+		}
+	}
+	if opts.WithSchema && (opts.multiSheet() || opts.Format != formatXML) {
+		processingErr = ErrMsg{
+			Err:  errors.New("--with-schema requires a single sheet and the xml format"),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if !slices.Contains(xmlModes, opts.Mode) {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("unknown --mode '%s', expected one of %s", opts.Mode, strings.Join(xmlModes, ", ")),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if opts.Mode == modeAttributes && (opts.Format != formatXML || opts.Typed || opts.WithSchema) {
+		processingErr = ErrMsg{
+			Err:  errors.New("--mode attributes requires the xml format and cannot be used with --typed or --with-schema"),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	for _, element := range []string{opts.RootElement, opts.RowElement} {
+		if len(element) > 0 && !validElementName(element) {
+			processingErr = ErrMsg{
+				Err:  fmt.Errorf("'%s' is not a valid XML element name", element),
+				Code: ErrInvalidArgs,
+			}
+			return
+		}
+	}
+	if !slices.Contains(headerCases, opts.HeaderCase) {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("unknown --header-case '%s', expected one of %s", opts.HeaderCase, strings.Join(headerCases, ", ")),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if !slices.Contains(dupeStrategies, opts.DupeStrategy) {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("unknown --dupe-strategy '%s', expected one of %s", opts.DupeStrategy, strings.Join(dupeStrategies, ", ")),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if !slices.Contains(blankHeaderModes, opts.BlankHeaders) {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("unknown --blank-headers '%s', expected one of %s", opts.BlankHeaders, strings.Join(blankHeaderModes, ", ")),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if FixXMLTags(opts.Replacement) != opts.Replacement {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("--invalid-char-replacement '%s' must not itself contain invalid characters", opts.Replacement),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if len(opts.EmptyCells) > 0 && !slices.Contains(emptyCellModes, opts.EmptyCells) {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("unknown --empty-cells '%s', expected one of %s", opts.EmptyCells, strings.Join(emptyCellModes, ", ")),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if opts.EmptyCells == emptyCellsNil && opts.Mode == modeAttributes {
+		processingErr = ErrMsg{
+			Err:  errors.New("--empty-cells nil cannot be used with --mode attributes, use omit or empty instead"),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if len(opts.NamespacePrefix) > 0 && (len(opts.Namespace) == 0 || !validElementName(opts.NamespacePrefix)) {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("--namespace-prefix '%s' must be a valid XML name and requires --namespace", opts.NamespacePrefix),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if len(opts.TableName) > 0 && opts.multiSheet() {
+		processingErr = ErrMsg{
+			Err:  errors.New("--table-name requires a single sheet, since each table of a DataSet is named after its sheet"),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if opts.Values != valuesFormatted && opts.Values != valuesRaw {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("unknown --values '%s', expected '%s' or '%s'", opts.Values, valuesFormatted, valuesRaw),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if !slices.Contains(formulaModes, opts.Formulas) {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("unknown --formulas '%s', expected one of %s", opts.Formulas, strings.Join(formulaModes, ", ")),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if len(opts.Range) > 0 {
+		if _, rangeErr := parseCellRange(opts.Range); rangeErr != nil {
+			processingErr = ErrMsg{Err: rangeErr, Code: ErrInvalidArgs}
+			return
+		}
+	}
+	if opts.Offset < 0 || opts.Limit < 0 {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("--offset and --limit must not be negative, got %d and %d", opts.Offset, opts.Limit),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	if opts.HeaderRow < 1 {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("--header-row must be at least 1, got %d", opts.HeaderRow),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
 	// Get user input
 	if inputErr != nil {
-		processingErr = ErrMsg{Err: inputErr, Code: ErrStdin}
+		processingErr = ToErrMsg(inputErr, ErrStdin)
+		return
 	}
-	// Validate user input
-	if len(filePath) < 1 {
-		processingErr = ErrMsg{Code: ErrNoInput}
+	parse := func(w io.Writer) error {
+		return parseXlsxFile(filePath, opts, w)
 	}
-	// Validate file path
-	exists, pathErr := PathExists(filePath)
-	if pathErr != nil || !exists {
-		processingErr = ErrMsg{Err: pathErr, Code: ErrNoFile}
+	if opts.Stdin {
+		parse = func(w io.Writer) error {
+			return parseXlsxReader(os.Stdin, opts, w)
+		}
+	} else {
+		if fileErr := checkXlsxFile(filePath, opts.Strict); fileErr.Code != Success {
+			processingErr = fileErr
+			return
+		}
 	}
-	// Validate file type
-	if !isXlsxFile(filePath) {
-		processingErr = ErrMsg{
-			Err:  errors.New("invalid file type"),
-			Code: ErrInvalidFileType,
+	if opts.BOM == BOMAdd {
+		parseContent := parse
+		parse = func(w io.Writer) error {
+			if err := WriteBOM(w, opts.BOM, false); err != nil {
+				return err
+			}
+			return parseContent(w)
 		}
 	}
-	// Parse the file as XML
-	output, parseErr := parseXlsxFile(filePath, sheetName)
-	if parseErr != nil {
-		processingErr = ErrMsg{Err: parseErr, Code: ErrParse}
+	// Parse the file, writing the output to the requested file or stdout as it is produced
+	if len(opts.Output) > 0 {
+		// The document replaces the file only once it is complete, so a failure leaves any existing file as it was
+		outputFile, createErr := CreateAtomic(opts.Output)
+		if createErr != nil {
+			processingErr = ErrMsg{Err: createErr, Code: ErrWriteFile}
+			return
+		}
+		output := &trackedWriter{Writer: outputFile}
+		parseErr := parse(output)
+		if output.err != nil {
+			outputFile.Abort()
+			processingErr = ErrMsg{Err: output.err, Code: ErrWriteFile}
+		} else if parseErr != nil {
+			outputFile.Abort()
+			processingErr = ErrMsg{Err: parseErr, Code: ErrParse}
+		} else if commitErr := outputFile.Commit(); commitErr != nil {
+			processingErr = ErrMsg{Err: commitErr, Code: ErrWriteFile}
+		}
 	} else {
-		// Write the output to stdout
-		_, writeErr := os.Stdout.Write(output)
-		if writeErr != nil {
-			processingErr = ErrMsg{Err: writeErr, Code: ErrStdout}
+		output := &trackedWriter{Writer: os.Stdout}
+		parseErr := parse(output)
+		if output.err != nil {
+			processingErr = ErrMsg{Err: output.err, Code: ErrStdout}
+		} else if parseErr != nil {
+			processingErr = ErrMsg{Err: parseErr, Code: ErrParse}
+		}
+	}
+}
+
+// trackedWriter records the first error returned by the underlying writer,
+// so failures to write the output can be told apart from failures to parse the workbook.
+type trackedWriter struct {
+	io.Writer
+	err error
+}
+
+// Write writes p to the underlying writer, remembering the error if it fails.
+func (w *trackedWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	return n, err
+}
+
+// checkXlsxFile checks that the file at path exists and is a workbook that can be parsed, as isXlsxFile decides.
+func checkXlsxFile(path string, strict bool) ErrMsg {
+	if _, statErr := os.Stat(path); statErr != nil {
+		return ErrMsg{Err: fmt.Errorf("cannot read file '%s': %w", path, statErr), Code: ErrNoFile}
+	}
+	if !isXlsxFile(path, strict) {
+		return ErrMsg{
+			Err:  fmt.Errorf("file '%s' is not a workbook", path),
+			Code: ErrInvalidFileType,
 		}
 	}
+	return ErrMsg{Code: Success}
 }
 
-// CheckExtension checks if the given file path has the specified extension.
-// It adds a dot to the beginning of the extension if it's missing.
-// Returns true if the file extension matches the specified extension, and false otherwise.
-func isXlsxFile(path string) bool {
-	return CheckExtension(path, ".xlsx")
+// isXlsxFile checks if the given file path is a workbook that can be parsed.
+// Macro-enabled and template workbooks (.xlsm, .xltx, .xltm) are accepted unless strict is set,
+// in which case only the .xlsx extension is.
+func isXlsxFile(path string, strict bool) bool {
+	return IsWorkbookFile(path, strict)
 }
 
-func parseXlsxFile(path, targetSheet string) (output []byte, parseErr error) {
+// parseXlsxFile opens the workbook at path and writes it to w as described on parseWorkbook.
+func parseXlsxFile(path string, opts options, w io.Writer) (parseErr error) {
 	// Open the .xlsx file
 	file, openFileErr := excelize.OpenFile(path)
 	if openFileErr != nil {
-		return nil, openFileErr
+		return openFileErr
 	}
 	defer func(file *excelize.File) {
 		err := file.Close()
-		if err != nil {
+		if err != nil && parseErr == nil {
 			parseErr = err
 		}
 	}(file)
+	return parseWorkbook(file, opts, w)
+}
 
-	// Get the target sheet, or the default if no target was provided
-	var rows *excelize.Rows
-	var rowsErr error
+// parseXlsxReader reads a whole workbook from r, such as standard input, and writes it to w
+// as described on parseWorkbook.
+func parseXlsxReader(r io.Reader, opts options, w io.Writer) (parseErr error) {
+	file, openErr := excelize.OpenReader(r)
+	if openErr != nil {
+		return openErr
+	}
+	defer func(file *excelize.File) {
+		err := file.Close()
+		if err != nil && parseErr == nil {
+			parseErr = err
+		}
+	}(file)
+	return parseWorkbook(file, opts, w)
+}
 
-	if len(targetSheet) > 1 {
-		rows, rowsErr = file.Rows(targetSheet)
+// parseWorkbook writes the selected worksheet of file to w as a DataTable document,
+// or the selected worksheets as a DataSet document if several are selected, using the format given by opts.Format.
+// Plain XML output is streamed row by row; other formats, and options that need to see a whole sheet
+// before writing it, are built in memory first. If opts.Table names an Excel table, only its header and data rows are read.
+// With opts.Metadata, the workbook's properties are written in place of its data.
+func parseWorkbook(file *excelize.File, opts options, w io.Writer) error {
+	if opts.Metadata {
+		return writeMetadata(file, opts, w)
+	}
+	var sheetNames []string
+	if len(opts.Table) > 0 {
+		sheet, ref, tableErr := findTable(file, opts.Table)
+		if tableErr != nil {
+			return tableErr
+		}
+		sheetNames, opts.Range = []string{sheet}, ref
 	} else {
-		rows, rowsErr = file.Rows(file.GetSheetName(0))
+		var sheetsErr error
+		if sheetNames, sheetsErr = targetSheets(file, opts); sheetsErr != nil {
+			return sheetsErr
+		}
+	}
+	if canStream(opts) {
+		return streamXML(file, sheetNames, opts, w)
 	}
+	var tables []DataTable
+	for _, sheetName := range sheetNames {
+		dataTable, sheetErr := parseSheet(file, sheetName, opts)
+		if sheetErr != nil {
+			return sheetErr
+		}
+		if opts.multiSheet() {
+			dataTable.Name = sheetName
+		} else {
+			dataTable.Name = opts.TableName
+		}
+		tables = append(tables, dataTable)
+	}
+	// Marshal the data into the requested format
+	marshalled, marshalErr := marshalDocument(tables, opts)
+	if marshalErr != nil {
+		return marshalErr
+	}
+	_, writeErr := w.Write(marshalled)
+	return writeErr
+}
+
+// targetSheets returns the names of the worksheets to parse: every sheet if opts.AllSheets is set,
+// otherwise the sheets named by opts.Sheets followed by those matching opts.SheetPattern, in workbook order
+// and without repeats, or the sheet selected by index, or the first sheet if no target was provided.
+func targetSheets(file *excelize.File, opts options) ([]string, error) {
+	if opts.AllSheets {
+		return file.GetSheetList(), nil
+	}
+	if len(opts.Sheets) > 0 || len(opts.SheetPattern) > 0 {
+		var targets []string
+		for _, sheet := range opts.Sheets {
+			if index, _ := file.GetSheetIndex(sheet); index < 0 {
+				return nil, fmt.Errorf("sheet '%s' does not exist", sheet)
+			}
+			if !slices.Contains(targets, sheet) {
+				targets = append(targets, sheet)
+			}
+		}
+		if len(opts.SheetPattern) > 0 {
+			pattern, patternErr := regexp.Compile(opts.SheetPattern)
+			if patternErr != nil {
+				return nil, patternErr
+			}
+			matched := 0
+			for _, sheet := range file.GetSheetList() {
+				if pattern.MatchString(sheet) {
+					matched++
+					if !slices.Contains(targets, sheet) {
+						targets = append(targets, sheet)
+					}
+				}
+			}
+			if matched == 0 {
+				return nil, fmt.Errorf("no sheet matches the pattern '%s'", opts.SheetPattern)
+			}
+		}
+		return targets, nil
+	}
+	targetSheet := file.GetSheetName(0)
+	if opts.SheetIndex >= 0 {
+		if targetSheet = file.GetSheetName(opts.SheetIndex); len(targetSheet) < 1 {
+			return nil, fmt.Errorf("sheet index %d is out of range", opts.SheetIndex)
+		}
+	}
+	return []string{targetSheet}, nil
+}
+
+// openSheet prepares a tableReader over the named worksheet of file, restricted to opts.Range if one was given,
+// and ignoring any rows above opts.HeaderRow. The reader must be closed once the rows have been read.
+func openSheet(file *excelize.File, sheetName string, opts options) (*tableReader, error) {
+	rows, rowsErr := file.Rows(sheetName)
 	if rowsErr != nil {
 		return nil, rowsErr
 	}
-	// Marshal the data into XML
-	xmlOutput, marshalErr := xml.MarshalIndent(buildDataTable(rows), "", "  ")
-	if marshalErr != nil {
-		return nil, marshalErr
-	} else {
-		output = xmlOutput
+	var source rowSource = &sheetRows{Rows: rows}
+	if opts.FillMerged || len(opts.MergeMark) > 0 {
+		merged, mergedErr := newMergedRows(source, file, sheetName, opts.MergeMark)
+		if mergedErr != nil {
+			_ = rows.Close()
+			return nil, mergedErr
+		}
+		source = merged
+	}
+	if len(opts.Range) > 0 {
+		bounds, rangeErr := parseCellRange(opts.Range)
+		if rangeErr != nil {
+			_ = rows.Close()
+			return nil, rangeErr
+		}
+		source = &rangeRows{rows: source, bounds: bounds}
+	}
+	if opts.HeaderRow > 1 {
+		source = &headerRows{rows: source, skip: opts.HeaderRow - 1}
 	}
-	return output, nil
+	var inspector *cellInspector
+	if needsInspector(opts) {
+		inspector = newCellInspector(file, sheetName, opts)
+	}
+	reader := newTableReader(source, opts, inspector)
+	reader.closer = rows
+	if reader.Err() != nil {
+		_ = rows.Close()
+		return nil, fmt.Errorf("sheet '%s': %w", sheetName, reader.Err())
+	}
+	if opts.Verbose {
+		reader.progress = newProgressLogger(file, sheetName)
+	}
+	return reader, nil
+}
+
+// parseSheet reads the named worksheet of file into a DataTable, restricted to opts.Range if one was given,
+// and ignoring any rows above opts.HeaderRow.
+func parseSheet(file *excelize.File, sheetName string, opts options) (DataTable, error) {
+	reader, openErr := openSheet(file, sheetName, opts)
+	if openErr != nil {
+		return DataTable{}, openErr
+	}
+	defer func(reader *tableReader) {
+		_ = reader.Close()
+	}(reader)
+	dataTable, readErr := collectDataTable(reader)
+	if readErr != nil {
+		return DataTable{}, fmt.Errorf("sheet '%s': %w", sheetName, readErr)
+	}
+	if opts.DropBlank {
+		dropBlankColumns(&dataTable)
+	}
+	if len(opts.Columns) > 0 || len(opts.Exclude) > 0 {
+		if filterErr := filterColumns(&dataTable, opts.Columns, opts.Exclude); filterErr != nil {
+			return DataTable{}, filterErr
+		}
+	}
+	return dataTable, nil
+}
+
+// isBlankRow checks if every cell of a row is empty or whitespace.
+func isBlankRow(columns []string) bool {
+	for _, column := range columns {
+		if len(strings.TrimSpace(column)) > 0 {
+			return false
+		}
+	}
+	return true
 }
 
 // cleanHeader takes a pointer to a string `header` as input and modifies it.
@@ -152,46 +720,206 @@ func cleanHeader(header *string) {
 	*header = newHeader
 }
 
-// buildDataTable takes an excelize.Rows pointer as input and converts it into a DataTable struct.
-// It iterates over each row in the rows and converts each row into a DataRow struct.
-// If the rows pointer is nil, it returns an empty DataTable struct.
-// For the first row, it renames any duplicate headers using the RenameDuplicates function.
-// It then calls the cleanHeader function to clean each header.
-// For subsequent rows, it converts each column into a DataColumn struct and appends it to the DataRow struct.
-// The DataRow struct is then appended to the Rows field of the DataTable struct.
-// The function returns the populated DataTable struct.
-func buildDataTable(rows *excelize.Rows) DataTable {
-	var dataTable DataTable
-	var headerRow []string
-	var rowIndex int
-	if rows == nil {
-		return dataTable
-	}
-	for rows.Next() {
-		columns, colErr := rows.Columns()
-		if colErr != nil {
-			return DataTable{}
+// tableReader converts the rows of a rowSource into DataRows one at a time, so a sheet can be written out
+// as it is read rather than held in memory.
+// The first row is consumed as the header row when the reader is created, with blank headers named as set by
+// opts.BlankHeaders, transliterated if opts.Transliterate is set, converted to opts.HeaderCase and cleaned with
+// the cleanHeader function; any headers that are then duplicates are handled as opts.DupeStrategy says,
+// renaming them with the RenameDuplicates function by default.
+type tableReader struct {
+	rows      rowSource
+	opts      options
+	inspector *cellInspector
+	closer    io.Closer
+	progress  *progressLogger
+	layout    *xmlLayout
+	// keep holds the sheet columns read into each row, or nil to read them all.
+	keep          []int
+	Headers       []string
+	sourceHeaders []string
+	// width is the number of columns in the header row, before any duplicates are dropped.
+	width int
+	// warnedWide records that a row with values beyond the last header has been reported.
+	warnedWide bool
+	// skipped and emitted count the data rows passed over for opts.Offset and returned for opts.Limit.
+	skipped, emitted int
+	err              error
+}
+
+// newTableReader reads the header row of rows and returns a tableReader positioned on the first data row.
+// If an inspector is given, each value is typed using the cell's metadata in the sheet.
+func newTableReader(rows rowSource, opts options, inspector *cellInspector) *tableReader {
+	reader := &tableReader{rows: rows, opts: opts, inspector: inspector, layout: newXMLLayout(opts)}
+	if rows == nil || !rows.Next() {
+		return reader
+	}
+	columns, colErr := rows.Columns(excelize.Options{RawCellValue: opts.Values == valuesRaw})
+	if colErr != nil {
+		rowNum, _ := rows.Position()
+		reader.err = fmt.Errorf("header row %d: %w", rowNum, colErr)
+		return reader
+	}
+	reader.width = len(columns)
+	reader.sourceHeaders = append([]string(nil), columns...)
+	_, firstColumn := rows.Position()
+	fillBlankHeaders(columns, opts.BlankHeaders, firstColumn)
+	for headerIndex := range columns {
+		if opts.Transliterate {
+			columns[headerIndex] = TransliterateXMLTag(columns[headerIndex])
 		}
-		if rowIndex == 0 {
-			headerRow = RenameDuplicates(columns, false)
-			for headerIndex := range headerRow {
-				cleanHeader(&headerRow[headerIndex])
+		columns[headerIndex] = applyHeaderCase(columns[headerIndex], opts.HeaderCase)
+		// Headers are cleaned before duplicates are looked for, as cleaning can make distinct headers equal
+		if len(opts.Replacement) > 0 {
+			columns[headerIndex] = ReplaceXMLTagChars(columns[headerIndex], opts.Replacement)
+		} else {
+			cleanHeader(&columns[headerIndex])
+		}
+	}
+	switch opts.DupeStrategy {
+	case dupeError:
+		if duplicates := duplicateHeaders(columns); len(duplicates) > 0 {
+			reader.err = fmt.Errorf("duplicate headers: %s", strings.Join(duplicates, ", "))
+			return reader
+		}
+	case dupeDrop:
+		reader.keep = firstOccurrences(columns)
+		if len(reader.keep) < len(columns) {
+			kept, keptSource := make([]string, len(reader.keep)), make([]string, len(reader.keep))
+			for i, columnIndex := range reader.keep {
+				kept[i], keptSource[i] = columns[columnIndex], reader.sourceHeaders[columnIndex]
 			}
+			log.Warn("Dropped duplicate columns", "columns", len(columns)-len(kept))
+			columns, reader.sourceHeaders = kept, keptSource
 		} else {
-			// Dirty workaround because `(*rows).Columns()` doesn't do what it says it does.
-			for len(columns) < len(headerRow) {
-				columns = append(columns, "")
+			reader.keep = nil
+		}
+	}
+	named := slices.Clone(columns)
+	reader.Headers = RenameDuplicates(columns, false)
+	for headerIndex := range reader.Headers {
+		if reader.Headers[headerIndex] != named[headerIndex] {
+			// Diagnostics go to stderr so they never mix with a document written to stdout
+			log.Warn("Renamed duplicate header", "header", named[headerIndex], "to", reader.Headers[headerIndex])
+		}
+	}
+	return reader
+}
+
+// Next converts the next data row into a DataRow, skipping rows whose every cell is empty if opts.SkipBlank is set.
+// The first opts.Offset data rows are passed over, and once opts.Limit rows have been returned the rest of the
+// sheet is not read at all.
+// It returns false once the rows are exhausted or an error occurs; Err reports which, along with the sheet row
+// that could not be read.
+// Values beyond the last header have no element name, so they are dropped with a warning,
+// or reported as an error if opts.StrictRows is set.
+func (t *tableReader) Next() (DataRow, bool) {
+	if t.rows == nil || t.err != nil {
+		return DataRow{}, false
+	}
+	for (t.opts.Limit == 0 || t.emitted < t.opts.Limit) && t.rows.Next() {
+		rowNum, firstColumn := t.rows.Position()
+		t.progress.update(rowNum)
+		columns, colErr := t.rows.Columns(excelize.Options{RawCellValue: t.opts.Values == valuesRaw})
+		if colErr != nil {
+			t.err = fmt.Errorf("row %d: %w", rowNum, colErr)
+			return DataRow{}, false
+		}
+		if t.opts.SkipBlank && isBlankRow(columns) {
+			continue
+		}
+		if t.skipped < t.opts.Offset {
+			t.skipped++
+			continue
+		}
+		if len(columns) > t.width {
+			if !isBlankRow(columns[t.width:]) {
+				if t.opts.StrictRows {
+					t.err = fmt.Errorf("row %d has values in %d columns but the header row only has %d", rowNum, len(columns), t.width)
+					return DataRow{}, false
+				}
+				if !t.warnedWide {
+					log.Warn("Dropped values beyond the last header", "row", rowNum, "columns", len(columns), "headers", t.width)
+					t.warnedWide = true
+				}
+			}
+			columns = columns[:t.width]
+		}
+		// Dirty workaround because `(*rows).Columns()` doesn't do what it says it does.
+		width := len(t.Headers)
+		if len(t.keep) > 0 {
+			width = t.keep[len(t.keep)-1] + 1
+		}
+		for len(columns) < width {
+			columns = append(columns, "")
+		}
+		dataRow := DataRow{layout: t.layout}
+		indexes := t.keep
+		if indexes == nil {
+			indexes = make([]int, len(columns))
+			for i := range indexes {
+				indexes[i] = i
 			}
-			var dataRow DataRow
-			for columnIndex := range columns {
-				columnName := headerRow[columnIndex]
-				columnValue := ConvertToISO8601(columns[columnIndex])
-				column := DataColumn{XMLName: xml.Name{Local: columnName}, Value: columnValue}
-				dataRow.Columns = append(dataRow.Columns, column)
+		}
+		for headerIndex, columnIndex := range indexes {
+			columnName := t.Headers[headerIndex]
+			column := DataColumn{XMLName: xml.Name{Local: columnName}}
+			if t.inspector != nil {
+				column.kind, column.Value = t.inspector.typedValue(firstColumn+columnIndex, rowNum, columns[columnIndex])
+				if t.opts.Typed {
+					column.Type = column.kind
+				}
+				column.Href, column.Comment = t.inspector.annotations(firstColumn+columnIndex, rowNum)
+			} else {
+				column.Value = ConvertToISO8601(columns[columnIndex])
 			}
-			dataTable.Rows = append(dataTable.Rows, dataRow)
+			dataRow.Columns = append(dataRow.Columns, column)
 		}
-		rowIndex++
+		t.emitted++
+		return dataRow, true
+	}
+	// excelize reports a failure to read the sheet XML through Error rather than Columns.
+	if source, ok := t.rows.(interface{ Error() error }); ok && source.Error() != nil {
+		rowNum, _ := t.rows.Position()
+		t.err = fmt.Errorf("after row %d: %w", rowNum, source.Error())
+	}
+	t.progress.done()
+	t.rows = nil
+	return DataRow{}, false
+}
+
+// Err returns the first error encountered while reading the rows.
+func (t *tableReader) Err() error {
+	return t.err
+}
+
+// Close releases the underlying sheet rows, if the reader was opened over a worksheet.
+func (t *tableReader) Close() error {
+	if t.closer == nil {
+		return nil
 	}
-	return dataTable
+	return t.closer.Close()
+}
+
+// collectDataTable reads every remaining row of reader into a DataTable.
+// If the rows cannot be read in full, it returns the reader's error rather than a partial table,
+// so a failed read is never mistaken for a sheet without data.
+func collectDataTable(reader *tableReader) (DataTable, error) {
+	dataTable := DataTable{Headers: reader.Headers, sourceHeaders: reader.sourceHeaders}
+	for dataRow, ok := reader.Next(); ok; dataRow, ok = reader.Next() {
+		dataTable.Rows = append(dataTable.Rows, dataRow)
+	}
+	if reader.Err() != nil {
+		return DataTable{}, reader.Err()
+	}
+	return dataTable, nil
+}
+
+// buildDataTable takes a rowSource, such as an excelize.Rows pointer, as input and converts it into a DataTable struct.
+// The first row provides the headers and each subsequent row becomes a DataRow, as described on tableReader.
+// If the rows source is nil, it returns an empty DataTable struct.
+// If an inspector is given, each value is typed using the cell's metadata in the sheet.
+// The function returns the populated DataTable struct, or the first error met while reading the rows,
+// identifying the row that could not be read.
+func buildDataTable(rows rowSource, opts options, inspector *cellInspector) (DataTable, error) {
+	return collectDataTable(newTableReader(rows, opts, inspector))
 }
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
+	. "GoTools/pkg/helpers"
 	"github.com/xuri/excelize/v2"
 )
 
@@ -82,7 +86,11 @@ func createOutputXmlFile(fileName string, xmlData []byte) (string, error) {
 		fileName = strings.TrimSuffix(fileName, suffix) + ".xml"
 	}
 	dir, _ := os.Getwd()
-	filePath := filepath.Join(dir, "test-results", fileName)
+	outputDir := filepath.Join(dir, "test-results")
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return "", err
+	}
+	filePath := filepath.Join(outputDir, fileName)
 
 	// write output to xml file
 	xmlFile, xmlFileErr := os.Create(filePath)
@@ -101,6 +109,10 @@ func createOutputXmlFile(fileName string, xmlData []byte) (string, error) {
 	return filePath, nil
 }
 
+func sheetIndex(index int) *int {
+	return &index
+}
+
 func TestParseXlsxFile(t *testing.T) {
 	// Define test files.
 	type testFile struct {
@@ -119,6 +131,14 @@ func TestParseXlsxFile(t *testing.T) {
 		name        string
 		filePath    string
 		targetSheet string
+		sheetIndex  *int
+		allSheets   bool
+		format      string
+		cellRange   string
+		headerRow   int
+		skipBlank   bool
+		typed       bool
+		withSchema  bool
 		wantErr     bool
 	}{
 		{
@@ -137,6 +157,81 @@ func TestParseXlsxFile(t *testing.T) {
 			targetSheet: "InvalidSheet",
 			wantErr:     true,
 		},
+		{
+			name:       "Valid Sheet Index",
+			sheetIndex: sheetIndex(1),
+			wantErr:    false,
+		},
+		{
+			name:       "Invalid Sheet Index",
+			sheetIndex: sheetIndex(5),
+			wantErr:    true,
+		},
+		{
+			name:        "Valid Range",
+			targetSheet: "TestSheet",
+			cellRange:   "B2:E6",
+			wantErr:     false,
+		},
+		{
+			name:        "Invalid Range",
+			targetSheet: "TestSheet",
+			cellRange:   "B2",
+			wantErr:     true,
+		},
+		{
+			name:        "Header Row",
+			targetSheet: "TestSheet",
+			headerRow:   3,
+			wantErr:     false,
+		},
+		{
+			name:        "Skip Blank Rows",
+			targetSheet: "TestSheet",
+			skipBlank:   true,
+			wantErr:     false,
+		},
+		{
+			name:        "Typed",
+			targetSheet: "TestSheet",
+			typed:       true,
+			wantErr:     false,
+		},
+		{
+			name:        "With Schema",
+			targetSheet: "TestSheet",
+			withSchema:  true,
+			wantErr:     false,
+		},
+		{
+			name:      "All Sheets",
+			allSheets: true,
+			wantErr:   false,
+		},
+		{
+			name:        "JSON Format",
+			targetSheet: "TestSheet",
+			format:      formatJSON,
+			wantErr:     false,
+		},
+		{
+			name:        "CSV Format",
+			targetSheet: "TestSheet",
+			format:      formatCSV,
+			wantErr:     false,
+		},
+		{
+			name:      "YAML Format All Sheets",
+			allSheets: true,
+			format:    formatYAML,
+			wantErr:   false,
+		},
+		{
+			name:      "CSV Format All Sheets",
+			allSheets: true,
+			format:    formatCSV,
+			wantErr:   true,
+		},
 	}
 	// Now start with the testing.
 	for fileNum, file := range testFiles {
@@ -152,7 +247,23 @@ func TestParseXlsxFile(t *testing.T) {
 			}
 			// Clean up the test file when done.
 			t.Run(tt.name, func(t *testing.T) {
-				output, err := parseXlsxFile(tt.filePath, tt.targetSheet)
+				opts := options{
+					Sheets:     splitList(tt.targetSheet),
+					SheetIndex: -1,
+					AllSheets:  tt.allSheets,
+					Format:     tt.format,
+					Range:      tt.cellRange,
+					HeaderRow:  tt.headerRow,
+					SkipBlank:  tt.skipBlank,
+					Typed:      tt.typed,
+					WithSchema: tt.withSchema,
+				}
+				if tt.sheetIndex != nil {
+					opts.SheetIndex = *tt.sheetIndex
+				}
+				var buf bytes.Buffer
+				err := parseXlsxFile(tt.filePath, opts, &buf)
+				output := buf.Bytes()
 				if (err != nil) != tt.wantErr {
 					t.Errorf("parseXlsxFile() error = %v, wantErr %v", err, tt.wantErr)
 				}
@@ -174,3 +285,729 @@ func TestParseXlsxFile(t *testing.T) {
 		}
 	}
 }
+
+// sliceRows is an in-memory rowSource used to exercise buildDataTable without a workbook.
+type sliceRows struct {
+	rows  [][]string
+	index int
+}
+
+func (r *sliceRows) Next() bool {
+	r.index++
+	return r.index <= len(r.rows)
+}
+
+func (r *sliceRows) Columns(...excelize.Options) ([]string, error) {
+	return r.rows[r.index-1], nil
+}
+
+func (r *sliceRows) Position() (row, column int) {
+	return r.index, 1
+}
+
+func TestMergedRows(t *testing.T) {
+	file := excelize.NewFile()
+	defer func() {
+		_ = file.Close()
+	}()
+	rows := [][]any{{"Name", "Amount", "Note"}, {"ann", 1234.5, "wide"}, {"bob"}}
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := file.SetSheetRow("Sheet1", cell, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	style, styleErr := file.NewStyle(&excelize.Style{NumFmt: 4})
+	if styleErr != nil {
+		t.Fatal(styleErr)
+	}
+	if err := file.SetCellStyle("Sheet1", "B2", "B2", style); err != nil {
+		t.Fatal(err)
+	}
+	for _, cells := range [][2]string{{"B2", "B3"}, {"C2", "D2"}} {
+		if err := file.MergeCell("Sheet1", cells[0], cells[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "merged.xlsx")
+	if err := file.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		marker string
+		raw    bool
+		want   [][]string
+	}{
+		{name: "Formatted", want: [][]string{{"Name", "Amount", "Note"}, {"ann", "1,234.50", "wide", "wide"}, {"bob", "1,234.50"}}},
+		{name: "Raw", raw: true, want: [][]string{{"Name", "Amount", "Note"}, {"ann", "1234.5", "wide", "wide"}, {"bob", "1234.5"}}},
+		{name: "Marker", marker: "^", want: [][]string{{"Name", "Amount", "Note"}, {"ann", "1,234.50", "wide", "^"}, {"bob", "^"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved, openErr := excelize.OpenFile(path)
+			if openErr != nil {
+				t.Fatal(openErr)
+			}
+			defer func() {
+				_ = saved.Close()
+			}()
+			sheet, rowsErr := saved.Rows("Sheet1")
+			if rowsErr != nil {
+				t.Fatal(rowsErr)
+			}
+			merged, mergedErr := newMergedRows(&sheetRows{Rows: sheet}, saved, "Sheet1", tt.marker)
+			if mergedErr != nil {
+				t.Fatal(mergedErr)
+			}
+			var got [][]string
+			for merged.Next() {
+				columns, err := merged.Columns(excelize.Options{RawCellValue: tt.raw})
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, columns)
+			}
+			if !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("rows = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildDataTableSkipBlankRows(t *testing.T) {
+	rows := [][]string{
+		{"Name", "Age"},
+		{"Bob", "3"},
+		{"", " "},
+		{},
+		{"Ann", ""},
+	}
+	tests := []struct {
+		name      string
+		skipBlank bool
+		wantRows  int
+	}{
+		{name: "Keep Blank Rows", skipBlank: false, wantRows: 4},
+		{name: "Skip Blank Rows", skipBlank: true, wantRows: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataTable, _ := buildDataTable(&sliceRows{rows: rows}, options{SkipBlank: tt.skipBlank}, nil)
+			if len(dataTable.Rows) != tt.wantRows {
+				t.Errorf("buildDataTable() rows = %d, want %d", len(dataTable.Rows), tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestDropBlankColumns(t *testing.T) {
+	rows := [][]string{
+		{"Name", "", "Age", ""},
+		{"Bob", "", "3", "kept"},
+		{"Ann", "", "", ""},
+	}
+	dataTable, _ := buildDataTable(&sliceRows{rows: rows}, options{}, nil)
+	dropBlankColumns(&dataTable)
+	wantHeaders := []string{"Name", "Age", "_2"}
+	if strings.Join(dataTable.Headers, ",") != strings.Join(wantHeaders, ",") {
+		t.Errorf("dropBlankColumns() headers = %q, want %q", dataTable.Headers, wantHeaders)
+	}
+	for _, dataRow := range dataTable.Rows {
+		if len(dataRow.Columns) != len(wantHeaders) {
+			t.Errorf("dropBlankColumns() row has %d columns, want %d", len(dataRow.Columns), len(wantHeaders))
+		}
+	}
+}
+
+func TestFilterColumns(t *testing.T) {
+	rows := [][]string{
+		{"Name", "Work Email", "Notes", "Age"},
+		{"Bob", "bob@example.com", "none", "3"},
+	}
+	tests := []struct {
+		name        string
+		include     []string
+		exclude     []string
+		wantHeaders []string
+		wantErr     bool
+	}{
+		{name: "Include", include: []string{"Name", "Work Email"}, wantHeaders: []string{"Name", "Work_x0020_Email"}},
+		{name: "Include Cleaned Name", include: []string{"Work_x0020_Email"}, wantHeaders: []string{"Work_x0020_Email"}},
+		{name: "Exclude", exclude: []string{"Notes"}, wantHeaders: []string{"Name", "Work_x0020_Email", "Age"}},
+		{name: "Include And Exclude", include: []string{"Name", "Age"}, exclude: []string{"Age"}, wantHeaders: []string{"Name"}},
+		{name: "Unknown Column", include: []string{"Missing"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataTable, _ := buildDataTable(&sliceRows{rows: rows}, options{}, nil)
+			err := filterColumns(&dataTable, tt.include, tt.exclude)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("filterColumns() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if strings.Join(dataTable.Headers, ",") != strings.Join(tt.wantHeaders, ",") {
+				t.Errorf("filterColumns() headers = %q, want %q", dataTable.Headers, tt.wantHeaders)
+			}
+			if len(dataTable.Rows[0].Columns) != len(tt.wantHeaders) {
+				t.Errorf("filterColumns() row has %d columns, want %d", len(dataTable.Rows[0].Columns), len(tt.wantHeaders))
+			}
+		})
+	}
+}
+
+func TestIsDateFormat(t *testing.T) {
+	tests := map[string]bool{
+		"yyyy-mm-dd":            true,
+		"h:mm AM/PM":            true,
+		"0.00":                  false,
+		"#,##0 \"days\"":        false,
+		"[Red]0.00;[Blue]-0.00": false,
+		"[$-409]d-mmm-yy":       true,
+	}
+	for format, want := range tests {
+		if got := isDateFormat(format); got != want {
+			t.Errorf("isDateFormat(%q) = %v, want %v", format, got, want)
+		}
+	}
+}
+
+func TestToGoLayout(t *testing.T) {
+	tests := []struct {
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{format: "", want: ""},
+		{format: "yyyy-MM-dd", want: "2006-01-02"},
+		{format: "dd/MM/yy HH:mm:ss", want: "02/01/06 15:04:05"},
+		{format: "d/M/yyyy H:m:s", want: "2/1/2006 15:4:5"},
+		{format: "dddd d MMMM, h:mm tt", want: "Monday 2 January, 3:04 PM"},
+		{format: "2006-01-02T15:04:05", want: "2006-01-02T15:04:05"},
+		{format: "yyyy-MM-ddTHH:mm", wantErr: true},
+		{format: "yyy", wantErr: true},
+		{format: "dd.MM.yyyy fff", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := toGoLayout(test.format)
+		if test.wantErr != (err != nil) || got != test.want {
+			t.Errorf("toGoLayout(%q) = %q, %v, want %q, error %v", test.format, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestStreamXMLMatchesMarshal(t *testing.T) {
+	filePath, err := createTestXlsx("streamTest.xlsx", true, true)
+	if err != nil {
+		t.Fatalf("Error creating test file: %v", err)
+	}
+	defer os.Remove(filePath)
+	tests := []struct {
+		name string
+		opts options
+	}{
+		{name: "Single sheet", opts: options{Sheets: []string{"TestSheet"}, SheetIndex: -1, Format: formatXML}},
+		{name: "All sheets typed", opts: options{SheetIndex: -1, AllSheets: true, Format: formatXML, Typed: true}},
+		{name: "Filtered columns", opts: options{Sheets: []string{"TestSheet"}, SheetIndex: -1, Format: formatXML, Exclude: []string{"ColumnB1"}}},
+		{name: "Attributes mode", opts: options{Sheets: []string{"TestSheet"}, SheetIndex: -1, Format: formatXML, Mode: modeAttributes}},
+		{name: "Custom element names", opts: options{Sheets: []string{"TestSheet"}, SheetIndex: -1, Format: formatXML, RootElement: "Items", RowElement: "Item", TableName: "Test"}},
+		{name: "Custom names all sheets", opts: options{SheetIndex: -1, AllSheets: true, Format: formatXML, RootElement: "Book", RowElement: "Item"}},
+		{name: "Default namespace", opts: options{Sheets: []string{"TestSheet"}, SheetIndex: -1, Format: formatXML, Namespace: "urn:test", Typed: true}},
+		{name: "Prefixed namespace all sheets", opts: options{SheetIndex: -1, AllSheets: true, Format: formatXML, Namespace: "urn:test", NamespacePrefix: "t"}},
+		{name: "Nil empty cells", opts: options{Sheets: []string{"TestSheet"}, SheetIndex: -1, Format: formatXML, EmptyCells: emptyCellsNil}},
+		{name: "Omitted empty cells", opts: options{Sheets: []string{"TestSheet"}, SheetIndex: -1, Format: formatXML, EmptyCells: emptyCellsOmit, Mode: modeAttributes}},
+		{name: "Empty sheet", opts: options{Sheets: []string{"Sheet1"}, SheetIndex: -1, Format: formatXML, SkipBlank: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !canStream(tt.opts) {
+				t.Fatalf("canStream() = false for %+v", tt.opts)
+			}
+			var streamed bytes.Buffer
+			if err := parseXlsxFile(filePath, tt.opts, &streamed); err != nil {
+				t.Fatalf("parseXlsxFile() error = %v", err)
+			}
+			file, err := excelize.OpenFile(filePath)
+			if err != nil {
+				t.Fatalf("Error opening test file: %v", err)
+			}
+			defer file.Close()
+			sheetNames, err := targetSheets(file, tt.opts)
+			if err != nil {
+				t.Fatalf("targetSheets() error = %v", err)
+			}
+			var tables []DataTable
+			for _, sheetName := range sheetNames {
+				dataTable, err := parseSheet(file, sheetName, tt.opts)
+				if err != nil {
+					t.Fatalf("parseSheet() error = %v", err)
+				}
+				if tt.opts.AllSheets {
+					dataTable.Name = sheetName
+				} else {
+					dataTable.Name = tt.opts.TableName
+				}
+				tables = append(tables, dataTable)
+			}
+			marshalled, err := marshalXML(tables, tt.opts)
+			if err != nil {
+				t.Fatalf("marshalXML() error = %v", err)
+			}
+			if streamed.String() != string(marshalled) {
+				t.Errorf("streamed output differs from marshalled output:\n%s\n---\n%s", streamed.String(), marshalled)
+			}
+		})
+	}
+}
+
+func TestParseXlsxReader(t *testing.T) {
+	filePath, err := createTestXlsx("readerTest.xlsx", false, false)
+	if err != nil {
+		t.Fatalf("Error creating test file: %v", err)
+	}
+	defer os.Remove(filePath)
+	opts := options{Sheets: []string{"TestSheet"}, SheetIndex: -1, Format: formatJSON}
+	var fromPath, fromReader bytes.Buffer
+	if err := parseXlsxFile(filePath, opts, &fromPath); err != nil {
+		t.Fatalf("parseXlsxFile() error = %v", err)
+	}
+	input, err := os.Open(filePath)
+	if err != nil {
+		t.Fatalf("Error opening test file: %v", err)
+	}
+	defer input.Close()
+	if err := parseXlsxReader(input, opts, &fromReader); err != nil {
+		t.Fatalf("parseXlsxReader() error = %v", err)
+	}
+	if fromReader.String() != fromPath.String() {
+		t.Errorf("parseXlsxReader() output differs from parseXlsxFile():\n%s\n---\n%s", fromReader.String(), fromPath.String())
+	}
+	if err := parseXlsxReader(strings.NewReader("not a workbook"), opts, &fromReader); err == nil {
+		t.Errorf("parseXlsxReader() expected an error for non-workbook input")
+	}
+}
+
+func TestApplyHeaderCase(t *testing.T) {
+	tests := []struct {
+		header string
+		style  string
+		want   string
+	}{
+		{"Customer ID", headerCaseNone, "Customer ID"},
+		{"Customer ID", headerCasePascal, "CustomerId"},
+		{"Customer ID", headerCaseCamel, "customerId"},
+		{"Customer ID", headerCaseSnake, "customer_id"},
+		{"first_name", headerCasePascal, "FirstName"},
+		{"XMLHttpRequest", headerCaseSnake, "xml_http_request"},
+		{"orderDate", headerCaseSnake, "order_date"},
+		{"Amount ($)", headerCaseCamel, "amount"},
+		{"Line 2 Total", headerCasePascal, "Line2Total"},
+	}
+	for _, tt := range tests {
+		if got := applyHeaderCase(tt.header, tt.style); got != tt.want {
+			t.Errorf("applyHeaderCase(%q, %q) = %q, want %q", tt.header, tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestDupeStrategy(t *testing.T) {
+	tests := []struct {
+		strategy    string
+		wantErr     bool
+		wantHeaders []string
+	}{
+		{strategy: dupeSuffix, wantHeaders: []string{"Name", "Age", "Name_2", ""}},
+		{strategy: dupeDrop, wantHeaders: []string{"Name", "Age", ""}},
+		{strategy: dupeError, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			rows := [][]string{
+				{"Name", "Age", "Name", ""},
+				{"Alice", "30", "Smith"},
+			}
+			reader := newTableReader(&sliceRows{rows: rows}, options{DupeStrategy: tt.strategy}, nil)
+			if (reader.Err() != nil) != tt.wantErr {
+				t.Fatalf("newTableReader() error = %v, wantErr %v", reader.Err(), tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			dataTable, _ := collectDataTable(reader)
+			if !slices.Equal(dataTable.Headers, tt.wantHeaders) {
+				t.Errorf("headers = %v, want %v", dataTable.Headers, tt.wantHeaders)
+			}
+			if len(dataTable.Rows) != 1 || len(dataTable.Rows[0].Columns) != len(tt.wantHeaders) {
+				t.Fatalf("rows = %+v, want one row of %d columns", dataTable.Rows, len(tt.wantHeaders))
+			}
+			if got := dataTable.Rows[0].Columns[1].Value; got != "30" {
+				t.Errorf("Age = %q, want %q", got, "30")
+			}
+		})
+	}
+}
+
+func TestHeaderSanitization(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        options
+		wantHeaders []string
+	}{
+		// Removing the symbols mashes both amounts into the same element name, so the second is renamed.
+		{name: "Remove", opts: options{}, wantHeaders: []string{"Amount_x0020_", "Amount_x0020__2", "Café"}},
+		{name: "Replace", opts: options{Replacement: "_"}, wantHeaders: []string{"Amount_x0020____", "Amount_x0020_____2", "Café"}},
+		{name: "Error", opts: options{DupeStrategy: dupeError}, wantHeaders: nil},
+		{name: "Drop", opts: options{DupeStrategy: dupeDrop}, wantHeaders: []string{"Amount_x0020_", "Café"}},
+		{name: "Transliterate", opts: options{Transliterate: true}, wantHeaders: []string{"Amount_x0020_Dollar", "Amount_x0020_Percent", "Cafe"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := [][]string{{"Amount ($)", "Amount (%)", "Café"}}
+			dataTable, err := buildDataTable(&sliceRows{rows: rows}, tt.opts, nil)
+			if tt.wantHeaders == nil {
+				if err == nil {
+					t.Errorf("headers = %v, want a duplicate headers error", dataTable.Headers)
+				}
+				return
+			}
+			if !slices.Equal(dataTable.Headers, tt.wantHeaders) {
+				t.Errorf("headers = %v, want %v", dataTable.Headers, tt.wantHeaders)
+			}
+		})
+	}
+}
+
+func TestMarshalCSVSanitize(t *testing.T) {
+	rows := [][]string{{"Name", "Balance"}, {"=HYPERLINK(\"http://x\")", "-42"}, {"@SUM(A1)", "-total"}}
+	dataTable, _ := buildDataTable(&sliceRows{rows: rows}, options{}, nil)
+	tests := []struct {
+		name     string
+		sanitize bool
+		want     string
+	}{
+		{name: "Off", sanitize: false, want: "Name,Balance\n\"=HYPERLINK(\"\"http://x\"\")\",-42\n@SUM(A1),-total\n"},
+		{name: "On", sanitize: true, want: "Name,Balance\n\"'=HYPERLINK(\"\"http://x\"\")\",-42\n'@SUM(A1),'-total\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := marshalCSV([]DataTable{dataTable}, false, tt.sanitize)
+			if err != nil {
+				t.Fatalf("marshalCSV() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("marshalCSV() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYamlScalar(t *testing.T) {
+	tests := map[string]string{
+		"ann":        "ann",
+		"New York":   "New York",
+		"30":         "30",
+		"0":          "0",
+		"1.5":        "1.5",
+		"10.5.3":     "10.5.3",
+		"":           `""`,
+		"trail ":     `"trail "`,
+		"-42":        `"-42"`,
+		"007":        `"007"`,
+		"1e3":        `"1e3"`,
+		"1_000":      `"1_000"`,
+		".5":         `".5"`,
+		".inf":       `".inf"`,
+		".NaN":       `".NaN"`,
+		"0x1F":       `"0x1F"`,
+		"0o17":       `"0o17"`,
+		"2024-01-31": `"2024-01-31"`,
+		"on":         `"on"`,
+		"Yes":        `"Yes"`,
+		"~":          `"~"`,
+		"a: b":       `"a: b"`,
+	}
+	for value, want := range tests {
+		if got := yamlScalar(value); got != want {
+			t.Errorf("yamlScalar(%q) = %s, want %s", value, got, want)
+		}
+	}
+}
+
+func TestTargetSheets(t *testing.T) {
+	file := excelize.NewFile()
+	defer func() {
+		_ = file.Close()
+	}()
+	for _, sheet := range []string{"Data_Jan", "Summary", "Data_Feb"} {
+		if _, err := file.NewSheet(sheet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name    string
+		opts    options
+		want    []string
+		wantErr bool
+	}{
+		{name: "Default", opts: options{SheetIndex: -1}, want: []string{"Sheet1"}},
+		{name: "Repeated", opts: options{Sheets: []string{"Summary", "Data_Jan", "Summary"}, SheetIndex: -1}, want: []string{"Summary", "Data_Jan"}},
+		{name: "Pattern", opts: options{SheetPattern: "^Data_", SheetIndex: -1}, want: []string{"Data_Jan", "Data_Feb"}},
+		{name: "Names then pattern", opts: options{Sheets: []string{"Data_Feb"}, SheetPattern: "^Data_", SheetIndex: -1}, want: []string{"Data_Feb", "Data_Jan"}},
+		{name: "Missing sheet", opts: options{Sheets: []string{"Nope"}, SheetIndex: -1}, wantErr: true},
+		{name: "No match", opts: options{SheetPattern: "^Report", SheetIndex: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := targetSheets(file, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("targetSheets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("targetSheets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckXlsxFile(t *testing.T) {
+	dir := t.TempDir()
+	workbook := filepath.Join(dir, "book.xlsm")
+	text := filepath.Join(dir, "notes.txt")
+	for _, path := range []string{workbook, text} {
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name     string
+		path     string
+		strict   bool
+		wantCode int
+	}{
+		{name: "Workbook", path: workbook, wantCode: Success},
+		{name: "Missing", path: filepath.Join(dir, "missing.xlsx"), wantCode: ErrNoFile},
+		{name: "Not a workbook", path: text, wantCode: ErrInvalidFileType},
+		{name: "Strict", path: workbook, strict: true, wantCode: ErrInvalidFileType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkXlsxFile(tt.path, tt.strict)
+			if got.Code != tt.wantCode {
+				t.Fatalf("checkXlsxFile() code = %d, want %d (%v)", got.Code, tt.wantCode, got.Err)
+			}
+			if tt.wantCode != Success && (got.Err == nil || !strings.Contains(got.Err.Error(), tt.path)) {
+				t.Errorf("checkXlsxFile() error = %v, want it to name %s", got.Err, tt.path)
+			}
+		})
+	}
+}
+
+// failingRows is a rowSource whose Columns fails on the given row.
+type failingRows struct {
+	sliceRows
+	failAt int
+}
+
+func (r *failingRows) Columns(opts ...excelize.Options) ([]string, error) {
+	if r.index == r.failAt {
+		return nil, errors.New("corrupt cell")
+	}
+	return r.sliceRows.Columns(opts...)
+}
+
+func TestBuildDataTableErrors(t *testing.T) {
+	rows := [][]string{{"A", "B"}, {"1", "2"}, {"3", "4", "5"}, {"6", "7", ""}}
+	tests := []struct {
+		name     string
+		source   rowSource
+		opts     options
+		wantErr  string
+		wantRows int
+	}{
+		{name: "Unreadable row", source: &failingRows{sliceRows: sliceRows{rows: rows}, failAt: 3}, wantErr: "row 3: corrupt cell"},
+		{name: "Wide row dropped", source: &sliceRows{rows: rows}, wantRows: 3},
+		{name: "Wide row strict", source: &sliceRows{rows: rows}, opts: options{StrictRows: true}, wantErr: "row 3 has values in 3 columns"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataTable, err := buildDataTable(tt.source, tt.opts, nil)
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("buildDataTable() error = %v, want %q", err, tt.wantErr)
+				}
+				if len(dataTable.Rows) > 0 {
+					t.Errorf("buildDataTable() returned %d rows alongside an error", len(dataTable.Rows))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("buildDataTable() error = %v", err)
+			}
+			if len(dataTable.Rows) != tt.wantRows {
+				t.Fatalf("buildDataTable() rows = %d, want %d", len(dataTable.Rows), tt.wantRows)
+			}
+			for _, dataRow := range dataTable.Rows {
+				if len(dataRow.Columns) != len(dataTable.Headers) {
+					t.Errorf("row has %d columns, want %d", len(dataRow.Columns), len(dataTable.Headers))
+				}
+			}
+		})
+	}
+}
+
+func TestFindTable(t *testing.T) {
+	file := excelize.NewFile()
+	defer func() {
+		_ = file.Close()
+	}()
+	if _, err := file.NewSheet("Governed"); err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]interface{}{{"Note"}, {}, {"Code", "Amount"}, {"A", 1}, {"B", 2}, {"Total", 3}}
+	for rowIndex, row := range rows {
+		cellName, _ := excelize.CoordinatesToCellName(2, rowIndex+1)
+		if err := file.SetSheetRow("Governed", cellName, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := file.AddTable("Governed", &excelize.Table{Range: "B3:C6", Name: "Balances"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sheet, ref, err := findTable(file, "balances")
+	if err != nil {
+		t.Fatalf("findTable() error = %v", err)
+	}
+	if sheet != "Governed" || ref != "B3:C6" {
+		t.Errorf("findTable() = %s, %s, want Governed, B3:C6", sheet, ref)
+	}
+	if _, _, err = findTable(file, "Missing"); err == nil {
+		t.Error("findTable() found a table that does not exist")
+	}
+	if err = file.AddTable("Governed", &excelize.Table{Range: "E1:F3", Name: "Headless", ShowHeaderRow: new(bool)}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = findTable(file, "Headless"); err == nil {
+		t.Error("findTable() accepted a table without a header row")
+	}
+
+	var output bytes.Buffer
+	opts := options{Table: "Balances", SheetIndex: -1, Format: formatCSV}
+	if err = parseWorkbook(file, opts, &output); err != nil {
+		t.Fatalf("parseWorkbook() error = %v", err)
+	}
+	if want := "Code,Amount\nA,1\nB,2\nTotal,3\n"; output.String() != want {
+		t.Errorf("parseWorkbook() = %q, want %q", output.String(), want)
+	}
+}
+
+func TestBlankHeaders(t *testing.T) {
+	rows := [][]string{
+		{"x", "y", "Name", "", "C", " "},
+		{"x", "y", "Alice", "30", "Smith", "yes"},
+	}
+	tests := []struct {
+		mode        string
+		wantHeaders []string
+	}{
+		{mode: blankHeadersNone, wantHeaders: []string{"Name", "", "C", "_x0020_"}},
+		{mode: blankHeadersLetter, wantHeaders: []string{"Name", "D", "C", "F"}},
+		{mode: blankHeadersIndex, wantHeaders: []string{"Name", "Column2", "C", "Column4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			source := &rangeRows{rows: &sliceRows{rows: rows}, bounds: cellRange{FirstColumn: 3, FirstRow: 1, LastColumn: 6, LastRow: 2}}
+			dataTable, err := buildDataTable(source, options{BlankHeaders: tt.mode}, nil)
+			if err != nil {
+				t.Fatalf("buildDataTable() error = %v", err)
+			}
+			if !slices.Equal(dataTable.Headers, tt.wantHeaders) {
+				t.Errorf("headers = %v, want %v", dataTable.Headers, tt.wantHeaders)
+			}
+			if got := dataTable.Rows[0].Columns[3].Value; got != "yes" {
+				t.Errorf("last column = %q, want %q", got, "yes")
+			}
+		})
+	}
+}
+
+func TestOffsetLimit(t *testing.T) {
+	rows := [][]string{{"N"}, {"1"}, {""}, {"2"}, {"3"}, {"4"}}
+	tests := []struct {
+		name string
+		opts options
+		want []string
+	}{
+		{name: "All", opts: options{}, want: []string{"1", "", "2", "3", "4"}},
+		{name: "Limit", opts: options{Limit: 2}, want: []string{"1", ""}},
+		{name: "Offset", opts: options{Offset: 3}, want: []string{"3", "4"}},
+		{name: "Window", opts: options{Offset: 1, Limit: 2, SkipBlank: true}, want: []string{"2", "3"}},
+		{name: "Past the end", opts: options{Offset: 10}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &sliceRows{rows: rows}
+			dataTable, err := buildDataTable(source, tt.opts, nil)
+			if err != nil {
+				t.Fatalf("buildDataTable() error = %v", err)
+			}
+			var got []string
+			for _, dataRow := range dataTable.Rows {
+				got = append(got, dataRow.Columns[0].Value)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("rows = %v, want %v", got, tt.want)
+			}
+			if tt.opts.Limit > 0 && source.index > tt.opts.Offset+tt.opts.Limit+2 {
+				t.Errorf("read %d rows, want the read to stop after the limit", source.index)
+			}
+		})
+	}
+}
+
+func TestWriteMetadata(t *testing.T) {
+	file := excelize.NewFile()
+	defer func() {
+		_ = file.Close()
+	}()
+	if err := file.SetDocProps(&excelize.DocProperties{Creator: "Finance", Created: "2024-03-01T09:00:00Z", Title: "Balances"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.NewSheet("Hidden"); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.SetSheetVisible("Hidden", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.SetDefinedName(&excelize.DefinedName{Name: "Rates", RefersTo: "Sheet1!$A$1:$B$4"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	if err := parseWorkbook(file, options{Metadata: true, Format: formatXML}, &output); err != nil {
+		t.Fatalf("parseWorkbook() error = %v", err)
+	}
+	for _, want := range []string{
+		"<Creator>Finance</Creator>",
+		"<Created>2024-03-01T09:00:00Z</Created>",
+		`<Sheet name="Hidden" index="1" visible="false"`,
+		`<DefinedName name="Rates" refersTo="Sheet1!$A$1:$B$4">`,
+	} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("metadata is missing %s:\n%s", want, output.String())
+		}
+	}
+
+	output.Reset()
+	if err := parseWorkbook(file, options{Metadata: true, Format: formatJSON}, &output); err != nil {
+		t.Fatalf("parseWorkbook() error = %v", err)
+	}
+	for _, want := range []string{`"creator": "Finance"`, `"name": "Hidden"`, `"refersTo": "Sheet1!$A$1:$B$4"`} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("metadata is missing %s:\n%s", want, output.String())
+		}
+	}
+}
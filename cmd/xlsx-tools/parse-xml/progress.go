@@ -0,0 +1,72 @@
+package main
+
+import (
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/xuri/excelize/v2"
+)
+
+// progressInterval is the minimum time between two progress messages for the same sheet.
+const progressInterval = 2 * time.Second
+
+// progressLogger periodically logs how far through a sheet the parser has got, so a slow parse of a large
+// sheet can be told apart from a hang. Messages are logged at debug level and only appear with --verbose.
+// A nil progressLogger logs nothing.
+type progressLogger struct {
+	sheet string
+	// total is the last row of the sheet's used range, or 0 if the workbook does not record it.
+	total int
+	rows  int
+	start time.Time
+	last  time.Time
+}
+
+// newProgressLogger starts tracking progress through the named sheet of file.
+// The total row count is taken from the dimension recorded in the sheet, so the sheet is not read twice.
+func newProgressLogger(file *excelize.File, sheetName string) *progressLogger {
+	progress := &progressLogger{sheet: sheetName, start: time.Now()}
+	progress.last = progress.start
+	if dimension, dimensionErr := file.GetSheetDimension(sheetName); dimensionErr == nil {
+		if bounds, rangeErr := parseCellRange(dimension); rangeErr == nil {
+			progress.total = bounds.LastRow
+		}
+	}
+	if progress.total > 0 {
+		log.Debug("Parsing sheet", "sheet", sheetName, "total", progress.total)
+	} else {
+		log.Debug("Parsing sheet", "sheet", sheetName)
+	}
+	return progress
+}
+
+// update records that row has been read, logging the progress if progressInterval has passed since the last message.
+func (p *progressLogger) update(row int) {
+	if p == nil {
+		return
+	}
+	p.rows = row
+	if now := time.Now(); now.Sub(p.last) >= progressInterval {
+		p.last = now
+		p.log("Parsing sheet")
+	}
+}
+
+// done logs the final row count and the time taken to read the sheet.
+func (p *progressLogger) done() {
+	if p == nil {
+		return
+	}
+	p.log("Parsed sheet")
+}
+
+// log writes a progress message with the rows read so far and the elapsed time.
+func (p *progressLogger) log(msg string) {
+	elapsed := time.Since(p.start).Round(time.Millisecond)
+	if p.total > 0 {
+		log.Debug(msg, "sheet", p.sheet, "rows", p.rows, "total", p.total,
+			"percent", p.rows*100/p.total, "elapsed", elapsed)
+	} else {
+		log.Debug(msg, "sheet", p.sheet, "rows", p.rows, "elapsed", elapsed)
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// rowSource is the subset of *excelize.Rows used to build a DataTable,
+// allowing the rows of a sheet to be filtered before they are parsed.
+// Position reports the sheet row number of the current row and the column number of its first cell,
+// so cells can be located in the sheet for metadata lookups.
+type rowSource interface {
+	Next() bool
+	Columns(opts ...excelize.Options) ([]string, error)
+	Position() (row, column int)
+}
+
+// sheetRows wraps *excelize.Rows to keep track of the current row number.
+type sheetRows struct {
+	*excelize.Rows
+	rowNum int
+}
+
+// Next advances to the next row of the sheet.
+func (r *sheetRows) Next() bool {
+	r.rowNum++
+	return r.Rows.Next()
+}
+
+// Position returns the current row number; rows always start in the first column.
+func (r *sheetRows) Position() (row, column int) {
+	return r.rowNum, 1
+}
+
+// cellRange is a rectangular region of a worksheet, using one-based row and column numbers.
+type cellRange struct {
+	FirstColumn, FirstRow int
+	LastColumn, LastRow   int
+}
+
+// parseCellRange converts a reference such as "A1:F500" into a cellRange.
+// The corners may be given in any order.
+func parseCellRange(ref string) (cellRange, error) {
+	first, last, found := strings.Cut(strings.TrimSpace(ref), ":")
+	if !found {
+		return cellRange{}, fmt.Errorf("invalid range '%s', expected the form A1:F500", ref)
+	}
+	firstColumn, firstRow, firstErr := excelize.CellNameToCoordinates(first)
+	if firstErr != nil {
+		return cellRange{}, fmt.Errorf("invalid range '%s': %w", ref, firstErr)
+	}
+	lastColumn, lastRow, lastErr := excelize.CellNameToCoordinates(last)
+	if lastErr != nil {
+		return cellRange{}, fmt.Errorf("invalid range '%s': %w", ref, lastErr)
+	}
+	return cellRange{
+		FirstColumn: min(firstColumn, lastColumn),
+		FirstRow:    min(firstRow, lastRow),
+		LastColumn:  max(firstColumn, lastColumn),
+		LastRow:     max(firstRow, lastRow),
+	}, nil
+}
+
+// rangeRows restricts a rowSource to the rows and columns of a cellRange.
+type rangeRows struct {
+	rows   rowSource
+	bounds cellRange
+	rowNum int
+}
+
+// Next advances to the next row inside the range, skipping the rows above it.
+func (r *rangeRows) Next() bool {
+	for r.rows.Next() {
+		r.rowNum++
+		if r.rowNum < r.bounds.FirstRow {
+			continue
+		}
+		return r.rowNum <= r.bounds.LastRow
+	}
+	return false
+}
+
+// Position returns the current row number and the first column of the range.
+func (r *rangeRows) Position() (row, column int) {
+	return r.rowNum, r.bounds.FirstColumn
+}
+
+// Columns returns the cells of the current row that fall inside the range, padded to the range width.
+func (r *rangeRows) Columns(opts ...excelize.Options) ([]string, error) {
+	columns, err := r.rows.Columns(opts...)
+	if err != nil {
+		return nil, err
+	}
+	width := r.bounds.LastColumn - r.bounds.FirstColumn + 1
+	cells := make([]string, width)
+	for i := range cells {
+		if index := r.bounds.FirstColumn - 1 + i; index < len(columns) {
+			cells[i] = columns[index]
+		}
+	}
+	return cells, nil
+}
+
+// headerRows skips the rows of a rowSource that sit above the header row,
+// such as titles, logos and filters.
+type headerRows struct {
+	rows rowSource
+	skip int
+}
+
+// Next advances to the next row, discarding any rows above the header first.
+func (r *headerRows) Next() bool {
+	for ; r.skip > 0; r.skip-- {
+		if !r.rows.Next() {
+			return false
+		}
+	}
+	return r.rows.Next()
+}
+
+// Columns returns the cells of the current row.
+func (r *headerRows) Columns(opts ...excelize.Options) ([]string, error) {
+	return r.rows.Columns(opts...)
+}
+
+// Position returns the position of the current row.
+func (r *headerRows) Position() (row, column int) {
+	return r.rows.Position()
+}
+
+// mergedRows fills the cells covered by merged ranges, which excelize otherwise reports as empty
+// everywhere but the top-left cell of the range.
+type mergedRows struct {
+	rows rowSource
+	// fills maps the row and then the column of each covered cell to the value it should take, as formatted
+	// and, in rawFills, as stored; the two only differ for numbers and dates.
+	fills    map[int]map[int]string
+	rawFills map[int]map[int]string
+}
+
+// newMergedRows wraps rows of the named sheet of file so that the covered cells of every merged range
+// in the sheet receive either the value of the range's top-left cell, or marker if one is given.
+func newMergedRows(rows rowSource, file *excelize.File, sheet, marker string) (*mergedRows, error) {
+	mergeCells, mergeErr := file.GetMergeCells(sheet)
+	if mergeErr != nil {
+		return nil, mergeErr
+	}
+	merged := &mergedRows{rows: rows, fills: make(map[int]map[int]string), rawFills: make(map[int]map[int]string)}
+	for _, mergeCell := range mergeCells {
+		firstColumn, firstRow, startErr := excelize.CellNameToCoordinates(mergeCell.GetStartAxis())
+		if startErr != nil {
+			return nil, startErr
+		}
+		lastColumn, lastRow, endErr := excelize.CellNameToCoordinates(mergeCell.GetEndAxis())
+		if endErr != nil {
+			return nil, endErr
+		}
+		value, rawValue := marker, marker
+		if len(marker) == 0 {
+			value = mergeCell.GetCellValue()
+			var rawErr error
+			if rawValue, rawErr = file.GetCellValue(sheet, mergeCell.GetStartAxis(), excelize.Options{RawCellValue: true}); rawErr != nil {
+				return nil, rawErr
+			}
+		}
+		for row := firstRow; row <= lastRow; row++ {
+			if merged.fills[row] == nil {
+				merged.fills[row] = make(map[int]string)
+				merged.rawFills[row] = make(map[int]string)
+			}
+			for column := firstColumn; column <= lastColumn; column++ {
+				if row != firstRow || column != firstColumn {
+					merged.fills[row][column] = value
+					merged.rawFills[row][column] = rawValue
+				}
+			}
+		}
+	}
+	return merged, nil
+}
+
+// Next advances to the next row.
+func (r *mergedRows) Next() bool {
+	return r.rows.Next()
+}
+
+// Position returns the position of the current row.
+func (r *mergedRows) Position() (row, column int) {
+	return r.rows.Position()
+}
+
+// Columns returns the cells of the current row with any covered cells filled in, using the stored values of
+// the merged cells if opts asks for raw cell values.
+func (r *mergedRows) Columns(opts ...excelize.Options) ([]string, error) {
+	columns, err := r.rows.Columns(opts...)
+	if err != nil {
+		return columns, err
+	}
+	row, firstColumn := r.rows.Position()
+	fills := r.fills[row]
+	if len(opts) > 0 && opts[0].RawCellValue {
+		fills = r.rawFills[row]
+	}
+	for column, value := range fills {
+		index := column - firstColumn
+		if index < 0 {
+			continue
+		}
+		for len(columns) <= index {
+			columns = append(columns, "")
+		}
+		columns[index] = value
+	}
+	return columns, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import "encoding/xml"
+
+// msdataNamespace is the namespace of the Microsoft-specific schema annotations understood by DataSet.ReadXml.
+const msdataNamespace = "urn:schemas-microsoft-com:xml-msdata"
+
+// xsdSchema is an inline XML Schema describing a DataTable document, in the layout written by
+// DataSet.WriteXml(XmlWriteMode.WriteSchema): the root element is the DataSet and each Row is a table record.
+type xsdSchema struct {
+	ID      string     `xml:"id,attr"`
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Element xsdElement `xml:"xs:element"`
+}
+
+// xsdElement declares an element, optionally with a complex type holding child elements.
+type xsdElement struct {
+	Name        string          `xml:"name,attr"`
+	Type        string          `xml:"type,attr,omitempty"`
+	IsDataSet   string          `xml:"msdata:IsDataSet,attr,omitempty"`
+	MinOccurs   string          `xml:"minOccurs,attr,omitempty"`
+	Nillable    string          `xml:"nillable,attr,omitempty"`
+	ComplexType *xsdComplexType `xml:"xs:complexType,omitempty"`
+}
+
+// xsdComplexType holds either a repeating choice of records or a sequence of columns.
+type xsdComplexType struct {
+	Choice   *xsdGroup `xml:"xs:choice,omitempty"`
+	Sequence *xsdGroup `xml:"xs:sequence,omitempty"`
+}
+
+// xsdGroup is a compositor (xs:choice or xs:sequence) of element declarations.
+type xsdGroup struct {
+	MinOccurs string       `xml:"minOccurs,attr,omitempty"`
+	MaxOccurs string       `xml:"maxOccurs,attr,omitempty"`
+	Elements  []xsdElement `xml:"xs:element"`
+}
+
+// buildSchema describes dataTable as an inline schema, with rootElement as the DataSet element
+// and rowElement as the table record. If namespace is set it becomes the schema's target namespace. Each column is typed with the type shared by all of
+// its non-blank cells, falling back to xs:string for mixed or untyped columns, and is marked nillable if
+// any of its cells is blank.
+func buildSchema(dataTable DataTable, rootElement, rowElement, namespace string) *xsdSchema {
+	columnTypes := make([]string, len(dataTable.Headers))
+	nillable := make([]bool, len(dataTable.Headers))
+	for _, dataRow := range dataTable.Rows {
+		for columnIndex, column := range dataRow.Columns {
+			if columnIndex >= len(columnTypes) {
+				break
+			}
+			if len(column.Value) == 0 {
+				nillable[columnIndex] = true
+				continue
+			}
+			kind := column.kind
+			if len(kind) == 0 {
+				kind = xsString
+			}
+			if len(columnTypes[columnIndex]) == 0 {
+				columnTypes[columnIndex] = kind
+			} else if columnTypes[columnIndex] != kind {
+				columnTypes[columnIndex] = xsString
+			}
+		}
+	}
+
+	columns := make([]xsdElement, 0, len(dataTable.Headers))
+	for columnIndex, header := range dataTable.Headers {
+		column := xsdElement{Name: header, Type: columnTypes[columnIndex], MinOccurs: "0"}
+		if len(column.Type) == 0 {
+			column.Type = xsString
+		}
+		if nillable[columnIndex] {
+			column.Nillable = "true"
+		}
+		columns = append(columns, column)
+	}
+
+	attrs := []xml.Attr{
+		{Name: xml.Name{Local: "xmlns"}, Value: namespace},
+		{Name: xml.Name{Local: "xmlns:xs"}, Value: xsNamespace},
+		{Name: xml.Name{Local: "xmlns:msdata"}, Value: msdataNamespace},
+	}
+	if len(namespace) > 0 {
+		attrs = append(attrs,
+			xml.Attr{Name: xml.Name{Local: "targetNamespace"}, Value: namespace},
+			xml.Attr{Name: xml.Name{Local: "elementFormDefault"}, Value: "qualified"},
+		)
+	}
+	return &xsdSchema{
+		ID:    rootElement,
+		Attrs: attrs,
+		Element: xsdElement{
+			Name:      rootElement,
+			IsDataSet: "true",
+			ComplexType: &xsdComplexType{
+				Choice: &xsdGroup{
+					MinOccurs: "0",
+					MaxOccurs: "unbounded",
+					Elements: []xsdElement{{
+						Name:        rowElement,
+						ComplexType: &xsdComplexType{Sequence: &xsdGroup{Elements: columns}},
+					}},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// canStream checks if the output for opts can be written while the rows are read.
+// The schema and --drop-blank-columns both need to see every row before the first one is written,
+// and only the XML format is encoded incrementally.
+func canStream(opts options) bool {
+	return opts.Format == formatXML && !opts.WithSchema && !opts.DropBlank
+}
+
+// streamXML writes the named sheets of file to w as a DataTable document, or as a DataSet document
+// if several sheets are selected, encoding each row as soon as it is read so memory use does not grow with
+// the size of the sheet. The output is the same as marshalXML produces for the same sheets.
+func streamXML(file *excelize.File, sheetNames []string, opts options, w io.Writer) error {
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	layout := newXMLLayout(opts)
+	root := xml.StartElement{Name: xml.Name{Local: layout.qualify(rootElementName(opts))}}
+	if !opts.multiSheet() && len(opts.TableName) > 0 {
+		root.Attr = append(root.Attr, xml.Attr{Name: xml.Name{Local: "name"}, Value: opts.TableName})
+	}
+	root.Attr = append(root.Attr, rootNamespaces(opts)...)
+	if err := encoder.EncodeToken(root); err != nil {
+		return err
+	}
+	for _, sheetName := range sheetNames {
+		if !opts.multiSheet() {
+			if err := streamSheet(encoder, file, sheetName, opts); err != nil {
+				return err
+			}
+			continue
+		}
+		table := xml.StartElement{
+			Name: xml.Name{Local: layout.qualify("Table")},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "name"}, Value: sheetName}},
+		}
+		if err := encoder.EncodeToken(table); err != nil {
+			return err
+		}
+		if err := streamSheet(encoder, file, sheetName, opts); err != nil {
+			return err
+		}
+		if err := encoder.EncodeToken(table.End()); err != nil {
+			return err
+		}
+	}
+	if err := encoder.EncodeToken(root.End()); err != nil {
+		return err
+	}
+	return encoder.Flush()
+}
+
+// streamSheet encodes each row of the named sheet as a Row element, applying any column filter as it goes.
+func streamSheet(encoder *xml.Encoder, file *excelize.File, sheetName string, opts options) error {
+	reader, openErr := openSheet(file, sheetName, opts)
+	if openErr != nil {
+		return openErr
+	}
+	defer func(reader *tableReader) {
+		_ = reader.Close()
+	}(reader)
+	var keep []int
+	filtered := len(opts.Columns) > 0 || len(opts.Exclude) > 0
+	if filtered {
+		headers := DataTable{Headers: reader.Headers, sourceHeaders: reader.sourceHeaders}
+		var keepErr error
+		if keep, keepErr = keptColumns(&headers, opts.Columns, opts.Exclude); keepErr != nil {
+			return keepErr
+		}
+	}
+	rowElement := xml.StartElement{Name: xml.Name{Local: rowElementName(opts)}}
+	for dataRow, ok := reader.Next(); ok; dataRow, ok = reader.Next() {
+		if filtered {
+			dataRow = selectRowColumns(dataRow, keep)
+		}
+		if err := encoder.EncodeElement(dataRow, rowElement); err != nil {
+			return err
+		}
+	}
+	if readErr := reader.Err(); readErr != nil {
+		return fmt.Errorf("sheet '%s': %w", sheetName, readErr)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// tablePart holds the parts of an Excel table definition (xl/tables/tableN.xml) that excelize does not expose.
+type tablePart struct {
+	Name           string `xml:"name,attr"`
+	DisplayName    string `xml:"displayName,attr"`
+	HeaderRowCount *int   `xml:"headerRowCount,attr"`
+	TotalsRowCount int    `xml:"totalsRowCount,attr"`
+}
+
+// findTable locates the Excel table (ListObject) with the given name, which like Excel is matched ignoring case,
+// and returns the sheet holding it and the range of its header and data rows, leaving out any totals row.
+func findTable(file *excelize.File, name string) (sheet, ref string, err error) {
+	for _, sheetName := range file.GetSheetList() {
+		tables, tablesErr := file.GetTables(sheetName)
+		if tablesErr != nil {
+			return "", "", fmt.Errorf("sheet '%s': %w", sheetName, tablesErr)
+		}
+		for _, table := range tables {
+			if !strings.EqualFold(table.Name, name) {
+				continue
+			}
+			part := readTablePart(file, table.Name)
+			if part.HeaderRowCount != nil && *part.HeaderRowCount == 0 {
+				return "", "", fmt.Errorf("table '%s' has no header row", table.Name)
+			}
+			bounds, rangeErr := parseCellRange(table.Range)
+			if rangeErr != nil {
+				return "", "", fmt.Errorf("table '%s': %w", table.Name, rangeErr)
+			}
+			bounds.LastRow -= part.TotalsRowCount
+			first, _ := excelize.CoordinatesToCellName(bounds.FirstColumn, bounds.FirstRow)
+			last, _ := excelize.CoordinatesToCellName(bounds.LastColumn, max(bounds.LastRow, bounds.FirstRow))
+			return sheetName, first + ":" + last, nil
+		}
+	}
+	return "", "", fmt.Errorf("table '%s' does not exist", name)
+}
+
+// readTablePart finds and decodes the definition of the named table among the workbook's parts.
+// A zero tablePart, describing a table with one header row and no totals row, is returned if it cannot be read.
+func readTablePart(file *excelize.File, name string) tablePart {
+	var found tablePart
+	file.Pkg.Range(func(key, value interface{}) bool {
+		path, _ := key.(string)
+		content, isBytes := value.([]byte)
+		if !isBytes || !strings.HasPrefix(path, "xl/tables/") || !strings.HasSuffix(path, ".xml") {
+			return true
+		}
+		var part tablePart
+		if err := xml.NewDecoder(bytes.NewReader(content)).Decode(&part); err != nil {
+			return true
+		}
+		if part.Name == name || part.DisplayName == name {
+			found = part
+			return false
+		}
+		return true
+	})
+	return found
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	sheetAdded    = "sheet-added"
+	sheetRemoved  = "sheet-removed"
+	columnAdded   = "column-added"
+	columnRemoved = "column-removed"
+	rowAdded      = "row-added"
+	rowRemoved    = "row-removed"
+	cellChanged   = "cell-changed"
+)
+
+// Difference is a single change between the old and the new workbook.
+// Cell refers to the new workbook, except for removals where it refers to the old one.
+type Difference struct {
+	Sheet  string `json:"sheet"`
+	Kind   string `json:"kind"`
+	Cell   string `json:"cell,omitempty"`
+	Key    string `json:"key,omitempty"`
+	Column string `json:"column,omitempty"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+}
+
+// diffWorkbooks compares the sheets of two workbooks, restricted to sheets if any are given.
+// Sheets are matched by name; rows are matched by position, or by the value of the keyColumn header if given.
+func diffWorkbooks(oldFile, newFile *excelize.File, sheets []string, keyColumn string) ([]Difference, error) {
+	oldSheets, newSheets := oldFile.GetSheetList(), newFile.GetSheetList()
+	if len(sheets) == 0 {
+		sheets = slices.Clone(oldSheets)
+		for _, sheet := range newSheets {
+			if !slices.Contains(sheets, sheet) {
+				sheets = append(sheets, sheet)
+			}
+		}
+	}
+	var differences []Difference
+	for _, sheet := range sheets {
+		inOld, inNew := slices.Contains(oldSheets, sheet), slices.Contains(newSheets, sheet)
+		switch {
+		case !inOld && !inNew:
+			return nil, fmt.Errorf("sheet '%s' is in neither workbook", sheet)
+		case !inOld:
+			differences = append(differences, Difference{Sheet: sheet, Kind: sheetAdded})
+			continue
+		case !inNew:
+			differences = append(differences, Difference{Sheet: sheet, Kind: sheetRemoved})
+			continue
+		}
+		oldRows, oldErr := oldFile.GetRows(sheet)
+		if oldErr != nil {
+			return nil, fmt.Errorf("sheet '%s' of the old workbook: %w", sheet, oldErr)
+		}
+		newRows, newErr := newFile.GetRows(sheet)
+		if newErr != nil {
+			return nil, fmt.Errorf("sheet '%s' of the new workbook: %w", sheet, newErr)
+		}
+		var sheetDifferences []Difference
+		var diffErr error
+		if len(keyColumn) > 0 {
+			sheetDifferences, diffErr = diffKeyed(sheet, oldRows, newRows, keyColumn)
+		} else {
+			sheetDifferences = diffCells(sheet, oldRows, newRows)
+		}
+		if diffErr != nil {
+			return nil, diffErr
+		}
+		differences = append(differences, sheetDifferences...)
+	}
+	return differences, nil
+}
+
+// diffCells compares two sheets cell by cell, reporting every position whose value differs.
+func diffCells(sheet string, oldRows, newRows [][]string) []Difference {
+	var differences []Difference
+	for rowIndex := 0; rowIndex < max(len(oldRows), len(newRows)); rowIndex++ {
+		oldRow, newRow := rowAt(oldRows, rowIndex), rowAt(newRows, rowIndex)
+		for columnIndex := 0; columnIndex < max(len(oldRow), len(newRow)); columnIndex++ {
+			oldValue, newValue := valueAt(oldRow, columnIndex), valueAt(newRow, columnIndex)
+			if oldValue == newValue {
+				continue
+			}
+			cellName, _ := excelize.CoordinatesToCellName(columnIndex+1, rowIndex+1)
+			differences = append(differences, Difference{
+				Sheet: sheet, Kind: cellChanged, Cell: cellName, Old: oldValue, New: newValue,
+			})
+		}
+	}
+	return differences
+}
+
+// diffKeyed compares two sheets whose first row holds the headers, matching rows by the value in the
+// keyColumn column and columns by header, so that inserted rows or reordered columns are not reported
+// as changes to every cell after them.
+func diffKeyed(sheet string, oldRows, newRows [][]string, keyColumn string) ([]Difference, error) {
+	oldHeaders, newHeaders := rowAt(oldRows, 0), rowAt(newRows, 0)
+	oldKey, newKey := headerIndex(oldHeaders, keyColumn), headerIndex(newHeaders, keyColumn)
+	if oldKey < 0 || newKey < 0 {
+		return nil, fmt.Errorf("sheet '%s': key column '%s' not found in both workbooks", sheet, keyColumn)
+	}
+	oldIndex, oldErr := indexRows(oldRows, oldKey)
+	if oldErr != nil {
+		return nil, fmt.Errorf("sheet '%s' of the old workbook: %w", sheet, oldErr)
+	}
+	newIndex, newErr := indexRows(newRows, newKey)
+	if newErr != nil {
+		return nil, fmt.Errorf("sheet '%s' of the new workbook: %w", sheet, newErr)
+	}
+
+	var differences []Difference
+	for _, header := range oldHeaders {
+		if headerIndex(newHeaders, header) < 0 {
+			differences = append(differences, Difference{Sheet: sheet, Kind: columnRemoved, Column: header})
+		}
+	}
+	for _, header := range newHeaders {
+		if headerIndex(oldHeaders, header) < 0 {
+			differences = append(differences, Difference{Sheet: sheet, Kind: columnAdded, Column: header})
+		}
+	}
+	for rowIndex := 1; rowIndex < len(oldRows); rowIndex++ {
+		key := valueAt(oldRows[rowIndex], oldKey)
+		if _, found := newIndex[key]; !found && len(strings.TrimSpace(key)) > 0 {
+			cellName, _ := excelize.CoordinatesToCellName(1, rowIndex+1)
+			differences = append(differences, Difference{Sheet: sheet, Kind: rowRemoved, Cell: cellName, Key: key})
+		}
+	}
+	for rowIndex := 1; rowIndex < len(newRows); rowIndex++ {
+		newRow := newRows[rowIndex]
+		key := valueAt(newRow, newKey)
+		if len(strings.TrimSpace(key)) == 0 {
+			continue
+		}
+		oldRowIndex, found := oldIndex[key]
+		if !found {
+			cellName, _ := excelize.CoordinatesToCellName(1, rowIndex+1)
+			differences = append(differences, Difference{Sheet: sheet, Kind: rowAdded, Cell: cellName, Key: key})
+			continue
+		}
+		oldRow := oldRows[oldRowIndex]
+		for newColumn, header := range newHeaders {
+			oldColumn := headerIndex(oldHeaders, header)
+			if oldColumn < 0 {
+				continue
+			}
+			oldValue, newValue := valueAt(oldRow, oldColumn), valueAt(newRow, newColumn)
+			if oldValue == newValue {
+				continue
+			}
+			cellName, _ := excelize.CoordinatesToCellName(newColumn+1, rowIndex+1)
+			differences = append(differences, Difference{
+				Sheet: sheet, Kind: cellChanged, Cell: cellName, Key: key, Column: header, Old: oldValue, New: newValue,
+			})
+		}
+	}
+	return differences, nil
+}
+
+// indexRows maps the key of each data row (every row after the header) to its index in rows.
+// Rows with a blank key are ignored; a key that appears twice is an error, since rows could not be matched.
+func indexRows(rows [][]string, keyColumn int) (map[string]int, error) {
+	index := make(map[string]int)
+	for rowIndex := 1; rowIndex < len(rows); rowIndex++ {
+		key := valueAt(rows[rowIndex], keyColumn)
+		if len(strings.TrimSpace(key)) == 0 {
+			continue
+		}
+		if _, duplicate := index[key]; duplicate {
+			return nil, fmt.Errorf("key '%s' appears more than once", key)
+		}
+		index[key] = rowIndex
+	}
+	return index, nil
+}
+
+// headerIndex returns the position of header in headers, ignoring surrounding whitespace, or -1 if absent.
+func headerIndex(headers []string, header string) int {
+	return slices.IndexFunc(headers, func(candidate string) bool {
+		return strings.TrimSpace(candidate) == strings.TrimSpace(header)
+	})
+}
+
+// rowAt returns the row at index, or nil if the sheet has fewer rows.
+func rowAt(rows [][]string, index int) []string {
+	if index < len(rows) {
+		return rows[index]
+	}
+	return nil
+}
+
+// valueAt returns the cell at index, or an empty string if the row is shorter.
+func valueAt(row []string, index int) string {
+	if index < len(row) {
+		return row[index]
+	}
+	return ""
+}
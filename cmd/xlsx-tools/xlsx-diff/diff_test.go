@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// describeAll writes each difference on one line, for comparing with the expected differences.
+func describeAll(differences []Difference) []string {
+	var lines []string
+	for _, d := range differences {
+		lines = append(lines, fmt.Sprintf("%s %s key=%s column=%s %s->%s", d.Kind, d.Cell, d.Key, d.Column, d.Old, d.New))
+	}
+	return lines
+}
+
+func TestDiffCells(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new [][]string
+		want     []string
+	}{
+		{
+			name: "Same",
+			old:  [][]string{{"Id", "Name"}, {"1", "Ann"}},
+			new:  [][]string{{"Id", "Name"}, {"1", "Ann"}},
+		},
+		{
+			name: "Changed",
+			old:  [][]string{{"Id", "Name"}, {"1", "Ann"}},
+			new:  [][]string{{"Id", "Name"}, {"1", "Anne"}},
+			want: []string{"cell-changed B2 key= column= Ann->Anne"},
+		},
+		{
+			name: "LongerRowsAndSheets",
+			old:  [][]string{{"Id"}},
+			new:  [][]string{{"Id", "Name"}, {"1"}},
+			want: []string{"cell-changed B1 key= column= ->Name", "cell-changed A2 key= column= ->1"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := describeAll(diffCells("Sheet1", test.old, test.new)); !slices.Equal(got, test.want) {
+				t.Errorf("diffCells() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDiffKeyed(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new [][]string
+		want     []string
+		wantErr  string
+	}{
+		{
+			name: "Reordered",
+			old:  [][]string{{"Id", "Name"}, {"1", "Ann"}, {"2", "Bob"}},
+			new:  [][]string{{"Name", "Id"}, {"Bob", "2"}, {"Ann", "1"}},
+		},
+		{
+			name: "Changes",
+			old:  [][]string{{"Id", "Name", "Age"}, {"1", "Ann", "42"}, {"2", "Bob", "7"}},
+			new:  [][]string{{"Id", "Name", "Town"}, {"2", "Bobby", "Leeds"}, {"3", "Cy", "York"}},
+			want: []string{
+				"column-removed  key= column=Age ->",
+				"column-added  key= column=Town ->",
+				"row-removed A2 key=1 column= ->",
+				"cell-changed B2 key=2 column=Name Bob->Bobby",
+				"row-added A3 key=3 column= ->",
+			},
+		},
+		{
+			name:    "MissingKey",
+			old:     [][]string{{"Id"}, {"1"}},
+			new:     [][]string{{"Key"}, {"1"}},
+			wantErr: "key column 'Id' not found",
+		},
+		{
+			name:    "DuplicateKey",
+			old:     [][]string{{"Id"}, {"1"}, {"1"}},
+			new:     [][]string{{"Id"}, {"1"}},
+			wantErr: "key '1' appears more than once",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			differences, err := diffKeyed("Sheet1", test.old, test.new, "Id")
+			if len(test.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("diffKeyed() error = %v, want one containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := describeAll(differences); !slices.Equal(got, test.want) {
+				t.Errorf("diffKeyed() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+)
+
+const (
+	formatText = "text"
+	formatHTML = "html"
+	formatJSON = "json"
+)
+
+// outputFormats lists the values accepted by the --format flag.
+var outputFormats = []string{formatText, formatHTML, formatJSON}
+
+// diffReport is the document written by every output format.
+type diffReport struct {
+	Old         string       `json:"old"`
+	New         string       `json:"new"`
+	Count       int          `json:"count"`
+	Differences []Difference `json:"differences"`
+}
+
+// writeReport writes the report to w in the given format.
+func writeReport(w io.Writer, report diffReport, format string) error {
+	switch format {
+	case formatJSON:
+		if report.Differences == nil {
+			report.Differences = []Difference{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	case formatHTML:
+		return htmlReport.Execute(w, report)
+	default:
+		return writeText(w, report)
+	}
+}
+
+// writeText writes one line per difference, followed by a summary line.
+func writeText(w io.Writer, report diffReport) error {
+	for _, difference := range report.Differences {
+		if _, err := fmt.Fprintln(w, describe(difference)); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d difference(s) between %s and %s\n", report.Count, report.Old, report.New)
+	return err
+}
+
+// describe renders a difference as a single line of text, e.g. `Sheet1!B3 [key 42, Price]: "1.50" -> "1.75"`.
+func describe(difference Difference) string {
+	location := difference.Sheet
+	if len(difference.Cell) > 0 {
+		location += "!" + difference.Cell
+	}
+	var context string
+	switch {
+	case len(difference.Key) > 0 && len(difference.Column) > 0:
+		context = fmt.Sprintf(" [key %s, %s]", difference.Key, difference.Column)
+	case len(difference.Key) > 0:
+		context = fmt.Sprintf(" [key %s]", difference.Key)
+	case len(difference.Column) > 0:
+		context = fmt.Sprintf(" [%s]", difference.Column)
+	}
+	switch difference.Kind {
+	case cellChanged:
+		return fmt.Sprintf("%s%s: %s -> %s", location, context, strconv.Quote(difference.Old), strconv.Quote(difference.New))
+	default:
+		return fmt.Sprintf("%s%s: %s", location, context, difference.Kind)
+	}
+}
+
+// htmlReport renders the report as a standalone HTML page with one table row per difference.
+var htmlReport = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Differences between {{.Old}} and {{.New}}</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+.old { background: #fdd; }
+.new { background: #dfd; }
+</style>
+</head>
+<body>
+<h1>{{.Count}} difference(s)</h1>
+<p>Old: {{.Old}}<br>New: {{.New}}</p>
+{{if .Differences}}<table>
+<tr><th>Sheet</th><th>Cell</th><th>Change</th><th>Key</th><th>Column</th><th>Old</th><th>New</th></tr>
+{{range .Differences}}<tr><td>{{.Sheet}}</td><td>{{.Cell}}</td><td>{{.Kind}}</td><td>{{.Key}}</td><td>{{.Column}}</td><td class="old">{{.Old}}</td><td class="new">{{.New}}</td></tr>
+{{end}}</table>{{end}}
+</body>
+</html>
+`))
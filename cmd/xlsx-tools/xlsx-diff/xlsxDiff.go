@@ -0,0 +1,126 @@
+// This program compares two workbooks sheet by sheet and cell by cell, or row by row keyed on a column,
+// and reports the differences as text, HTML or JSON, e.g. to check that a regenerated workbook only
+// changed what it should.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
+	"github.com/xuri/excelize/v2"
+)
+
+// options holds the command line settings controlling the comparison.
+type options struct {
+	OldPath    string
+	NewPath    string
+	Sheets     []string
+	Key        string
+	Format     string
+	Output     string
+	FailOnDiff bool
+}
+
+func main() {
+	processingErr := ErrMsg{Code: Success}
+	defer func() {
+		processingErr.Exit()
+	}()
+	opts, argErr := getInput()
+	if argErr != nil {
+		processingErr = *argErr
+		return
+	}
+	processingErr = compareWorkbooks(opts)
+}
+
+// getInput parses the command line flags. The workbooks may also be given as two positional arguments.
+func getInput() (options, *ErrMsg) {
+	var opts options
+	var sheets string
+	flag.StringVar(&opts.OldPath, "old", "", "The path to the original workbook")
+	flag.StringVar(&opts.NewPath, "new", "", "The path to the workbook to compare against the original")
+	flag.StringVar(&sheets, "sheet", "", "Comma-separated list of worksheets to compare (defaults to all sheets)")
+	flag.StringVar(&opts.Key, "key", "", "Match rows by the value in this header's column instead of by position")
+	flag.StringVar(&opts.Format, "format", formatText, "The report format: "+strings.Join(outputFormats, ", "))
+	flag.StringVar(&opts.Output, "output", "", "Write the report to this file instead of stdout")
+	flag.BoolVar(&opts.FailOnDiff, "fail-on-diff", false, "Exit with a non-zero code if any differences are found")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if logErr := logging.Setup(); logErr != nil {
+		return opts, &ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+	}
+
+	if flag.NArg() == 2 && len(opts.OldPath) == 0 && len(opts.NewPath) == 0 {
+		opts.OldPath, opts.NewPath = flag.Arg(0), flag.Arg(1)
+	}
+	opts.OldPath, opts.NewPath = strings.TrimSpace(opts.OldPath), strings.TrimSpace(opts.NewPath)
+	if len(opts.OldPath) == 0 || len(opts.NewPath) == 0 {
+		return opts, &ErrMsg{Err: errors.New("two workbooks are required, via --old and --new or as arguments"), Code: ErrNoInput}
+	}
+	if !slices.Contains(outputFormats, opts.Format) {
+		return opts, &ErrMsg{Err: fmt.Errorf("unsupported format '%s'", opts.Format), Code: ErrInvalidArgs}
+	}
+	for _, sheet := range strings.Split(sheets, ",") {
+		if sheet = strings.TrimSpace(sheet); sheet != "" {
+			opts.Sheets = append(opts.Sheets, sheet)
+		}
+	}
+	return opts, nil
+}
+
+func compareWorkbooks(opts options) ErrMsg {
+	var files []*excelize.File
+	defer func() {
+		for _, file := range files {
+			_ = file.Close()
+		}
+	}()
+	for _, path := range []string{opts.OldPath, opts.NewPath} {
+		if exists, _ := PathExists(path); !exists {
+			return ErrMsg{Err: fmt.Errorf("file '%s' does not exist", path), Code: ErrNoFile}
+		}
+		if !IsWorkbookFile(path, false) {
+			return ErrMsg{Err: fmt.Errorf("file '%s' is not an Excel workbook", path), Code: ErrInvalidFileType}
+		}
+		file, openErr := excelize.OpenFile(path)
+		if openErr != nil {
+			return ErrMsg{Err: openErr, Code: ErrReadFile}
+		}
+		files = append(files, file)
+	}
+
+	differences, diffErr := diffWorkbooks(files[0], files[1], opts.Sheets, opts.Key)
+	if diffErr != nil {
+		return ErrMsg{Err: diffErr, Code: ErrParse}
+	}
+	report := diffReport{Old: opts.OldPath, New: opts.NewPath, Count: len(differences), Differences: differences}
+
+	var output io.Writer = os.Stdout
+	errCode := ErrStdout
+	if len(opts.Output) > 0 {
+		outputFile, createErr := os.Create(opts.Output)
+		if createErr != nil {
+			return ErrMsg{Err: createErr, Code: ErrWriteFile}
+		}
+		defer func(outputFile *os.File) {
+			_ = outputFile.Close()
+		}(outputFile)
+		output, errCode = outputFile, ErrWriteFile
+	}
+	if writeErr := writeReport(output, report, opts.Format); writeErr != nil {
+		return ErrMsg{Err: writeErr, Code: errCode}
+	}
+	if opts.FailOnDiff && len(differences) > 0 {
+		return ErrMsg{Code: ErrDifferences}
+	}
+	return ErrMsg{Code: Success}
+}
@@ -0,0 +1,349 @@
+// This program merges several workbooks into one. By default each selected worksheet is copied into the
+// output workbook as its own sheet; with --combine the rows of every selected sheet are appended to a single
+// sheet instead, with columns matched up by header name.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
+	"github.com/charmbracelet/log"
+	"github.com/xuri/excelize/v2"
+)
+
+// options holds the command line settings controlling the merge.
+type options struct {
+	Inputs        []string
+	Output        string
+	Force         bool
+	SheetPattern  string
+	Combine       bool
+	CombinedSheet string
+	SourceColumn  string
+	HeaderRow     int
+}
+
+// sourceSheet is a worksheet selected from one of the input workbooks.
+type sourceSheet struct {
+	file      *excelize.File
+	path      string
+	sheet     string
+	headers   []string
+	dataStart int
+}
+
+func main() {
+	startTime := time.Now()
+
+	processingErr := ErrMsg{Code: Success}
+	defer func() {
+		log.Debug(
+			"DONE!",
+			"time", time.Since(startTime),
+		)
+		processingErr.Exit()
+	}()
+	opts, argErr := getInput()
+	if argErr != nil {
+		processingErr = *argErr
+		return
+	}
+	processingErr = mergeWorkbooks(opts)
+}
+
+// getInput parses the command line flags; the workbooks to merge are given as arguments, or as paths piped
+// through standard input.
+func getInput() (options, *ErrMsg) {
+	var opts options
+	flag.StringVar(&opts.Output, "output", "", "The path of the merged workbook to write")
+	flag.StringVar(&opts.Output, "o", "", "Shorthand for --output")
+	flag.BoolVar(&opts.Force, "force", false, "Overwrite the output workbook if it already exists")
+	flag.StringVar(&opts.SheetPattern, "sheet-pattern", "*", "Only merge worksheets whose name matches this glob pattern, e.g. 'Sales*'")
+	flag.BoolVar(&opts.Combine, "combine", false, "Append the rows of every sheet to a single sheet, matching columns by header")
+	flag.StringVar(&opts.CombinedSheet, "combined-sheet", "Merged", "The name of the single sheet written by --combine")
+	flag.StringVar(&opts.SourceColumn, "source-column", "", "With --combine, add a column with this header recording each row's workbook and sheet")
+	flag.IntVar(&opts.HeaderRow, "header-row", 1, "With --combine, the one-based row holding the headers of each sheet")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if logErr := logging.Setup(); logErr != nil {
+		return opts, &ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+	}
+
+	inputs, inputErr := ResolveInputs("", flag.Args())
+	if inputErr != nil {
+		errMsg := ToErrMsg(inputErr, ErrNoInput)
+		return opts, &errMsg
+	}
+	for _, input := range inputs {
+		if input.IsStdin() {
+			return opts, &ErrMsg{Err: errors.New("workbooks cannot be read from standard input, give the path of a file"), Code: ErrInvalidArgs}
+		}
+		opts.Inputs = append(opts.Inputs, input.Path)
+	}
+	if len(opts.Output) == 0 {
+		return opts, &ErrMsg{Err: errors.New("an output workbook is required, use --output"), Code: ErrInvalidArgs}
+	}
+	if _, patternErr := path.Match(opts.SheetPattern, ""); patternErr != nil {
+		return opts, &ErrMsg{Err: fmt.Errorf("invalid --sheet-pattern '%s': %w", opts.SheetPattern, patternErr), Code: ErrInvalidArgs}
+	}
+	if opts.HeaderRow < 1 {
+		return opts, &ErrMsg{Err: fmt.Errorf("--header-row must be at least 1, got %d", opts.HeaderRow), Code: ErrInvalidArgs}
+	}
+	return opts, nil
+}
+
+func mergeWorkbooks(opts options) ErrMsg {
+	if exists, _ := PathExists(opts.Output); exists && !opts.Force {
+		return ErrMsg{Err: fmt.Errorf("output file '%s' already exists, use --force to overwrite it", opts.Output), Code: ErrWriteFile}
+	}
+	var sources []sourceSheet
+	defer func() {
+		for index, source := range sources {
+			if index == 0 || source.file != sources[index-1].file {
+				_ = source.file.Close()
+			}
+		}
+	}()
+	for _, input := range opts.Inputs {
+		if exists, _ := PathExists(input); !exists {
+			return ErrMsg{Err: fmt.Errorf("file '%s' does not exist", input), Code: ErrNoFile}
+		}
+		if !IsWorkbookFile(input, false) {
+			return ErrMsg{Err: fmt.Errorf("file '%s' is not an Excel workbook", input), Code: ErrInvalidFileType}
+		}
+		file, openErr := excelize.OpenFile(input)
+		if openErr != nil {
+			return ErrMsg{Err: openErr, Code: ErrReadFile}
+		}
+		selected := 0
+		for _, sheet := range file.GetSheetList() {
+			if matched, _ := path.Match(opts.SheetPattern, sheet); matched {
+				sources = append(sources, sourceSheet{file: file, path: input, sheet: sheet})
+				selected++
+			}
+		}
+		if selected == 0 {
+			log.Warn("No sheets match the pattern", "file", input, "pattern", opts.SheetPattern)
+			_ = file.Close()
+		}
+	}
+	if len(sources) == 0 {
+		return ErrMsg{Err: fmt.Errorf("no sheets match '%s' in the given workbooks", opts.SheetPattern), Code: ErrNoInput}
+	}
+
+	output := excelize.NewFile()
+	defer func(output *excelize.File) {
+		_ = output.Close()
+	}(output)
+	var mergeErr error
+	if opts.Combine {
+		mergeErr = combineSheets(output, sources, opts)
+	} else {
+		mergeErr = copySheets(output, sources)
+	}
+	if mergeErr != nil {
+		return ErrMsg{Err: mergeErr, Code: ErrReadWrite}
+	}
+	if saveErr := output.SaveAs(opts.Output); saveErr != nil {
+		return ErrMsg{Err: saveErr, Code: ErrWriteFile}
+	}
+	log.Info("Merged workbooks", "sheets", len(sources), "output", opts.Output)
+	return ErrMsg{Code: Success}
+}
+
+// copySheets copies each source sheet into output as a sheet of its own. Sheets whose name is already
+// taken are renamed after their workbook, e.g. "Sheet1 (sales)", and numbered if that is taken too.
+func copySheets(output *excelize.File, sources []sourceSheet) error {
+	var used []string
+	for index, source := range sources {
+		name := uniqueSheetName(source, used)
+		used = append(used, name)
+		if index == 0 {
+			if err := output.SetSheetName(output.GetSheetName(0), name); err != nil {
+				return err
+			}
+		} else if _, err := output.NewSheet(name); err != nil {
+			return err
+		}
+		writer, writerErr := output.NewStreamWriter(name)
+		if writerErr != nil {
+			return writerErr
+		}
+		if err := eachRow(source, 1, func(rowNum int, columns []string) error {
+			return writeRow(writer, rowNum, columns)
+		}); err != nil {
+			return fmt.Errorf("%s, sheet '%s': %w", source.path, source.sheet, err)
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		log.Info("Copied sheet", "file", source.path, "sheet", source.sheet, "as", name)
+	}
+	return nil
+}
+
+// combineSheets appends the data rows of every source sheet to a single sheet of output.
+// The combined header row is the union of the sheets' headers in order of first appearance, and each value
+// is written under the column with its header, so sheets with reordered or extra columns line up.
+func combineSheets(output *excelize.File, sources []sourceSheet, opts options) error {
+	var headers []string
+	if len(opts.SourceColumn) > 0 {
+		headers = append(headers, opts.SourceColumn)
+	}
+	for index := range sources {
+		sourceHeaders, headerErr := readHeaders(sources[index].file, sources[index].sheet, opts.HeaderRow)
+		if headerErr != nil {
+			return fmt.Errorf("%s, sheet '%s': %w", sources[index].path, sources[index].sheet, headerErr)
+		}
+		sources[index].headers = sourceHeaders
+		for _, header := range sourceHeaders {
+			if !slices.Contains(headers, header) {
+				headers = append(headers, header)
+			}
+		}
+	}
+	name := opts.CombinedSheet
+	if err := output.SetSheetName(output.GetSheetName(0), name); err != nil {
+		return err
+	}
+	writer, writerErr := output.NewStreamWriter(name)
+	if writerErr != nil {
+		return writerErr
+	}
+	if err := writeRow(writer, 1, headers); err != nil {
+		return err
+	}
+	rowNum := 1
+	for _, source := range sources {
+		positions := make([]int, len(source.headers))
+		for i, header := range source.headers {
+			positions[i] = slices.Index(headers, header)
+		}
+		sourceName := filepath.Base(source.path) + ":" + source.sheet
+		rowCount := 0
+		if err := eachRow(source, opts.HeaderRow+1, func(_ int, columns []string) error {
+			record := make([]string, len(headers))
+			if len(opts.SourceColumn) > 0 {
+				record[0] = sourceName
+			}
+			for i, value := range columns {
+				if i < len(positions) {
+					record[positions[i]] = value
+				} else if len(strings.TrimSpace(value)) > 0 {
+					return fmt.Errorf("row %d has a value in column %d, which has no header", opts.HeaderRow+rowCount+1, i+1)
+				}
+			}
+			rowNum++
+			rowCount++
+			return writeRow(writer, rowNum, record)
+		}); err != nil {
+			return fmt.Errorf("%s, sheet '%s': %w", source.path, source.sheet, err)
+		}
+		log.Info("Combined sheet", "file", source.path, "sheet", source.sheet, "rows", rowCount)
+	}
+	return writer.Flush()
+}
+
+// readHeaders returns the headers in headerRow of a sheet, naming blank headers after their column letter
+// so that every column can be reconciled. Duplicate headers within a sheet are renamed with RenameDuplicates.
+func readHeaders(file *excelize.File, sheet string, headerRow int) ([]string, error) {
+	var headers []string
+	err := eachRow(sourceSheet{file: file, sheet: sheet}, headerRow, func(rowNum int, columns []string) error {
+		if rowNum == headerRow {
+			headers = slices.Clone(columns)
+			return errStopRows
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStopRows) {
+		return nil, err
+	}
+	for i, header := range headers {
+		if headers[i] = strings.TrimSpace(header); len(headers[i]) == 0 {
+			headers[i], _ = excelize.ColumnNumberToName(i + 1)
+		}
+	}
+	return RenameDuplicates(headers, false), nil
+}
+
+// errStopRows ends eachRow early without reporting an error.
+var errStopRows = errors.New("stop reading rows")
+
+// eachRow calls fn with the one-based row number and values of every row of the source sheet from
+// firstRow onwards, stopping at the first error fn returns.
+func eachRow(source sourceSheet, firstRow int, fn func(rowNum int, columns []string) error) error {
+	rows, rowsErr := source.file.Rows(source.sheet)
+	if rowsErr != nil {
+		return rowsErr
+	}
+	defer func(rows *excelize.Rows) {
+		_ = rows.Close()
+	}(rows)
+	rowNum := 0
+	for rows.Next() {
+		rowNum++
+		if rowNum < firstRow {
+			continue
+		}
+		columns, colErr := rows.Columns()
+		if colErr != nil {
+			return colErr
+		}
+		if err := fn(rowNum, columns); err != nil {
+			return err
+		}
+	}
+	return rows.Error()
+}
+
+// writeRow writes values to the given row of the stream, as numbers or booleans where they read as one.
+func writeRow(writer *excelize.StreamWriter, rowNum int, values []string) error {
+	cells := make([]interface{}, len(values))
+	for i, value := range values {
+		cells[i] = TypedValue(value)
+	}
+	cellName, nameErr := excelize.CoordinatesToCellName(1, rowNum)
+	if nameErr != nil {
+		return nameErr
+	}
+	return writer.SetRow(cellName, cells)
+}
+
+// uniqueSheetName returns the source's sheet name, or if that is already used, the name followed by its
+// workbook's name and then a number, truncated to Excel's limit of 31 characters.
+func uniqueSheetName(source sourceSheet, used []string) string {
+	taken := func(name string) bool {
+		return slices.ContainsFunc(used, func(other string) bool { return strings.EqualFold(other, name) })
+	}
+	truncate := func(name string) string {
+		if runes := []rune(name); len(runes) > 31 {
+			return string(runes[:31])
+		}
+		return name
+	}
+	name := truncate(source.sheet)
+	if !taken(name) {
+		return name
+	}
+	workbook := strings.TrimSuffix(filepath.Base(source.path), filepath.Ext(source.path))
+	name = truncate(fmt.Sprintf("%s (%s)", source.sheet, workbook))
+	for count := 2; taken(name); count++ {
+		suffix := " " + strconv.Itoa(count)
+		name = truncate(source.sheet)
+		if runes := []rune(name); len(runes)+len(suffix) > 31 {
+			name = string(runes[:31-len(suffix)])
+		}
+		name += suffix
+	}
+	return name
+}
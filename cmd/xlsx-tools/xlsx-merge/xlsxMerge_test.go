@@ -0,0 +1,179 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	. "GoTools/pkg/helpers"
+	"github.com/xuri/excelize/v2"
+)
+
+// createTestWorkbook saves a workbook at dir/name holding the given sheets, in order, and returns its path.
+func createTestWorkbook(t *testing.T, dir, name string, sheets map[string][][]any, order ...string) string {
+	t.Helper()
+	file := excelize.NewFile()
+	defer func() {
+		_ = file.Close()
+	}()
+	for index, sheet := range order {
+		if index == 0 {
+			if err := file.SetSheetName("Sheet1", sheet); err != nil {
+				t.Fatal(err)
+			}
+		} else if _, err := file.NewSheet(sheet); err != nil {
+			t.Fatal(err)
+		}
+		for i, row := range sheets[sheet] {
+			cell, _ := excelize.CoordinatesToCellName(1, i+1)
+			if err := file.SetSheetRow(sheet, cell, &row); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	path := filepath.Join(dir, name)
+	if err := file.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readWorkbook returns the rows of every sheet of the workbook at path, keyed by sheet name.
+func readWorkbook(t *testing.T, path string) map[string][][]string {
+	t.Helper()
+	file, err := excelize.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	sheets := make(map[string][][]string)
+	for _, sheet := range file.GetSheetList() {
+		if sheets[sheet], err = file.GetRows(sheet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return sheets
+}
+
+func TestMergeWorkbooks(t *testing.T) {
+	dir := t.TempDir()
+	sales := createTestWorkbook(t, dir, "sales.xlsx", map[string][][]any{
+		"Sheet1": {{"Id", "Amount"}, {1, 9.5}},
+		"Notes":  {{"Text"}, {"hello"}},
+	}, "Sheet1", "Notes")
+	returns := createTestWorkbook(t, dir, "returns.xlsx", map[string][][]any{
+		"Sheet1": {{"Amount", "Id", "Reason"}, {2, 7, "broken"}},
+	}, "Sheet1")
+	stray := createTestWorkbook(t, dir, "stray.xlsx", map[string][][]any{
+		"Sheet1": {{"Id"}, {1, "no header"}},
+	}, "Sheet1")
+	tests := []struct {
+		name     string
+		opts     options
+		want     map[string][][]string
+		wantCode int
+	}{
+		{
+			name: "Copy",
+			opts: options{Inputs: []string{sales, returns}, SheetPattern: "*"},
+			want: map[string][][]string{
+				"Sheet1":           {{"Id", "Amount"}, {"1", "9.5"}},
+				"Notes":            {{"Text"}, {"hello"}},
+				"Sheet1 (returns)": {{"Amount", "Id", "Reason"}, {"2", "7", "broken"}},
+			},
+			wantCode: Success,
+		},
+		{
+			name: "Combine",
+			opts: options{
+				Inputs: []string{sales, returns}, SheetPattern: "Sheet*", Combine: true,
+				CombinedSheet: "All", SourceColumn: "Source", HeaderRow: 1,
+			},
+			want: map[string][][]string{
+				"All": {
+					{"Source", "Id", "Amount", "Reason"},
+					{"sales.xlsx:Sheet1", "1", "9.5"},
+					{"returns.xlsx:Sheet1", "7", "2", "broken"},
+				},
+			},
+			wantCode: Success,
+		},
+		{
+			name:     "No Matching Sheets",
+			opts:     options{Inputs: []string{sales}, SheetPattern: "Missing*"},
+			wantCode: ErrNoInput,
+		},
+		{
+			name:     "Value Without Header",
+			opts:     options{Inputs: []string{stray}, SheetPattern: "*", Combine: true, CombinedSheet: "All", HeaderRow: 1},
+			wantCode: ErrReadWrite,
+		},
+		{
+			name:     "Missing Input",
+			opts:     options{Inputs: []string{filepath.Join(dir, "missing.xlsx")}, SheetPattern: "*"},
+			wantCode: ErrNoFile,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Output = filepath.Join(t.TempDir(), "merged.xlsx")
+			if got := mergeWorkbooks(tt.opts); got.Code != tt.wantCode {
+				t.Fatalf("mergeWorkbooks() = %v, want code %d", got, tt.wantCode)
+			}
+			if tt.wantCode != Success {
+				if exists, _ := PathExists(tt.opts.Output); exists {
+					t.Errorf("mergeWorkbooks() failed but wrote %s", tt.opts.Output)
+				}
+				return
+			}
+			if got := readWorkbook(t, tt.opts.Output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("merged workbook = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeWorkbooksExistingOutput(t *testing.T) {
+	dir := t.TempDir()
+	input := createTestWorkbook(t, dir, "input.xlsx", map[string][][]any{"Sheet1": {{"Id"}}}, "Sheet1")
+	output := filepath.Join(dir, "merged.xlsx")
+	if err := os.WriteFile(output, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := options{Inputs: []string{input}, Output: output, SheetPattern: "*"}
+	if got := mergeWorkbooks(opts); got.Code != ErrWriteFile {
+		t.Fatalf("mergeWorkbooks() = %v, want code %d", got, ErrWriteFile)
+	}
+	opts.Force = true
+	if got := mergeWorkbooks(opts); got.Code != Success {
+		t.Fatalf("mergeWorkbooks() with Force = %v, want success", got)
+	}
+}
+
+func TestUniqueSheetName(t *testing.T) {
+	long := strings.Repeat("x", 40)
+	tests := []struct {
+		name  string
+		sheet string
+		path  string
+		used  []string
+		want  string
+	}{
+		{name: "Free", sheet: "Data", path: "a.xlsx", want: "Data"},
+		{name: "Taken", sheet: "Data", path: "dir/sales.xlsx", used: []string{"data"}, want: "Data (sales)"},
+		{name: "Numbered", sheet: "Data", path: "sales.xlsx", used: []string{"Data", "Data (sales)", "Data 2"}, want: "Data 3"},
+		{name: "Truncated", sheet: long, path: "a.xlsx", want: long[:31]},
+		{name: "Truncated Numbered", sheet: long, path: "a.xlsx", used: []string{long[:31]}, want: long[:29] + " 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := uniqueSheetName(sourceSheet{path: tt.path, sheet: tt.sheet}, tt.used); got != tt.want {
+				t.Errorf("uniqueSheetName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
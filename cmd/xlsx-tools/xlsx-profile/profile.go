@@ -0,0 +1,142 @@
+package main
+
+import (
+	"math"
+	"strings"
+
+	. "GoTools/pkg/helpers"
+	"github.com/xuri/excelize/v2"
+)
+
+// SheetProfile summarises a single worksheet.
+type SheetProfile struct {
+	Name    string          `json:"name"`
+	Rows    int             `json:"rows"`
+	Columns int             `json:"columns"`
+	Fields  []ColumnProfile `json:"fields"`
+}
+
+// ColumnProfile summarises the values below a single header.
+type ColumnProfile struct {
+	Column       string  `json:"column"`
+	Header       string  `json:"header"`
+	Type         string  `json:"type"`
+	Blank        int     `json:"blank"`
+	BlankPercent float64 `json:"blank_percent"`
+	// Distinct counts the different non-blank values, stopping at the --max-distinct limit.
+	Distinct  int            `json:"distinct"`
+	Truncated bool           `json:"distinct_truncated,omitempty"`
+	Types     map[string]int `json:"types"`
+	MaxLength int            `json:"max_length"`
+
+	values map[string]struct{}
+}
+
+// profileSheet reads the rows of a worksheet, taking the headers from headerRow (or naming the columns
+// by letter if headerRow is 0), and profiles each column. Rows above the header row are ignored.
+func profileSheet(file *excelize.File, name string, headerRow, maxDistinct int) (SheetProfile, error) {
+	profile := SheetProfile{Name: name}
+	rows, rowsErr := file.Rows(name)
+	if rowsErr != nil {
+		return profile, rowsErr
+	}
+	defer func(rows *excelize.Rows) {
+		_ = rows.Close()
+	}(rows)
+	var headers []string
+	rowIndex := 0
+	for rows.Next() {
+		rowIndex++
+		columns, colErr := rows.Columns()
+		if colErr != nil {
+			return profile, colErr
+		}
+		if rowIndex < headerRow {
+			continue
+		} else if rowIndex == headerRow {
+			headers = columns
+			continue
+		}
+		profile.Rows++
+		for len(profile.Fields) < max(len(columns), len(headers)) {
+			profile.Fields = append(profile.Fields, newColumnProfile(len(profile.Fields), headers, profile.Rows-1))
+		}
+		for columnIndex := range profile.Fields {
+			var value string
+			if columnIndex < len(columns) {
+				value = columns[columnIndex]
+			}
+			profile.Fields[columnIndex].add(value, maxDistinct)
+		}
+	}
+	profile.Columns = len(profile.Fields)
+	for columnIndex := range profile.Fields {
+		profile.Fields[columnIndex].finish(profile.Rows)
+	}
+	return profile, rows.Error()
+}
+
+// newColumnProfile starts the profile of the column at columnIndex, counting the rows read before the
+// column was first seen as blank.
+func newColumnProfile(columnIndex int, headers []string, blankRows int) ColumnProfile {
+	column, _ := excelize.ColumnNumberToName(columnIndex + 1)
+	profile := ColumnProfile{
+		Column: column,
+		Header: column,
+		Blank:  blankRows,
+		Types:  make(map[string]int),
+		values: make(map[string]struct{}),
+	}
+	if columnIndex < len(headers) && len(strings.TrimSpace(headers[columnIndex])) > 0 {
+		profile.Header = headers[columnIndex]
+	}
+	if blankRows > 0 {
+		profile.Types["empty"] = blankRows
+	}
+	return profile
+}
+
+// add records a single value of the column.
+func (p *ColumnProfile) add(value string, maxDistinct int) {
+	valueType := InferValueType(value)
+	p.Types[valueType]++
+	if valueType == "empty" {
+		p.Blank++
+		return
+	}
+	p.MaxLength = max(p.MaxLength, len([]rune(value)))
+	if _, seen := p.values[value]; !seen {
+		if maxDistinct > 0 && len(p.values) >= maxDistinct {
+			p.Truncated = true
+			return
+		}
+		p.values[value] = struct{}{}
+	}
+}
+
+// finish works out the column's overall type and blank percentage once every row has been added.
+// The type is the single type shared by all non-blank values, "number" for a mix of integers and numbers,
+// "mixed" for any other combination, and "empty" if every value is blank.
+func (p *ColumnProfile) finish(rowCount int) {
+	p.Distinct = len(p.values)
+	p.values = nil
+	if rowCount > 0 {
+		p.BlankPercent = math.Round(float64(p.Blank)*1000/float64(rowCount)) / 10
+	}
+	var found []string
+	for valueType := range p.Types {
+		if valueType != "empty" {
+			found = append(found, valueType)
+		}
+	}
+	switch {
+	case len(found) == 0:
+		p.Type = "empty"
+	case len(found) == 1:
+		p.Type = found[0]
+	case len(found) == 2 && p.Types["integer"] > 0 && p.Types["number"] > 0:
+		p.Type = "number"
+	default:
+		p.Type = "mixed"
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestProfileSheet(t *testing.T) {
+	file := excelize.NewFile()
+	defer func() { _ = file.Close() }()
+	rows := [][]interface{}{
+		{"Title row"},
+		{"Id", "Amount", "Note", ""},
+		{"1", "1.5", "a", "x"},
+		{"2", "3", "", "y"},
+		{"3", "", "a", "2024-01-31"},
+		{"3", "4.25", "b"},
+	}
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := file.SetSheetRow("Sheet1", cell, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	profile, err := profileSheet(file, "Sheet1", 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.Rows != 4 || profile.Columns != 4 {
+		t.Fatalf("Rows, Columns = %d, %d, want 4, 4", profile.Rows, profile.Columns)
+	}
+	tests := []struct {
+		header       string
+		typ          string
+		blank        int
+		blankPercent float64
+		distinct     int
+		truncated    bool
+		maxLength    int
+	}{
+		{header: "Id", typ: "integer", distinct: 2, truncated: true, maxLength: 1},
+		{header: "Amount", typ: "number", blank: 1, blankPercent: 25, distinct: 2, truncated: true, maxLength: 4},
+		{header: "Note", typ: "string", blank: 1, blankPercent: 25, distinct: 2, maxLength: 1},
+		{header: "D", typ: "mixed", blank: 1, blankPercent: 25, distinct: 2, truncated: true, maxLength: 10},
+	}
+	for i, test := range tests {
+		field := profile.Fields[i]
+		if field.Header != test.header || field.Type != test.typ || field.Blank != test.blank ||
+			field.BlankPercent != test.blankPercent || field.Distinct != test.distinct ||
+			field.Truncated != test.truncated || field.MaxLength != test.maxLength {
+			t.Errorf("field %d = %+v, want %+v", i, field, test)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+// This program profiles the worksheets of a .xlsx file, reporting the row and column counts of each sheet
+// and, for each column, its inferred type, how many cells are blank and how many distinct values it holds,
+// as a pre-flight check before a workbook is parsed.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
+	"github.com/xuri/excelize/v2"
+)
+
+// options holds the command line settings controlling the profile.
+type options struct {
+	FilePath    string
+	Sheets      []string
+	HeaderRow   int
+	MaxDistinct int
+	AsJSON      bool
+}
+
+func main() {
+	processingErr := ErrMsg{Code: Success}
+	defer func() {
+		processingErr.Exit()
+	}()
+	var opts options
+	var sheets string
+	flag.StringVar(&opts.FilePath, "path", "", "The path to the .xlsx file to profile")
+	flag.StringVar(&sheets, "sheet", "", "Comma-separated list of worksheets to profile (defaults to all sheets)")
+	flag.IntVar(&opts.HeaderRow, "header-row", 1, "The one-based row holding the headers, or 0 if the sheets have none")
+	flag.IntVar(&opts.MaxDistinct, "max-distinct", 100000, "Stop counting distinct values in a column after this many, or 0 for no limit")
+	flag.BoolVar(&opts.AsJSON, "json", false, "Print the profile as JSON")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if logErr := logging.Setup(); logErr != nil {
+		processingErr = ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+		return
+	}
+
+	opts.Sheets = splitList(sheets)
+	if opts.HeaderRow < 0 || opts.MaxDistinct < 0 {
+		processingErr = ErrMsg{Err: errors.New("--header-row and --max-distinct cannot be negative"), Code: ErrInvalidArgs}
+		return
+	}
+	path, inputErr := ResolveFile(opts.FilePath, flag.Args())
+	if inputErr != nil {
+		processingErr = ToErrMsg(inputErr, ErrNoInput)
+		return
+	}
+	opts.FilePath = path
+	if exists, _ := PathExists(opts.FilePath); !exists {
+		processingErr = ErrMsg{Err: fmt.Errorf("file '%s' does not exist", opts.FilePath), Code: ErrNoFile}
+		return
+	}
+	if !IsWorkbookFile(opts.FilePath, false) {
+		processingErr = ErrMsg{Err: fmt.Errorf("file '%s' is not an Excel workbook", opts.FilePath), Code: ErrInvalidFileType}
+		return
+	}
+
+	profiles, profileErr := profileWorkbook(opts)
+	if profileErr != nil {
+		processingErr = ErrMsg{Err: profileErr, Code: ErrParse}
+		return
+	}
+	var writeErr error
+	if opts.AsJSON {
+		writeErr = printJSON(os.Stdout, profiles)
+	} else {
+		writeErr = printText(os.Stdout, profiles)
+	}
+	if writeErr != nil {
+		processingErr = ErrMsg{Err: writeErr, Code: ErrStdout}
+	}
+}
+
+// profileWorkbook opens the workbook and profiles the selected sheets in workbook order.
+func profileWorkbook(opts options) (profiles []SheetProfile, err error) {
+	file, openErr := excelize.OpenFile(opts.FilePath)
+	if openErr != nil {
+		return nil, openErr
+	}
+	defer func(file *excelize.File) {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}(file)
+	sheetList := file.GetSheetList()
+	for _, sheet := range opts.Sheets {
+		if !slices.Contains(sheetList, sheet) {
+			return nil, fmt.Errorf("sheet '%s' does not exist", sheet)
+		}
+	}
+	for _, sheet := range sheetList {
+		if len(opts.Sheets) > 0 && !slices.Contains(opts.Sheets, sheet) {
+			continue
+		}
+		profile, sheetErr := profileSheet(file, sheet, opts.HeaderRow, opts.MaxDistinct)
+		if sheetErr != nil {
+			return nil, fmt.Errorf("sheet '%s': %w", sheet, sheetErr)
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
+
+// splitList splits a comma-separated flag value, discarding surrounding whitespace and blanks.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// printText writes a heading per sheet followed by an aligned table of its columns.
+func printText(w io.Writer, profiles []SheetProfile) error {
+	for i, profile := range profiles {
+		if i > 0 {
+			_, _ = fmt.Fprintln(w)
+		}
+		_, _ = fmt.Fprintf(w, "%s: %d rows, %d columns\n", profile.Name, profile.Rows, profile.Columns)
+		writer := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		_, _ = fmt.Fprintln(writer, "COLUMN\tHEADER\tTYPE\tBLANK\tBLANK%\tDISTINCT\tMAX LENGTH")
+		for _, field := range profile.Fields {
+			distinct := strconv.Itoa(field.Distinct)
+			if field.Truncated {
+				distinct += "+"
+			}
+			_, _ = fmt.Fprintf(
+				writer, "%s\t%s\t%s\t%d\t%.1f\t%s\t%d\n",
+				field.Column, field.Header, field.Type, field.Blank, field.BlankPercent, distinct, field.MaxLength,
+			)
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printJSON writes the profiles as a JSON array.
+func printJSON(w io.Writer, profiles []SheetProfile) error {
+	if profiles == nil {
+		profiles = []SheetProfile{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(profiles)
+}
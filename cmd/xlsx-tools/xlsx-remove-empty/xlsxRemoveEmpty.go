@@ -0,0 +1,263 @@
+// This program deletes fully blank rows and columns from the worksheets of a .xlsx file, in place or to a
+// copy given with --output, and shrinks each sheet's used range to match. A cell is blank if it is empty or
+// whitespace and holds no formula. Rows and columns are removed with excelize, which shifts the cells after
+// them and updates references to them where it can.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
+	"github.com/charmbracelet/log"
+	"github.com/xuri/excelize/v2"
+)
+
+// options holds the command line settings controlling the cleanup.
+type options struct {
+	FilePath string
+	Sheets   []string
+	Output   string
+	Force    bool
+	Rows     bool
+	Columns  bool
+}
+
+func main() {
+	startTime := time.Now()
+
+	processingErr := ErrMsg{Code: Success}
+	defer func() {
+		log.Debug(
+			"DONE!",
+			"time", time.Since(startTime),
+		)
+		processingErr.Exit()
+	}()
+	opts, argErr := getInput()
+	if argErr != nil {
+		processingErr = *argErr
+		return
+	}
+	processingErr = processWorkbook(opts)
+}
+
+// getInput parses the command line flags. The file is given with --path or as an argument, falling back to
+// a path piped through standard input.
+func getInput() (options, *ErrMsg) {
+	var opts options
+	var sheets string
+	flag.StringVar(&opts.FilePath, "path", "", "The path to the .xlsx file to clean up")
+	flag.StringVar(&sheets, "sheet", "", "Comma-separated list of worksheets to clean up (defaults to all sheets)")
+	flag.StringVar(&opts.Output, "output", "", "Write the cleaned workbook to this path instead of amending the original")
+	flag.BoolVar(&opts.Force, "force", false, "Overwrite the --output workbook if it already exists")
+	flag.BoolVar(&opts.Rows, "rows", true, "Remove blank rows (use --rows=false to keep them)")
+	flag.BoolVar(&opts.Columns, "columns", true, "Remove blank columns (use --columns=false to keep them)")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if logErr := logging.Setup(); logErr != nil {
+		return opts, &ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+	}
+
+	if !opts.Rows && !opts.Columns {
+		return opts, &ErrMsg{Err: errors.New("nothing to remove, --rows and --columns are both false"), Code: ErrInvalidArgs}
+	}
+	for _, sheet := range strings.Split(sheets, ",") {
+		if sheet = strings.TrimSpace(sheet); sheet != "" {
+			opts.Sheets = append(opts.Sheets, sheet)
+		}
+	}
+
+	path, inputErr := ResolveFile(opts.FilePath, flag.Args())
+	if inputErr != nil {
+		errMsg := ToErrMsg(inputErr, ErrNoInput)
+		return opts, &errMsg
+	}
+	opts.FilePath = path
+	return opts, nil
+}
+
+func processWorkbook(opts options) ErrMsg {
+	if exists, _ := PathExists(opts.FilePath); !exists {
+		return ErrMsg{Err: fmt.Errorf("file '%s' does not exist", opts.FilePath), Code: ErrNoFile}
+	}
+	if !IsWorkbookFile(opts.FilePath, false) {
+		return ErrMsg{Err: fmt.Errorf("file '%s' is not an Excel workbook", opts.FilePath), Code: ErrInvalidFileType}
+	}
+	if exists, _ := PathExists(opts.Output); exists && !opts.Force {
+		return ErrMsg{Err: fmt.Errorf("output file '%s' already exists, use --force to overwrite it", opts.Output), Code: ErrWriteFile}
+	}
+	file, openErr := excelize.OpenFile(opts.FilePath)
+	if openErr != nil {
+		return ErrMsg{Err: openErr, Code: ErrReadFile}
+	}
+	defer func(file *excelize.File) {
+		if err := file.Close(); err != nil {
+			log.Error(err)
+		}
+	}(file)
+
+	sheets := opts.Sheets
+	if len(sheets) == 0 {
+		sheets = file.GetSheetList()
+	}
+	for _, sheet := range sheets {
+		if index, _ := file.GetSheetIndex(sheet); index < 0 {
+			return ErrMsg{Err: fmt.Errorf("sheet '%s' does not exist", sheet), Code: ErrInvalidArgs}
+		}
+		removedRows, removedCols, removeErr := removeEmpty(file, sheet, opts)
+		if removeErr != nil {
+			return ErrMsg{Err: fmt.Errorf("sheet '%s': %w", sheet, removeErr), Code: ErrReadWrite}
+		}
+		log.Info("Removed blank rows and columns", "sheet", sheet, "rows", removedRows, "columns", removedCols)
+	}
+	if saveErr := saveWorkbook(file, opts); saveErr != nil {
+		return ErrMsg{Err: saveErr, Code: ErrWriteFile}
+	}
+	return ErrMsg{Code: Success}
+}
+
+// removeEmpty deletes the blank rows and columns of a sheet, returning how many of each were removed.
+// Rows and columns past the last used cell but inside the stored dimension are removed too, and the
+// dimension is then reset to the cells that remain.
+func removeEmpty(file *excelize.File, sheet string, opts options) (removedRows, removedCols int, err error) {
+	rows, rowsErr := file.GetRows(sheet)
+	if rowsErr != nil {
+		return 0, 0, rowsErr
+	}
+	height, width := sheetSize(file, sheet)
+	height = max(height, len(rows))
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	blankRows := make([]bool, height)
+	blankCols := make([]bool, width)
+	for col := range blankCols {
+		blankCols[col] = true
+	}
+	for rowIndex := range blankRows {
+		blankRows[rowIndex] = true
+		if rowIndex >= len(rows) {
+			continue
+		}
+		for col, value := range rows[rowIndex] {
+			if len(strings.TrimSpace(value)) == 0 {
+				continue
+			}
+			blankRows[rowIndex] = false
+			blankCols[col] = false
+		}
+	}
+	if clearErr := keepFormulas(file, sheet, blankRows, blankCols); clearErr != nil {
+		return 0, 0, clearErr
+	}
+
+	// Remove from the end so the indexes still to be visited are not shifted.
+	if opts.Rows {
+		for rowIndex := height - 1; rowIndex >= 0; rowIndex-- {
+			if !blankRows[rowIndex] {
+				continue
+			}
+			if removeErr := file.RemoveRow(sheet, rowIndex+1); removeErr != nil {
+				return removedRows, removedCols, fmt.Errorf("removing row %d: %w", rowIndex+1, removeErr)
+			}
+			removedRows++
+		}
+	}
+	if opts.Columns {
+		for col := width - 1; col >= 0; col-- {
+			if !blankCols[col] {
+				continue
+			}
+			name, _ := excelize.ColumnNumberToName(col + 1)
+			if removeErr := file.RemoveCol(sheet, name); removeErr != nil {
+				return removedRows, removedCols, fmt.Errorf("removing column %s: %w", name, removeErr)
+			}
+			removedCols++
+		}
+	}
+	return removedRows, removedCols, shrinkDimension(file, sheet)
+}
+
+// keepFormulas marks rows and columns that look blank as used if any of their cells holds a formula,
+// since a formula whose result is empty is still content.
+func keepFormulas(file *excelize.File, sheet string, blankRows, blankCols []bool) error {
+	for rowIndex := range blankRows {
+		for col := range blankCols {
+			if !blankRows[rowIndex] && !blankCols[col] {
+				continue
+			}
+			cellName, _ := excelize.CoordinatesToCellName(col+1, rowIndex+1)
+			formula, formulaErr := file.GetCellFormula(sheet, cellName)
+			if formulaErr != nil {
+				return formulaErr
+			}
+			if len(formula) > 0 {
+				blankRows[rowIndex] = false
+				blankCols[col] = false
+			}
+		}
+	}
+	return nil
+}
+
+// shrinkDimension sets the used range of a sheet to the cells that remain, or removes it if none do.
+func shrinkDimension(file *excelize.File, sheet string) error {
+	rows, rowsErr := file.GetRows(sheet)
+	if rowsErr != nil {
+		return rowsErr
+	}
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	if len(rows) == 0 || width == 0 {
+		return file.SetSheetDimension(sheet, "")
+	}
+	lastCell, _ := excelize.CoordinatesToCellName(width, len(rows))
+	return file.SetSheetDimension(sheet, "A1:"+lastCell)
+}
+
+// sheetSize returns the number of rows and columns in the stored used range of a worksheet,
+// or zeros if it is unknown.
+func sheetSize(file *excelize.File, sheet string) (rows, columns int) {
+	dimension, dimErr := file.GetSheetDimension(sheet)
+	if dimErr != nil {
+		return 0, 0
+	}
+	_, lastCell, found := strings.Cut(dimension, ":")
+	if !found {
+		lastCell = dimension
+	}
+	columns, rows, cellErr := excelize.CellNameToCoordinates(lastCell)
+	if cellErr != nil {
+		return 0, 0
+	}
+	return rows, columns
+}
+
+// saveWorkbook writes the workbook to --output, or else replaces the original file through an AtomicFile,
+// so the original is only replaced once the amended workbook has been written in full next to it.
+func saveWorkbook(file *excelize.File, opts options) error {
+	if len(opts.Output) > 0 {
+		return file.SaveAs(opts.Output)
+	}
+	output, createErr := CreateAtomic(opts.FilePath)
+	if createErr != nil {
+		return createErr
+	}
+	if writeErr := file.Write(output); writeErr != nil {
+		output.Abort()
+		return writeErr
+	}
+	if commitErr := output.Commit(); commitErr != nil {
+		return commitErr
+	}
+	log.Info("Successfully amended file", "file", opts.FilePath)
+	return nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	. "GoTools/pkg/helpers"
+	"github.com/xuri/excelize/v2"
+)
+
+// createTestWorkbook saves a workbook whose Data sheet has a blank row 2 and a blank column B, and a formula
+// with an empty result in D4, returning its path.
+func createTestWorkbook(t *testing.T, dir string) string {
+	t.Helper()
+	file := excelize.NewFile()
+	defer func() {
+		_ = file.Close()
+	}()
+	if err := file.SetSheetName("Sheet1", "Data"); err != nil {
+		t.Fatal(err)
+	}
+	for cell, value := range map[string]any{"A1": "Name", "C1": "Age", "B2": "  ", "A3": "ann", "C3": 30} {
+		if err := file.SetCellValue("Data", cell, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := file.SetCellFormula("Data", "D4", `""`); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "book.xlsx")
+	if err := file.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readSheet returns the rows of the Data sheet and the cell holding its formula.
+func readSheet(t *testing.T, path string) (rows [][]string, formulaCell string) {
+	t.Helper()
+	file, err := excelize.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	if rows, err = file.GetRows("Data"); err != nil {
+		t.Fatal(err)
+	}
+	for col := 1; col <= 5; col++ {
+		for row := 1; row <= 5; row++ {
+			cell, _ := excelize.CoordinatesToCellName(col, row)
+			if formula, _ := file.GetCellFormula("Data", cell); formula != "" {
+				return rows, cell
+			}
+		}
+	}
+	return rows, ""
+}
+
+func TestProcessWorkbook(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        options
+		want        [][]string
+		wantFormula string
+		wantCode    int
+	}{
+		{
+			name:        "Rows And Columns",
+			opts:        options{Rows: true, Columns: true},
+			want:        [][]string{{"Name", "Age"}, {"ann", "30"}, {"", "", ""}},
+			wantFormula: "C3",
+			wantCode:    Success,
+		},
+		{
+			name:        "Rows Only",
+			opts:        options{Rows: true},
+			want:        [][]string{{"Name", "", "Age"}, {"ann", "", "30"}, {"", "", "", ""}},
+			wantFormula: "D3",
+			wantCode:    Success,
+		},
+		{
+			name:        "Columns Only",
+			opts:        options{Columns: true},
+			want:        [][]string{{"Name", "Age"}, nil, {"ann", "30"}, {"", "", ""}},
+			wantFormula: "C4",
+			wantCode:    Success,
+		},
+		{
+			name:     "Missing Sheet",
+			opts:     options{Sheets: []string{"Nope"}, Rows: true, Columns: true},
+			wantCode: ErrInvalidArgs,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.FilePath = createTestWorkbook(t, t.TempDir())
+			if got := processWorkbook(tt.opts); got.Code != tt.wantCode {
+				t.Fatalf("processWorkbook() = %v, want code %d", got, tt.wantCode)
+			}
+			if tt.wantCode != Success {
+				return
+			}
+			rows, formulaCell := readSheet(t, tt.opts.FilePath)
+			if !reflect.DeepEqual(rows, tt.want) {
+				t.Errorf("rows = %q, want %q", rows, tt.want)
+			}
+			if formulaCell != tt.wantFormula {
+				t.Errorf("formula in %s, want %s", formulaCell, tt.wantFormula)
+			}
+		})
+	}
+}
+
+func TestProcessWorkbookOutput(t *testing.T) {
+	dir := t.TempDir()
+	input := createTestWorkbook(t, dir)
+	before, err := os.ReadFile(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "clean.xlsx")
+	if err = os.WriteFile(output, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := options{FilePath: input, Output: output, Rows: true, Columns: true}
+	if got := processWorkbook(opts); got.Code != ErrWriteFile {
+		t.Fatalf("processWorkbook() = %v, want code %d", got, ErrWriteFile)
+	}
+	opts.Force = true
+	if got := processWorkbook(opts); got.Code != Success {
+		t.Fatalf("processWorkbook() with Force = %v, want success", got)
+	}
+	if rows, _ := readSheet(t, output); !reflect.DeepEqual(rows, [][]string{{"Name", "Age"}, {"ann", "30"}, {"", "", ""}}) {
+		t.Errorf("output rows = %q", rows)
+	}
+	if after, _ := os.ReadFile(input); !reflect.DeepEqual(before, after) {
+		t.Error("processWorkbook() with --output changed the original workbook")
+	}
+}
@@ -0,0 +1,205 @@
+// This program renames duplicate headers in the worksheets of a .xlsx file the same way the CSV
+// rename-dupe-cols tool does, so the second "Name" column becomes "Name_2" and so on. The header row is
+// rewritten in place, or the result is written to a copy given with --output. Blank headers are left blank.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
+	"github.com/charmbracelet/log"
+	"github.com/xuri/excelize/v2"
+)
+
+// options holds the command line settings controlling the rename.
+type options struct {
+	FilePath  string
+	Sheets    []string
+	HeaderRow int
+	Output    string
+	Force     bool
+}
+
+func main() {
+	startTime := time.Now()
+
+	processingErr := ErrMsg{Code: Success}
+	var report RunReport
+	reportFormatPtr := flag.String("report", "", "Write a run report in the given format (json)")
+	reportFilePtr := flag.String("report-file", "", "Write the run report to this file instead of stdout")
+	defer func() {
+		report.Finish(startTime, processingErr.Code)
+		if reportErr := WriteReport(report, *reportFormatPtr, *reportFilePtr); reportErr != nil {
+			log.Error("Failed to write run report", "error", reportErr)
+		}
+		log.Debug(
+			"DONE!",
+			"time", time.Since(startTime),
+		)
+		processingErr.Exit()
+	}()
+	opts, argErr := getInput()
+	if argErr != nil {
+		processingErr = *argErr
+		return
+	}
+	if !ValidReportFormat(*reportFormatPtr) {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("unsupported report format '%s'", *reportFormatPtr),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	processingErr = processWorkbook(opts, &report)
+}
+
+// getInput parses the command line flags. The file is given with --path or as an argument, falling back to
+// a path piped through standard input.
+func getInput() (options, *ErrMsg) {
+	var opts options
+	var sheets string
+	flag.StringVar(&opts.FilePath, "path", "", "The path to the .xlsx file to amend")
+	flag.StringVar(&sheets, "sheet", "", "Comma-separated list of worksheets to amend (defaults to all sheets)")
+	flag.IntVar(&opts.HeaderRow, "header-row", 1, "The one-based row holding the headers")
+	flag.StringVar(&opts.Output, "output", "", "Write the amended workbook to this path instead of amending the original")
+	flag.BoolVar(&opts.Force, "force", false, "Overwrite the --output workbook if it already exists")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if logErr := logging.Setup(); logErr != nil {
+		return opts, &ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+	}
+
+	if opts.HeaderRow < 1 {
+		return opts, &ErrMsg{Err: fmt.Errorf("--header-row must be at least 1, got %d", opts.HeaderRow), Code: ErrInvalidArgs}
+	}
+	for _, sheet := range strings.Split(sheets, ",") {
+		if sheet = strings.TrimSpace(sheet); sheet != "" {
+			opts.Sheets = append(opts.Sheets, sheet)
+		}
+	}
+
+	path, inputErr := ResolveFile(opts.FilePath, flag.Args())
+	if inputErr != nil {
+		errMsg := ToErrMsg(inputErr, ErrNoInput)
+		return opts, &errMsg
+	}
+	opts.FilePath = path
+	return opts, nil
+}
+
+func processWorkbook(opts options, report *RunReport) ErrMsg {
+	report.File = opts.FilePath
+	if exists, _ := PathExists(opts.FilePath); !exists {
+		return ErrMsg{Err: fmt.Errorf("file '%s' does not exist", opts.FilePath), Code: ErrNoFile}
+	}
+	if !IsWorkbookFile(opts.FilePath, false) {
+		return ErrMsg{Err: fmt.Errorf("file '%s' is not an Excel workbook", opts.FilePath), Code: ErrInvalidFileType}
+	}
+	if exists, _ := PathExists(opts.Output); exists && !opts.Force {
+		return ErrMsg{Err: fmt.Errorf("output file '%s' already exists, use --force to overwrite it", opts.Output), Code: ErrWriteFile}
+	}
+	file, openErr := excelize.OpenFile(opts.FilePath)
+	if openErr != nil {
+		return ErrMsg{Err: openErr, Code: ErrReadFile}
+	}
+	defer func(file *excelize.File) {
+		if err := file.Close(); err != nil {
+			log.Error(err)
+		}
+	}(file)
+
+	sheets := opts.Sheets
+	if len(sheets) == 0 {
+		sheets = file.GetSheetList()
+	}
+	for _, sheet := range sheets {
+		if index, _ := file.GetSheetIndex(sheet); index < 0 {
+			return ErrMsg{Err: fmt.Errorf("sheet '%s' does not exist", sheet), Code: ErrInvalidArgs}
+		}
+		renamed, renameErr := renameHeaders(file, sheet, opts.HeaderRow)
+		if renameErr != nil {
+			return ErrMsg{Err: fmt.Errorf("sheet '%s': %w", sheet, renameErr), Code: ErrReadWrite}
+		}
+		report.HeadersRenamed += renamed
+		log.Info("Renamed duplicate columns", "sheet", sheet, "renamed", renamed)
+	}
+	if saveErr := saveWorkbook(file, opts); saveErr != nil {
+		return ErrMsg{Err: saveErr, Code: ErrWriteFile}
+	}
+	return ErrMsg{Code: Success}
+}
+
+// renameHeaders renames the duplicate headers in headerRow of a sheet, returning how many were renamed.
+// Only the cells that change are written, so their styles are kept.
+func renameHeaders(file *excelize.File, sheet string, headerRow int) (int, error) {
+	rows, rowsErr := file.Rows(sheet)
+	if rowsErr != nil {
+		return 0, rowsErr
+	}
+	var headers []string
+	for rowNum := 1; rows.Next(); rowNum++ {
+		if rowNum == headerRow {
+			var colErr error
+			headers, colErr = rows.Columns()
+			if colErr != nil {
+				_ = rows.Close()
+				return 0, colErr
+			}
+			break
+		}
+	}
+	if closeErr := rows.Close(); closeErr != nil {
+		return 0, closeErr
+	}
+
+	// Blank headers are left out so they are neither renamed nor reported as duplicates.
+	var positions []int
+	var named []string
+	for i, header := range headers {
+		if len(strings.TrimSpace(header)) > 0 {
+			positions = append(positions, i)
+			named = append(named, header)
+		}
+	}
+	original := slices.Clone(named)
+	named = RenameDuplicates(named, true)
+	renamed := 0
+	for i := range named {
+		if named[i] == original[i] {
+			continue
+		}
+		cellName, _ := excelize.CoordinatesToCellName(positions[i]+1, headerRow)
+		if err := file.SetCellStr(sheet, cellName, named[i]); err != nil {
+			return renamed, err
+		}
+		renamed++
+	}
+	return renamed, nil
+}
+
+// saveWorkbook writes the workbook to --output, or else replaces the original file through an AtomicFile,
+// so the original is only replaced once the amended workbook has been written in full next to it.
+func saveWorkbook(file *excelize.File, opts options) error {
+	if len(opts.Output) > 0 {
+		return file.SaveAs(opts.Output)
+	}
+	output, createErr := CreateAtomic(opts.FilePath)
+	if createErr != nil {
+		return createErr
+	}
+	if writeErr := file.Write(output); writeErr != nil {
+		output.Abort()
+		return writeErr
+	}
+	if commitErr := output.Commit(); commitErr != nil {
+		return commitErr
+	}
+	log.Info("Successfully amended file", "file", opts.FilePath)
+	return nil
+}
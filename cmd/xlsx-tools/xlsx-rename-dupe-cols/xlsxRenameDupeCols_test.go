@@ -0,0 +1,140 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	. "GoTools/pkg/helpers"
+	"github.com/xuri/excelize/v2"
+)
+
+// createTestWorkbook saves a workbook with a Data sheet whose header row repeats a name around a blank
+// header and next to a differently cased one, and a Notes sheet whose headers are in row 2, returning its path.
+func createTestWorkbook(t *testing.T, dir string) string {
+	t.Helper()
+	file := excelize.NewFile()
+	defer func() {
+		_ = file.Close()
+	}()
+	if err := file.SetSheetName("Sheet1", "Data"); err != nil {
+		t.Fatal(err)
+	}
+	sheets := map[string][][]any{
+		"Data":  {{"Id", "Name", "", "Name", "name"}, {1, "ann", "", "bob", "cy"}},
+		"Notes": {{"Notes"}, {"Text", "Text"}},
+	}
+	if _, err := file.NewSheet("Notes"); err != nil {
+		t.Fatal(err)
+	}
+	for sheet, rows := range sheets {
+		for i, row := range rows {
+			cell, _ := excelize.CoordinatesToCellName(1, i+1)
+			if err := file.SetSheetRow(sheet, cell, &row); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	path := filepath.Join(dir, "book.xlsx")
+	if err := file.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readWorkbook returns the rows of every sheet of the workbook at path, keyed by sheet name.
+func readWorkbook(t *testing.T, path string) map[string][][]string {
+	t.Helper()
+	file, err := excelize.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	sheets := make(map[string][][]string)
+	for _, sheet := range file.GetSheetList() {
+		if sheets[sheet], err = file.GetRows(sheet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return sheets
+}
+
+func TestProcessWorkbook(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        options
+		want        map[string][][]string
+		wantRenamed int
+		wantCode    int
+	}{
+		{
+			name: "All Sheets",
+			opts: options{HeaderRow: 1},
+			want: map[string][][]string{
+				"Data":  {{"Id", "Name", "", "Name_2", "name"}, {"1", "ann", "", "bob", "cy"}},
+				"Notes": {{"Notes"}, {"Text", "Text"}},
+			},
+			wantRenamed: 1,
+			wantCode:    Success,
+		},
+		{
+			name: "Header Row",
+			opts: options{Sheets: []string{"Notes"}, HeaderRow: 2},
+			want: map[string][][]string{
+				"Data":  {{"Id", "Name", "", "Name", "name"}, {"1", "ann", "", "bob", "cy"}},
+				"Notes": {{"Notes"}, {"Text", "Text_2"}},
+			},
+			wantRenamed: 1,
+			wantCode:    Success,
+		},
+		{
+			name:     "Missing Sheet",
+			opts:     options{Sheets: []string{"Nope"}, HeaderRow: 1},
+			wantCode: ErrInvalidArgs,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.FilePath = createTestWorkbook(t, t.TempDir())
+			report := &RunReport{}
+			if got := processWorkbook(tt.opts, report); got.Code != tt.wantCode {
+				t.Fatalf("processWorkbook() = %v, want code %d", got, tt.wantCode)
+			}
+			if tt.wantCode != Success {
+				return
+			}
+			if report.HeadersRenamed != tt.wantRenamed {
+				t.Errorf("HeadersRenamed = %d, want %d", report.HeadersRenamed, tt.wantRenamed)
+			}
+			if got := readWorkbook(t, tt.opts.FilePath); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("workbook = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessWorkbookOutput(t *testing.T) {
+	dir := t.TempDir()
+	input := createTestWorkbook(t, dir)
+	output := filepath.Join(dir, "renamed.xlsx")
+	if err := os.WriteFile(output, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := options{FilePath: input, Output: output, HeaderRow: 1}
+	if got := processWorkbook(opts, &RunReport{}); got.Code != ErrWriteFile {
+		t.Fatalf("processWorkbook() = %v, want code %d", got, ErrWriteFile)
+	}
+	opts.Force = true
+	if got := processWorkbook(opts, &RunReport{}); got.Code != Success {
+		t.Fatalf("processWorkbook() with Force = %v, want success", got)
+	}
+	if got := readWorkbook(t, output)["Data"][0]; !reflect.DeepEqual(got, []string{"Id", "Name", "", "Name_2", "name"}) {
+		t.Errorf("output headers = %q", got)
+	}
+	if got := readWorkbook(t, input)["Data"][0]; !reflect.DeepEqual(got, []string{"Id", "Name", "", "Name", "name"}) {
+		t.Errorf("processWorkbook() with --output changed the original headers to %q", got)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+
+	. "GoTools/pkg/helpers"
+	"github.com/xuri/excelize/v2"
+)
+
+// sourceRow is a row read from the worksheet being split, with the style ID of each of its cells.
+type sourceRow struct {
+	number int
+	values []string
+	styles []int
+}
+
+// part is one output workbook being written by the split.
+// Cell styles and column widths are copied from the source so that number formats, fonts and fills survive.
+type part struct {
+	path   string
+	source *excelize.File
+	file   *excelize.File
+	writer *excelize.StreamWriter
+	styles map[int]int
+	rowNum int
+	rows   int
+}
+
+// newPart creates an output workbook at path holding a single sheet named after the source sheet,
+// with the column widths of the source sheet and the given header rows already written.
+func newPart(path string, source *excelize.File, sheet string, width int, headers []sourceRow) (*part, error) {
+	file := excelize.NewFile()
+	if err := file.SetSheetName(file.GetSheetName(0), sheet); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	writer, writerErr := file.NewStreamWriter(sheet)
+	if writerErr != nil {
+		_ = file.Close()
+		return nil, writerErr
+	}
+	p := &part{path: path, source: source, file: file, writer: writer, styles: map[int]int{}}
+	for col := 1; col <= width; col++ {
+		name, _ := excelize.ColumnNumberToName(col)
+		colWidth, widthErr := source.GetColWidth(sheet, name)
+		if widthErr != nil {
+			continue
+		}
+		if err := writer.SetColWidth(col, col, colWidth); err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+	}
+	for _, header := range headers {
+		if err := p.write(header); err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+	}
+	p.rows = 0
+	return p, nil
+}
+
+// write appends a row to the part, copying each cell's style into the part's workbook on first use.
+func (p *part) write(row sourceRow) error {
+	cells := make([]interface{}, len(row.values))
+	for i, value := range row.values {
+		styleID, copyErr := p.style(row.styles[i])
+		if copyErr != nil {
+			return copyErr
+		}
+		cells[i] = excelize.Cell{StyleID: styleID, Value: TypedValue(value)}
+	}
+	p.rowNum++
+	p.rows++
+	cellName, _ := excelize.CoordinatesToCellName(1, p.rowNum)
+	return p.writer.SetRow(cellName, cells)
+}
+
+// style returns the ID in the part's workbook of the source style sourceID, creating it if needed.
+func (p *part) style(sourceID int) (int, error) {
+	if sourceID == 0 {
+		return 0, nil
+	}
+	if id, copied := p.styles[sourceID]; copied {
+		return id, nil
+	}
+	style, styleErr := p.source.GetStyle(sourceID)
+	if styleErr != nil {
+		return 0, fmt.Errorf("reading style %d: %w", sourceID, styleErr)
+	}
+	id, newErr := p.file.NewStyle(style)
+	if newErr != nil {
+		return 0, fmt.Errorf("copying style %d: %w", sourceID, newErr)
+	}
+	p.styles[sourceID] = id
+	return id, nil
+}
+
+// save flushes the part's rows and writes its workbook to disk.
+func (p *part) save() error {
+	defer func(file *excelize.File) {
+		_ = file.Close()
+	}(p.file)
+	if err := p.writer.Flush(); err != nil {
+		return err
+	}
+	return p.file.SaveAs(p.path)
+}
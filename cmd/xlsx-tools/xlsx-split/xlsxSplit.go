@@ -0,0 +1,337 @@
+// This program splits a workbook into smaller workbooks. By default each worksheet is written to a workbook
+// of its own; with --rows or --key each selected sheet is instead split into several workbooks by row count or
+// by the value of a key column. Header rows are repeated in every part, and cell styles and column widths are
+// copied from the source. Cells are copied as values, so formulas are not preserved.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
+	"github.com/charmbracelet/log"
+	"github.com/xuri/excelize/v2"
+)
+
+// options holds the command line settings controlling the split.
+type options struct {
+	FilePath   string
+	Sheets     []string
+	OutputDir  string
+	Rows       int
+	Key        string
+	HeaderRows int
+	Force      bool
+}
+
+func main() {
+	startTime := time.Now()
+
+	processingErr := ErrMsg{Code: Success}
+	defer func() {
+		log.Debug(
+			"DONE!",
+			"time", time.Since(startTime),
+		)
+		processingErr.Exit()
+	}()
+	opts, argErr := getInput()
+	if argErr != nil {
+		processingErr = *argErr
+		return
+	}
+	processingErr = splitWorkbook(opts)
+}
+
+// getInput parses the command line flags. The file is given with --path or as an argument, falling back to
+// a path piped through standard input.
+func getInput() (options, *ErrMsg) {
+	var opts options
+	var sheets string
+	flag.StringVar(&opts.FilePath, "path", "", "The path to the .xlsx file to split")
+	flag.StringVar(&sheets, "sheet", "", "Comma-separated list of worksheets to split (defaults to all sheets)")
+	flag.StringVar(&opts.OutputDir, "output-dir", "", "Directory for the split workbooks (defaults to the workbook's directory)")
+	flag.IntVar(&opts.Rows, "rows", 0, "Split each sheet into workbooks of at most this many data rows")
+	flag.StringVar(&opts.Key, "key", "", "Split each sheet into one workbook per value of the column with this header")
+	flag.IntVar(&opts.HeaderRows, "header-rows", 1, "The number of header rows at the top of each sheet, repeated in every part")
+	flag.BoolVar(&opts.Force, "force", false, "Overwrite split workbooks that already exist")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if logErr := logging.Setup(); logErr != nil {
+		return opts, &ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+	}
+
+	if opts.Rows < 0 {
+		return opts, &ErrMsg{Err: fmt.Errorf("--rows must not be negative, got %d", opts.Rows), Code: ErrInvalidArgs}
+	}
+	if opts.Rows > 0 && len(opts.Key) > 0 {
+		return opts, &ErrMsg{Err: errors.New("--rows and --key cannot be used together"), Code: ErrInvalidArgs}
+	}
+	if opts.HeaderRows < 0 || (len(opts.Key) > 0 && opts.HeaderRows < 1) {
+		return opts, &ErrMsg{Err: fmt.Errorf("invalid --header-rows %d, --key needs a header row to find its column", opts.HeaderRows), Code: ErrInvalidArgs}
+	}
+	for _, sheet := range strings.Split(sheets, ",") {
+		if sheet = strings.TrimSpace(sheet); sheet != "" {
+			opts.Sheets = append(opts.Sheets, sheet)
+		}
+	}
+
+	path, inputErr := ResolveFile(opts.FilePath, flag.Args())
+	if inputErr != nil {
+		errMsg := ToErrMsg(inputErr, ErrNoInput)
+		return opts, &errMsg
+	}
+	opts.FilePath = path
+	return opts, nil
+}
+
+func splitWorkbook(opts options) ErrMsg {
+	if exists, _ := PathExists(opts.FilePath); !exists {
+		return ErrMsg{Err: fmt.Errorf("file '%s' does not exist", opts.FilePath), Code: ErrNoFile}
+	}
+	if !IsWorkbookFile(opts.FilePath, false) {
+		return ErrMsg{Err: fmt.Errorf("file '%s' is not an Excel workbook", opts.FilePath), Code: ErrInvalidFileType}
+	}
+	file, openErr := excelize.OpenFile(opts.FilePath)
+	if openErr != nil {
+		return ErrMsg{Err: openErr, Code: ErrReadFile}
+	}
+	defer func(file *excelize.File) {
+		if err := file.Close(); err != nil {
+			log.Error(err)
+		}
+	}(file)
+
+	sheets := opts.Sheets
+	if len(sheets) == 0 {
+		sheets = file.GetSheetList()
+	}
+	outputDir := opts.OutputDir
+	if outputDir == "" {
+		outputDir = filepath.Dir(opts.FilePath)
+	}
+	workbook := strings.TrimSuffix(filepath.Base(opts.FilePath), filepath.Ext(opts.FilePath))
+	for _, sheet := range sheets {
+		if index, _ := file.GetSheetIndex(sheet); index < 0 {
+			return ErrMsg{Err: fmt.Errorf("sheet '%s' does not exist", sheet), Code: ErrInvalidArgs}
+		}
+		s := &splitter{
+			file:   file,
+			sheet:  sheet,
+			prefix: filepath.Join(outputDir, workbook+"_"+sanitizeFileName(sheet)),
+			opts:   opts,
+			width:  sheetWidth(file, sheet),
+			parts:  map[string]*part{},
+		}
+		if splitErr := s.split(); splitErr != nil {
+			var existsErr *existsError
+			if errors.As(splitErr, &existsErr) {
+				return ErrMsg{Err: splitErr, Code: ErrWriteFile}
+			}
+			return ErrMsg{Err: fmt.Errorf("sheet '%s': %w", sheet, splitErr), Code: ErrReadWrite}
+		}
+	}
+	return ErrMsg{Code: Success}
+}
+
+// existsError reports a split workbook that would overwrite an existing file without --force.
+type existsError struct {
+	path string
+}
+
+func (e *existsError) Error() string {
+	return fmt.Sprintf("output file '%s' already exists, use --force to overwrite it", e.path)
+}
+
+// splitter writes the rows of one worksheet to its parts.
+type splitter struct {
+	file    *excelize.File
+	sheet   string
+	prefix  string
+	opts    options
+	width   int
+	headers []sourceRow
+	keyCol  int
+	parts   map[string]*part
+	order   []string
+	current *part
+}
+
+// split reads the sheet row by row, sending each data row to the part chosen by the split mode,
+// then saves every part.
+func (s *splitter) split() (err error) {
+	defer func() {
+		for _, key := range s.order {
+			if s.parts[key].file != nil {
+				_ = s.parts[key].file.Close()
+			}
+		}
+	}()
+	rows, rowsErr := s.file.Rows(s.sheet)
+	if rowsErr != nil {
+		return rowsErr
+	}
+	defer func(rows *excelize.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	s.keyCol = -1
+	rowNum := 0
+	for rows.Next() {
+		rowNum++
+		values, colErr := rows.Columns(excelize.Options{RawCellValue: true})
+		if colErr != nil {
+			return fmt.Errorf("row %d: %w", rowNum, colErr)
+		}
+		row, readErr := s.readRow(rowNum, values)
+		if readErr != nil {
+			return readErr
+		}
+		if rowNum <= s.opts.HeaderRows {
+			s.headers = append(s.headers, row)
+			// The stored dimension can be stale, so also copy the widths of every column with a header.
+			s.width = max(s.width, len(row.values))
+			if rowNum == s.opts.HeaderRows && len(s.opts.Key) > 0 {
+				s.keyCol = slices.IndexFunc(row.values, func(header string) bool {
+					return strings.TrimSpace(header) == s.opts.Key
+				})
+				if s.keyCol < 0 {
+					return fmt.Errorf("no column has the header '%s'", s.opts.Key)
+				}
+			}
+			continue
+		}
+		target, partErr := s.partFor(row)
+		if partErr != nil {
+			return partErr
+		}
+		if err := target.write(row); err != nil {
+			return fmt.Errorf("row %d: %w", rowNum, err)
+		}
+	}
+	if err := rows.Error(); err != nil {
+		return err
+	}
+	if len(s.order) == 0 {
+		// A sheet without data rows still gets a part, holding just its headers.
+		if _, partErr := s.newPart(""); partErr != nil {
+			return partErr
+		}
+	}
+	for _, key := range s.order {
+		p := s.parts[key]
+		if saveErr := p.save(); saveErr != nil {
+			return saveErr
+		}
+		p.file = nil
+		log.Info("Wrote part", "sheet", s.sheet, "file", p.path, "rows", p.rows)
+	}
+	return nil
+}
+
+// readRow pairs the values of a row with the style IDs of its cells.
+func (s *splitter) readRow(rowNum int, values []string) (sourceRow, error) {
+	row := sourceRow{number: rowNum, values: values, styles: make([]int, len(values))}
+	for i := range values {
+		cellName, _ := excelize.CoordinatesToCellName(i+1, rowNum)
+		styleID, styleErr := s.file.GetCellStyle(s.sheet, cellName)
+		if styleErr != nil {
+			return row, fmt.Errorf("cell %s: %w", cellName, styleErr)
+		}
+		row.styles[i] = styleID
+	}
+	return row, nil
+}
+
+// partFor returns the part a data row belongs to, starting a new one when needed.
+func (s *splitter) partFor(row sourceRow) (*part, error) {
+	switch {
+	case len(s.opts.Key) > 0:
+		// The key is read formatted, so that e.g. dates group by the date shown rather than its serial number.
+		cellName, _ := excelize.CoordinatesToCellName(s.keyCol+1, row.number)
+		value, valueErr := s.file.GetCellValue(s.sheet, cellName)
+		if valueErr != nil {
+			return nil, valueErr
+		}
+		value = strings.TrimSpace(value)
+		if existing, found := s.parts[value]; found {
+			return existing, nil
+		}
+		suffix := sanitizeFileName(value)
+		if suffix == "" {
+			suffix = "blank"
+		}
+		return s.newPart(value, suffix)
+	case s.opts.Rows > 0:
+		if s.current == nil || s.current.rows >= s.opts.Rows {
+			return s.newPart(strconv.Itoa(len(s.order)+1), strconv.Itoa(len(s.order)+1))
+		}
+		return s.current, nil
+	default:
+		if s.current == nil {
+			return s.newPart("")
+		}
+		return s.current, nil
+	}
+}
+
+// newPart starts the part for key, named after the sheet plus the given suffixes. If two keys sanitize to
+// the same file name, the later one is numbered so that no part overwrites another.
+func (s *splitter) newPart(key string, suffixes ...string) (*part, error) {
+	base := s.prefix
+	for _, suffix := range suffixes {
+		base += "_" + suffix
+	}
+	path := base + ".xlsx"
+	for count := 2; slices.ContainsFunc(s.order, func(other string) bool {
+		return strings.EqualFold(s.parts[other].path, path)
+	}); count++ {
+		path = fmt.Sprintf("%s_%d.xlsx", base, count)
+	}
+	if exists, _ := PathExists(path); exists && !s.opts.Force {
+		return nil, &existsError{path: path}
+	}
+	p, partErr := newPart(path, s.file, s.sheet, s.width, s.headers)
+	if partErr != nil {
+		return nil, partErr
+	}
+	s.parts[key] = p
+	s.order = append(s.order, key)
+	s.current = p
+	return p, nil
+}
+
+// sanitizeFileName replaces characters that are not allowed in file names on common platforms.
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>:"/\|?*`, r) || r < 32 {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// sheetWidth returns the number of columns in the used range of a worksheet, or 0 if it is unknown.
+func sheetWidth(file *excelize.File, sheet string) int {
+	dimension, dimErr := file.GetSheetDimension(sheet)
+	if dimErr != nil {
+		return 0
+	}
+	_, lastCell, found := strings.Cut(dimension, ":")
+	if !found {
+		lastCell = dimension
+	}
+	column, _, cellErr := excelize.CellNameToCoordinates(lastCell)
+	if cellErr != nil {
+		return 0
+	}
+	return column
+}
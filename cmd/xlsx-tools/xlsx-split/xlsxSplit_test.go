@@ -0,0 +1,140 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	. "GoTools/pkg/helpers"
+	"github.com/xuri/excelize/v2"
+)
+
+// createTestWorkbook saves a workbook with an Orders sheet of five data rows under one header row, and returns its path.
+func createTestWorkbook(t *testing.T, dir string) string {
+	t.Helper()
+	file := excelize.NewFile()
+	defer func() {
+		_ = file.Close()
+	}()
+	if err := file.SetSheetName("Sheet1", "Orders"); err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]any{
+		{"Id", "City"},
+		{1, "Oslo"},
+		{2, "Rome"},
+		{3, "Oslo"},
+		{4, "a/b"},
+		{5, "Rome"},
+	}
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := file.SetSheetRow("Orders", cell, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(dir, "book.xlsx")
+	if err := file.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readParts returns the rows of the Orders sheet of every workbook in dir, keyed by file name.
+func readParts(t *testing.T, dir string) map[string][][]string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := make(map[string][][]string)
+	for _, entry := range entries {
+		file, openErr := excelize.OpenFile(filepath.Join(dir, entry.Name()))
+		if openErr != nil {
+			t.Fatal(openErr)
+		}
+		if parts[entry.Name()], err = file.GetRows("Orders"); err != nil {
+			t.Fatal(err)
+		}
+		_ = file.Close()
+	}
+	return parts
+}
+
+func TestSplitWorkbook(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     options
+		want     map[string][][]string
+		wantCode int
+	}{
+		{
+			name: "Rows",
+			opts: options{Rows: 2, HeaderRows: 1},
+			want: map[string][][]string{
+				"book_Orders_1.xlsx": {{"Id", "City"}, {"1", "Oslo"}, {"2", "Rome"}},
+				"book_Orders_2.xlsx": {{"Id", "City"}, {"3", "Oslo"}, {"4", "a/b"}},
+				"book_Orders_3.xlsx": {{"Id", "City"}, {"5", "Rome"}},
+			},
+			wantCode: Success,
+		},
+		{
+			name: "Key",
+			opts: options{Key: "City", HeaderRows: 1},
+			want: map[string][][]string{
+				"book_Orders_Oslo.xlsx": {{"Id", "City"}, {"1", "Oslo"}, {"3", "Oslo"}},
+				"book_Orders_Rome.xlsx": {{"Id", "City"}, {"2", "Rome"}, {"5", "Rome"}},
+				"book_Orders_a_b.xlsx":  {{"Id", "City"}, {"4", "a/b"}},
+			},
+			wantCode: Success,
+		},
+		{
+			name: "No Header Rows",
+			opts: options{Rows: 4},
+			want: map[string][][]string{
+				"book_Orders_1.xlsx": {{"Id", "City"}, {"1", "Oslo"}, {"2", "Rome"}, {"3", "Oslo"}},
+				"book_Orders_2.xlsx": {{"4", "a/b"}, {"5", "Rome"}},
+			},
+			wantCode: Success,
+		},
+		{
+			name:     "Missing Key",
+			opts:     options{Key: "Country", HeaderRows: 1},
+			want:     map[string][][]string{},
+			wantCode: ErrReadWrite,
+		},
+		{
+			name:     "Missing Sheet",
+			opts:     options{Sheets: []string{"Nope"}, Rows: 2, HeaderRows: 1},
+			want:     map[string][][]string{},
+			wantCode: ErrInvalidArgs,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.FilePath = createTestWorkbook(t, t.TempDir())
+			tt.opts.OutputDir = t.TempDir()
+			if got := splitWorkbook(tt.opts); got.Code != tt.wantCode {
+				t.Fatalf("splitWorkbook() = %v, want code %d", got, tt.wantCode)
+			}
+			if got := readParts(t, tt.opts.OutputDir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parts = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitWorkbookExistingPart(t *testing.T) {
+	opts := options{FilePath: createTestWorkbook(t, t.TempDir()), OutputDir: t.TempDir(), Rows: 10, HeaderRows: 1}
+	if got := splitWorkbook(opts); got.Code != Success {
+		t.Fatalf("splitWorkbook() = %v, want success", got)
+	}
+	if got := splitWorkbook(opts); got.Code != ErrWriteFile {
+		t.Fatalf("splitWorkbook() again = %v, want code %d", got, ErrWriteFile)
+	}
+	opts.Force = true
+	if got := splitWorkbook(opts); got.Code != Success {
+		t.Fatalf("splitWorkbook() with Force = %v, want success", got)
+	}
+}
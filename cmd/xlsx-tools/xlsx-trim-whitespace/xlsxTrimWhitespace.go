@@ -0,0 +1,205 @@
+// This program trims leading and trailing whitespace, including non-breaking spaces, from the text cells of
+// a .xlsx file's worksheets, like the CSV trim-whitespace tool. The workbook is amended in place, or written
+// to a copy given with --output. Numbers, formulas and rich text cells are left untouched.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
+	"github.com/charmbracelet/log"
+	"github.com/xuri/excelize/v2"
+)
+
+// options holds the command line settings controlling the trim.
+type options struct {
+	FilePath string
+	Sheets   []string
+	Output   string
+	Force    bool
+}
+
+func main() {
+	startTime := time.Now()
+
+	processingErr := ErrMsg{Code: Success}
+	var report RunReport
+	reportFormatPtr := flag.String("report", "", "Write a run report in the given format (json)")
+	reportFilePtr := flag.String("report-file", "", "Write the run report to this file instead of stdout")
+	defer func() {
+		report.Finish(startTime, processingErr.Code)
+		if reportErr := WriteReport(report, *reportFormatPtr, *reportFilePtr); reportErr != nil {
+			log.Error("Failed to write run report", "error", reportErr)
+		}
+		log.Debug(
+			"DONE!",
+			"time", time.Since(startTime),
+		)
+		processingErr.Exit()
+	}()
+	opts, argErr := getInput()
+	if argErr != nil {
+		processingErr = *argErr
+		return
+	}
+	if !ValidReportFormat(*reportFormatPtr) {
+		processingErr = ErrMsg{
+			Err:  fmt.Errorf("unsupported report format '%s'", *reportFormatPtr),
+			Code: ErrInvalidArgs,
+		}
+		return
+	}
+	processingErr = processWorkbook(opts, &report)
+}
+
+// getInput parses the command line flags. The file is given with --path or as an argument, falling back to
+// a path piped through standard input.
+func getInput() (options, *ErrMsg) {
+	var opts options
+	var sheets string
+	flag.StringVar(&opts.FilePath, "path", "", "The path to the .xlsx file to amend")
+	flag.StringVar(&sheets, "sheet", "", "Comma-separated list of worksheets to amend (defaults to all sheets)")
+	flag.StringVar(&opts.Output, "output", "", "Write the amended workbook to this path instead of amending the original")
+	flag.BoolVar(&opts.Force, "force", false, "Overwrite the --output workbook if it already exists")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if logErr := logging.Setup(); logErr != nil {
+		return opts, &ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+	}
+
+	for _, sheet := range strings.Split(sheets, ",") {
+		if sheet = strings.TrimSpace(sheet); sheet != "" {
+			opts.Sheets = append(opts.Sheets, sheet)
+		}
+	}
+
+	path, inputErr := ResolveFile(opts.FilePath, flag.Args())
+	if inputErr != nil {
+		errMsg := ToErrMsg(inputErr, ErrNoInput)
+		return opts, &errMsg
+	}
+	opts.FilePath = path
+	return opts, nil
+}
+
+func processWorkbook(opts options, report *RunReport) ErrMsg {
+	report.File = opts.FilePath
+	if exists, _ := PathExists(opts.FilePath); !exists {
+		return ErrMsg{Err: fmt.Errorf("file '%s' does not exist", opts.FilePath), Code: ErrNoFile}
+	}
+	if !IsWorkbookFile(opts.FilePath, false) {
+		return ErrMsg{Err: fmt.Errorf("file '%s' is not an Excel workbook", opts.FilePath), Code: ErrInvalidFileType}
+	}
+	if exists, _ := PathExists(opts.Output); exists && !opts.Force {
+		return ErrMsg{Err: fmt.Errorf("output file '%s' already exists, use --force to overwrite it", opts.Output), Code: ErrWriteFile}
+	}
+	file, openErr := excelize.OpenFile(opts.FilePath)
+	if openErr != nil {
+		return ErrMsg{Err: openErr, Code: ErrReadFile}
+	}
+	defer func(file *excelize.File) {
+		if err := file.Close(); err != nil {
+			log.Error(err)
+		}
+	}(file)
+
+	sheets := opts.Sheets
+	if len(sheets) == 0 {
+		sheets = file.GetSheetList()
+	}
+	for _, sheet := range sheets {
+		if index, _ := file.GetSheetIndex(sheet); index < 0 {
+			return ErrMsg{Err: fmt.Errorf("sheet '%s' does not exist", sheet), Code: ErrInvalidArgs}
+		}
+		trimErr := trimSheet(file, sheet, report)
+		if trimErr != nil {
+			return ErrMsg{Err: fmt.Errorf("sheet '%s': %w", sheet, trimErr), Code: ErrReadWrite}
+		}
+	}
+	if saveErr := saveWorkbook(file, opts); saveErr != nil {
+		return ErrMsg{Err: saveErr, Code: ErrWriteFile}
+	}
+	return ErrMsg{Code: Success}
+}
+
+// trimSheet trims the text cells of a sheet, counting the rows read and cells changed in report.
+// Only the cells that change are written, so their styles are kept.
+func trimSheet(file *excelize.File, sheet string, report *RunReport) error {
+	rows, rowsErr := file.GetRows(sheet, excelize.Options{RawCellValue: true})
+	if rowsErr != nil {
+		return rowsErr
+	}
+	trimmed := 0
+	for rowIndex, row := range rows {
+		report.RowsRead++
+		for col, value := range row {
+			// strings.TrimSpace also trims non-breaking spaces, which unicode counts as whitespace.
+			newValue := strings.TrimSpace(value)
+			if newValue == value {
+				continue
+			}
+			cellName, _ := excelize.CoordinatesToCellName(col+1, rowIndex+1)
+			if isText, textErr := isPlainText(file, sheet, cellName); textErr != nil {
+				return textErr
+			} else if !isText {
+				continue
+			}
+			if err := file.SetCellStr(sheet, cellName, newValue); err != nil {
+				return err
+			}
+			trimmed++
+		}
+	}
+	report.FieldsTrimmed += trimmed
+	log.Info("Trimmed whitespace", "sheet", sheet, "cells", trimmed)
+	return nil
+}
+
+// isPlainText checks if a cell holds a string without a formula or rich text formatting,
+// which SetCellStr would otherwise discard.
+func isPlainText(file *excelize.File, sheet, cellName string) (bool, error) {
+	cellType, typeErr := file.GetCellType(sheet, cellName)
+	if typeErr != nil {
+		return false, typeErr
+	}
+	if cellType != excelize.CellTypeSharedString && cellType != excelize.CellTypeInlineString {
+		return false, nil
+	}
+	formula, formulaErr := file.GetCellFormula(sheet, cellName)
+	if formulaErr != nil || len(formula) > 0 {
+		return false, formulaErr
+	}
+	runs, richErr := file.GetCellRichText(sheet, cellName)
+	if richErr != nil {
+		return false, richErr
+	}
+	// Plain strings come back as a single run without a font.
+	return !slices.ContainsFunc(runs, func(run excelize.RichTextRun) bool { return run.Font != nil }), nil
+}
+
+// saveWorkbook writes the workbook to --output, or else replaces the original file through an AtomicFile,
+// so the original is only replaced once the amended workbook has been written in full next to it.
+func saveWorkbook(file *excelize.File, opts options) error {
+	if len(opts.Output) > 0 {
+		return file.SaveAs(opts.Output)
+	}
+	output, createErr := CreateAtomic(opts.FilePath)
+	if createErr != nil {
+		return createErr
+	}
+	if writeErr := file.Write(output); writeErr != nil {
+		output.Abort()
+		return writeErr
+	}
+	if commitErr := output.Commit(); commitErr != nil {
+		return commitErr
+	}
+	log.Info("Successfully amended file", "file", opts.FilePath)
+	return nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	. "GoTools/pkg/helpers"
+	"github.com/xuri/excelize/v2"
+)
+
+// createTestWorkbook saves a workbook whose Data sheet mixes padded text, a number, rich text and a formula,
+// and whose Notes sheet holds one padded cell, returning its path.
+func createTestWorkbook(t *testing.T, dir string) string {
+	t.Helper()
+	file := excelize.NewFile()
+	defer func() {
+		_ = file.Close()
+	}()
+	if err := file.SetSheetName("Sheet1", "Data"); err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]any{{" Name ", "Age"}, {"ann\u00a0", 30}}
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := file.SetSheetRow("Data", cell, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runs := []excelize.RichTextRun{{Text: " bold ", Font: &excelize.Font{Bold: true}}}
+	if err := file.SetCellRichText("Data", "A3", runs); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.SetCellFormula("Data", "B3", `" f "`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.NewSheet("Notes"); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.SetCellStr("Notes", "A1", "\tnote\n"); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "book.xlsx")
+	if err := file.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readWorkbook returns the rows of every sheet of the workbook at path, keyed by sheet name.
+func readWorkbook(t *testing.T, path string) map[string][][]string {
+	t.Helper()
+	file, err := excelize.OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	sheets := make(map[string][][]string)
+	for _, sheet := range file.GetSheetList() {
+		if sheets[sheet], err = file.GetRows(sheet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return sheets
+}
+
+func TestProcessWorkbook(t *testing.T) {
+	tests := []struct {
+		name        string
+		sheets      []string
+		want        map[string][][]string
+		wantTrimmed int
+		wantCode    int
+	}{
+		{
+			name: "All Sheets",
+			want: map[string][][]string{
+				"Data":  {{"Name", "Age"}, {"ann", "30"}, {" bold ", ""}},
+				"Notes": {{"note"}},
+			},
+			wantTrimmed: 3,
+			wantCode:    Success,
+		},
+		{
+			name:   "Named Sheet",
+			sheets: []string{"Notes"},
+			want: map[string][][]string{
+				"Data":  {{" Name ", "Age"}, {"ann\u00a0", "30"}, {" bold ", ""}},
+				"Notes": {{"note"}},
+			},
+			wantTrimmed: 1,
+			wantCode:    Success,
+		},
+		{
+			name:     "Missing Sheet",
+			sheets:   []string{"Nope"},
+			wantCode: ErrInvalidArgs,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := options{FilePath: createTestWorkbook(t, t.TempDir()), Sheets: tt.sheets}
+			report := &RunReport{}
+			if got := processWorkbook(opts, report); got.Code != tt.wantCode {
+				t.Fatalf("processWorkbook() = %v, want code %d", got, tt.wantCode)
+			}
+			if tt.wantCode != Success {
+				return
+			}
+			if report.FieldsTrimmed != tt.wantTrimmed {
+				t.Errorf("FieldsTrimmed = %d, want %d", report.FieldsTrimmed, tt.wantTrimmed)
+			}
+			if got := readWorkbook(t, opts.FilePath); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("workbook = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessWorkbookOutput(t *testing.T) {
+	dir := t.TempDir()
+	input := createTestWorkbook(t, dir)
+	output := filepath.Join(dir, "trimmed.xlsx")
+	if err := os.WriteFile(output, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := options{FilePath: input, Output: output, Sheets: []string{"Notes"}}
+	if got := processWorkbook(opts, &RunReport{}); got.Code != ErrWriteFile {
+		t.Fatalf("processWorkbook() = %v, want code %d", got, ErrWriteFile)
+	}
+	opts.Force = true
+	if got := processWorkbook(opts, &RunReport{}); got.Code != Success {
+		t.Fatalf("processWorkbook() with Force = %v, want success", got)
+	}
+	if got := readWorkbook(t, output)["Notes"]; !reflect.DeepEqual(got, [][]string{{"note"}}) {
+		t.Errorf("output rows = %q", got)
+	}
+	if got := readWorkbook(t, input)["Notes"]; !reflect.DeepEqual(got, [][]string{{"\tnote\n"}}) {
+		t.Errorf("processWorkbook() with --output changed the original to %q", got)
+	}
+}
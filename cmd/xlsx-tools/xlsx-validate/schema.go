@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+)
+
+const (
+	typeString  = "string"
+	typeInteger = "integer"
+	typeNumber  = "number"
+	typeBoolean = "boolean"
+	typeDate    = "date"
+)
+
+// columnTypes lists the values accepted by a column's "type" in the schema file.
+var columnTypes = []string{typeString, typeInteger, typeNumber, typeBoolean, typeDate}
+
+// Schema describes what a valid workbook looks like. It is read from a JSON file such as:
+//
+//	{
+//	  "sheets": [
+//	    {
+//	      "name": "Customers",
+//	      "required": true,
+//	      "columns": [
+//	        {"name": "ID", "required": true, "type": "integer", "unique": true, "allowBlank": false},
+//	        {"name": "Status", "allowed": ["active", "inactive"]},
+//	        {"name": "Joined", "type": "date", "format": "2006-01-02"},
+//	        {"name": "Email", "pattern": "^[^@]+@[^@]+$"}
+//	      ]
+//	    }
+//	  ]
+//	}
+type Schema struct {
+	Sheets []SheetRule `json:"sheets"`
+}
+
+// SheetRule lists the checks applied to a single worksheet.
+type SheetRule struct {
+	Name string `json:"name"`
+	// Required reports a violation if the sheet is missing; otherwise a missing sheet is skipped.
+	Required bool `json:"required"`
+	// HeaderRow is the one-based row holding the headers, defaulting to the first row.
+	HeaderRow int          `json:"headerRow"`
+	Columns   []ColumnRule `json:"columns"`
+}
+
+// ColumnRule lists the checks applied to the cells below a header.
+type ColumnRule struct {
+	Name string `json:"name"`
+	// Required reports a violation if no header has this name.
+	Required bool `json:"required"`
+	// Type is one of columnTypes; values that cannot be read as this type are violations.
+	Type string `json:"type"`
+	// Format is the Go time layout of date values, e.g. 2006-01-02; common layouts are tried if it is empty.
+	Format string `json:"format"`
+	// AllowBlank permits empty cells; it defaults to true.
+	AllowBlank *bool    `json:"allowBlank"`
+	Allowed    []string `json:"allowed"`
+	Pattern    string   `json:"pattern"`
+	Unique     bool     `json:"unique"`
+
+	pattern *regexp.Regexp
+}
+
+// loadSchema reads and checks the schema file at path, compiling its patterns.
+func loadSchema(path string) (Schema, error) {
+	var schema Schema
+	content, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return schema, readErr
+	}
+	if err := json.Unmarshal(content, &schema); err != nil {
+		return schema, fmt.Errorf("invalid schema file '%s': %w", path, err)
+	}
+	for sheetIndex := range schema.Sheets {
+		sheet := &schema.Sheets[sheetIndex]
+		if len(sheet.Name) == 0 {
+			return schema, fmt.Errorf("sheet %d of the schema has no name", sheetIndex+1)
+		}
+		if sheet.HeaderRow < 1 {
+			sheet.HeaderRow = 1
+		}
+		for columnIndex := range sheet.Columns {
+			column := &sheet.Columns[columnIndex]
+			if len(column.Name) == 0 {
+				return schema, fmt.Errorf("sheet '%s': column %d of the schema has no name", sheet.Name, columnIndex+1)
+			}
+			if len(column.Type) > 0 && !slices.Contains(columnTypes, column.Type) {
+				return schema, fmt.Errorf("sheet '%s', column '%s': unknown type '%s'", sheet.Name, column.Name, column.Type)
+			}
+			if len(column.Pattern) > 0 {
+				pattern, patternErr := regexp.Compile(column.Pattern)
+				if patternErr != nil {
+					return schema, fmt.Errorf("sheet '%s', column '%s': %w", sheet.Name, column.Name, patternErr)
+				}
+				column.pattern = pattern
+			}
+		}
+	}
+	return schema, nil
+}
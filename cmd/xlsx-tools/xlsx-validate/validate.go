@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// dateLayouts are tried in turn for date columns without a format.
+var dateLayouts = []string{
+	"2006-01-02", "2006-01-02 15:04:05", "2006-01-02T15:04:05", time.RFC3339,
+	"01-02-06", "01-02-06 15:04", "1/2/06", "1/2/06 15:04", "1/2/2006", "02/01/2006", "2 January 2006",
+}
+
+// Violation is a single way in which the workbook breaks the schema.
+type Violation struct {
+	Sheet   string `json:"sheet"`
+	Cell    string `json:"cell,omitempty"`
+	Column  string `json:"column,omitempty"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// validateWorkbook checks every sheet named in the schema, returning all the violations found.
+func validateWorkbook(file *excelize.File, schema Schema) ([]Violation, error) {
+	var violations []Violation
+	sheets := file.GetSheetList()
+	for _, rule := range schema.Sheets {
+		if !slices.Contains(sheets, rule.Name) {
+			if rule.Required {
+				violations = append(violations, Violation{
+					Sheet: rule.Name, Rule: "required", Message: "required sheet is missing",
+				})
+			}
+			continue
+		}
+		rows, rowsErr := file.GetRows(rule.Name)
+		if rowsErr != nil {
+			return nil, fmt.Errorf("sheet '%s': %w", rule.Name, rowsErr)
+		}
+		violations = append(violations, validateSheet(rule, rows)...)
+	}
+	return violations, nil
+}
+
+// validateSheet checks the rows of a single sheet against rule.
+func validateSheet(rule SheetRule, rows [][]string) []Violation {
+	var violations []Violation
+	var headers []string
+	if rule.HeaderRow <= len(rows) {
+		headers = rows[rule.HeaderRow-1]
+	}
+	for _, column := range rule.Columns {
+		columnIndex := slices.IndexFunc(headers, func(header string) bool {
+			return strings.TrimSpace(header) == column.Name
+		})
+		if columnIndex < 0 {
+			if column.Required {
+				violations = append(violations, Violation{
+					Sheet: rule.Name, Column: column.Name, Rule: "required", Message: "required column is missing",
+				})
+			}
+			continue
+		}
+		seen := make(map[string]string)
+		for rowIndex := rule.HeaderRow; rowIndex < len(rows); rowIndex++ {
+			var value string
+			if columnIndex < len(rows[rowIndex]) {
+				value = rows[rowIndex][columnIndex]
+			}
+			cellName, _ := excelize.CoordinatesToCellName(columnIndex+1, rowIndex+1)
+			violation := Violation{Sheet: rule.Name, Cell: cellName, Column: column.Name}
+			for _, problem := range checkValue(column, value, seen, cellName) {
+				violation.Rule, violation.Message = problem[0], problem[1]
+				violations = append(violations, violation)
+			}
+		}
+	}
+	return violations
+}
+
+// checkValue applies the rules of column to a single value, returning each broken rule with a message.
+// seen maps the values met so far in the column to their cell, for the uniqueness check.
+func checkValue(column ColumnRule, value string, seen map[string]string, cellName string) [][2]string {
+	trimmed := strings.TrimSpace(value)
+	if len(trimmed) == 0 {
+		if column.AllowBlank != nil && !*column.AllowBlank {
+			return [][2]string{{"allowBlank", "value is blank"}}
+		}
+		return nil
+	}
+	var problems [][2]string
+	if len(column.Type) > 0 && !isType(trimmed, column.Type, column.Format) {
+		problems = append(problems, [2]string{"type", fmt.Sprintf("'%s' is not a valid %s", value, column.Type)})
+	}
+	if len(column.Allowed) > 0 && !slices.Contains(column.Allowed, trimmed) {
+		problems = append(problems, [2]string{"allowed", fmt.Sprintf("'%s' is not one of %s", value, strings.Join(column.Allowed, ", "))})
+	}
+	if column.pattern != nil && !column.pattern.MatchString(value) {
+		problems = append(problems, [2]string{"pattern", fmt.Sprintf("'%s' does not match %s", value, column.Pattern)})
+	}
+	if column.Unique {
+		if first, duplicate := seen[trimmed]; duplicate {
+			problems = append(problems, [2]string{"unique", fmt.Sprintf("'%s' already appears in %s", value, first)})
+		} else {
+			seen[trimmed] = cellName
+		}
+	}
+	return problems
+}
+
+// isType checks if value can be read as the given column type.
+func isType(value, columnType, format string) bool {
+	switch columnType {
+	case typeInteger:
+		_, err := strconv.ParseInt(strings.ReplaceAll(value, ",", ""), 10, 64)
+		return err == nil
+	case typeNumber:
+		_, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64)
+		return err == nil
+	case typeBoolean:
+		return slices.Contains([]string{"true", "false", "yes", "no", "1", "0"}, strings.ToLower(value))
+	case typeDate:
+		layouts := dateLayouts
+		if len(format) > 0 {
+			layouts = []string{format}
+		}
+		for _, layout := range layouts {
+			if _, err := time.Parse(layout, value); err == nil {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// loadTestSchema writes content to a schema file and loads it.
+func loadTestSchema(t *testing.T, content string) (Schema, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "schema.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return loadSchema(path)
+}
+
+func TestValidateSheet(t *testing.T) {
+	schema, loadErr := loadTestSchema(t, `{"sheets": [{"name": "Customers", "columns": [
+		{"name": "ID", "required": true, "type": "integer", "unique": true, "allowBlank": false},
+		{"name": "Status", "allowed": ["active", "inactive"]},
+		{"name": "Joined", "type": "date", "format": "2006-01-02"},
+		{"name": "Email", "pattern": "^[^@]+@[^@]+$"},
+		{"name": "Region", "required": true}
+	]}]}`)
+	if loadErr != nil {
+		t.Fatal(loadErr)
+	}
+	rows := [][]string{
+		{"ID", "Status", "Joined", "Email"},
+		{"1", "active", "2024-01-31", "ann@example.com"},
+		{"1", "gone", "31/01/2024", "bob"},
+		{"", "inactive"},
+		{"x"},
+	}
+	var got []string
+	for _, violation := range validateSheet(schema.Sheets[0], rows) {
+		got = append(got, fmt.Sprintf("%s %s %s", violation.Cell, violation.Column, violation.Rule))
+	}
+	want := []string{
+		" Region required",
+		"A3 ID unique",
+		"A4 ID allowBlank",
+		"A5 ID type",
+		"B3 Status allowed",
+		"C3 Joined type",
+		"D3 Email pattern",
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("validateSheet() = %q, want %q", got, want)
+	}
+}
+
+func TestIsType(t *testing.T) {
+	tests := []struct {
+		value, columnType, format string
+		want                      bool
+	}{
+		{"1,234", typeInteger, "", true},
+		{"1.5", typeInteger, "", false},
+		{"1.5", typeNumber, "", true},
+		{"abc", typeNumber, "", false},
+		{"Yes", typeBoolean, "", true},
+		{"maybe", typeBoolean, "", false},
+		{"2024-01-31", typeDate, "", true},
+		{"1/31/2024", typeDate, "", true},
+		{"2024-01-31", typeDate, "02/01/2006", false},
+		{"anything", typeString, "", true},
+	}
+	for _, test := range tests {
+		if got := isType(test.value, test.columnType, test.format); got != test.want {
+			t.Errorf("isType(%q, %s, %q) = %v, want %v", test.value, test.columnType, test.format, got, test.want)
+		}
+	}
+}
+
+func TestLoadSchemaErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"InvalidJSON", `{"sheets": [`, "invalid schema file"},
+		{"UnnamedSheet", `{"sheets": [{"columns": []}]}`, "has no name"},
+		{"UnnamedColumn", `{"sheets": [{"name": "S", "columns": [{"type": "string"}]}]}`, "has no name"},
+		{"UnknownType", `{"sheets": [{"name": "S", "columns": [{"name": "C", "type": "money"}]}]}`, "unknown type 'money'"},
+		{"InvalidPattern", `{"sheets": [{"name": "S", "columns": [{"name": "C", "pattern": "("}]}]}`, "missing closing )"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := loadTestSchema(t, test.content); err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("loadSchema() error = %v, want one containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+// This program checks a workbook against a JSON schema file listing the required sheets and columns,
+// column types, allowed values, patterns and uniqueness, so bad uploads are rejected before they are parsed.
+// It exits with a non-zero code and a report of every violation if the workbook does not conform.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// options holds the command line settings controlling the validation.
+type options struct {
+	FilePath   string
+	SchemaPath string
+	Format     string
+}
+
+func main() {
+	processingErr := ErrMsg{Code: Success}
+	defer func() {
+		processingErr.Exit()
+	}()
+	opts, argErr := getInput()
+	if argErr != nil {
+		processingErr = *argErr
+		return
+	}
+	processingErr = validate(opts)
+}
+
+// getInput parses the command line flags, falling back to a workbook path piped through standard input.
+func getInput() (options, *ErrMsg) {
+	var opts options
+	flag.StringVar(&opts.FilePath, "path", "", "The path to the .xlsx file to validate")
+	flag.StringVar(&opts.SchemaPath, "schema", "", "The path to the JSON schema file describing a valid workbook")
+	flag.StringVar(&opts.Format, "format", formatText, "The violation report format: text or json")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if logErr := logging.Setup(); logErr != nil {
+		return opts, &ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+	}
+
+	if opts.Format != formatText && opts.Format != formatJSON {
+		return opts, &ErrMsg{Err: fmt.Errorf("unsupported format '%s'", opts.Format), Code: ErrInvalidArgs}
+	}
+	if len(strings.TrimSpace(opts.SchemaPath)) == 0 {
+		return opts, &ErrMsg{Err: errors.New("a schema file is required, use --schema"), Code: ErrInvalidArgs}
+	}
+	path, inputErr := ResolveFile(opts.FilePath, flag.Args())
+	if inputErr != nil {
+		errMsg := ToErrMsg(inputErr, ErrNoInput)
+		return opts, &errMsg
+	}
+	opts.FilePath = path
+	return opts, nil
+}
+
+func validate(opts options) ErrMsg {
+	schema, schemaErr := loadSchema(opts.SchemaPath)
+	if schemaErr != nil {
+		return ErrMsg{Err: schemaErr, Code: ErrReadFile}
+	}
+	if exists, _ := PathExists(opts.FilePath); !exists {
+		return ErrMsg{Err: fmt.Errorf("file '%s' does not exist", opts.FilePath), Code: ErrNoFile}
+	}
+	if !IsWorkbookFile(opts.FilePath, false) {
+		return ErrMsg{Err: fmt.Errorf("file '%s' is not an Excel workbook", opts.FilePath), Code: ErrInvalidFileType}
+	}
+	file, openErr := excelize.OpenFile(opts.FilePath)
+	if openErr != nil {
+		return ErrMsg{Err: openErr, Code: ErrReadFile}
+	}
+	defer func(file *excelize.File) {
+		_ = file.Close()
+	}(file)
+
+	violations, validateErr := validateWorkbook(file, schema)
+	if validateErr != nil {
+		return ErrMsg{Err: validateErr, Code: ErrParse}
+	}
+	if writeErr := writeViolations(os.Stdout, opts, violations); writeErr != nil {
+		return ErrMsg{Err: writeErr, Code: ErrStdout}
+	}
+	if len(violations) > 0 {
+		return ErrMsg{Err: fmt.Errorf("%d violation(s) found in '%s'", len(violations), opts.FilePath), Code: ErrValidation}
+	}
+	return ErrMsg{Code: Success}
+}
+
+// writeViolations writes the report in the requested format: one line per violation for text,
+// or an object with the file, a valid flag and the list of violations for JSON.
+func writeViolations(w io.Writer, opts options, violations []Violation) error {
+	if opts.Format == formatJSON {
+		if violations == nil {
+			violations = []Violation{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			File       string      `json:"file"`
+			Valid      bool        `json:"valid"`
+			Violations []Violation `json:"violations"`
+		}{opts.FilePath, len(violations) == 0, violations})
+	}
+	for _, violation := range violations {
+		location := violation.Sheet
+		if len(violation.Cell) > 0 {
+			location += "!" + violation.Cell
+		}
+		if len(violation.Column) > 0 {
+			location += " [" + violation.Column + "]"
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s (%s)\n", location, violation.Message, violation.Rule); err != nil {
+			return err
+		}
+	}
+	if len(violations) == 0 {
+		_, err := fmt.Fprintf(w, "%s is valid\n", opts.FilePath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+// This program extracts worksheets of a .xlsx file into CSV files, one per sheet,
+// named <workbook>_<sheet>.csv and written next to the workbook unless --output-dir is given.
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
+	"github.com/charmbracelet/log"
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// encodings maps the values accepted by --encoding to their text encoders.
+// A nil encoding writes plain UTF-8.
+var encodings = map[string]encoding.Encoding{
+	"utf-8":        nil,
+	"utf-8-bom":    unicode.UTF8BOM,
+	"utf-16le":     unicode.UTF16(unicode.LittleEndian, unicode.UseBOM),
+	"utf-16be":     unicode.UTF16(unicode.BigEndian, unicode.UseBOM),
+	"windows-1252": charmap.Windows1252,
+	"latin1":       charmap.ISO8859_1,
+}
+
+// options holds the command line settings controlling the extraction.
+type options struct {
+	FilePath  string
+	Sheets    []string
+	OutputDir string
+	Delimiter rune
+	Encoding  string
+	BOM       string
+	Sanitize  bool
+}
+
+func main() {
+	startTime := time.Now()
+
+	processingErr := ErrMsg{Code: Success}
+	defer func() {
+		log.Debug(
+			"DONE!",
+			"time", time.Since(startTime),
+		)
+		processingErr.Exit()
+	}()
+	opts, argErr := getInput()
+	if argErr != nil {
+		processingErr = *argErr
+		return
+	}
+	processingErr = processWorkbook(opts)
+}
+
+// getInput parses the command line flags. The file is given with --path or as an argument, falling back to
+// a path piped through standard input.
+func getInput() (options, *ErrMsg) {
+	var opts options
+	var sheets, delimiter string
+	flag.StringVar(&opts.FilePath, "path", "", "The path to the .xlsx file to extract")
+	flag.StringVar(&sheets, "sheet", "", "Comma-separated list of worksheets to extract (defaults to all sheets)")
+	flag.StringVar(&opts.OutputDir, "output-dir", "", "Directory for the CSV files (defaults to the workbook's directory)")
+	flag.StringVar(&delimiter, "delimiter", ",", "The field delimiter of the CSV files (use \\t for tabs)")
+	flag.StringVar(&opts.Encoding, "encoding", "utf-8", "The character encoding of the CSV files: "+strings.Join(encodingNames(), ", "))
+	flag.StringVar(&opts.BOM, "bom", BOMPreserve, "Whether UTF-8 CSV files start with a byte order mark: 'add' writes one (as Excel expects), 'strip' leaves it out, 'preserve' does as --encoding says")
+	flag.BoolVar(&opts.Sanitize, "sanitize-formulas", false, "Prefix values starting with =, +, -, @, tab or carriage return with a quote to guard against CSV injection")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if logErr := logging.Setup(); logErr != nil {
+		return opts, &ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+	}
+
+	if delimiter == `\t` {
+		delimiter = "\t"
+	}
+	if utf8.RuneCountInString(delimiter) != 1 {
+		return opts, &ErrMsg{Err: fmt.Errorf("delimiter must be a single character, got '%s'", delimiter), Code: ErrInvalidArgs}
+	}
+	opts.Delimiter, _ = utf8.DecodeRuneInString(delimiter)
+	opts.Encoding = strings.ToLower(opts.Encoding)
+	if _, known := encodings[opts.Encoding]; !known {
+		return opts, &ErrMsg{Err: fmt.Errorf("unsupported encoding '%s'", opts.Encoding), Code: ErrInvalidArgs}
+	}
+	if !ValidBOMMode(opts.BOM) {
+		return opts, &ErrMsg{Err: fmt.Errorf("unknown --bom '%s', expected one of %s", opts.BOM, strings.Join(BOMModes, ", ")), Code: ErrInvalidArgs}
+	}
+	// A byte order mark is optional in UTF-8 only; UTF-16 needs one for readers to tell the byte order
+	switch {
+	case opts.Encoding == "utf-8" && opts.BOM == BOMAdd:
+		opts.Encoding = "utf-8-bom"
+	case opts.Encoding == "utf-8-bom" && opts.BOM == BOMStrip:
+		opts.Encoding = "utf-8"
+	}
+	for _, sheet := range strings.Split(sheets, ",") {
+		if sheet = strings.TrimSpace(sheet); sheet != "" {
+			opts.Sheets = append(opts.Sheets, sheet)
+		}
+	}
+
+	path, inputErr := ResolveFile(opts.FilePath, flag.Args())
+	if inputErr != nil {
+		errMsg := ToErrMsg(inputErr, ErrNoInput)
+		return opts, &errMsg
+	}
+	opts.FilePath = path
+	return opts, nil
+}
+
+// encodingNames returns the supported --encoding values in a stable order.
+func encodingNames() []string {
+	names := make([]string, 0, len(encodings))
+	for name := range encodings {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func processWorkbook(opts options) ErrMsg {
+	if exists, _ := PathExists(opts.FilePath); !exists {
+		return ErrMsg{Err: fmt.Errorf("file '%s' does not exist", opts.FilePath), Code: ErrNoFile}
+	}
+	if !IsWorkbookFile(opts.FilePath, false) {
+		return ErrMsg{Err: fmt.Errorf("file '%s' is not an Excel workbook", opts.FilePath), Code: ErrInvalidFileType}
+	}
+	file, openErr := excelize.OpenFile(opts.FilePath)
+	if openErr != nil {
+		return ErrMsg{Err: openErr, Code: ErrReadFile}
+	}
+	defer func(file *excelize.File) {
+		if err := file.Close(); err != nil {
+			log.Error(err)
+		}
+	}(file)
+
+	sheets := opts.Sheets
+	if len(sheets) == 0 {
+		sheets = file.GetSheetList()
+	}
+	outputDir := opts.OutputDir
+	if outputDir == "" {
+		outputDir = filepath.Dir(opts.FilePath)
+	} else if mkdirErr := os.MkdirAll(outputDir, 0o755); mkdirErr != nil {
+		return ErrMsg{Err: mkdirErr, Code: ErrWriteFile}
+	}
+	workbook := strings.TrimSuffix(filepath.Base(opts.FilePath), filepath.Ext(opts.FilePath))
+	for _, sheet := range sheets {
+		if index, _ := file.GetSheetIndex(sheet); index < 0 {
+			return ErrMsg{Err: fmt.Errorf("sheet '%s' does not exist", sheet), Code: ErrInvalidArgs}
+		}
+		csvPath := filepath.Join(outputDir, fmt.Sprintf("%s_%s.csv", workbook, sanitizeFileName(sheet)))
+		rowCount, writeErr := writeSheet(file, sheet, csvPath, opts)
+		if writeErr != nil {
+			return ErrMsg{Err: fmt.Errorf("sheet '%s': %w", sheet, writeErr), Code: ErrReadWrite}
+		}
+		log.Info("Extracted sheet", "sheet", sheet, "file", csvPath, "rows", rowCount)
+	}
+	return ErrMsg{Code: Success}
+}
+
+// sanitizeFileName replaces characters that are not allowed in file names on common platforms.
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>:"/\|?*`, r) || r < 32 {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// sheetWidth returns the number of columns in the used range of a worksheet, or 0 if it is unknown.
+func sheetWidth(file *excelize.File, sheet string) int {
+	dimension, dimErr := file.GetSheetDimension(sheet)
+	if dimErr != nil {
+		return 0
+	}
+	_, lastCell, found := strings.Cut(dimension, ":")
+	if !found {
+		lastCell = dimension
+	}
+	column, _, cellErr := excelize.CellNameToCoordinates(lastCell)
+	if cellErr != nil {
+		return 0
+	}
+	return column
+}
+
+// writeSheet streams the rows of a worksheet into a CSV file, returning the number of rows written.
+func writeSheet(file *excelize.File, sheet, csvPath string, opts options) (rowCount int, err error) {
+	rows, rowsErr := file.Rows(sheet)
+	if rowsErr != nil {
+		return 0, rowsErr
+	}
+	defer func(rows *excelize.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	csvFile, createErr := os.Create(csvPath)
+	if createErr != nil {
+		return 0, createErr
+	}
+	defer func(csvFile *os.File) {
+		if closeErr := csvFile.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}(csvFile)
+
+	var output io.Writer = csvFile
+	if enc := encodings[opts.Encoding]; enc != nil {
+		output = enc.NewEncoder().Writer(csvFile)
+	}
+	writer := csv.NewWriter(output)
+	writer.Comma = opts.Delimiter
+
+	width := sheetWidth(file, sheet)
+	for rows.Next() {
+		record, colErr := rows.Columns()
+		if colErr != nil {
+			return rowCount, colErr
+		}
+		// Pad short rows so every record has as many fields as the sheet, or at least the header.
+		width = max(width, len(record))
+		for len(record) < width {
+			record = append(record, "")
+		}
+		if opts.Sanitize {
+			for i, value := range record {
+				record[i] = SanitizeFormula(value)
+			}
+		}
+		if writeErr := writer.Write(record); writeErr != nil {
+			return rowCount, writeErr
+		}
+		rowCount++
+	}
+	writer.Flush()
+	return rowCount, writer.Error()
+}
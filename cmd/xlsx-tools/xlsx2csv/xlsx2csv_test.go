@@ -0,0 +1,146 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "GoTools/pkg/helpers"
+	"github.com/xuri/excelize/v2"
+)
+
+// createTestWorkbook saves a workbook with a Data sheet holding a short row and a "Q1|Q2" sheet, returning its path.
+func createTestWorkbook(t *testing.T, dir string) string {
+	t.Helper()
+	file := excelize.NewFile()
+	defer func() {
+		_ = file.Close()
+	}()
+	if err := file.SetSheetName("Sheet1", "Data"); err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]any{{"Name", "City", "Age"}, {"ann", "Oslo", 30}, {"bob"}}
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := file.SetSheetRow("Data", cell, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := file.NewSheet("Q1|Q2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.SetSheetRow("Q1|Q2", "A1", &[]any{"Total", "café"}); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "book.xlsx")
+	if err := file.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProcessWorkbook(t *testing.T) {
+	tests := []struct {
+		name      string
+		sheets    []string
+		delimiter rune
+		encoding  string
+		want      map[string]string
+		wantCode  int
+	}{
+		{
+			name:     "All Sheets",
+			want:     map[string]string{"book_Data.csv": "Name,City,Age\nann,Oslo,30\nbob,,\n", "book_Q1_Q2.csv": "Total,café\n"},
+			wantCode: Success,
+		},
+		{
+			name:      "Named Sheet And Delimiter",
+			sheets:    []string{"Data"},
+			delimiter: '\t',
+			want:      map[string]string{"book_Data.csv": "Name\tCity\tAge\nann\tOslo\t30\nbob\t\t\n"},
+			wantCode:  Success,
+		},
+		{
+			name:     "Encoding",
+			sheets:   []string{"Q1|Q2"},
+			encoding: "windows-1252",
+			want:     map[string]string{"book_Q1_Q2.csv": "Total,caf\xe9\n"},
+			wantCode: Success,
+		},
+		{
+			name:     "Byte Order Mark",
+			sheets:   []string{"Q1|Q2"},
+			encoding: "utf-8-bom",
+			want:     map[string]string{"book_Q1_Q2.csv": "\ufeffTotal,café\n"},
+			wantCode: Success,
+		},
+		{
+			name:     "Missing Sheet",
+			sheets:   []string{"Nope"},
+			want:     map[string]string{},
+			wantCode: ErrInvalidArgs,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			outputDir := filepath.Join(dir, "out")
+			if err := os.Mkdir(outputDir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			opts := options{
+				FilePath:  createTestWorkbook(t, dir),
+				Sheets:    tt.sheets,
+				OutputDir: outputDir,
+				Delimiter: ',',
+				Encoding:  "utf-8",
+			}
+			if tt.delimiter != 0 {
+				opts.Delimiter = tt.delimiter
+			}
+			if tt.encoding != "" {
+				opts.Encoding = tt.encoding
+			}
+			if got := processWorkbook(opts); got.Code != tt.wantCode {
+				t.Fatalf("processWorkbook() = %v, want code %d", got, tt.wantCode)
+			}
+			entries, _ := os.ReadDir(outputDir)
+			if len(entries) != len(tt.want) {
+				t.Errorf("processWorkbook() wrote %d files, want %d", len(entries), len(tt.want))
+			}
+			for name, want := range tt.want {
+				got, err := os.ReadFile(filepath.Join(outputDir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessWorkbookMissingFile(t *testing.T) {
+	opts := options{FilePath: filepath.Join(t.TempDir(), "missing.xlsx"), Delimiter: ',', Encoding: "utf-8"}
+	if got := processWorkbook(opts); got.Code != ErrNoFile {
+		t.Errorf("processWorkbook() = %v, want code %d", got, ErrNoFile)
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Sheet1", "Sheet1"},
+		{"Q1/Q2", "Q1_Q2"},
+		{`a<b>c:d"e\f|g?h*i`, "a_b_c_d_e_f_g_h_i"},
+		{"tab\there", "tab_here"},
+	}
+	for _, tt := range tests {
+		if got := sanitizeFileName(tt.name); got != tt.want {
+			t.Errorf("sanitizeFileName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
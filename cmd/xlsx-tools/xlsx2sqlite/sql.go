@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	. "GoTools/pkg/helpers"
+	"github.com/xuri/excelize/v2"
+)
+
+// SQLite column types chosen from the values of each column.
+const (
+	sqlInteger = "INTEGER"
+	sqlReal    = "REAL"
+	sqlText    = "TEXT"
+)
+
+// sheetTable is a worksheet read into memory, ready to be written as a SQLite table.
+type sheetTable struct {
+	Name    string
+	Columns []string
+	Types   []string
+	Rows    [][]string
+}
+
+// newSheetTable builds the table for a sheet from its rows, the first of which holds the headers.
+// Blank headers are named after their column letter and repeated ones are renamed, so every column
+// has a distinct name; rows wider than the header row get columns named the same way.
+func newSheetTable(name string, rows [][]string) *sheetTable {
+	table := &sheetTable{Name: name}
+	if len(rows) == 0 {
+		return table
+	}
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	table.Columns = make([]string, width)
+	for i := range table.Columns {
+		if i < len(rows[0]) {
+			table.Columns[i] = strings.TrimSpace(rows[0][i])
+		}
+		if len(table.Columns[i]) == 0 {
+			table.Columns[i], _ = excelize.ColumnNumberToName(i + 1)
+		}
+	}
+	// SQLite compares column names case-insensitively, so duplicates are found ignoring case.
+	lower := make([]string, width)
+	for i, column := range table.Columns {
+		lower[i] = strings.ToLower(column)
+	}
+	renamed := RenameDuplicates(slices.Clone(lower), false)
+	for i := range table.Columns {
+		table.Columns[i] += strings.TrimPrefix(renamed[i], lower[i])
+	}
+	table.Rows = rows[1:]
+	table.Types = make([]string, width)
+	for i := range table.Types {
+		table.Types[i] = columnType(table.Rows, i)
+	}
+	return table
+}
+
+// columnType picks the SQLite type of a column: INTEGER if every value is a whole number or boolean,
+// REAL if every value is a number, and TEXT otherwise. A column of only blanks is TEXT.
+func columnType(rows [][]string, column int) string {
+	columnKind := ""
+	for _, row := range rows {
+		if column >= len(row) {
+			continue
+		}
+		switch kind := InferValueType(row[column]); kind {
+		case "empty":
+		case "integer", "boolean":
+			if columnKind == "" {
+				columnKind = sqlInteger
+			}
+		case "number":
+			if columnKind != sqlText {
+				columnKind = sqlReal
+			}
+		default:
+			return sqlText
+		}
+	}
+	if columnKind == "" {
+		return sqlText
+	}
+	return columnKind
+}
+
+// writeScript writes a SQL script creating and filling a table per sheet, inside a single transaction.
+// With replace set, existing tables of the same names are dropped first.
+func writeScript(w io.Writer, tables []*sheetTable, replace bool) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "BEGIN TRANSACTION;")
+	for _, table := range tables {
+		if replace {
+			fmt.Fprintf(out, "DROP TABLE IF EXISTS %s;\n", quoteIdent(table.Name))
+		}
+		definitions := make([]string, len(table.Columns))
+		for i, column := range table.Columns {
+			definitions[i] = quoteIdent(column) + " " + table.Types[i]
+		}
+		fmt.Fprintf(out, "CREATE TABLE %s (\n\t%s\n);\n", quoteIdent(table.Name), strings.Join(definitions, ",\n\t"))
+		if len(table.Columns) == 0 {
+			continue
+		}
+		columns := make([]string, len(table.Columns))
+		for i, column := range table.Columns {
+			columns[i] = quoteIdent(column)
+		}
+		insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES (", quoteIdent(table.Name), strings.Join(columns, ", "))
+		for _, row := range table.Rows {
+			values := make([]string, len(table.Columns))
+			for i := range table.Columns {
+				value := ""
+				if i < len(row) {
+					value = row[i]
+				}
+				values[i] = sqlLiteral(value, table.Types[i])
+			}
+			fmt.Fprintf(out, "%s%s);\n", insert, strings.Join(values, ", "))
+		}
+	}
+	fmt.Fprintln(out, "COMMIT;")
+	return out.Flush()
+}
+
+// sqlLiteral formats a cell value as a SQL literal for a column of the given type.
+// Blank cells become NULL, booleans become 1 or 0 and dates in TEXT columns are written in ISO 8601 form.
+// Values are only written unquoted once they are known to be numbers, anything else in a numeric column
+// is quoted as text.
+func sqlLiteral(value, columnType string) string {
+	trimmed := strings.TrimSpace(value)
+	if len(trimmed) == 0 {
+		return "NULL"
+	}
+	kind := InferValueType(trimmed)
+	switch columnType {
+	case sqlInteger:
+		if boolean, err := strconv.ParseBool(trimmed); err == nil && kind == "boolean" {
+			if boolean {
+				return "1"
+			}
+			return "0"
+		}
+		if kind == "integer" {
+			return trimmed
+		}
+	case sqlReal:
+		if kind == "integer" || kind == "number" {
+			return trimmed
+		}
+	}
+	if date, isDate := ParseDate(trimmed); isDate && kind == "date" {
+		if date.Hour() == 0 && date.Minute() == 0 && date.Second() == 0 {
+			return quoteString(date.Format(time.DateOnly))
+		}
+		return quoteString(date.Format(time.DateTime))
+	}
+	return quoteString(value)
+}
+
+// quoteIdent quotes a table or column name for SQLite.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteString quotes a text value for SQLite.
+func quoteString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
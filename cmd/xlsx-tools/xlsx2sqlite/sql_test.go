@@ -0,0 +1,56 @@
+package main
+
+import "testing"
+
+func TestSQLLiteral(t *testing.T) {
+	tests := []struct {
+		name       string
+		value      string
+		columnType string
+		want       string
+	}{
+		{"Blank", "  ", sqlInteger, "NULL"},
+		{"Integer", "42", sqlInteger, "42"},
+		{"Boolean", "TRUE", sqlInteger, "1"},
+		{"IntegerInReal", "42", sqlReal, "42"},
+		{"Real", "3.5", sqlReal, "3.5"},
+		{"NaNInReal", "NaN", sqlReal, "'NaN'"},
+		{"InfInReal", "-Inf", sqlReal, "'-Inf'"},
+		{"StatementInInteger", "1); DROP TABLE t; --", sqlInteger, "'1); DROP TABLE t; --'"},
+		{"RealInInteger", "3.5", sqlInteger, "'3.5'"},
+		{"Text", "O'Brien", sqlText, "'O''Brien'"},
+		{"Date", "2024-01-31", sqlText, "'2024-01-31'"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := sqlLiteral(test.value, test.columnType); got != test.want {
+				t.Errorf("sqlLiteral(%q, %s) = %s, want %s", test.value, test.columnType, got, test.want)
+			}
+		})
+	}
+}
+
+func TestColumnType(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   string
+	}{
+		{"Integers", []string{"1", "", "TRUE"}, sqlInteger},
+		{"Reals", []string{"1", "2.5"}, sqlReal},
+		{"NaN", []string{"1.5", "NaN"}, sqlText},
+		{"Text", []string{"1", "abc"}, sqlText},
+		{"Blank", []string{"", " "}, sqlText},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rows := make([][]string, len(test.values))
+			for i, value := range test.values {
+				rows[i] = []string{value}
+			}
+			if got := columnType(rows, 0); got != test.want {
+				t.Errorf("columnType(%q) = %s, want %s", test.values, got, test.want)
+			}
+		})
+	}
+}
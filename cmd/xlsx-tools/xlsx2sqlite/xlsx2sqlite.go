@@ -0,0 +1,186 @@
+// This program loads worksheets of a .xlsx file into tables of a SQLite database, one table per sheet,
+// named after the sheet and with columns named after its header row. Column types are inferred from the
+// values: INTEGER, REAL or TEXT. The data is written as a SQL script and loaded with the sqlite3 command
+// line shell, so no database driver is needed; use --sql to keep the script, or to write only the script
+// when sqlite3 is not installed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
+	"github.com/charmbracelet/log"
+	"github.com/xuri/excelize/v2"
+)
+
+// options holds the command line settings controlling the export.
+type options struct {
+	FilePath string
+	Sheets   []string
+	Database string
+	Script   string
+	SQLite   string
+	Replace  bool
+}
+
+func main() {
+	startTime := time.Now()
+
+	processingErr := ErrMsg{Code: Success}
+	defer func() {
+		log.Debug(
+			"DONE!",
+			"time", time.Since(startTime),
+		)
+		processingErr.Exit()
+	}()
+	opts, argErr := getInput()
+	if argErr != nil {
+		processingErr = *argErr
+		return
+	}
+	processingErr = exportWorkbook(opts)
+}
+
+// getInput parses the command line flags. The file is given with --path or as an argument, falling back to
+// a path piped through standard input.
+func getInput() (options, *ErrMsg) {
+	var opts options
+	var sheets string
+	flag.StringVar(&opts.FilePath, "path", "", "The path to the .xlsx file to export")
+	flag.StringVar(&sheets, "sheet", "", "Comma-separated list of worksheets to export (defaults to all sheets)")
+	flag.StringVar(&opts.Database, "db", "", "The SQLite database to load the sheets into (defaults to the workbook's path with a .db extension)")
+	flag.StringVar(&opts.Script, "sql", "", "Write the SQL script to this path ('-' for standard output) instead of loading it with sqlite3")
+	flag.StringVar(&opts.SQLite, "sqlite3", "sqlite3", "The sqlite3 command line shell used to load the database")
+	flag.BoolVar(&opts.Replace, "replace", false, "Drop existing tables with the same names as the sheets before loading them")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if logErr := logging.Setup(); logErr != nil {
+		return opts, &ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+	}
+
+	for _, sheet := range strings.Split(sheets, ",") {
+		if sheet = strings.TrimSpace(sheet); sheet != "" {
+			opts.Sheets = append(opts.Sheets, sheet)
+		}
+	}
+	path, inputErr := ResolveFile(opts.FilePath, flag.Args())
+	if inputErr != nil {
+		errMsg := ToErrMsg(inputErr, ErrNoInput)
+		return opts, &errMsg
+	}
+	opts.FilePath = path
+	if len(opts.Database) == 0 {
+		opts.Database = strings.TrimSuffix(opts.FilePath, filepath.Ext(opts.FilePath)) + ".db"
+	}
+	return opts, nil
+}
+
+func exportWorkbook(opts options) ErrMsg {
+	if exists, _ := PathExists(opts.FilePath); !exists {
+		return ErrMsg{Err: fmt.Errorf("file '%s' does not exist", opts.FilePath), Code: ErrNoFile}
+	}
+	if !IsWorkbookFile(opts.FilePath, false) {
+		return ErrMsg{Err: fmt.Errorf("file '%s' is not an Excel workbook", opts.FilePath), Code: ErrInvalidFileType}
+	}
+	sqlite := ""
+	if len(opts.Script) == 0 {
+		var lookErr error
+		if sqlite, lookErr = exec.LookPath(opts.SQLite); lookErr != nil {
+			return ErrMsg{Err: fmt.Errorf("the sqlite3 command line shell is needed to load '%s' but '%s' was not found, install sqlite3, give its path with --sqlite3 or write a script with --sql instead: %w", opts.Database, opts.SQLite, lookErr), Code: ErrInvalidArgs}
+		}
+	}
+	file, openErr := excelize.OpenFile(opts.FilePath)
+	if openErr != nil {
+		return ErrMsg{Err: openErr, Code: ErrReadFile}
+	}
+	defer func(file *excelize.File) {
+		if err := file.Close(); err != nil {
+			log.Error(err)
+		}
+	}(file)
+
+	sheets := opts.Sheets
+	if len(sheets) == 0 {
+		sheets = file.GetSheetList()
+	}
+	var tables []*sheetTable
+	for _, sheet := range sheets {
+		if index, _ := file.GetSheetIndex(sheet); index < 0 {
+			return ErrMsg{Err: fmt.Errorf("sheet '%s' does not exist", sheet), Code: ErrInvalidArgs}
+		}
+		rows, rowsErr := file.GetRows(sheet)
+		if rowsErr != nil {
+			return ErrMsg{Err: fmt.Errorf("sheet '%s': %w", sheet, rowsErr), Code: ErrReadFile}
+		}
+		table := newSheetTable(sheet, rows)
+		if len(table.Columns) == 0 {
+			log.Warn("Skipping empty sheet", "sheet", sheet)
+			continue
+		}
+		tables = append(tables, table)
+	}
+
+	switch {
+	case opts.Script == "-":
+		if err := writeScript(os.Stdout, tables, opts.Replace); err != nil {
+			return ErrMsg{Err: err, Code: ErrStdout}
+		}
+	case len(opts.Script) > 0:
+		if err := writeScriptFile(opts.Script, tables, opts.Replace); err != nil {
+			return ErrMsg{Err: err, Code: ErrWriteFile}
+		}
+		log.Info("Wrote SQL script", "file", opts.Script)
+	default:
+		if err := loadDatabase(sqlite, opts.Database, tables, opts.Replace); err != nil {
+			return ErrMsg{Err: err, Code: ErrWriteFile}
+		}
+		for _, table := range tables {
+			log.Info("Loaded table", "table", table.Name, "rows", len(table.Rows), "columns", len(table.Columns))
+		}
+	}
+	return ErrMsg{Code: Success}
+}
+
+// writeScriptFile writes the SQL script for tables to path.
+func writeScriptFile(path string, tables []*sheetTable, replace bool) (err error) {
+	scriptFile, createErr := os.Create(path)
+	if createErr != nil {
+		return createErr
+	}
+	defer func(scriptFile *os.File) {
+		if closeErr := scriptFile.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}(scriptFile)
+	return writeScript(scriptFile, tables, replace)
+}
+
+// loadDatabase pipes the SQL script for tables into the sqlite3 shell, creating the database if needed.
+// The shell stops at the first failing statement, and the transaction leaves the database unchanged.
+func loadDatabase(sqlite, database string, tables []*sheetTable, replace bool) error {
+	var stderr strings.Builder
+	cmd := exec.Command(sqlite, "-bail", database)
+	cmd.Stderr = &stderr
+	stdin, pipeErr := cmd.StdinPipe()
+	if pipeErr != nil {
+		return pipeErr
+	}
+	if startErr := cmd.Start(); startErr != nil {
+		return startErr
+	}
+	writeErr := writeScript(stdin, tables, replace)
+	_ = stdin.Close()
+	if waitErr := cmd.Wait(); waitErr != nil {
+		return fmt.Errorf("sqlite3 failed loading '%s': %w: %s", database, waitErr, strings.TrimSpace(stderr.String()))
+	}
+	return writeErr
+}
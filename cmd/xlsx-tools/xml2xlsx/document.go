@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"slices"
+
+	. "GoTools/pkg/helpers"
+)
+
+const xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+
+// xmlNode is an element of the input document, keeping only what is needed to rebuild the tables.
+type xmlNode struct {
+	Name     string
+	Attrs    []xml.Attr
+	Text     string
+	Children []*xmlNode
+}
+
+// attr returns the value of the attribute with the given local name and namespace, if present.
+func (n *xmlNode) attr(space, local string) (string, bool) {
+	for _, attr := range n.Attrs {
+		if attr.Name.Local == local && attr.Name.Space == space {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// cell is a single value read from the document, along with the annotations written by parse-xml.
+type cell struct {
+	Value   string
+	Type    string
+	Href    string
+	Comment string
+}
+
+// table is a set of records that becomes one worksheet.
+type table struct {
+	Name    string
+	Headers []string
+	Rows    []map[string]cell
+}
+
+// readDocument decodes the XML read from r into a tree of elements.
+// Inline schemas (xs:schema) are skipped, since the data is rebuilt from the records themselves.
+func readDocument(r io.Reader) (*xmlNode, error) {
+	decoder := xml.NewDecoder(r)
+	var root *xmlNode
+	var stack []*xmlNode
+	for {
+		token, tokenErr := decoder.Token()
+		if errors.Is(tokenErr, io.EOF) {
+			break
+		} else if tokenErr != nil {
+			return nil, tokenErr
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "schema" && len(stack) > 0 {
+				if skipErr := decoder.Skip(); skipErr != nil {
+					return nil, skipErr
+				}
+				continue
+			}
+			node := &xmlNode{Name: t.Name.Local, Attrs: slices.Clone(t.Attr)}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, node)
+			} else {
+				root = node
+			}
+			stack = append(stack, node)
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].Text += string(t)
+			}
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if root == nil {
+		return nil, errors.New("the document has no root element")
+	}
+	return root, nil
+}
+
+// collectTables finds the records of the document and groups them into tables.
+//
+// A record is an element whose children, if any, hold only text; its children (or attributes) are its columns.
+// When every child of container is a record, the records are grouped by element name: records named after
+// rowElement form a table named by the container's name attribute, or defaultName, as written by parse-xml,
+// while other records form a table named after the record element itself, as written by DataSet.WriteXml.
+// Otherwise collectTables looks for records inside each child, which covers parse-xml's --all-sheets documents.
+func collectTables(container *xmlNode, rowElement, defaultName string) []*table {
+	isRecords := len(container.Children) > 0
+	for _, child := range container.Children {
+		for _, grandchild := range child.Children {
+			if len(grandchild.Children) > 0 {
+				isRecords = false
+			}
+		}
+	}
+	if !isRecords {
+		var tables []*table
+		for _, child := range container.Children {
+			if len(child.Children) > 0 {
+				tables = append(tables, collectTables(child, rowElement, defaultName)...)
+			}
+		}
+		return tables
+	}
+
+	var tables []*table
+	byName := make(map[string]*table)
+	for _, record := range container.Children {
+		name := DecodeXMLName(record.Name)
+		if record.Name == rowElement {
+			name = defaultName
+			if tableName, ok := container.attr("", "name"); ok && len(tableName) > 0 {
+				name = tableName
+			}
+		}
+		current, found := byName[name]
+		if !found {
+			current = &table{Name: name}
+			byName[name] = current
+			tables = append(tables, current)
+		}
+		current.addRecord(record)
+	}
+	return tables
+}
+
+// addRecord appends the values of record as a new row, adding any columns not seen before.
+// Column elements marked xsi:nil are read as blank cells.
+func (t *table) addRecord(record *xmlNode) {
+	row := make(map[string]cell)
+	setColumn := func(name string, value cell) {
+		header := DecodeXMLName(name)
+		if !slices.Contains(t.Headers, header) {
+			t.Headers = append(t.Headers, header)
+		}
+		row[header] = value
+	}
+	for _, attr := range record.Attrs {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" || attr.Name.Space == xsiNamespace {
+			continue
+		}
+		setColumn(attr.Name.Local, cell{Value: attr.Value})
+	}
+	for _, column := range record.Children {
+		value := cell{Value: column.Text}
+		if isNil, _ := column.attr(xsiNamespace, "nil"); isNil == "true" {
+			value.Value = ""
+		}
+		value.Type, _ = column.attr(xsiNamespace, "type")
+		value.Href, _ = column.attr("", "href")
+		value.Comment, _ = column.attr("", "comment")
+		setColumn(column.Name, value)
+	}
+	t.Rows = append(t.Rows, row)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollectTables(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name: "ParseXML",
+			input: `<DataTable name="Orders"><Row><Id>1</Id><Order_x0020_Date>2024-01-31</Order_x0020_Date></Row>` +
+				`<Row><Id>2</Id><Note>late</Note></Row></DataTable>`,
+			want: []string{"Orders [Id Order Date Note] 2 rows"},
+		},
+		{
+			name:  "DefaultName",
+			input: `<DataTable><Row><Id>1</Id></Row></DataTable>`,
+			want:  []string{"Sheet1 [Id] 1 rows"},
+		},
+		{
+			name:  "DataSet",
+			input: `<NewDataSet><Customer><Id>1</Id></Customer><Order><Id>7</Id></Order><Customer><Id>2</Id></Customer></NewDataSet>`,
+			want:  []string{"Customer [Id] 2 rows", "Order [Id] 1 rows"},
+		},
+		{
+			name:  "AllSheets",
+			input: `<Workbook><DataTable name="A"><Row><X>1</X></Row></DataTable><DataTable name="B"><Row><Y>2</Y></Row></DataTable></Workbook>`,
+			want:  []string{"A [X] 1 rows", "B [Y] 1 rows"},
+		},
+		{
+			name:  "Attributes",
+			input: `<DataTable xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"><Row id="1" xsi:type="x"/></DataTable>`,
+			want:  []string{"Sheet1 [id] 1 rows"},
+		},
+		{
+			name:  "InlineSchema",
+			input: `<DataTable><xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="Row"/></xs:schema><Row><Id>1</Id></Row></DataTable>`,
+			want:  []string{"Sheet1 [Id] 1 rows"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root, readErr := readDocument(strings.NewReader(test.input))
+			if readErr != nil {
+				t.Fatal(readErr)
+			}
+			var got []string
+			for _, table := range collectTables(root, "Row", "Sheet1") {
+				got = append(got, fmt.Sprintf("%s %v %d rows", table.Name, table.Headers, len(table.Rows)))
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("collectTables() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAddRecordAnnotations(t *testing.T) {
+	root, readErr := readDocument(strings.NewReader(`<DataTable xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">` +
+		`<Row><A xsi:type="xs:double">1.5</A><B xsi:nil="true">x</B><C href="http://example.com" comment="note">link</C></Row></DataTable>`))
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	tables := collectTables(root, "Row", "Sheet1")
+	if len(tables) != 1 || len(tables[0].Rows) != 1 {
+		t.Fatalf("collectTables() = %v, want one table with one row", tables)
+	}
+	row := tables[0].Rows[0]
+	want := map[string]cell{
+		"A": {Value: "1.5", Type: "xs:double"},
+		"B": {},
+		"C": {Value: "link", Href: "http://example.com", Comment: "note"},
+	}
+	for header, value := range want {
+		if row[header] != value {
+			t.Errorf("row[%q] = %+v, want %+v", header, row[header], value)
+		}
+	}
+}
+
+func TestCellValue(t *testing.T) {
+	tests := []struct {
+		value      cell
+		inferTypes bool
+		want       interface{}
+	}{
+		{cell{Value: "1.5", Type: "xs:double"}, false, 1.5},
+		{cell{Value: "abc", Type: "xs:double"}, false, "abc"},
+		{cell{Value: "true", Type: "xs:boolean"}, false, true},
+		{cell{Value: "2024-01-31", Type: "xs:date"}, false, time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{cell{Value: "2024-01-31T10:30:00", Type: "xs:dateTime"}, false, time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC)},
+		{cell{Value: "42"}, false, "42"},
+		{cell{Value: "42"}, true, 42.0},
+		{cell{Value: "007"}, true, "007"},
+	}
+	for _, test := range tests {
+		if got := cellValue(test.value, test.inferTypes); got != test.want {
+			t.Errorf("cellValue(%+v, %v) = %#v, want %#v", test.value, test.inferTypes, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// This program converts DataTable-style XML, as written by parse-xml or by .NET's DataSet.WriteXml,
+// back into a .xlsx workbook with one worksheet per table, so an exported sheet can be edited and reimported.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
+	"GoTools/pkg/xlsxwriter"
+	"github.com/charmbracelet/log"
+	"github.com/xuri/excelize/v2"
+)
+
+// options holds the command line settings controlling the conversion.
+type options struct {
+	FilePath   string
+	Output     string
+	Force      bool
+	SheetName  string
+	RowElement string
+	InferTypes bool
+}
+
+func main() {
+	startTime := time.Now()
+
+	processingErr := ErrMsg{Code: Success}
+	defer func() {
+		log.Debug(
+			"DONE!",
+			"time", time.Since(startTime),
+		)
+		processingErr.Exit()
+	}()
+	opts, argErr := getInput()
+	if argErr != nil {
+		processingErr = *argErr
+		return
+	}
+	processingErr = processDocument(opts)
+}
+
+// getInput parses the command line flags. The file is given with --path or as an argument, falling back to
+// a path piped through standard input.
+func getInput() (options, *ErrMsg) {
+	var opts options
+	flag.StringVar(&opts.FilePath, "path", "", "The path to the XML file to convert")
+	flag.StringVar(&opts.Output, "output", "", "The path of the workbook to write (defaults to the XML file's path with a .xlsx extension)")
+	flag.StringVar(&opts.Output, "o", "", "Shorthand for --output")
+	flag.BoolVar(&opts.Force, "force", false, "Overwrite the output workbook if it already exists")
+	flag.StringVar(&opts.SheetName, "sheet", "Sheet1", "The worksheet name for a table whose document does not name it")
+	flag.StringVar(&opts.RowElement, "row-element", "Row", "The name of the element wrapping each row in parse-xml documents")
+	flag.BoolVar(&opts.InferTypes, "infer-types", false, "Write numbers and booleans without an xsi:type as numeric and boolean cells rather than text")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if logErr := logging.Setup(); logErr != nil {
+		return opts, &ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+	}
+
+	path, inputErr := ResolveFile(opts.FilePath, flag.Args())
+	if inputErr != nil {
+		errMsg := ToErrMsg(inputErr, ErrNoInput)
+		return opts, &errMsg
+	}
+	opts.FilePath = path
+	if len(opts.Output) == 0 {
+		opts.Output = strings.TrimSuffix(opts.FilePath, filepath.Ext(opts.FilePath)) + ".xlsx"
+	}
+	return opts, nil
+}
+
+func processDocument(opts options) ErrMsg {
+	if exists, _ := PathExists(opts.FilePath); !exists {
+		return ErrMsg{Err: fmt.Errorf("file '%s' does not exist", opts.FilePath), Code: ErrNoFile}
+	}
+	if exists, _ := PathExists(opts.Output); exists && !opts.Force {
+		return ErrMsg{Err: fmt.Errorf("output file '%s' already exists, use --force to overwrite it", opts.Output), Code: ErrWriteFile}
+	}
+	input, openErr := os.Open(opts.FilePath)
+	if openErr != nil {
+		return ErrMsg{Err: openErr, Code: ErrReadFile}
+	}
+	defer func(input *os.File) {
+		_ = input.Close()
+	}(input)
+	root, readErr := readDocument(bufio.NewReader(input))
+	if readErr != nil {
+		return ErrMsg{Err: readErr, Code: ErrParse}
+	}
+	tables := collectTables(root, opts.RowElement, opts.SheetName)
+
+	file, writeErr := writeWorkbook(tables, opts)
+	if writeErr != nil {
+		return ErrMsg{Err: writeErr, Code: ErrWriteFile}
+	}
+	defer func(file *excelize.File) {
+		_ = file.Close()
+	}(file)
+	if saveErr := file.SaveAs(opts.Output); saveErr != nil {
+		return ErrMsg{Err: saveErr, Code: ErrWriteFile}
+	}
+	for _, table := range tables {
+		log.Info("Wrote sheet", "sheet", table.Name, "rows", len(table.Rows), "columns", len(table.Headers))
+	}
+	return ErrMsg{Code: Success}
+}
+
+// writeWorkbook creates a workbook with one worksheet per table, each with a header row followed by its records.
+// A document without any records produces a workbook with a single empty sheet.
+func writeWorkbook(tables []*table, opts options) (*excelize.File, error) {
+	file := excelize.NewFile()
+	var used []string
+	for tableIndex, table := range tables {
+		table.Name = xlsxwriter.SheetName(table.Name, used)
+		used = append(used, table.Name)
+		if tableIndex == 0 {
+			if renameErr := file.SetSheetName(file.GetSheetName(0), table.Name); renameErr != nil {
+				return file, renameErr
+			}
+		} else if _, newErr := file.NewSheet(table.Name); newErr != nil {
+			return file, newErr
+		}
+		if writeErr := writeTable(file, table, opts); writeErr != nil {
+			return file, fmt.Errorf("sheet '%s': %w", table.Name, writeErr)
+		}
+	}
+	if len(tables) == 0 {
+		if renameErr := file.SetSheetName(file.GetSheetName(0), xlsxwriter.SheetName(opts.SheetName, nil)); renameErr != nil {
+			return file, renameErr
+		}
+	}
+	return file, nil
+}
+
+// writeTable writes the headers and rows of table to its worksheet, restoring hyperlinks and comments.
+func writeTable(file *excelize.File, table *table, opts options) error {
+	headers := make([]interface{}, len(table.Headers))
+	for i, header := range table.Headers {
+		headers[i] = header
+	}
+	if err := file.SetSheetRow(table.Name, "A1", &headers); err != nil {
+		return err
+	}
+	for rowIndex, row := range table.Rows {
+		for columnIndex, header := range table.Headers {
+			value, found := row[header]
+			if !found || len(value.Value) == 0 {
+				continue
+			}
+			cellName, nameErr := excelize.CoordinatesToCellName(columnIndex+1, rowIndex+2)
+			if nameErr != nil {
+				return nameErr
+			}
+			if err := file.SetCellValue(table.Name, cellName, cellValue(value, opts.InferTypes)); err != nil {
+				return err
+			}
+			if len(value.Href) > 0 {
+				linkType := "External"
+				if strings.HasPrefix(value.Href, "#") {
+					linkType = "Location"
+				}
+				if err := file.SetCellHyperLink(table.Name, cellName, strings.TrimPrefix(value.Href, "#"), linkType); err != nil {
+					return err
+				}
+			}
+			if len(value.Comment) > 0 {
+				comment := excelize.Comment{Cell: cellName, Text: value.Comment}
+				if err := file.AddComment(table.Name, comment); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// cellValue converts a value to the Go type matching its xsi:type, so that numbers, booleans and dates
+// become real cells rather than text. Untyped values are written as text unless inferTypes is set.
+func cellValue(value cell, inferTypes bool) interface{} {
+	switch value.Type {
+	case "xs:double", "xs:decimal", "xs:int", "xs:integer", "xs:long":
+		if number, err := strconv.ParseFloat(value.Value, 64); err == nil {
+			return number
+		}
+	case "xs:boolean":
+		if boolean, err := strconv.ParseBool(value.Value); err == nil {
+			return boolean
+		}
+	case "xs:dateTime", "xs:date":
+		for _, layout := range []string{"2006-01-02T15:04:05", time.RFC3339, time.DateOnly} {
+			if date, err := time.Parse(layout, value.Value); err == nil {
+				return date
+			}
+		}
+	case "":
+		if inferTypes {
+			return TypedValue(value.Value)
+		}
+	}
+	return value.Value
+}
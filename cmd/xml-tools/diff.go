@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lineEdit is one step of an edit script between two lists of lines: keeping a line of both (' '),
+// deleting a line of the old list ('-') or inserting a line of the new one ('+').
+type lineEdit struct {
+	op      byte
+	oldLine int
+	newLine int
+}
+
+// diffLines returns the shortest edit script turning oldLines into newLines, using Myers' algorithm in its
+// linear-space form, so reformatting that touches every line of a large file does not need quadratic memory.
+func diffLines(oldLines, newLines []string) []lineEdit {
+	// Lines are compared as numbers, which is cheaper than comparing the strings over and over
+	ids := make(map[string]int)
+	number := func(lines []string) []int {
+		numbered := make([]int, len(lines))
+		for i, line := range lines {
+			id, found := ids[line]
+			if !found {
+				id = len(ids)
+				ids[line] = id
+			}
+			numbered[i] = id
+		}
+		return numbered
+	}
+	var edits []lineEdit
+	compareLines(number(oldLines), number(newLines), 0, 0, &edits)
+	return edits
+}
+
+// compareLines appends the edits turning a into b, whose first lines are line aStart and bStart of the
+// whole lists, dividing the problem at a point the shortest edit script passes through.
+func compareLines(a, b []int, aStart, bStart int, edits *[]lineEdit) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		*edits = append(*edits, lineEdit{op: ' ', oldLine: aStart + prefix, newLine: bStart + prefix})
+		prefix++
+	}
+	a, b = a[prefix:], b[prefix:]
+	aStart, bStart = aStart+prefix, bStart+prefix
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	switch {
+	case len(a) == 0:
+		for j := range b {
+			*edits = append(*edits, lineEdit{op: '+', oldLine: aStart, newLine: bStart + j})
+		}
+	case len(b) == 0:
+		for i := range a {
+			*edits = append(*edits, lineEdit{op: '-', oldLine: aStart + i, newLine: bStart})
+		}
+	default:
+		x, y := middleSnake(a, b)
+		compareLines(a[:x], b[:y], aStart, bStart, edits)
+		compareLines(a[x:], b[y:], aStart+x, bStart+y, edits)
+	}
+	for s := 0; s < suffix; s++ {
+		*edits = append(*edits, lineEdit{op: ' ', oldLine: aStart + len(a) + s, newLine: bStart + len(b) + s})
+	}
+}
+
+// middleSnake searches for the shortest edit script between a and b from both ends at once, returning the
+// point where the two searches meet, which the script passes through. Both lists are non-empty and differ in
+// their first and last lines, so the point is never at either end and each half is smaller than the whole.
+func middleSnake(a, b []int) (int, int) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD + 1
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+	for i := range forward {
+		forward[i], backward[i] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+	delta := n - m
+	// With an odd delta the searches meet while searching forwards, otherwise while searching backwards
+	odd := delta%2 != 0
+	// Diagonals whose paths have run off the edge of the grid are not searched further
+	forwardStart, forwardEnd, backwardStart, backwardEnd := 0, 0, 0, 0
+	for d := 0; d < maxD; d++ {
+		for k := -d + forwardStart; k <= d-forwardEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x, y = x+1, y+1
+			}
+			forward[offset+k] = x
+			switch {
+			case x > n:
+				forwardEnd += 2
+			case y > m:
+				forwardStart += 2
+			case odd:
+				if reverse := offset + delta - k; reverse >= 0 && reverse < len(backward) && backward[reverse] != -1 {
+					if x >= n-backward[reverse] {
+						return x, y
+					}
+				}
+			}
+		}
+		for k := -d + backwardStart; k <= d-backwardEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x, y = x+1, y+1
+			}
+			backward[offset+k] = x
+			switch {
+			case x > n:
+				backwardEnd += 2
+			case y > m:
+				backwardStart += 2
+			case !odd:
+				if front := offset + delta - k; front >= 0 && front < len(forward) && forward[front] != -1 {
+					if fx := forward[front]; fx >= n-x {
+						return fx, fx - (front - offset)
+					}
+				}
+			}
+		}
+	}
+	// The lists have nothing in common: delete one and insert the other
+	return n, 0
+}
+
+// unifiedDiff renders the differences between two texts as a unified diff with the given lines of context,
+// returning an empty string when they are the same.
+func unifiedDiff(oldName, newName, oldText, newText string, context int) string {
+	if oldText == newText {
+		return ""
+	}
+	oldLines, newLines := splitLines(oldText), splitLines(newText)
+	edits := diffLines(oldLines, newLines)
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "--- %s\n+++ %s\n", oldName, newName)
+	for start := 0; start < len(edits); {
+		// Find the next change, then extend the hunk while changes are close enough to share context
+		for start < len(edits) && edits[start].op == ' ' {
+			start++
+		}
+		if start == len(edits) {
+			break
+		}
+		first := max(start-context, 0)
+		end := start
+		for end < len(edits) {
+			next := end
+			for next < len(edits) && edits[next].op != ' ' {
+				next++
+			}
+			unchanged := next
+			for unchanged < len(edits) && edits[unchanged].op == ' ' {
+				unchanged++
+			}
+			end = next
+			if unchanged == len(edits) || unchanged-next > 2*context {
+				break
+			}
+			end = unchanged
+		}
+		last := min(end+context, len(edits))
+		writeHunk(&builder, edits[first:last], oldLines, newLines)
+		start = last
+	}
+	return builder.String()
+}
+
+// writeHunk writes one hunk of a unified diff, with its header giving the lines it covers in each text.
+func writeHunk(builder *strings.Builder, hunk []lineEdit, oldLines, newLines []string) {
+	oldStart, newStart := hunk[0].oldLine, hunk[0].newLine
+	oldCount, newCount := 0, 0
+	for _, edit := range hunk {
+		if edit.op != '+' {
+			oldCount++
+		}
+		if edit.op != '-' {
+			newCount++
+		}
+	}
+	fmt.Fprintf(builder, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+	for _, edit := range hunk {
+		line := ""
+		if edit.op == '+' {
+			line = newLines[edit.newLine]
+		} else {
+			line = oldLines[edit.oldLine]
+		}
+		builder.WriteByte(edit.op)
+		builder.WriteString(strings.TrimSuffix(line, "\n"))
+		builder.WriteByte('\n')
+		if !strings.HasSuffix(line, "\n") {
+			builder.WriteString("\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats the start and length of a hunk as unified diffs do, where an empty range starts
+// at the line before it.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprint(start + 1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitLines splits text into lines, each keeping its line break so a missing final one can be shown.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
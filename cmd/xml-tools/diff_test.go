@@ -0,0 +1,47 @@
+package main
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		context  int
+		want     string
+	}{
+		{
+			name: "Same",
+			old:  "x\n",
+			new:  "x\n",
+			want: "",
+		},
+		{
+			name:    "SeparateHunks",
+			old:     "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			new:     "1\n2\n3\nfour\n5\n6\n7\n8\n9\nten\n",
+			context: 1,
+			want:    "--- a\n+++ b\n@@ -3,3 +3,3 @@\n 3\n-4\n+four\n 5\n@@ -9,2 +9,2 @@\n 9\n-10\n+ten\n",
+		},
+		{
+			name:    "SharedContext",
+			old:     "1\n2\n3\n4\n5\n",
+			new:     "one\n2\n3\n4\nfive\n",
+			context: 3,
+			want:    "--- a\n+++ b\n@@ -1,5 +1,5 @@\n-1\n+one\n 2\n 3\n 4\n-5\n+five\n",
+		},
+		{
+			name:    "NoNewlineAtEnd",
+			old:     "x",
+			new:     "y",
+			context: 3,
+			want:    "--- a\n+++ b\n@@ -1 +1 @@\n-x\n\\ No newline at end of file\n+y\n\\ No newline at end of file\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := unifiedDiff("a", "b", test.old, test.new, test.context); got != test.want {
+				t.Errorf("unifiedDiff() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
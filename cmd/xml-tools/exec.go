@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/charmbracelet/log"
+)
+
+// execMutex keeps the --exec commands of different workers from running at the same time, as commands such
+// as git add fail when another holds the same lock.
+var execMutex sync.Mutex
+
+// splitCommand splits an --exec value into the command and its arguments at spaces, except those inside single
+// or double quotes, which are removed. It is not run through a shell, so file names never need escaping.
+func splitCommand(value string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	started := false
+	for _, r := range value {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote, started = r, true
+		case r == ' ' || r == '\t':
+			if started {
+				args = append(args, current.String())
+				current.Reset()
+				started = false
+			}
+		default:
+			current.WriteRune(r)
+			started = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in '%s'", quote, value)
+	}
+	if started {
+		args = append(args, current.String())
+	}
+	if len(args) == 0 {
+		return nil, errors.New("no command given")
+	}
+	return args, nil
+}
+
+// runExec runs the --exec command for the file at path, with each {} in its arguments replaced by path,
+// or path added as the last argument if there is none. The command's combined output and error output is
+// logged if it succeeds, and added to the error if it fails, so a failing hook says why.
+func runExec(path string) error {
+	args := make([]string, 0, len(execCommand)+1)
+	placed := false
+	for _, arg := range execCommand {
+		if strings.Contains(arg, "{}") {
+			arg, placed = strings.ReplaceAll(arg, "{}", path), true
+		}
+		args = append(args, arg)
+	}
+	if !placed {
+		args = append(args, path)
+	}
+
+	execMutex.Lock()
+	defer execMutex.Unlock()
+	output, err := exec.Command(args[0], args[1:]...).CombinedOutput()
+	output = bytes.TrimSpace(output)
+	if err != nil {
+		if len(output) > 0 {
+			return fmt.Errorf("running %s: %w: %s", args[0], err, output)
+		}
+		return fmt.Errorf("running %s: %w", args[0], err)
+	}
+	if len(output) > 0 {
+		log.Info("Command output", "command", args[0], "file name", path, "output", string(output))
+	}
+	return nil
+}
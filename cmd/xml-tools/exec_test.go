@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/log"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{value: "git add", want: []string{"git", "add"}},
+		{value: "  git\tadd  {} ", want: []string{"git", "add", "{}"}},
+		{value: `sh -c "echo 'a b' {}"`, want: []string{"sh", "-c", "echo 'a b' {}"}},
+		{value: `cmd 'two words' ""`, want: []string{"cmd", "two words", ""}},
+		{value: `a"b c"d`, want: []string{"ab cd"}},
+		{value: `cmd "open`, wantErr: true},
+		{value: "   ", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := splitCommand(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("splitCommand(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("splitCommand(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRunExec(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a file.xml")
+	tests := []struct {
+		name    string
+		command []string
+		wantErr string
+		wantLog string
+	}{
+		{name: "Placeholder", command: []string{"sh", "-c", `echo "got $0"`, "{}"}, wantLog: "got " + path},
+		{name: "Appended", command: []string{"sh", "-c", `echo "got $0"`}, wantLog: "got " + path},
+		{name: "Quiet", command: []string{"true"}},
+		{name: "FailureOutput", command: []string{"sh", "-c", "echo first; echo second >&2; exit 3"}, wantErr: "exit status 3: first\nsecond"},
+		{name: "FailureSilent", command: []string{"false"}, wantErr: "running false: exit status 1"},
+	}
+	defer func(command []string) {
+		execCommand = command
+	}(execCommand)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			defer log.SetOutput(os.Stderr)
+			execCommand = tt.command
+			err := runExec(path)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("runExec() error = %v", err)
+			} else if tt.wantErr != "" && (err == nil || !strings.HasSuffix(err.Error(), tt.wantErr)) {
+				t.Fatalf("runExec() error = %v, want it to end with %q", err, tt.wantErr)
+			}
+			if !strings.Contains(logs.String(), tt.wantLog) {
+				t.Errorf("runExec() logged %q, want %q", logs.String(), tt.wantLog)
+			}
+		})
+	}
+}
@@ -3,27 +3,89 @@ package main
 import (
 	"bufio"
 	"bytes"
-	"encoding/xml"
 	"errors"
 	"flag"
+	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
+	"GoTools/pkg/xmlfmt"
 	"github.com/charmbracelet/log"
 )
 
 var (
-	verbose bool
 	dirPath string
+	// formatOptions holds the flags controlling how each document is formatted; Indent is resolved from
+	// --indent by parseIndent and SortElements from --sort-elements by xmlfmt.ParseSortKeys.
+	formatOptions = xmlfmt.DefaultOptions()
+	// check reports the files that are not formatted instead of rewriting them.
+	check bool
+	// showDiff reports the changes formatting would make as unified diffs; it implies check.
+	showDiff bool
+	// outputDir, if set, receives the formatted files instead of overwriting the originals.
+	outputDir string
+	// backup saves each original before it is rewritten in place, as <name>.bak or under backupDir.
+	backup    bool
+	backupDir string
+	// preserveMtime gives each rewritten file the modification time its original had.
+	preserveMtime bool
+	// execCommand, if set, is the command and arguments run for each file written; see runExec.
+	execCommand []string
+	// validateOnly checks that each file is well-formed without formatting or writing anything.
+	validateOnly bool
+	// lint reports duplicate IDs and references to undefined ones in each document, taking the attributes
+	// named by lintRefs as references; see xmlfmt.Lint.
+	lint     bool
+	lintRefs []string
+	// workers is the number of files formatted at the same time.
+	workers int
+	// failFast stops handing out files once one has failed; those already being formatted are finished.
+	failFast bool
+	// progress controls the progress line shown while files are processed: auto, always or never.
+	progress string
+	// filter formats the document read from stdin to stdout, as set by --path - or by piping in a document.
+	filter bool
+	// followSymlinks formats the files that symbolic links found in directories and globs lead to, rather than
+	// skipping the links; files named directly by --path are always followed.
+	followSymlinks bool
+	// sniff selects the files found in directories that start like an XML document, whatever their extension.
+	sniff bool
+	// includePatterns and excludePatterns narrow the files found in directories and globs, see isSelected.
+	includePatterns []string
+	excludePatterns []string
+	// maxSize, if positive, is the size in bytes above which files are skipped rather than formatted.
+	maxSize int64
+	// reportFormat and reportFile request a run report, written to reportFile or stdout at the end of the run.
+	reportFormat string
+	reportFile   string
 )
 
 type TargetFile struct {
 	Path string
-	Err  error
+	// Rel is the path of the file relative to the directory or glob that named it, used with --output-dir.
+	Rel string
+	Err error
+	// Changed records whether formatting changed the file's content.
+	Changed bool
+	// Diff holds the unified diff of the changes formatting would make, with --diff.
+	Diff string
+	// Skipped records that the file was left alone for being larger than --max-size.
+	Skipped bool
+	// Issues holds the problems --lint found in the file.
+	Issues []xmlfmt.LintIssue
+	// BytesBefore and BytesAfter are the sizes of the file before and after formatting, for the run report.
+	BytesBefore int64
+	BytesAfter  int64
 }
 
 func handleError(target *TargetFile, err error, errChan chan<- *TargetFile) {
@@ -31,181 +93,766 @@ func handleError(target *TargetFile, err error, errChan chan<- *TargetFile) {
 	errChan <- target
 }
 
-func getXmlEncoderDecoder(file *os.File) (*xml.Encoder, *xml.Decoder, *bytes.Buffer) {
-	// Create a new buffered reader from the file
-	reader := bufio.NewReader(file)
-
+// formatDocument formats the document read from r into memory, as formatTo does.
+func formatDocument(r io.Reader, name string) (*bytes.Buffer, error) {
 	var buf bytes.Buffer
-	encoder := xml.NewEncoder(&buf)
-	encoder.Indent(" ", "\t")
-
-	decoder := xml.NewDecoder(reader)
+	if err := formatTo(r, &buf, name); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
 
-	return encoder, decoder, &buf
+// formatTo formats the document read from r to w with the options set by the flags, see xmlfmt.Format.
+// Each repair --lenient makes is logged as a warning, with name identifying the document.
+func formatTo(r io.Reader, w io.Writer, name string) error {
+	opts := formatOptions
+	opts.Warn = func(repair, element string, line, column int) {
+		log.Warn(repair, "file name", filepath.Base(name), "element", element, "line", line, "column", column)
+	}
+	return xmlfmt.Format(r, w, opts)
 }
 
 func formatXmlFile(target *TargetFile, errChan chan<- *TargetFile, wg *sync.WaitGroup) {
 	defer wg.Done()
 
-	file, err := os.Open(target.Path)
-	if err != nil {
+	if err := formatTarget(target); err != nil {
 		handleError(target, err, errChan)
 		return
 	}
+	errChan <- target
+}
 
-	defer func() {
-		if err := file.Close(); err != nil {
-			handleError(target, err, errChan)
+// formatTarget formats a single file, streaming the output into a temporary file that replaces the original,
+// or the file under --output-dir, only once it is complete. The output is compared with the original as it
+// is written, so an unchanged file is left alone and --check writes nothing, without either being held in memory.
+// Files larger than --max-size are skipped. With --preserve-mtime, the file written keeps the original's modification time.
+// With --lint, the file is read once more beforehand to find its issues.
+// The --exec command is run for each file written, failing the file if the command fails.
+func formatTarget(target *TargetFile) error {
+	info, err := os.Stat(target.Path)
+	if err != nil {
+		return err
+	}
+	target.BytesBefore, target.BytesAfter = info.Size(), info.Size()
+	if maxSize > 0 && info.Size() > maxSize {
+		target.Skipped = true
+		return nil
+	}
+	if lint {
+		if target.Issues, err = lintFile(target.Path); err != nil {
+			return err
 		}
-	}()
-
-	encoder, decoder, buf := getXmlEncoderDecoder(file)
+	}
+	input, err := os.Open(target.Path)
+	if err != nil {
+		return err
+	}
+	defer func(input *os.File) {
+		_ = input.Close()
+	}(input)
+	if validateOnly {
+		return xmlfmt.Validate(bufio.NewReader(input), formatOptions)
+	}
+	if showDiff {
+		return diffTarget(target, input)
+	}
 
-	for {
-		t, err := decoder.Token()
-		if err != nil {
-			if err == io.EOF {
-				break
+	original, err := os.Open(target.Path)
+	if err != nil {
+		return err
+	}
+	defer func(original *os.File) {
+		_ = original.Close()
+	}(original)
+	compare := newCompareWriter(original)
+	var output *AtomicFile
+	var w io.Writer = compare
+	if !check {
+		// A symbolic link is rewritten by replacing the file it leads to, so the link itself stays in place
+		destination, evalErr := filepath.EvalSymlinks(target.Path)
+		if evalErr != nil {
+			return evalErr
+		}
+		if len(outputDir) > 0 {
+			destination = filepath.Join(outputDir, target.Rel)
+			if err = os.MkdirAll(filepath.Dir(destination), 0o755); err != nil {
+				return err
 			}
-			handleError(target, err, errChan)
-			return
 		}
-		if t == nil {
-			break
+		if output, err = CreateAtomic(destination); err != nil {
+			return err
 		}
-		if err := encoder.EncodeToken(t); err != nil {
-			handleError(target, err, errChan)
-			return
+		if preserveMtime {
+			output.ModTime = info.ModTime()
 		}
+		w = io.MultiWriter(output, compare)
 	}
-
-	if err := encoder.Flush(); err != nil {
-		handleError(target, err, errChan)
-		return
+	if err = formatTo(input, w, target.Path); err != nil {
+		if output != nil {
+			output.Abort()
+		}
+		return err
+	}
+	target.Changed = compare.Changed()
+	target.BytesAfter = compare.written
+	switch {
+	case output == nil:
+		return nil
+	case len(outputDir) == 0 && !target.Changed:
+		output.Abort()
+		return nil
+	case len(outputDir) == 0 && backup:
+		if err = backupFile(target); err != nil {
+			output.Abort()
+			return fmt.Errorf("backing up the original: %w", err)
+		}
+	}
+	if err = output.Commit(); err != nil {
+		return err
+	}
+	if len(execCommand) > 0 {
+		written := target.Path
+		if len(outputDir) > 0 {
+			written = output.Name()
+		}
+		if err = runExec(written); err != nil {
+			return fmt.Errorf("--exec: %w", err)
+		}
 	}
+	return nil
+}
 
-	outFile, err := os.Create(target.Path)
+// lintFile returns the issues --lint finds in the file at path.
+func lintFile(path string) ([]xmlfmt.LintIssue, error) {
+	input, err := os.Open(path)
 	if err != nil {
-		handleError(target, err, errChan)
-		return
+		return nil, err
 	}
+	defer func(input *os.File) {
+		_ = input.Close()
+	}(input)
+	return xmlfmt.Lint(bufio.NewReader(input), formatOptions, lintRefs)
+}
 
-	defer func(outFile *os.File) {
-		if err := outFile.Close(); err != nil {
-			handleError(target, err, errChan)
-		}
-	}(outFile)
+// logIssues logs each issue --lint found in the document at name as a warning.
+func logIssues(name string, issues []xmlfmt.LintIssue) {
+	for _, issue := range issues {
+		log.Warn(issue.Message, "file name", filepath.Base(name), "line", issue.Line, "column", issue.Column)
+	}
+}
 
-	writer := bufio.NewWriter(outFile)
-	if _, err = writer.Write(buf.Bytes()); err != nil {
-		handleError(target, err, errChan)
-		return
+// diffTarget records in target the unified diff between the document read from input and its formatted version.
+// Unlike formatting, this holds both versions in memory.
+func diffTarget(target *TargetFile, input io.Reader) error {
+	original, err := io.ReadAll(input)
+	if err != nil {
+		return err
+	}
+	buf, err := formatDocument(bytes.NewReader(original), target.Path)
+	if err != nil {
+		return err
 	}
+	target.Changed = !bytes.Equal(original, buf.Bytes())
+	target.BytesAfter = int64(buf.Len())
+	target.Diff, err = documentDiff(target.Path, original, buf.Bytes())
+	return err
+}
 
-	if err = writer.Flush(); err != nil {
-		handleError(target, err, errChan)
-		return
+// documentDiff returns the unified diff between the original and formatted versions of the document at name.
+// Documents kept in an encoding other than UTF-8 are decoded first, so the diff can be read.
+func documentDiff(name string, original, formatted []byte) (string, error) {
+	if enc, _ := xmlfmt.SniffCharset(original); enc != nil && !formatOptions.ToUTF8 && !formatOptions.Canonical {
+		var err error
+		if original, err = enc.NewDecoder().Bytes(original); err != nil {
+			return "", err
+		}
+		if formatted, err = enc.NewDecoder().Bytes(formatted); err != nil {
+			return "", err
+		}
 	}
+	return unifiedDiff(name+"\toriginal", name+"\tformatted", string(original), string(formatted), diffContext), nil
+}
 
-	errChan <- target
+// diffContext is the number of unchanged lines shown around each change by --diff.
+const diffContext = 3
+
+// backupFile copies target beside itself with a .bak suffix, or under --backup-dir at its relative path,
+// with the same permissions as the original.
+func backupFile(target *TargetFile) error {
+	source, err := os.Open(target.Path)
+	if err != nil {
+		return err
+	}
+	defer func(source *os.File) {
+		_ = source.Close()
+	}(source)
+	info, err := source.Stat()
+	if err != nil {
+		return err
+	}
+	backupPath := target.Path + ".bak"
+	if len(backupDir) > 0 {
+		backupPath = filepath.Join(backupDir, target.Rel)
+		if err = os.MkdirAll(filepath.Dir(backupPath), 0o755); err != nil {
+			return err
+		}
+	}
+	destination, err := os.OpenFile(backupPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(destination, source); err != nil {
+		_ = destination.Close()
+		return err
+	}
+	return destination.Close()
 }
 
 func parseArgs() error {
-	flag.StringVar(&dirPath, "path", "", "Path to directory containing XML files")
-	flag.BoolVar(&verbose, "verbose", false, "Enable verbose logging")
+	flag.StringVar(&dirPath, "path", "", "Path to a directory containing XML files, an XML file, or a glob such as 'configs/**/*.xml'; separate several with commas, or use - to format stdin to stdout")
+	indentFlag := flag.String("indent", "tab", "The indentation for each nesting level: a number of spaces, or 'tab'")
+	flag.StringVar(&formatOptions.Prefix, "prefix", "", "A prefix written at the start of every line after the first, before the indentation")
+	flag.BoolVar(&formatOptions.Minify, "minify", false, "Remove the whitespace between elements instead of indenting, writing compact single-line XML")
+	flag.StringVar(&formatOptions.Doctype, "doctype", xmlfmt.DoctypePreserve, "How to handle DOCTYPE declarations: 'preserve' keeps them, 'strip' removes them, 'reject' fails the file; external entities are never loaded")
+	flag.BoolVar(&formatOptions.PreserveCDATA, "preserve-cdata", false, "Keep CDATA sections as they are instead of converting them to escaped text, so embedded scripts and HTML stay readable")
+	flag.StringVar(&formatOptions.SelfClose, "self-close", xmlfmt.SelfCloseExpand, "How to write empty elements: 'empty' as <a/>, 'expand' as <a></a>, 'preserve' as they were written")
+	flag.IntVar(&formatOptions.MaxLineWidth, "max-line-width", 0, "Write the attributes of start tags on lines wider than this one per line, indented one level below the element, counting tabs as 4 columns (0 never wraps)")
+	flag.BoolVar(&formatOptions.SortAttributes, "sort-attributes", false, "Write each element's attributes in alphabetical order, after any namespace declarations (values are always double-quoted)")
+	sortFlag := flag.String("sort-elements", "", "Comma-separated elements to sort among their siblings by an attribute or child value, as in 'Row:@Id,Setting:Name', so regenerated XML stays stable (numbers are compared as numbers)")
+	flag.BoolVar(&formatOptions.PruneNamespaces, "prune-namespaces", false, "Remove namespace declarations that nothing in their scope uses, and those repeating one already in scope")
+	flag.BoolVar(&formatOptions.Lenient, "lenient", false, "Format HTML and other documents that are not well-formed, accepting unescaped ampersands, HTML entities, unquoted attributes and unclosed tags, and warning about each repair instead of failing")
+	flag.BoolVar(&formatOptions.Canonical, "canonical", false, "Write Exclusive XML Canonicalization (without comments) for signing and hash comparison, instead of indenting")
+	flag.BoolVar(&formatOptions.ToUTF8, "to-utf8", false, "Write documents read as UTF-16 or in the encoding their XML declaration names, such as ISO-8859-1, as UTF-8, updating the declaration, instead of in their original encoding")
+	flag.StringVar(&formatOptions.BOM, "bom", BOMPreserve, "How to handle a UTF-8 byte order mark: 'preserve' keeps the one a document has, 'strip' removes it for parsers that fail on it, 'add' writes one; UTF-16 documents keep theirs, and --canonical never writes one")
+	flag.BoolVar(&validateOnly, "validate-only", false, "Only check that each file is well-formed XML, reporting syntax errors with their line and column, without writing anything")
+	flag.BoolVar(&lint, "lint", false, "Also report IDs given to more than one element and references to IDs that no element has, exiting with an error if there are any; id attributes in any case, such as Id, are IDs")
+	lintRefsFlag := flag.String("lint-refs", strings.Join(xmlfmt.DefaultRefAttributes, ","), "Comma-separated attributes that --lint takes as references to IDs, each holding one or more IDs separated by spaces; href attributes starting with '#' always are")
+	flag.BoolVar(&check, "check", false, "List the files whose formatting differs instead of rewriting them, exiting with an error if there are any")
+	flag.BoolVar(&showDiff, "diff", false, "Print a unified diff of the changes formatting would make to each file instead of rewriting them, exiting with an error if there are any (implies --check)")
+	flag.StringVar(&outputDir, "output-dir", "", "Write the formatted files under this directory, keeping their paths relative to each --path, instead of overwriting them")
+	flag.BoolVar(&backup, "backup", false, "Save each original as <name>.xml.bak before rewriting it")
+	flag.StringVar(&backupDir, "backup-dir", "", "Save each original under this directory, keeping its path relative to --path, before rewriting it (implies --backup)")
+	flag.BoolVar(&preserveMtime, "preserve-mtime", false, "Keep the modification time of each file that is rewritten, or give it to the file under --output-dir, so sync jobs do not pick up whitespace-only changes")
+	execFlag := flag.String("exec", "", "Run this command for each file that formatting rewrites or writes under --output-dir, one at a time, with {} replaced by its path or the path added at the end (e.g. 'git add {}'); a file whose command fails is reported as failed")
+	flag.BoolVar(&followSymlinks, "follow-symlinks", false, "Follow symbolic links to files and directories found in directories and globs instead of skipping them; links that lead back to a directory already visited are not followed again")
+	includeFlag := flag.String("include", "", "Comma-separated glob patterns of the files to format in directories and globs, instead of those ending in .xml (e.g. '*.xml,*.config')")
+	flag.BoolVar(&sniff, "sniff", false, "Without --include, also format files found in directories whose content starts like XML, such as .config, .xsl, .svg and extension-less files, not only those ending in .xml")
+	excludeFlag := flag.String("exclude", "", "Comma-separated glob patterns of files and directories to skip; patterns without a slash match names, others match paths relative to --path (e.g. '*.generated.xml,vendor/**')")
+	maxSizeFlag := flag.String("max-size", "0", "Skip files larger than this size, such as 512MB or 2GiB (0 formats files of any size)")
+	flag.IntVar(&workers, "workers", runtime.NumCPU(), "The number of files to format at the same time")
+	flag.BoolVar(&failFast, "fail-fast", false, "Stop after the first file that fails, instead of formatting the rest; files already being formatted are finished")
+	flag.StringVar(&reportFormat, "report", "", "Write a run report in the given format (json), listing the files processed, those that failed and why, and the bytes before and after")
+	flag.StringVar(&reportFile, "report-file", "", "Write the run report to this file instead of stdout")
+	flag.StringVar(&progress, "progress", progressAuto, "Show a progress line with the files done and the current file instead of logging each file: 'auto' when stderr is a terminal and logging is neither verbose nor JSON, 'always' or 'never'")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 
-	if verbose {
-		log.SetLevel(log.DebugLevel)
-		log.SetCallerFormatter(log.LongCallerFormatter)
-		log.SetReportCaller(true)
+	if logErr := logging.Setup(); logErr != nil {
+		return logErr
+	}
+	// The path may also be given as an argument, but standard input always holds a document rather than paths,
+	// as --path takes directories and globs
+	if flag.NArg() > 1 || flag.NArg() == 1 && len(dirPath) > 0 {
+		return fmt.Errorf("expected a single path from --path or an argument, got %d", flag.NArg()+min(len(dirPath), 1))
+	} else if flag.NArg() == 1 {
+		dirPath = flag.Arg(0)
 	}
-	if len(dirPath) == 0 {
-		log.Error("Enter either an absolute path to a directory or a specific XML file")
+	if dirPath == StdinPath || len(dirPath) == 0 && StdinRedirected() {
+		filter = true
+	} else if len(dirPath) == 0 {
+		log.Error("Enter a path to a directory, a specific XML file or a glob pattern, or pipe a document through stdin")
 		flag.Usage()
 		return errors.New("no path provided")
 	}
+	if !slices.Contains(xmlfmt.DoctypeModes, formatOptions.Doctype) {
+		return fmt.Errorf("unknown --doctype '%s', expected one of %s", formatOptions.Doctype, strings.Join(xmlfmt.DoctypeModes, ", "))
+	}
+	if !ValidBOMMode(formatOptions.BOM) {
+		return fmt.Errorf("unknown --bom '%s', expected one of %s", formatOptions.BOM, strings.Join(BOMModes, ", "))
+	}
+	if !slices.Contains(progressModes, progress) {
+		return fmt.Errorf("unknown --progress '%s', expected one of %s", progress, strings.Join(progressModes, ", "))
+	}
+	if !slices.Contains(xmlfmt.SelfCloseModes, formatOptions.SelfClose) {
+		return fmt.Errorf("unknown --self-close '%s', expected one of %s", formatOptions.SelfClose, strings.Join(xmlfmt.SelfCloseModes, ", "))
+	}
+	if formatOptions.Canonical {
+		var conflicts []string
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "indent", "prefix", "minify", "self-close", "sort-attributes", "sort-elements", "max-line-width", "prune-namespaces", "preserve-cdata":
+				conflicts = append(conflicts, "--"+f.Name)
+			}
+		})
+		if len(conflicts) > 0 {
+			return fmt.Errorf("--canonical fixes the output's layout, so it cannot be used with %s", strings.Join(conflicts, ", "))
+		}
+	}
+	if formatOptions.MaxLineWidth < 0 {
+		return fmt.Errorf("--max-line-width must not be negative, got %d", formatOptions.MaxLineWidth)
+	} else if formatOptions.MaxLineWidth > 0 && formatOptions.Minify {
+		return errors.New("--max-line-width cannot be used with --minify, which writes each document on one line")
+	}
+	if formatOptions.Lenient && (formatOptions.Canonical || validateOnly || formatOptions.PruneNamespaces) {
+		return errors.New("--lenient cannot be used with --canonical, --validate-only or --prune-namespaces, which need well-formed XML")
+	}
+	for _, ref := range strings.Split(*lintRefsFlag, ",") {
+		if ref = strings.TrimSpace(ref); len(ref) > 0 {
+			lintRefs = append(lintRefs, ref)
+		}
+	}
+	var sortErr error
+	if formatOptions.SortElements, sortErr = xmlfmt.ParseSortKeys(*sortFlag); sortErr != nil {
+		return fmt.Errorf("--sort-elements: %w", sortErr)
+	}
+	var patternErr error
+	if includePatterns, patternErr = parsePatterns(*includeFlag); patternErr != nil {
+		return fmt.Errorf("--include: %w", patternErr)
+	}
+	if excludePatterns, patternErr = parsePatterns(*excludeFlag); patternErr != nil {
+		return fmt.Errorf("--exclude: %w", patternErr)
+	}
+	var sizeErr error
+	if maxSize, sizeErr = ParseByteSize(*maxSizeFlag); sizeErr != nil {
+		return fmt.Errorf("invalid --max-size: %w", sizeErr)
+	}
+	if workers < 1 {
+		return fmt.Errorf("--workers must be at least 1, got %d", workers)
+	}
+	if len(backupDir) > 0 {
+		backup = true
+	}
+	if showDiff {
+		check = true
+	}
+	if validateOnly && (check || backup || len(outputDir) > 0) {
+		return errors.New("--validate-only cannot be used with --check, --backup or --output-dir")
+	}
+	if backup && (check || filter || len(outputDir) > 0) {
+		return errors.New("--backup only applies when files are rewritten in place, not with --check, --output-dir or stdin")
+	}
+	if preserveMtime && (check || validateOnly || filter) {
+		return errors.New("--preserve-mtime only applies when files are written, not with --check, --validate-only or stdin")
+	}
+	if len(*execFlag) > 0 {
+		if check || validateOnly || filter {
+			return errors.New("--exec only applies when files are written, not with --check, --validate-only or stdin")
+		}
+		var execErr error
+		if execCommand, execErr = splitCommand(*execFlag); execErr != nil {
+			return fmt.Errorf("--exec: %w", execErr)
+		}
+		if _, execErr = exec.LookPath(execCommand[0]); execErr != nil {
+			return fmt.Errorf("--exec: %w", execErr)
+		}
+	}
+	if len(outputDir) > 0 && (check || filter) {
+		return errors.New("--output-dir cannot be used with --check or when formatting stdin")
+	}
+	if !ValidReportFormat(reportFormat) {
+		return fmt.Errorf("unsupported report format '%s'", reportFormat)
+	}
+	if len(reportFormat) > 0 && (reportFile == "" || reportFile == "-") && (check || filter) {
+		return errors.New("--check and stdin formatting already write to stdout, so --report needs --report-file")
+	}
+	if formatOptions.Minify && len(formatOptions.Prefix) > 0 {
+		return errors.New("--prefix cannot be used with --minify")
+	}
+	var indentErr error
+	if formatOptions.Indent, indentErr = parseIndent(*indentFlag); indentErr != nil {
+		return indentErr
+	}
 	return nil
 }
 
+// parseIndent converts the value of --indent, either "tab" or a number of spaces, into the indentation string.
+func parseIndent(value string) (string, error) {
+	if strings.EqualFold(value, "tab") {
+		return "\t", nil
+	}
+	spaces, err := strconv.Atoi(value)
+	if err != nil || spaces < 0 || spaces > 16 {
+		return "", fmt.Errorf("invalid --indent '%s', expected 'tab' or a number of spaces from 0 to 16", value)
+	}
+	return strings.Repeat(" ", spaces), nil
+}
+
+// stdinName stands for standard input wherever a file name is written.
+const stdinName = "<standard input>"
+
 func main() {
-	defer func(startTime time.Time) {
-		log.Debug("TIME!", "execution time", time.Since(startTime))
-	}(time.Now())
+	startTime := time.Now()
+	var report RunReport
+	processingErr := run(&report)
+	report.Finish(startTime, processingErr.Code)
+	if reportErr := WriteReport(report, reportFormat, reportFile); reportErr != nil {
+		log.Error("Failed to write run report", "error", reportErr)
+	}
+	log.Debug("TIME!", "execution time", time.Since(startTime))
+	processingErr.Exit()
+}
 
+// run formats what the command line names, recording the outcome in report, and returns the ErrMsg
+// that main exits with.
+func run(report *RunReport) ErrMsg {
 	if argErr := parseArgs(); argErr != nil {
-		log.Error(argErr)
-		return
+		// The arguments asking for a report may be the ones at fault, so none is written
+		reportFormat = ""
+		return ErrMsg{Err: argErr, Code: ErrInvalidArgs}
+	}
+	report.File = dirPath
+	if filter {
+		report.File = StdinPath
+		return formatStream(os.Stdin, os.Stdout, report)
 	}
 	xmlFiles, dirErr := prepareXMLFiles()
 	if dirErr != nil {
-		log.Error(dirErr)
-		return
+		return ErrMsg{Err: dirErr, Code: ErrNoFile}
 	}
 
-	processFilesConcurrently(xmlFiles)
+	// A batch exits with ErrAllFailed if no file could be formatted and ErrPartialFailure if only some could,
+	// so scripts can tell the two apart; failures take precedence over the issues --lint finds, and those over
+	// the differences --check reports.
+	changed, failed, linted := processFilesConcurrently(xmlFiles, report)
+	switch {
+	case validateOnly && failed > 0:
+		return ErrMsg{Err: fmt.Errorf("%d of %d files are not well-formed", failed, len(xmlFiles)), Code: ErrValidation}
+	case failed == len(xmlFiles):
+		return ErrMsg{Err: fmt.Errorf("all %d files failed", failed), Code: ErrAllFailed}
+	case failed > 0:
+		return ErrMsg{Err: fmt.Errorf("%d of %d files failed", failed, len(xmlFiles)), Code: ErrPartialFailure}
+	case linted > 0:
+		return ErrMsg{Err: fmt.Errorf("%d of %d files have lint issues", linted, len(xmlFiles)), Code: ErrValidation}
+	case check && changed > 0:
+		return ErrMsg{Err: fmt.Errorf("%d of %d files are not formatted", changed, len(xmlFiles)), Code: ErrDifferences}
+	}
+	return ErrMsg{Code: Success}
 }
 
+// formatStream formats the document read from r and writes it to w, so the tool can be used as a filter.
+// The document is streamed, so output may already have been written when a fault is found late in a large one.
+// With --check nothing is formatted; "<standard input>" is written if the document is not formatted,
+// or the diff of the changes formatting would make with --diff,
+// for which the document is read into memory to compare it with the formatted version.
+// With --lint the document is also read into memory, to be linted before it is formatted; its issues take
+// precedence over the differences --check finds, as in a batch.
+// The outcome is recorded in report as a run over a single file.
+func formatStream(r io.Reader, w io.Writer, report *RunReport) (processingErr ErrMsg) {
+	input := &countingReader{reader: r}
+	report.FilesProcessed = 1
+	var issues []xmlfmt.LintIssue
+	defer func() {
+		// Deferred first, so the document is recorded in report as formatted even with lint issues
+		if len(issues) > 0 && (processingErr.Code == Success || processingErr.Code == ErrDifferences) {
+			processingErr = ErrMsg{Err: fmt.Errorf("%d lint issues found", len(issues)), Code: ErrValidation}
+		}
+	}()
+	defer func() {
+		report.BytesBefore = input.n
+		if processingErr.Err != nil {
+			report.FilesFailed = 1
+			report.Failures = []FileFailure{{File: stdinName, Error: processingErr.Err.Error()}}
+		} else {
+			report.FilesSucceeded = 1
+		}
+	}()
+	if lint {
+		data, readErr := io.ReadAll(input)
+		if readErr != nil {
+			return ErrMsg{Err: readErr, Code: ErrStdin}
+		}
+		var lintErr error
+		if issues, lintErr = xmlfmt.Lint(bytes.NewReader(data), formatOptions, lintRefs); lintErr != nil {
+			return ErrMsg{Err: lintErr, Code: ErrParse}
+		}
+		logIssues(stdinName, issues)
+		input = &countingReader{reader: bytes.NewReader(data)}
+	}
+	if validateOnly {
+		validateErr := xmlfmt.Validate(bufio.NewReader(input), formatOptions)
+		report.BytesAfter = input.n
+		if input.err != nil {
+			return ErrMsg{Err: input.err, Code: ErrStdin}
+		} else if validateErr != nil {
+			return ErrMsg{Err: validateErr, Code: ErrValidation}
+		}
+		return ErrMsg{Code: Success}
+	}
+	if check {
+		original, readErr := io.ReadAll(input)
+		if readErr != nil {
+			return ErrMsg{Err: readErr, Code: ErrStdin}
+		}
+		buf, formatErr := formatDocument(bytes.NewReader(original), stdinName)
+		if formatErr != nil {
+			return ErrMsg{Err: formatErr, Code: ErrParse}
+		}
+		report.BytesAfter = int64(buf.Len())
+		if bytes.Equal(original, buf.Bytes()) {
+			return ErrMsg{Code: Success}
+		}
+		line := stdinName + "\n"
+		if showDiff {
+			var diffErr error
+			if line, diffErr = documentDiff(stdinName, original, buf.Bytes()); diffErr != nil {
+				return ErrMsg{Err: diffErr, Code: ErrParse}
+			}
+		}
+		if _, writeErr := io.WriteString(w, line); writeErr != nil {
+			return ErrMsg{Err: writeErr, Code: ErrStdout}
+		}
+		return ErrMsg{Code: ErrDifferences}
+	}
+
+	writer := bufio.NewWriter(w)
+	output := &countingWriter{writer: writer}
+	formatErr := formatTo(input, output, stdinName)
+	if formatErr == nil {
+		formatErr = writer.Flush()
+		output.err = formatErr
+	}
+	report.BytesAfter = output.n
+	switch {
+	case input.err != nil:
+		return ErrMsg{Err: input.err, Code: ErrStdin}
+	case output.err != nil:
+		return ErrMsg{Err: output.err, Code: ErrStdout}
+	case formatErr != nil:
+		return ErrMsg{Err: formatErr, Code: ErrParse}
+	}
+	return ErrMsg{Code: Success}
+}
+
+// prepareXMLFiles resolves the comma-separated targets given by --path into the files to format.
+// Each target may be a directory, whose .xml files are formatted, a single XML file, or a glob pattern
+// such as configs/**/*.xml. Files named by more than one target, or reached through symbolic links,
+// are only formatted once.
 func prepareXMLFiles() ([]TargetFile, error) {
 	var xmlFiles []TargetFile
-	dirInfo, dirErr := os.Stat(dirPath)
-	if dirErr != nil {
-		return nil, dirErr
+	seen := make(map[string]bool)
+	for _, target := range strings.Split(dirPath, ",") {
+		target = strings.TrimSpace(target)
+		if len(target) == 0 {
+			continue
+		}
+		root, paths, targetErr := resolveTarget(target)
+		if targetErr != nil {
+			return nil, targetErr
+		}
+		for _, path := range paths {
+			// Files reached through several links are formatted once, as writing one file twice at once would race
+			realPath, evalErr := filepath.EvalSymlinks(path)
+			if evalErr != nil {
+				return nil, evalErr
+			}
+			if seen[realPath] {
+				continue
+			}
+			seen[realPath] = true
+			rel, relErr := filepath.Rel(root, path)
+			if relErr != nil {
+				return nil, relErr
+			}
+			xmlFiles = append(xmlFiles, TargetFile{Path: path, Rel: rel})
+		}
 	}
-	dirPath = filepath.Clean(dirPath)
+	if len(xmlFiles) == 0 {
+		return nil, fmt.Errorf("no XML files found for '%s'", dirPath)
+	}
+	return xmlFiles, nil
+}
 
-	if dirInfo.IsDir() {
-		log.Info("Processing XML files in directory", "path", dirPath)
-		files, err := os.ReadDir(dirPath)
+// resolveTarget returns the files named by a single --path target, along with the directory
+// their relative paths are kept from under --output-dir. Files are narrowed by --include and --exclude.
+func resolveTarget(target string) (string, []string, error) {
+	if hasGlobMeta(target) {
+		log.Info("Processing XML files matching pattern", "pattern", target)
+		return globFiles(target)
+	}
+	target = filepath.Clean(target)
+	info, statErr := os.Stat(target)
+	if statErr != nil {
+		return "", nil, statErr
+	}
+	root := filepath.Dir(target)
+	var paths []string
+	if info.IsDir() {
+		root = target
+		log.Info("Processing XML files in directory", "path", target)
+		files, err := os.ReadDir(target)
 		if err != nil {
-			return nil, err
+			return "", nil, err
 		}
 		for _, file := range files {
-			if strings.HasSuffix(file.Name(), ".xml") {
-				xmlFiles = append(
-					xmlFiles,
-					TargetFile{Path: filepath.Join(dirPath, file.Name())},
-				)
+			current := filepath.Join(target, file.Name())
+			if mode, ok := entryType(current, file); ok && mode.IsRegular() && isSelected(target, current, false) {
+				paths = append(paths, current)
 			}
 		}
-	} else if strings.HasSuffix(dirPath, ".xml") {
-		log.Info("Processing XML file", "path", dirPath)
-		xmlFiles = append(xmlFiles, TargetFile{Path: dirPath})
+	} else if isSelected(root, target, false) {
+		log.Info("Processing XML file", "path", target)
+		paths = append(paths, target)
+	} else {
+		log.Warn("Skipping file that is excluded or not recognised as XML", "path", target)
 	}
-	return xmlFiles, nil
+	return root, paths, nil
 }
 
-func processFilesConcurrently(xmlFiles []TargetFile) {
+// processFilesConcurrently formats the files with a pool of --workers goroutines, logging the outcome of each,
+// and returns the number of files whose content changed, or would change with --check, and the number that failed.
+// With --check, the paths of those files are printed to stdout in sorted order, or their diffs with --diff.
+// The outcome of every file is added to report. While a progress line is shown, files that succeed are only
+// logged at debug level, and the line is cleared before any warning or error is logged.
+// With --fail-fast, no more files are handed out once one has failed. The issues --lint finds are logged as
+// warnings, and the number of files with any is returned as well.
+func processFilesConcurrently(xmlFiles []TargetFile, report *RunReport) (changed, failed, linted int) {
 	result := make(chan *TargetFile, len(xmlFiles))
-	defer func(res chan *TargetFile) {
-		for r := range res {
-			if r.Err != nil {
-				log.Error(
-					"Error formatting XML file",
-					"file name", filepath.Base(r.Path),
-					"error", r.Err,
-				)
-			} else {
-				log.Info(
-					"XML file formatted successfully",
-					"file name", filepath.Base(r.Path),
-				)
-			}
-		}
-	}(result)
+	progressLine := newProgress(len(xmlFiles))
+	logFile := log.Info
+	if progressLine != nil {
+		logFile = log.Debug
+	}
+
+	jobs := make(chan *TargetFile)
+	stop := make(chan struct{})
+	var stopOnce sync.Once
 
 	var wg sync.WaitGroup
 	wg.Add(len(xmlFiles))
 
-	for i := 0; i < len(xmlFiles); i++ {
-		log.Info(
-			"Processing file",
-			"file name", filepath.Base(xmlFiles[i].Path),
-		)
-		go formatXmlFile(&xmlFiles[i], result, &wg)
+	for worker := 0; worker < min(workers, len(xmlFiles)); worker++ {
+		go func() {
+			for target := range jobs {
+				select {
+				case <-stop:
+					// Handed out before --fail-fast stopped the run, but not yet started
+					wg.Done()
+					continue
+				default:
+				}
+				if progressLine != nil {
+					progressLine.start(target.Path)
+				} else {
+					log.Info(
+						"Processing file",
+						"file name", filepath.Base(target.Path),
+					)
+				}
+				formatXmlFile(target, result, &wg)
+				if failFast && target.Err != nil {
+					stopOnce.Do(func() {
+						close(stop)
+					})
+				}
+			}
+		}()
 	}
+	go func() {
+		defer close(jobs)
+		for i := 0; i < len(xmlFiles); i++ {
+			select {
+			case jobs <- &xmlFiles[i]:
+			case <-stop:
+				// The files never handed out are done as far as the results are concerned
+				wg.Add(i - len(xmlFiles))
+				return
+			}
+		}
+	}()
 	go func() {
 		wg.Wait()
 		close(result)
 	}()
+
+	var unformatted []*TargetFile
+	stopped := false
+	for r := range result {
+		if progressLine != nil && (r.Err != nil || r.Skipped || len(r.Issues) > 0) {
+			progressLine.clear()
+		}
+		if len(r.Issues) > 0 {
+			linted++
+			logIssues(r.Path, r.Issues)
+		}
+		report.FilesProcessed++
+		report.BytesBefore += r.BytesBefore
+		report.BytesAfter += r.BytesAfter
+		switch {
+		case r.Err != nil:
+			report.FilesFailed++
+			report.Failures = append(report.Failures, FileFailure{File: r.Path, Error: r.Err.Error()})
+		case r.Skipped:
+			report.FilesSkipped++
+		default:
+			report.FilesSucceeded++
+		}
+		switch {
+		case r.Skipped:
+			log.Warn(
+				"Skipping file larger than --max-size",
+				"file name", filepath.Base(r.Path),
+				"size", r.BytesBefore,
+			)
+		case r.Err != nil && validateOnly:
+			failed++
+			log.Error(
+				"XML file is not well-formed",
+				"file name", filepath.Base(r.Path),
+				"error", r.Err,
+			)
+		case r.Err != nil:
+			failed++
+			log.Error(
+				"Error formatting XML file",
+				"file name", filepath.Base(r.Path),
+				"error", r.Err,
+			)
+		case validateOnly:
+			logFile(
+				"XML file is well-formed",
+				"file name", filepath.Base(r.Path),
+			)
+		case check && r.Changed:
+			changed++
+			unformatted = append(unformatted, r)
+		case check:
+			log.Debug(
+				"XML file is already formatted",
+				"file name", filepath.Base(r.Path),
+			)
+		default:
+			if r.Changed {
+				changed++
+			}
+			logFile(
+				"XML file formatted successfully",
+				"file name", filepath.Base(r.Path),
+			)
+		}
+		if failFast && r.Err != nil && !stopped {
+			stopped = true
+			log.Warn("Stopping after the first failure, as --fail-fast is set")
+		}
+		if progressLine != nil {
+			progressLine.finish()
+		}
+	}
+	if progressLine != nil {
+		progressLine.clear()
+	}
+	slices.SortFunc(report.Failures, func(a, b FileFailure) int {
+		return strings.Compare(a.File, b.File)
+	})
+	slices.SortFunc(unformatted, func(a, b *TargetFile) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	for _, target := range unformatted {
+		if showDiff {
+			fmt.Print(target.Diff)
+		} else {
+			fmt.Println(target.Path)
+		}
+	}
+	return changed, failed, linted
 }
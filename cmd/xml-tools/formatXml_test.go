@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/xmlfmt"
+)
+
+func TestParseIndent(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "tab", want: "\t"},
+		{value: "TAB", want: "\t"},
+		{value: "2", want: "  "},
+		{value: "0", want: ""},
+		{value: "17", wantErr: true},
+		{value: "-1", wantErr: true},
+		{value: "two", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := parseIndent(test.value)
+		if test.wantErr != (err != nil) || got != test.want {
+			t.Errorf("parseIndent(%q) = %q, %v, want %q, error %v", test.value, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+// unformatted is a document formatTarget changes, and formatted what it changes it to.
+const (
+	unformatted = "<a><b>1</b></a>"
+	formatted   = "<a>\n\t<b>1</b>\n</a>"
+)
+
+func TestFormatTarget(t *testing.T) {
+	tests := []struct {
+		name string
+		// set changes the flags for the test, which are reset afterwards
+		set         func(dir string)
+		content     string
+		wantErr     string
+		wantChanged bool
+		wantSkipped bool
+		// wantFiles maps paths relative to the test's directory to their expected content
+		wantFiles map[string]string
+	}{
+		{
+			name:        "Tab",
+			content:     unformatted,
+			wantChanged: true,
+			wantFiles:   map[string]string{"doc.xml": formatted},
+		},
+		{
+			name:        "Spaces",
+			set:         func(string) { formatOptions.Indent = "  " },
+			content:     "<a><b><c>1</c></b></a>",
+			wantChanged: true,
+			wantFiles:   map[string]string{"doc.xml": "<a>\n  <b>\n    <c>1</c>\n  </b>\n</a>"},
+		},
+		{
+			name:        "Prefix",
+			set:         func(string) { formatOptions.Prefix = "> " },
+			content:     unformatted,
+			wantChanged: true,
+			wantFiles:   map[string]string{"doc.xml": "> <a>\n> \t<b>1</b>\n> </a>"},
+		},
+		{
+			name:      "AlreadyFormatted",
+			content:   formatted,
+			wantFiles: map[string]string{"doc.xml": formatted},
+		},
+		{
+			name:        "Check",
+			set:         func(string) { check = true },
+			content:     unformatted,
+			wantChanged: true,
+			wantFiles:   map[string]string{"doc.xml": unformatted},
+		},
+		{
+			name:        "Diff",
+			set:         func(string) { check, showDiff = true, true },
+			content:     unformatted,
+			wantChanged: true,
+			wantFiles:   map[string]string{"doc.xml": unformatted},
+		},
+		{
+			name:        "Minify",
+			set:         func(string) { formatOptions.Minify = true },
+			content:     formatted,
+			wantChanged: true,
+			wantFiles:   map[string]string{"doc.xml": unformatted},
+		},
+		{
+			name:        "OutputDir",
+			set:         func(dir string) { outputDir = filepath.Join(dir, "out") },
+			content:     unformatted,
+			wantChanged: true,
+			wantFiles:   map[string]string{"doc.xml": unformatted, "out/doc.xml": formatted},
+		},
+		{
+			name:        "Backup",
+			set:         func(string) { backup = true },
+			content:     unformatted,
+			wantChanged: true,
+			wantFiles:   map[string]string{"doc.xml": formatted, "doc.xml.bak": unformatted},
+		},
+		{
+			name:        "BackupDir",
+			set:         func(dir string) { backup, backupDir = true, filepath.Join(dir, "backups") },
+			content:     unformatted,
+			wantChanged: true,
+			wantFiles:   map[string]string{"doc.xml": formatted, "backups/doc.xml": unformatted},
+		},
+		{
+			name:        "MaxSize",
+			set:         func(string) { maxSize = 4 },
+			content:     unformatted,
+			wantSkipped: true,
+			wantFiles:   map[string]string{"doc.xml": unformatted},
+		},
+		{
+			name:      "Validate",
+			set:       func(string) { validateOnly = true },
+			content:   "<a><b></a>",
+			wantErr:   "element <b> closed by </a>",
+			wantFiles: map[string]string{"doc.xml": "<a><b></a>"},
+		},
+		{
+			name:      "NotWellFormed",
+			content:   "<a><b></a>",
+			wantErr:   "element <b> closed by </a>",
+			wantFiles: map[string]string{"doc.xml": "<a><b></a>"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "doc.xml")
+			if err := os.WriteFile(path, []byte(test.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				check, showDiff, outputDir, backup, backupDir, maxSize, validateOnly = false, false, "", false, "", 0, false
+				formatOptions = xmlfmt.DefaultOptions()
+			}()
+			if test.set != nil {
+				test.set(dir)
+			}
+
+			target := TargetFile{Path: path, Rel: "doc.xml"}
+			err := formatTarget(&target)
+			if len(test.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Errorf("formatTarget() error = %v, want one containing %q", err, test.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("formatTarget(): %v", err)
+			}
+			if target.Changed != test.wantChanged || target.Skipped != test.wantSkipped {
+				t.Errorf("Changed, Skipped = %v, %v, want %v, %v", target.Changed, target.Skipped, test.wantChanged, test.wantSkipped)
+			}
+			if showDiff && !strings.Contains(target.Diff, "+\t<b>1</b>") {
+				t.Errorf("Diff = %q, want the indented line added", target.Diff)
+			}
+			for rel, want := range test.wantFiles {
+				content, readErr := os.ReadFile(filepath.Join(dir, rel))
+				if readErr != nil {
+					t.Errorf("reading %s: %v", rel, readErr)
+				} else if string(content) != want {
+					t.Errorf("%s = %q, want %q", rel, content, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatTargetPreserveMtime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doc.xml")
+	if err := os.WriteFile(path, []byte(unformatted), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	preserveMtime = true
+	defer func() { preserveMtime = false }()
+
+	if err := formatTarget(&TargetFile{Path: path, Rel: "doc.xml"}); err != nil {
+		t.Fatal(err)
+	}
+	info, statErr := os.Stat(path)
+	if statErr != nil {
+		t.Fatal(statErr)
+	}
+	if !info.ModTime().Equal(modTime) {
+		t.Errorf("ModTime() = %v, want %v", info.ModTime(), modTime)
+	}
+}
+
+func TestFormatStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		check    bool
+		input    string
+		want     string
+		wantCode int
+	}{
+		{name: "Format", input: unformatted, want: formatted, wantCode: Success},
+		{name: "CheckFormatted", check: true, input: formatted, want: "", wantCode: Success},
+		{name: "CheckUnformatted", check: true, input: unformatted, want: "<standard input>\n", wantCode: ErrDifferences},
+		{name: "NotWellFormed", input: "<a><b></a>", want: "", wantCode: ErrParse},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			check = test.check
+			defer func() { check = false }()
+
+			var out bytes.Buffer
+			var report RunReport
+			if got := formatStream(strings.NewReader(test.input), &out, &report); got.Code != test.wantCode {
+				t.Errorf("formatStream() = %v, want code %d", got, test.wantCode)
+			}
+			if failed := test.wantCode == ErrParse; report.FilesProcessed != 1 || (report.FilesFailed == 1) != failed {
+				t.Errorf("report = %+v, want one file processed, failed %v", report, failed)
+			}
+			if out.String() != test.want {
+				t.Errorf("output = %q, want %q", out.String(), test.want)
+			}
+		})
+	}
+}
+
+func TestProcessFilesConcurrently(t *testing.T) {
+	dir := t.TempDir()
+	var xmlFiles []TargetFile
+	for i, content := range []string{unformatted, formatted, unformatted, unformatted, "<a><b></a>"} {
+		rel := string(rune('a'+i)) + ".xml"
+		path := filepath.Join(dir, rel)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		xmlFiles = append(xmlFiles, TargetFile{Path: path, Rel: rel})
+	}
+	workers = 2
+	defer func() { workers = 0 }()
+
+	var report RunReport
+	if changed, failed, linted := processFilesConcurrently(xmlFiles, &report); changed != 3 || failed != 1 || linted != 0 {
+		t.Errorf("processFilesConcurrently() = %d, %d, %d, want 3, 1, 0", changed, failed, linted)
+	}
+	if report.FilesProcessed != 5 || report.FilesSucceeded != 4 || report.FilesFailed != 1 {
+		t.Errorf("report counts = %d, %d, %d, want 5, 4, 1", report.FilesProcessed, report.FilesSucceeded, report.FilesFailed)
+	}
+	if len(report.Failures) != 1 || report.Failures[0].File != xmlFiles[4].Path {
+		t.Errorf("report.Failures = %+v, want %s", report.Failures, xmlFiles[4].Path)
+	}
+	// The file that failed counts the same size before and after
+	wantBefore := int64(3*len(unformatted) + len(formatted) + len("<a><b></a>"))
+	if wantAfter := int64(4*len(formatted) + len("<a><b></a>")); report.BytesBefore != wantBefore || report.BytesAfter != wantAfter {
+		t.Errorf("report bytes = %d, %d, want %d, %d", report.BytesBefore, report.BytesAfter, wantBefore, wantAfter)
+	}
+	for i, want := range []string{formatted, formatted, formatted, formatted, "<a><b></a>"} {
+		if content, _ := os.ReadFile(xmlFiles[i].Path); string(content) != want {
+			t.Errorf("%s = %q, want %q", xmlFiles[i].Rel, content, want)
+		}
+	}
+	if xmlFiles[4].Err == nil {
+		t.Error("Err is nil for the file that is not well-formed")
+	}
+}
+
+func TestProcessFilesConcurrentlyFailFast(t *testing.T) {
+	dir := t.TempDir()
+	var xmlFiles []TargetFile
+	for i, content := range []string{"<a><b></a>", unformatted, unformatted} {
+		rel := string(rune('a'+i)) + ".xml"
+		path := filepath.Join(dir, rel)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		xmlFiles = append(xmlFiles, TargetFile{Path: path, Rel: rel})
+	}
+	// A single worker makes the order files are taken in, and so where the run stops, certain
+	workers, failFast = 1, true
+	defer func() { workers, failFast = 0, false }()
+
+	var report RunReport
+	if changed, failed, linted := processFilesConcurrently(xmlFiles, &report); changed != 0 || failed != 1 || linted != 0 {
+		t.Errorf("processFilesConcurrently() = %d, %d, %d, want 0, 1, 0", changed, failed, linted)
+	}
+	if report.FilesProcessed != 1 {
+		t.Errorf("FilesProcessed = %d, want 1", report.FilesProcessed)
+	}
+	for _, target := range xmlFiles[1:] {
+		if content, _ := os.ReadFile(target.Path); string(content) != unformatted {
+			t.Errorf("%s was formatted after the run stopped", target.Rel)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	. "GoTools/pkg/helpers"
+)
+
+const xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+
+// field is a key and value of a JSON object.
+type field struct {
+	Key   string
+	Value interface{}
+}
+
+// object is a JSON object with its keys in document order, so elements are written in the order they were read.
+type object []field
+
+// readDocument decodes a single JSON value from r, keeping numbers as written and object keys in order.
+func readDocument(r io.Reader) (interface{}, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	value, err := readValue(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if _, extraErr := decoder.Token(); !errors.Is(extraErr, io.EOF) {
+		return nil, errors.New("unexpected data after the top-level JSON value")
+	}
+	return value, nil
+}
+
+// readValue decodes the next JSON value from decoder, as an object, a slice or a scalar.
+func readValue(decoder *json.Decoder) (interface{}, error) {
+	token, tokenErr := decoder.Token()
+	if tokenErr != nil {
+		if errors.Is(tokenErr, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, tokenErr
+	}
+	delim, isDelim := token.(json.Delim)
+	if !isDelim {
+		return token, nil
+	}
+	switch delim {
+	case '{':
+		result := object{}
+		for decoder.More() {
+			keyToken, keyErr := decoder.Token()
+			if keyErr != nil {
+				return nil, keyErr
+			}
+			value, valueErr := readValue(decoder)
+			if valueErr != nil {
+				return nil, valueErr
+			}
+			result = append(result, field{Key: keyToken.(string), Value: value})
+		}
+		_, closeErr := decoder.Token()
+		return result, closeErr
+	case '[':
+		result := []interface{}{}
+		for decoder.More() {
+			value, valueErr := readValue(decoder)
+			if valueErr != nil {
+				return nil, valueErr
+			}
+			result = append(result, value)
+		}
+		_, closeErr := decoder.Token()
+		return result, closeErr
+	}
+	return nil, fmt.Errorf("unexpected '%s'", delim)
+}
+
+// elementName turns a JSON key into a valid XML name, cleaning it with FixXMLTags and
+// prefixing an underscore when it would otherwise start with a character a name cannot start with.
+func elementName(key string) string {
+	name := FixXMLTags(key)
+	if len(name) == 0 {
+		return "_"
+	}
+	first := []rune(name)[0]
+	if !unicode.IsLetter(first) && first != '_' {
+		return "_" + name
+	}
+	return name
+}
+
+// converter writes JSON values as XML elements following the conversion options.
+type converter struct {
+	opts    options
+	encoder *xml.Encoder
+}
+
+// needsXSI reports whether writing value uses the xsi namespace, for xsi:nil or xsi:type annotations,
+// so that it is only declared on the root element when needed.
+func (c converter) needsXSI(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case json.Number, bool:
+		return c.opts.WithTypes
+	case object:
+		for _, f := range v {
+			if !strings.HasPrefix(f.Key, c.opts.AttrPrefix) && c.needsXSI(f.Value) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if c.needsXSI(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// convert writes the document as XML. An object with a single key becomes an element named after that key,
+// the inverse of xml2json, unless a root name is given; any other value is wrapped in the root element,
+// with the items of an array written as item elements, which covers documents holding an array of rows.
+func (c converter) convert(document interface{}) error {
+	name, value := c.opts.Root, document
+	if single, ok := document.(object); ok && len(single) == 1 && len(c.opts.Root) == 0 {
+		if _, isArray := single[0].Value.([]interface{}); !isArray {
+			name, value = single[0].Key, single[0].Value
+		}
+	}
+	if len(name) == 0 {
+		name = defaultRoot
+	}
+	start := xml.StartElement{Name: xml.Name{Local: elementName(name)}}
+	if c.needsXSI(value) {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xmlns:xsi"}, Value: xsiNamespace})
+	}
+	if err := c.writeElement(start, value); err != nil {
+		return err
+	}
+	return c.encoder.Flush()
+}
+
+// writeElement writes value as the content of an element opened by start.
+// Object keys starting with the attribute prefix become attributes and the text key becomes the element's text,
+// while every other key becomes a child element, repeated once per item when its value is an array.
+func (c converter) writeElement(start xml.StartElement, value interface{}) error {
+	var children object
+	var text []interface{}
+	switch v := value.(type) {
+	case nil:
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xsi:nil"}, Value: "true"})
+	case object:
+		for _, f := range v {
+			switch {
+			case f.Key == c.opts.TextKey:
+				text = append(text, f.Value)
+			case len(c.opts.AttrPrefix) > 0 && strings.HasPrefix(f.Key, c.opts.AttrPrefix):
+				attrValue, err := scalarText(f.Value)
+				if err != nil {
+					return fmt.Errorf("attribute '%s': %w", f.Key, err)
+				}
+				start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: elementName(strings.TrimPrefix(f.Key, c.opts.AttrPrefix))}, Value: attrValue})
+			default:
+				children = append(children, f)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			children = append(children, field{Key: c.opts.Item, Value: item})
+		}
+	default:
+		if xsiType := c.xsiType(v); len(xsiType) > 0 {
+			start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "xsi:type"}, Value: xsiType})
+		}
+		text = append(text, v)
+	}
+
+	if err := c.encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, t := range text {
+		chars, err := scalarText(t)
+		if err != nil {
+			return fmt.Errorf("element '%s': %w", start.Name.Local, err)
+		}
+		if err := c.encoder.EncodeToken(xml.CharData(chars)); err != nil {
+			return err
+		}
+	}
+	for _, child := range children {
+		items, isArray := child.Value.([]interface{})
+		if !isArray {
+			items = []interface{}{child.Value}
+		}
+		childStart := xml.StartElement{Name: xml.Name{Local: elementName(child.Key)}}
+		for _, item := range items {
+			if err := c.writeElement(childStart, item); err != nil {
+				return err
+			}
+		}
+	}
+	return c.encoder.EncodeToken(start.End())
+}
+
+// xsiType returns the xsi:type annotation for a scalar when types are written, as parse-xml does.
+func (c converter) xsiType(value interface{}) string {
+	if !c.opts.WithTypes {
+		return ""
+	}
+	switch value.(type) {
+	case json.Number:
+		return "xs:double"
+	case bool:
+		return "xs:boolean"
+	}
+	return ""
+}
+
+// scalarText returns the text of a JSON string, number, boolean or null, rejecting objects and arrays,
+// which cannot be written as attribute values or text.
+func scalarText(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		if v {
+			return "true", nil
+		}
+		return "false", nil
+	}
+	return "", errors.New("objects and arrays cannot be written as text")
+}
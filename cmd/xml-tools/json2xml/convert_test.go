@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		options func(*options)
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "SingleKeyRoot",
+			input: `{"Row": {"Name": "Ann", "Age": 42}}`,
+			want:  `<Row><Name>Ann</Name><Age>42</Age></Row>`,
+		},
+		{
+			name:  "ArrayOfRows",
+			input: `[{"Id": 1}, {"Id": 2}]`,
+			want:  `<DataTable><Row><Id>1</Id></Row><Row><Id>2</Id></Row></DataTable>`,
+		},
+		{
+			name:  "RepeatedChild",
+			input: `{"DataTable": {"Row": [{"Id": "1"}, {"Id": "2"}]}}`,
+			want:  `<DataTable><Row><Id>1</Id></Row><Row><Id>2</Id></Row></DataTable>`,
+		},
+		{
+			name:    "OwnRootAndItem",
+			input:   `["a", "b"]`,
+			options: func(opts *options) { opts.Root, opts.Item = "List", "Entry" },
+			want:    `<List><Entry>a</Entry><Entry>b</Entry></List>`,
+		},
+		{
+			name:  "AttributesAndText",
+			input: `{"Cell": {"@ref": "A1", "#text": "Total"}}`,
+			want:  `<Cell ref="A1">Total</Cell>`,
+		},
+		{
+			name:  "Null",
+			input: `{"Row": {"A": null}}`,
+			want:  `<Row xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"><A xsi:nil="true"></A></Row>`,
+		},
+		{
+			name:    "WithTypes",
+			input:   `{"Row": {"A": 1.5, "B": true, "C": "x"}}`,
+			options: func(opts *options) { opts.WithTypes = true },
+			want:    `<Row xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"><A xsi:type="xs:double">1.5</A><B xsi:type="xs:boolean">true</B><C>x</C></Row>`,
+		},
+		{
+			name:  "InvalidNames",
+			input: `{"Row": {"Order Date": "x", "1st": "y"}}`,
+			want:  `<Row><Order_x0020_Date>x</Order_x0020_Date><_1st>y</_1st></Row>`,
+		},
+		{
+			name:    "ObjectAttribute",
+			input:   `{"Row": {"@a": {"b": 1}}}`,
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := options{Item: defaultItem, AttrPrefix: "@", TextKey: "#text"}
+			if test.options != nil {
+				test.options(&opts)
+			}
+			document, readErr := readDocument(strings.NewReader(test.input))
+			if readErr != nil {
+				t.Fatal(readErr)
+			}
+			var buf bytes.Buffer
+			err := converter{opts: opts, encoder: xml.NewEncoder(&buf)}.convert(document)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("convert() = %s, want an error", buf.String())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != test.want {
+				t.Errorf("convert() = %s, want %s", buf.String(), test.want)
+			}
+		})
+	}
+}
+
+func TestReadDocumentErrors(t *testing.T) {
+	for _, input := range []string{"", `{"a": 1`, `{"a": 1} {"b": 2}`} {
+		if _, err := readDocument(strings.NewReader(input)); err == nil {
+			t.Errorf("readDocument(%q) succeeded, want an error", input)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// This program converts JSON documents, such as an array of row objects, into XML,
+// naming elements after the JSON keys cleaned with FixXMLTags.
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
+	"github.com/charmbracelet/log"
+)
+
+const (
+	defaultRoot = "DataTable"
+	defaultItem = "Row"
+)
+
+// options holds the command line settings controlling the conversion.
+type options struct {
+	Input      Input
+	Output     string
+	Force      bool
+	Root       string
+	Item       string
+	AttrPrefix string
+	TextKey    string
+	WithTypes  bool
+	Compact    bool
+	BOM        string
+}
+
+func main() {
+	startTime := time.Now()
+
+	processingErr := ErrMsg{Code: Success}
+	defer func() {
+		log.Debug(
+			"DONE!",
+			"time", time.Since(startTime),
+		)
+		processingErr.Exit()
+	}()
+	opts, argErr := getInput()
+	if argErr != nil {
+		processingErr = *argErr
+		return
+	}
+	processingErr = processDocument(opts)
+}
+
+// getInput parses the command line flags. The file is given with --path or as an argument, falling back to
+// a path piped through standard input.
+func getInput() (options, *ErrMsg) {
+	var opts options
+	var path string
+	flag.StringVar(&path, "path", "", "The path to the JSON file to convert, or - to read the JSON from standard input")
+	flag.StringVar(&opts.Output, "output", "", "Write the XML to this file instead of stdout")
+	flag.StringVar(&opts.Output, "o", "", "Shorthand for --output")
+	flag.BoolVar(&opts.Force, "force", false, "Overwrite the --output file if it already exists")
+	flag.StringVar(&opts.Root, "root", "", "The name of the root element (default: the key of a single-key object, otherwise "+defaultRoot+")")
+	flag.StringVar(&opts.Item, "item", defaultItem, "The name of the elements written for the items of an array that has no key, such as a top-level array of rows")
+	flag.StringVar(&opts.AttrPrefix, "attr-prefix", "@", "Keys starting with this prefix are written as attributes rather than child elements")
+	flag.StringVar(&opts.TextKey, "text-key", "#text", "The key holding the text of an element that also has attributes or children")
+	flag.BoolVar(&opts.WithTypes, "with-types", false, "Annotate numbers and booleans with xsi:type, as parse-xml does")
+	flag.BoolVar(&opts.Compact, "compact", false, "Write the XML without indentation")
+	flag.StringVar(&opts.BOM, "bom", BOMPreserve, "Whether the XML starts with a UTF-8 byte order mark: 'preserve' writes one if the JSON had one, 'strip' never does, 'add' always does")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if logErr := logging.Setup(); logErr != nil {
+		return opts, &ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+	}
+
+	input, inputErr := ResolveInput(path, flag.Args())
+	if inputErr != nil {
+		errMsg := ToErrMsg(inputErr, ErrNoInput)
+		return opts, &errMsg
+	}
+	opts.Input = input
+	if len(strings.TrimSpace(opts.Item)) == 0 {
+		return opts, &ErrMsg{Err: errors.New("--item must not be empty"), Code: ErrInvalidArgs}
+	}
+	if len(opts.TextKey) == 0 {
+		return opts, &ErrMsg{Err: errors.New("--text-key must not be empty"), Code: ErrInvalidArgs}
+	}
+	if !ValidBOMMode(opts.BOM) {
+		return opts, &ErrMsg{Err: fmt.Errorf("unknown --bom '%s', expected one of %s", opts.BOM, strings.Join(BOMModes, ", ")), Code: ErrInvalidArgs}
+	}
+	return opts, nil
+}
+
+func processDocument(opts options) ErrMsg {
+	if exists, _ := PathExists(opts.Input.Path); !exists && !opts.Input.IsStdin() {
+		return ErrMsg{Err: fmt.Errorf("file '%s' does not exist", opts.Input), Code: ErrNoFile}
+	}
+	if len(opts.Output) > 0 {
+		if exists, _ := PathExists(opts.Output); exists && !opts.Force {
+			return ErrMsg{Err: fmt.Errorf("output file '%s' already exists, use --force to overwrite it", opts.Output), Code: ErrWriteFile}
+		}
+	}
+	input, openErr := opts.Input.Open()
+	if openErr != nil {
+		return ErrMsg{Err: openErr, Code: ErrReadFile}
+	}
+	defer func(input io.ReadCloser) {
+		_ = input.Close()
+	}(input)
+	// The JSON decoder fails on a byte order mark, so it is read separately
+	source, hadBOM, bomErr := SkipBOM(input)
+	if bomErr != nil {
+		return ErrMsg{Err: bomErr, Code: ErrReadFile}
+	}
+	document, readErr := readDocument(source)
+	if readErr != nil {
+		return ErrMsg{Err: readErr, Code: ErrParse}
+	}
+
+	var buf bytes.Buffer
+	_ = WriteBOM(&buf, opts.BOM, hadBOM)
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	if !opts.Compact {
+		encoder.Indent("", "  ")
+	}
+	if convertErr := (converter{opts: opts, encoder: encoder}).convert(document); convertErr != nil {
+		return ErrMsg{Err: convertErr, Code: ErrParse}
+	}
+	buf.WriteByte('\n')
+
+	if len(opts.Output) == 0 {
+		if _, writeErr := os.Stdout.Write(buf.Bytes()); writeErr != nil {
+			return ErrMsg{Err: writeErr, Code: ErrStdout}
+		}
+		return ErrMsg{Code: Success}
+	}
+	if writeErr := AtomicWriteFile(opts.Output, buf.Bytes()); writeErr != nil {
+		return ErrMsg{Err: writeErr, Code: ErrWriteFile}
+	}
+	log.Info("Wrote XML", "output", opts.Output)
+	return ErrMsg{Code: Success}
+}
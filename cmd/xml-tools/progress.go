@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"GoTools/pkg/logging"
+)
+
+const (
+	progressAuto   = "auto"
+	progressAlways = "always"
+	progressNever  = "never"
+)
+
+// progressModes lists the values accepted by the --progress flag.
+var progressModes = []string{progressAuto, progressAlways, progressNever}
+
+const (
+	// progressInterval limits how often the progress line is redrawn.
+	progressInterval = 100 * time.Millisecond
+	progressBarWidth = 20
+	// progressNameWidth is the most characters of the current file's path that are shown.
+	progressNameWidth = 50
+)
+
+// progressLine draws a single line on stderr showing how many files are done out of the total and the file
+// being worked on, redrawn in place as files are processed instead of logging each one.
+type progressLine struct {
+	mu      sync.Mutex
+	out     io.Writer
+	total   int
+	done    int
+	current string
+	drawn   time.Time
+}
+
+// newProgress returns the progress line for a run over total files, or nil if --progress does not call for one.
+// By default the line is shown when stderr is a terminal, logging is neither verbose nor JSON, which the line
+// would break up, and there is more than one file.
+func newProgress(total int) *progressLine {
+	switch progress {
+	case progressNever:
+		return nil
+	case progressAuto:
+		if stderrInfo, statErr := os.Stderr.Stat(); logging.Verbose() || logging.Format() == logging.FormatJSON || total < 2 || statErr != nil || stderrInfo.Mode()&os.ModeCharDevice == 0 {
+			return nil
+		}
+	}
+	return &progressLine{out: os.Stderr, total: total}
+}
+
+// start records that path is being worked on.
+func (p *progressLine) start(path string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.current = path
+	p.draw(false)
+}
+
+// finish records that a file is done.
+func (p *progressLine) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	p.draw(p.done == p.total)
+}
+
+// clear erases the line so a log message can be written in its place; the next update draws it again.
+func (p *progressLine) clear() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, _ = fmt.Fprint(p.out, "\r\x1b[K")
+	p.drawn = time.Time{}
+}
+
+// draw redraws the line, at most once per progressInterval unless force is set.
+func (p *progressLine) draw(force bool) {
+	if !force && time.Since(p.drawn) < progressInterval {
+		return
+	}
+	p.drawn = time.Now()
+	filled := progressBarWidth * p.done / p.total
+	name := p.current
+	if runes := []rune(name); len(runes) > progressNameWidth {
+		name = "…" + string(runes[len(runes)-progressNameWidth+1:])
+	}
+	_, _ = fmt.Fprintf(p.out, "\r\x1b[K[%s%s] %*d/%d %s",
+		strings.Repeat("#", filled), strings.Repeat("-", progressBarWidth-filled),
+		len(fmt.Sprint(p.total)), p.done, p.total, name)
+}
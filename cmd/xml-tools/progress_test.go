@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "GoTools/pkg/helpers"
+)
+
+func TestProgressLine(t *testing.T) {
+	var out bytes.Buffer
+	line := &progressLine{out: &out, total: 4}
+	line.start("a.xml")
+	line.finish()
+	line.start(strings.Repeat("d/", 30) + "b.xml")
+	if got, want := out.String(), "\r\x1b[K[--------------------] 0/4 a.xml"; got != want {
+		t.Errorf("first draw = %q, want %q", got, want)
+	}
+
+	// Finishing the last file draws the line straight away
+	out.Reset()
+	line.done = 3
+	line.finish()
+	if got := out.String(); !strings.HasPrefix(got, "\r\x1b[K[####################] 4/4 …") || !strings.HasSuffix(got, "d/b.xml") {
+		t.Errorf("last draw = %q, want a full bar and the end of the path", got)
+	}
+
+	out.Reset()
+	line.clear()
+	if got := out.String(); got != "\r\x1b[K" {
+		t.Errorf("clear() drew %q", got)
+	}
+}
+
+func TestProgressCountsFinishedFiles(t *testing.T) {
+	dir := t.TempDir()
+	var files []TargetFile
+	for _, name := range []string{"a.xml", "b.xml", "c.xml"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("<a><b>1</b></a>"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, TargetFile{Path: path, Rel: name})
+	}
+
+	// The progress line is drawn on stderr
+	stderr, createErr := os.Create(filepath.Join(t.TempDir(), "stderr"))
+	if createErr != nil {
+		t.Fatal(createErr)
+	}
+	savedStderr, savedProgress, savedWorkers := os.Stderr, progress, workers
+	os.Stderr, progress, workers = stderr, progressAlways, 2
+	defer func() {
+		os.Stderr, progress, workers = savedStderr, savedProgress, savedWorkers
+		_ = stderr.Close()
+	}()
+
+	if _, failed, _ := processFilesConcurrently(files, &RunReport{}); failed != 0 {
+		t.Fatalf("processFilesConcurrently() failed %d files", failed)
+	}
+	drawn, readErr := os.ReadFile(stderr.Name())
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	if !strings.Contains(string(drawn), "] 3/3 ") {
+		t.Errorf("progress line never reached 3/3, drew %q", drawn)
+	}
+}
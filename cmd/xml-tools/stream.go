@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// compareWriter checks what is written to it against the content read from original, recording whether
+// they differ and how much was written, so formatted output can be compared with its source without holding
+// either in memory.
+type compareWriter struct {
+	original *bufio.Reader
+	scratch  []byte
+	differs  bool
+	written  int64
+}
+
+func newCompareWriter(original io.Reader) *compareWriter {
+	return &compareWriter{original: bufio.NewReader(original), scratch: make([]byte, 32*1024)}
+}
+
+func (c *compareWriter) Write(p []byte) (int, error) {
+	c.written += int64(len(p))
+	for rest := p; len(rest) > 0 && !c.differs; {
+		chunk := rest[:min(len(rest), len(c.scratch))]
+		n, err := io.ReadFull(c.original, c.scratch[:len(chunk)])
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, err
+		}
+		c.differs = !bytes.Equal(chunk, c.scratch[:n])
+		rest = rest[len(chunk):]
+	}
+	return len(p), nil
+}
+
+// Changed reports whether the output differs from the original, including the original running on past it.
+func (c *compareWriter) Changed() bool {
+	if !c.differs {
+		if _, err := c.original.ReadByte(); !errors.Is(err, io.EOF) {
+			c.differs = true
+		}
+	}
+	return c.differs
+}
+
+// countingReader counts the bytes read through it and keeps the error from reading them, if any,
+// so a failure to read the input can be told apart from a fault in the document.
+type countingReader struct {
+	reader io.Reader
+	n      int64
+	err    error
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.n += int64(n)
+	if err != nil && !errors.Is(err, io.EOF) {
+		c.err = err
+	}
+	return n, err
+}
+
+// countingWriter counts the bytes written through it and keeps the error from writing them, if any.
+type countingWriter struct {
+	writer io.Writer
+	n      int64
+	err    error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.writer.Write(p)
+	c.n += int64(n)
+	if err != nil {
+		c.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"GoTools/pkg/xmlfmt"
+	"github.com/charmbracelet/log"
+)
+
+// hasGlobMeta checks if a path contains any of the characters special to glob patterns.
+func hasGlobMeta(target string) bool {
+	return strings.ContainsAny(target, "*?[")
+}
+
+// globFiles returns the regular files matching pattern, which may use "**" as a path segment to match
+// any number of directories, as in "configs/**/*.xml". Matches are returned in lexical order, along with
+// the deepest directory that the pattern names literally, which every match is inside.
+func globFiles(pattern string) (root string, files []string, err error) {
+	segments := strings.Split(filepath.ToSlash(filepath.Clean(pattern)), "/")
+	for _, segment := range segments {
+		if _, matchErr := path.Match(segment, ""); matchErr != nil {
+			return "", nil, matchErr
+		}
+	}
+	literal := 0
+	for literal < len(segments)-1 && !hasGlobMeta(segments[literal]) {
+		literal++
+	}
+	root = strings.Join(segments[:literal], "/")
+	if len(root) == 0 {
+		root = "."
+		if strings.HasPrefix(filepath.ToSlash(pattern), "/") {
+			root = "/"
+		}
+	}
+	root = filepath.FromSlash(root)
+	rest := segments[literal:]
+
+	if !strings.Contains(pattern, "**") {
+		matches, globErr := filepath.Glob(pattern)
+		if globErr != nil {
+			return root, nil, globErr
+		}
+		for _, match := range matches {
+			if !isSelected(root, match, true) || skipSymlink(match) {
+				continue
+			}
+			if info, statErr := os.Stat(match); statErr == nil && info.Mode().IsRegular() {
+				files = append(files, match)
+			}
+		}
+		return root, files, nil
+	}
+
+	walkErr := walkTree(root, func(current string) error {
+		rel, relErr := filepath.Rel(root, current)
+		if relErr != nil {
+			return relErr
+		}
+		if matchSegments(rest, strings.Split(filepath.ToSlash(rel), "/")) && isSelected(root, current, true) {
+			files = append(files, current)
+		}
+		return nil
+	})
+	return root, files, walkErr
+}
+
+// walkTree calls visit for each regular file below root in lexical order, skipping directories that match
+// an --exclude pattern. Symbolic links are skipped unless --follow-symlinks is set, in which case links to files
+// are visited and links to directories are followed. Each directory is read at most once, however many links
+// lead to it, so a link to one of its own parents cannot make the walk loop.
+func walkTree(root string, visit func(current string) error) error {
+	visited := make(map[string]bool)
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		realDir, evalErr := filepath.EvalSymlinks(dir)
+		if evalErr != nil {
+			return evalErr
+		}
+		if visited[realDir] {
+			log.Warn("Skipping directory already visited, the symbolic links form a cycle or share a target", "path", dir)
+			return nil
+		}
+		visited[realDir] = true
+		entries, readErr := os.ReadDir(dir)
+		if readErr != nil {
+			return readErr
+		}
+		for _, entry := range entries {
+			current := filepath.Join(dir, entry.Name())
+			mode, ok := entryType(current, entry)
+			switch {
+			case !ok:
+				continue
+			case mode.IsDir():
+				if isExcluded(root, current) {
+					continue
+				}
+				if err := walk(current); err != nil {
+					return err
+				}
+			case mode.IsRegular():
+				if err := visit(current); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return walk(root)
+}
+
+// entryType returns the type of a directory entry, or of what it links to if it is a symbolic link and
+// --follow-symlinks is set. It returns false for links that are to be skipped, including broken ones.
+func entryType(current string, entry fs.DirEntry) (fs.FileMode, bool) {
+	if entry.Type()&fs.ModeSymlink == 0 {
+		return entry.Type(), true
+	}
+	if !followSymlinks {
+		log.Debug("Skipping symbolic link, use --follow-symlinks to follow it", "path", current)
+		return 0, false
+	}
+	info, statErr := os.Stat(current)
+	if statErr != nil {
+		log.Warn("Skipping broken symbolic link", "path", current, "error", statErr)
+		return 0, false
+	}
+	return info.Mode().Type(), true
+}
+
+// skipSymlink checks if current is a symbolic link that is to be skipped because --follow-symlinks is not set.
+func skipSymlink(current string) bool {
+	info, statErr := os.Lstat(current)
+	if statErr != nil || info.Mode()&fs.ModeSymlink == 0 || followSymlinks {
+		return false
+	}
+	log.Debug("Skipping symbolic link, use --follow-symlinks to follow it", "path", current)
+	return true
+}
+
+// matchSegments checks if the segments of a slash-separated path match those of a pattern,
+// where a "**" segment matches zero or more path segments.
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for skip := 0; skip <= len(segments); skip++ {
+			if matchSegments(pattern[1:], segments[skip:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	matched, _ := path.Match(pattern[0], segments[0])
+	return matched && matchSegments(pattern[1:], segments[1:])
+}
+
+// parsePatterns splits a comma-separated --include or --exclude value into its glob patterns, checking each is valid.
+func parsePatterns(value string) ([]string, error) {
+	var patterns []string
+	for _, pattern := range strings.Split(value, ",") {
+		pattern = strings.Trim(filepath.ToSlash(strings.TrimSpace(pattern)), "/")
+		if len(pattern) == 0 {
+			continue
+		}
+		for _, segment := range strings.Split(pattern, "/") {
+			if _, matchErr := path.Match(segment, ""); matchErr != nil {
+				return nil, fmt.Errorf("invalid pattern '%s': %w", pattern, matchErr)
+			}
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// matchesPattern checks if the slash-separated path rel matches pattern. A pattern without a slash,
+// such as "*.generated.xml", is matched against the last segment only; other patterns are matched against
+// the whole path, with "**" matching any number of directories, as in "vendor/**".
+func matchesPattern(pattern, rel string) bool {
+	if !strings.Contains(pattern, "/") {
+		matched, _ := path.Match(pattern, path.Base(rel))
+		return matched
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+// isExcluded checks if the file or directory at current, relative to root, matches an --exclude pattern.
+func isExcluded(root, current string) bool {
+	rel, relErr := filepath.Rel(root, current)
+	if relErr != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range excludePatterns {
+		if matchesPattern(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSelected checks if the file at current, found under root, is to be formatted: it must not match an
+// --exclude pattern, and must match an --include pattern if any are given. Without --include, files found
+// in a directory need the .xml extension, or with --sniff to start like an XML document, while those matched
+// by a glob are taken as the glob selected them.
+func isSelected(root, current string, fromGlob bool) bool {
+	if isExcluded(root, current) {
+		return false
+	}
+	if len(includePatterns) == 0 {
+		return fromGlob || strings.HasSuffix(current, ".xml") || (sniff && looksLikeXML(current))
+	}
+	rel, relErr := filepath.Rel(root, current)
+	if relErr != nil {
+		return false
+	}
+	for _, pattern := range includePatterns {
+		if matchesPattern(pattern, filepath.ToSlash(rel)) {
+			return true
+		}
+	}
+	return false
+}
+
+// looksLikeXML checks if the file at current starts like an XML document, for --sniff: after any byte order mark
+// and whitespace, with an XML declaration, comment, DOCTYPE or start tag. Files holding NUL bytes once decoded
+// are taken as binary, and those declaring <!DOCTYPE html> as HTML. A file whose encoding cannot be read is
+// taken as XML, so the error is reported when it is formatted.
+func looksLikeXML(current string) bool {
+	file, openErr := os.Open(current)
+	if openErr != nil {
+		return false
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+	r, _, _, charsetErr := xmlfmt.DecodeCharset(file)
+	if charsetErr != nil {
+		return true
+	}
+	head := make([]byte, xmlfmt.SniffSize)
+	n, _ := io.ReadFull(r, head)
+	head = bytes.TrimLeft(head[:n], " \t\r\n")
+	switch {
+	case len(head) < 2 || head[0] != '<' || bytes.IndexByte(head, 0) >= 0:
+		return false
+	case len(head) >= 14 && strings.EqualFold(string(head[:14]), "<!DOCTYPE html"):
+		log.Debug("Skipping HTML file found by --sniff", "path", current)
+		return false
+	case head[1] == '?' || head[1] == '!':
+		return true
+	}
+	first, _ := utf8.DecodeRune(head[1:])
+	return unicode.IsLetter(first) || first == '_' || first == ':'
+}
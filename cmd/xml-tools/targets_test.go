@@ -0,0 +1,184 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGlobFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, rel := range []string{"a.xml", "b.txt", "configs/c.xml", "configs/deep/d.xml", "other/e.xml"} {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("<a/>"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		pattern  string
+		wantRoot string
+		want     []string
+		wantErr  bool
+	}{
+		{pattern: "*.xml", wantRoot: ".", want: []string{"a.xml"}},
+		{pattern: "configs/*.xml", wantRoot: "configs", want: []string{"configs/c.xml"}},
+		{pattern: "configs/**/*.xml", wantRoot: "configs", want: []string{"configs/c.xml", "configs/deep/d.xml"}},
+		{pattern: "**/*.xml", wantRoot: ".", want: []string{"a.xml", "configs/c.xml", "configs/deep/d.xml", "other/e.xml"}},
+		{pattern: "*/deep", wantRoot: ".", want: nil},
+		{pattern: "[a.xml", wantErr: true},
+	}
+	for _, test := range tests {
+		root, got, err := globFiles(filepath.Join(dir, test.pattern))
+		if test.wantErr != (err != nil) {
+			t.Errorf("globFiles(%q) error = %v, want error %v", test.pattern, err, test.wantErr)
+			continue
+		}
+		if wantRoot := filepath.Join(dir, test.wantRoot); !test.wantErr && root != wantRoot {
+			t.Errorf("globFiles(%q) root = %q, want %q", test.pattern, root, wantRoot)
+		}
+		var rels []string
+		for _, path := range got {
+			rels = append(rels, filepath.ToSlash(strings.TrimPrefix(path, dir+string(filepath.Separator))))
+		}
+		if !slices.Equal(rels, test.want) {
+			t.Errorf("globFiles(%q) = %q, want %q", test.pattern, rels, test.want)
+		}
+	}
+}
+
+func TestGlobFilesSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	realDir := filepath.Join(dir, "real")
+	if err := os.Mkdir(realDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(realDir, "a.xml"), []byte("<a/>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for link, target := range map[string]string{"linked": realDir, "real/loop": realDir, "real/b.xml": filepath.Join(realDir, "a.xml"), "real/broken.xml": filepath.Join(dir, "missing.xml")} {
+		if err := os.Symlink(target, filepath.Join(dir, filepath.FromSlash(link))); err != nil {
+			t.Skipf("creating symbolic links: %v", err)
+		}
+	}
+	tests := []struct {
+		name   string
+		follow bool
+		want   []string
+	}{
+		{name: "Skip", want: []string{"real/a.xml"}},
+		{name: "Follow", follow: true, want: []string{"linked/a.xml", "linked/b.xml"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			followSymlinks = test.follow
+			defer func() { followSymlinks = false }()
+
+			_, got, err := globFiles(filepath.Join(dir, "**", "*.xml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var rels []string
+			for _, path := range got {
+				rels = append(rels, filepath.ToSlash(strings.TrimPrefix(path, dir+string(filepath.Separator))))
+			}
+			if !slices.Equal(rels, test.want) {
+				t.Errorf("globFiles() = %q, want %q", rels, test.want)
+			}
+		})
+	}
+}
+
+func TestMatchSegments(t *testing.T) {
+	tests := []struct {
+		pattern, rel string
+		want         bool
+	}{
+		{"*.xml", "a.xml", true},
+		{"*.xml", "dir/a.xml", false},
+		{"**/*.xml", "a.xml", true},
+		{"**/*.xml", "dir/deep/a.xml", true},
+		{"dir/**", "dir/a/b.xml", true},
+		{"dir/**/a.xml", "dir/a.xml", true},
+		{"dir/**/a.xml", "other/a.xml", false},
+	}
+	for _, test := range tests {
+		if got := matchSegments(strings.Split(test.pattern, "/"), strings.Split(test.rel, "/")); got != test.want {
+			t.Errorf("matchSegments(%q, %q) = %v, want %v", test.pattern, test.rel, got, test.want)
+		}
+	}
+}
+func TestParsePatterns(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{value: "", want: nil},
+		{value: "*.generated.xml, vendor/**/ ,", want: []string{"*.generated.xml", "vendor/**"}},
+		{value: "[a", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := parsePatterns(test.value)
+		if test.wantErr != (err != nil) {
+			t.Errorf("parsePatterns(%q) error = %v, want error %v", test.value, err, test.wantErr)
+			continue
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("parsePatterns(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestMatchesPattern(t *testing.T) {
+	tests := []struct {
+		pattern, rel string
+		want         bool
+	}{
+		{"*.generated.xml", "a.generated.xml", true},
+		{"*.generated.xml", "deep/dir/a.generated.xml", true},
+		{"*.generated.xml", "a.xml", false},
+		{"vendor/**", "vendor/a/b.xml", true},
+		{"vendor/**", "src/vendor/b.xml", false},
+		{"**/test/*.xml", "test/a.xml", true},
+		{"**/test/*.xml", "a/b/test/a.xml", true},
+		{"**/test/*.xml", "a/test/b/a.xml", false},
+	}
+	for _, test := range tests {
+		if got := matchesPattern(test.pattern, test.rel); got != test.want {
+			t.Errorf("matchesPattern(%q, %q) = %v, want %v", test.pattern, test.rel, got, test.want)
+		}
+	}
+}
+
+func TestLooksLikeXML(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"Declaration", "<?xml version=\"1.0\"?><a/>", true},
+		{"Element", "\ufeff\n  <root/>", true},
+		{"Comment", "<!-- c --><a/>", true},
+		{"HTML", "<!DOCTYPE html><html></html>", false},
+		{"Text", "name,value\n", false},
+		{"Binary", "<\x00\x01\x02", false},
+		{"LessThan", "< 3", false},
+	}
+	dir := t.TempDir()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(dir, test.name)
+			if err := os.WriteFile(path, []byte(test.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if got := looksLikeXML(path); got != test.want {
+				t.Errorf("looksLikeXML(%q) = %v, want %v", test.content, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	elementAdded     = "element-added"
+	elementRemoved   = "element-removed"
+	attributeAdded   = "attribute-added"
+	attributeRemoved = "attribute-removed"
+	attributeChanged = "attribute-changed"
+	textChanged      = "text-changed"
+)
+
+// Difference is a single change between the old and the new document. Path locates the changed node,
+// in the new document except for removals where it refers to the old one, one segment per step.
+type Difference struct {
+	Kind string   `json:"kind"`
+	Path []string `json:"path"`
+	Old  string   `json:"old,omitempty"`
+	New  string   `json:"new,omitempty"`
+}
+
+// element is an element of a compared document. Names are compared with their namespace resolved,
+// so a change of prefix alone is not a difference, and Text holds the element's own text with the
+// surrounding whitespace, which only lays the document out, removed.
+type element struct {
+	Name     xml.Name
+	Attrs    []xml.Attr
+	Text     string
+	Children []*element
+}
+
+// readDocument decodes the XML read from r into a tree of elements, returning its root.
+// Comments, processing instructions and namespace declarations are left out, as they do not change the data.
+func readDocument(r io.Reader) (*element, error) {
+	decoder := xml.NewDecoder(r)
+	var root *element
+	var stack []*element
+	var texts []*strings.Builder
+	for {
+		token, tokenErr := decoder.Token()
+		if errors.Is(tokenErr, io.EOF) {
+			break
+		} else if tokenErr != nil {
+			line, column := decoder.InputPos()
+			return nil, fmt.Errorf("line %d, column %d: %w", line, column, tokenErr)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &element{Name: t.Name}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+					continue
+				}
+				node.Attrs = append(node.Attrs, attr)
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, node)
+			} else {
+				root = node
+			}
+			stack = append(stack, node)
+			texts = append(texts, &strings.Builder{})
+		case xml.CharData:
+			if len(texts) > 0 {
+				texts[len(texts)-1].Write(t)
+			}
+		case xml.EndElement:
+			stack[len(stack)-1].Text = strings.TrimSpace(texts[len(texts)-1].String())
+			stack, texts = stack[:len(stack)-1], texts[:len(texts)-1]
+		}
+	}
+	if root == nil {
+		return nil, errors.New("the document has no root element")
+	}
+	return root, nil
+}
+
+// differ compares two documents, matching repeated sibling elements by position,
+// or by the value of the key attribute when every one of them has it.
+type differ struct {
+	key         string
+	differences []Difference
+}
+
+// diffDocuments returns the differences between two root elements, in the order of the documents.
+func diffDocuments(oldRoot, newRoot *element, key string) []Difference {
+	d := &differ{key: key}
+	if oldRoot.Name != newRoot.Name {
+		d.add(elementRemoved, []string{oldRoot.Name.Local}, leafText(oldRoot), "")
+		d.add(elementAdded, []string{newRoot.Name.Local}, "", leafText(newRoot))
+		return d.differences
+	}
+	d.diffElement(oldRoot, newRoot, []string{newRoot.Name.Local})
+	return d.differences
+}
+
+func (d *differ) add(kind string, path []string, oldValue, newValue string) {
+	d.differences = append(d.differences, Difference{Kind: kind, Path: path, Old: oldValue, New: newValue})
+}
+
+// diffElement compares the attributes, text and children of two elements found at path.
+// Attributes are compared by name, so their order does not matter.
+func (d *differ) diffElement(oldElement, newElement *element, path []string) {
+	for _, oldAttr := range oldElement.Attrs {
+		attrPath := appendPath(path, "@"+oldAttr.Name.Local)
+		if newValue, found := attrValue(newElement, oldAttr.Name); !found {
+			d.add(attributeRemoved, attrPath, oldAttr.Value, "")
+		} else if newValue != oldAttr.Value {
+			d.add(attributeChanged, attrPath, oldAttr.Value, newValue)
+		}
+	}
+	for _, newAttr := range newElement.Attrs {
+		if _, found := attrValue(oldElement, newAttr.Name); !found {
+			d.add(attributeAdded, appendPath(path, "@"+newAttr.Name.Local), "", newAttr.Value)
+		}
+	}
+	if oldElement.Text != newElement.Text {
+		d.add(textChanged, path, oldElement.Text, newElement.Text)
+	}
+
+	var names []xml.Name
+	oldGroups, newGroups := make(map[xml.Name][]*element), make(map[xml.Name][]*element)
+	for _, child := range oldElement.Children {
+		if _, seen := oldGroups[child.Name]; !seen {
+			names = append(names, child.Name)
+		}
+		oldGroups[child.Name] = append(oldGroups[child.Name], child)
+	}
+	for _, child := range newElement.Children {
+		_, inOld := oldGroups[child.Name]
+		if _, seen := newGroups[child.Name]; !seen && !inOld {
+			names = append(names, child.Name)
+		}
+		newGroups[child.Name] = append(newGroups[child.Name], child)
+	}
+	for _, name := range names {
+		d.diffSiblings(oldGroups[name], newGroups[name], path)
+	}
+}
+
+// diffSiblings compares the children of the same name of two matched elements.
+func (d *differ) diffSiblings(oldChildren, newChildren []*element, path []string) {
+	if d.keyed(oldChildren) && d.keyed(newChildren) {
+		for _, oldChild := range oldChildren {
+			keyValue, _ := attrValue(oldChild, xml.Name{Local: d.key})
+			segment := fmt.Sprintf("%s[@%s='%s']", oldChild.Name.Local, d.key, keyValue)
+			if newChild := d.findKeyed(newChildren, keyValue); newChild != nil {
+				d.diffElement(oldChild, newChild, appendPath(path, segment))
+			} else {
+				d.add(elementRemoved, appendPath(path, segment), leafText(oldChild), "")
+			}
+		}
+		for _, newChild := range newChildren {
+			keyValue, _ := attrValue(newChild, xml.Name{Local: d.key})
+			if d.findKeyed(oldChildren, keyValue) == nil {
+				segment := fmt.Sprintf("%s[@%s='%s']", newChild.Name.Local, d.key, keyValue)
+				d.add(elementAdded, appendPath(path, segment), "", leafText(newChild))
+			}
+		}
+		return
+	}
+	count := max(len(oldChildren), len(newChildren))
+	for i := 0; i < count; i++ {
+		var name string
+		if i < len(oldChildren) {
+			name = oldChildren[i].Name.Local
+		} else {
+			name = newChildren[i].Name.Local
+		}
+		segment := name
+		if count > 1 {
+			segment = fmt.Sprintf("%s[%d]", name, i+1)
+		}
+		switch {
+		case i >= len(newChildren):
+			d.add(elementRemoved, appendPath(path, segment), leafText(oldChildren[i]), "")
+		case i >= len(oldChildren):
+			d.add(elementAdded, appendPath(path, segment), "", leafText(newChildren[i]))
+		default:
+			d.diffElement(oldChildren[i], newChildren[i], appendPath(path, segment))
+		}
+	}
+}
+
+// keyed checks if elements can be matched by the key attribute: every one has it, with a distinct value.
+func (d *differ) keyed(elements []*element) bool {
+	if len(d.key) == 0 {
+		return false
+	}
+	seen := make(map[string]bool)
+	for _, e := range elements {
+		value, found := attrValue(e, xml.Name{Local: d.key})
+		if !found || seen[value] {
+			return false
+		}
+		seen[value] = true
+	}
+	return true
+}
+
+func (d *differ) findKeyed(elements []*element, keyValue string) *element {
+	for _, e := range elements {
+		if value, _ := attrValue(e, xml.Name{Local: d.key}); value == keyValue {
+			return e
+		}
+	}
+	return nil
+}
+
+func attrValue(e *element, name xml.Name) (string, bool) {
+	for _, attr := range e.Attrs {
+		if attr.Name == name {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// leafText returns the text of an element without children, to show what was added or removed.
+func leafText(e *element) string {
+	if len(e.Children) > 0 {
+		return ""
+	}
+	return e.Text
+}
+
+// appendPath returns path extended by segment, without sharing its backing array with other paths.
+func appendPath(path []string, segment string) []string {
+	return append(path[:len(path):len(path)], segment)
+}
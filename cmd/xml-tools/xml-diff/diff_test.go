@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDiffDocuments(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		key      string
+		want     []string
+	}{
+		{
+			name: "Same",
+			old:  "<a x=\"1\" y=\"2\">\n  <b>t</b>\n</a>",
+			new:  "<a y=\"2\" x=\"1\"><b>t</b></a>",
+		},
+		{
+			name: "PrefixOnly",
+			old:  `<p:a xmlns:p="urn:x"><p:b>t</p:b></p:a>`,
+			new:  `<q:a xmlns:q="urn:x"><q:b>t</q:b></q:a>`,
+		},
+		{
+			name: "Attributes",
+			old:  `<a x="1" y="2"/>`,
+			new:  `<a x="3" z="4"/>`,
+			want: []string{
+				"attribute-changed a/@x 1 3",
+				"attribute-removed a/@y 2 ",
+				"attribute-added a/@z  4",
+			},
+		},
+		{
+			name: "Text",
+			old:  "<a><b>old</b></a>",
+			new:  "<a><b>new</b></a>",
+			want: []string{"text-changed a/b old new"},
+		},
+		{
+			name: "ByPosition",
+			old:  "<a><r>1</r><r>2</r></a>",
+			new:  "<a><r>1</r><r>3</r><r>4</r></a>",
+			want: []string{
+				"text-changed a/r[2] 2 3",
+				"element-added a/r[3]  4",
+			},
+		},
+		{
+			name: "ByKey",
+			old:  `<a><r id="1">x</r><r id="2">y</r></a>`,
+			new:  `<a><r id="2">z</r><r id="3">w</r></a>`,
+			key:  "id",
+			want: []string{
+				"element-removed a/r[@id='1'] x ",
+				"text-changed a/r[@id='2'] y z",
+				"element-added a/r[@id='3']  w",
+			},
+		},
+		{
+			name: "RootRenamed",
+			old:  "<a>1</a>",
+			new:  "<b>1</b>",
+			want: []string{"element-removed a 1 ", "element-added b  1"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			oldRoot, oldErr := readDocument(strings.NewReader(test.old))
+			newRoot, newErr := readDocument(strings.NewReader(test.new))
+			if oldErr != nil || newErr != nil {
+				t.Fatal(oldErr, newErr)
+			}
+			var got []string
+			for _, difference := range diffDocuments(oldRoot, newRoot, test.key) {
+				got = append(got, fmt.Sprintf("%s %s %s %s", difference.Kind, strings.Join(difference.Path, "/"), difference.Old, difference.New))
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("diffDocuments() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	formatText = "text"
+	formatJSON = "json"
+)
+
+// outputFormats lists the values accepted by the --format flag.
+var outputFormats = []string{formatText, formatJSON}
+
+// diffReport is the document written by every output format.
+type diffReport struct {
+	Old         string       `json:"old"`
+	New         string       `json:"new"`
+	Count       int          `json:"count"`
+	Differences []Difference `json:"differences"`
+}
+
+// writeReport writes the report to w in the given format.
+func writeReport(w io.Writer, report diffReport, format string) error {
+	if format == formatJSON {
+		if report.Differences == nil {
+			report.Differences = []Difference{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	return writeText(w, report)
+}
+
+// writeText writes the differences as a tree: the elements leading to each change are written once,
+// indented by depth, with the changes beneath them marked + (added), - (removed) or ~ (changed),
+// followed by a summary line.
+func writeText(w io.Writer, report diffReport) error {
+	var open []string
+	for _, difference := range report.Differences {
+		parents := difference.Path[:len(difference.Path)-1]
+		shared := 0
+		for shared < len(open) && shared < len(parents) && open[shared] == parents[shared] {
+			shared++
+		}
+		for depth := shared; depth < len(parents); depth++ {
+			if _, err := fmt.Fprintf(w, "%s%s\n", strings.Repeat("  ", depth), parents[depth]); err != nil {
+				return err
+			}
+		}
+		open = parents
+		if _, err := fmt.Fprintf(w, "%s%s\n", strings.Repeat("  ", len(parents)), describe(difference)); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d difference(s) between %s and %s\n", report.Count, report.Old, report.New)
+	return err
+}
+
+// describe renders a difference as a single line of text, e.g. `~ Price: "1.50" -> "1.75"`.
+func describe(difference Difference) string {
+	name := difference.Path[len(difference.Path)-1]
+	switch difference.Kind {
+	case elementAdded, attributeAdded:
+		if len(difference.New) > 0 {
+			return fmt.Sprintf("+ %s: %s", name, strconv.Quote(difference.New))
+		}
+		return "+ " + name
+	case elementRemoved, attributeRemoved:
+		if len(difference.Old) > 0 {
+			return fmt.Sprintf("- %s: %s", name, strconv.Quote(difference.Old))
+		}
+		return "- " + name
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", name, strconv.Quote(difference.Old), strconv.Quote(difference.New))
+	}
+}
@@ -0,0 +1,112 @@
+// This program compares two XML documents by their content rather than their text, ignoring attribute order,
+// namespace prefixes and the whitespace that only lays the document out, and reports the elements, attributes
+// and text that were added, removed or changed as a tree or as JSON.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
+)
+
+// options holds the command line settings controlling the comparison.
+type options struct {
+	OldPath    string
+	NewPath    string
+	Key        string
+	Format     string
+	Output     string
+	FailOnDiff bool
+}
+
+func main() {
+	processingErr := ErrMsg{Code: Success}
+	defer func() {
+		processingErr.Exit()
+	}()
+	opts, argErr := getInput()
+	if argErr != nil {
+		processingErr = *argErr
+		return
+	}
+	processingErr = compareDocuments(opts)
+}
+
+// getInput parses the command line flags. The documents may also be given as two positional arguments.
+func getInput() (options, *ErrMsg) {
+	var opts options
+	flag.StringVar(&opts.OldPath, "old", "", "The path to the original XML document")
+	flag.StringVar(&opts.NewPath, "new", "", "The path to the XML document to compare against the original")
+	flag.StringVar(&opts.Key, "key", "", "Match repeated elements by the value of this attribute instead of by position (e.g. id)")
+	flag.StringVar(&opts.Format, "format", formatText, "The report format: "+strings.Join(outputFormats, ", "))
+	flag.StringVar(&opts.Output, "output", "", "Write the report to this file instead of stdout")
+	flag.BoolVar(&opts.FailOnDiff, "fail-on-diff", false, "Exit with a non-zero code if any differences are found")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if logErr := logging.Setup(); logErr != nil {
+		return opts, &ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+	}
+
+	if flag.NArg() == 2 && len(opts.OldPath) == 0 && len(opts.NewPath) == 0 {
+		opts.OldPath, opts.NewPath = flag.Arg(0), flag.Arg(1)
+	}
+	opts.OldPath, opts.NewPath = strings.TrimSpace(opts.OldPath), strings.TrimSpace(opts.NewPath)
+	if len(opts.OldPath) == 0 || len(opts.NewPath) == 0 {
+		return opts, &ErrMsg{Err: errors.New("two XML documents are required, via --old and --new or as arguments"), Code: ErrNoInput}
+	}
+	if !slices.Contains(outputFormats, opts.Format) {
+		return opts, &ErrMsg{Err: fmt.Errorf("unsupported format '%s'", opts.Format), Code: ErrInvalidArgs}
+	}
+	return opts, nil
+}
+
+func compareDocuments(opts options) ErrMsg {
+	var roots []*element
+	for _, path := range []string{opts.OldPath, opts.NewPath} {
+		if exists, _ := PathExists(path); !exists {
+			return ErrMsg{Err: fmt.Errorf("file '%s' does not exist", path), Code: ErrNoFile}
+		}
+		file, openErr := os.Open(path)
+		if openErr != nil {
+			return ErrMsg{Err: openErr, Code: ErrReadFile}
+		}
+		root, readErr := readDocument(bufio.NewReader(file))
+		_ = file.Close()
+		if readErr != nil {
+			return ErrMsg{Err: fmt.Errorf("'%s': %w", path, readErr), Code: ErrParse}
+		}
+		roots = append(roots, root)
+	}
+
+	differences := diffDocuments(roots[0], roots[1], opts.Key)
+	report := diffReport{Old: opts.OldPath, New: opts.NewPath, Count: len(differences), Differences: differences}
+
+	var output io.Writer = os.Stdout
+	errCode := ErrStdout
+	if len(opts.Output) > 0 {
+		outputFile, createErr := os.Create(opts.Output)
+		if createErr != nil {
+			return ErrMsg{Err: createErr, Code: ErrWriteFile}
+		}
+		defer func(outputFile *os.File) {
+			_ = outputFile.Close()
+		}(outputFile)
+		output, errCode = outputFile, ErrWriteFile
+	}
+	if writeErr := writeReport(output, report, opts.Format); writeErr != nil {
+		return ErrMsg{Err: writeErr, Code: errCode}
+	}
+	if opts.FailOnDiff && len(differences) > 0 {
+		return ErrMsg{Code: ErrDifferences}
+	}
+	return ErrMsg{Code: Success}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type nodeKind int
+
+const (
+	documentNode nodeKind = iota
+	elementNode
+	attributeNode
+	textNode
+)
+
+// node is a node of a queried document. Elements keep the offsets of their source text,
+// so a matching element can be printed exactly as it was written.
+type node struct {
+	Kind     nodeKind
+	Name     xml.Name
+	Value    string
+	Attrs    []*node
+	Children []*node
+	Parent   *node
+	start    int64
+	end      int64
+	source   []byte
+}
+
+// readDocument decodes data into a tree of nodes, returning the document node above the root element.
+// Whitespace-only text is dropped, as it only lays the document out.
+func readDocument(data []byte) (*node, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	document := &node{Kind: documentNode, source: data}
+	current := document
+	for {
+		offset := decoder.InputOffset()
+		token, tokenErr := decoder.Token()
+		if errors.Is(tokenErr, io.EOF) {
+			break
+		} else if tokenErr != nil {
+			line, column := decoder.InputPos()
+			return nil, fmt.Errorf("line %d, column %d: %w", line, column, tokenErr)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			element := &node{Kind: elementNode, Name: t.Name, Parent: current, start: offset, source: data}
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+					continue
+				}
+				element.Attrs = append(element.Attrs, &node{Kind: attributeNode, Name: attr.Name, Value: attr.Value, Parent: element})
+			}
+			current.Children = append(current.Children, element)
+			current = element
+		case xml.EndElement:
+			current.end = decoder.InputOffset()
+			current = current.Parent
+		case xml.CharData:
+			if current.Kind == documentNode || len(strings.TrimSpace(string(t))) == 0 {
+				continue
+			}
+			current.Children = append(current.Children, &node{Kind: textNode, Value: string(t), Parent: current})
+		}
+	}
+	if len(document.Children) == 0 {
+		return nil, errors.New("the document has no root element")
+	}
+	return document, nil
+}
+
+// stringValue returns the text of n: the value of an attribute or text node,
+// or the text of all descendants of an element.
+func (n *node) stringValue() string {
+	if n.Kind == attributeNode || n.Kind == textNode {
+		return n.Value
+	}
+	var builder strings.Builder
+	for _, child := range n.Children {
+		builder.WriteString(child.stringValue())
+	}
+	return builder.String()
+}
+
+// outerXML returns the source text of an element or of the whole document, or the string value of any other node.
+func (n *node) outerXML() string {
+	switch n.Kind {
+	case documentNode:
+		return strings.TrimSpace(string(n.source))
+	case elementNode:
+		return string(n.source[n.start:n.end])
+	}
+	return n.stringValue()
+}
+
+// location returns an absolute path that selects only n, such as /DataTable/Row[2]/Age,
+// giving a position wherever an element has siblings of the same name.
+func (n *node) location() string {
+	switch n.Kind {
+	case documentNode:
+		return ""
+	case attributeNode:
+		return n.Parent.location() + "/@" + n.Name.Local
+	case textNode:
+		return n.Parent.location() + "/text()"
+	}
+	position, count := 0, 0
+	for _, sibling := range n.Parent.Children {
+		if sibling.Kind == elementNode && sibling.Name == n.Name {
+			count++
+			if sibling == n {
+				position = count
+			}
+		}
+	}
+	if count > 1 {
+		return fmt.Sprintf("%s/%s[%d]", n.Parent.location(), n.Name.Local, position)
+	}
+	return n.Parent.location() + "/" + n.Name.Local
+}
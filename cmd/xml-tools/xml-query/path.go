@@ -0,0 +1,361 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type stepKind int
+
+const (
+	childStep stepKind = iota
+	attributeStep
+	textStep
+	selfStep
+	parentStep
+)
+
+// step is one step of a path, such as Row, @name or text(), with any predicates that filter what it selects.
+// The name "*" matches any name.
+type step struct {
+	Kind       stepKind
+	Descendant bool
+	Name       string
+	Predicates []predicate
+}
+
+// predicate filters the nodes selected by a step, either by position ([2], [last()]),
+// by the existence of an operand ([@id], [Name]) or by comparing an operand with a literal ([@id='7'], [Age>30]).
+type predicate struct {
+	Position int
+	Last     bool
+	Operand  *step
+	Operator string
+	Literal  string
+}
+
+// comparisonOperators lists the operators a predicate accepts, longest first so that "<=" is not read as "<".
+var comparisonOperators = []string{"!=", "<=", ">=", "=", "<", ">"}
+
+// pathParser reads the subset of XPath location paths that xml-query evaluates.
+type pathParser struct {
+	path string
+	pos  int
+}
+
+// parsePath parses a location path such as //Row[@id='7']/Name or /DataTable/Row[last()]/@id.
+// Relative paths are evaluated from the document, so Row/Name is the same as /Row/Name.
+func parsePath(path string) ([]step, error) {
+	p := &pathParser{path: strings.TrimSpace(path)}
+	if len(p.path) == 0 {
+		return nil, fmt.Errorf("the path is empty")
+	}
+	var steps []step
+	for first := true; p.pos < len(p.path); first = false {
+		descendant := false
+		switch {
+		case strings.HasPrefix(p.path[p.pos:], "//"):
+			descendant = true
+			p.pos += 2
+		case p.path[p.pos] == '/':
+			p.pos++
+			if p.pos == len(p.path) && first {
+				return []step{{Kind: selfStep}}, nil
+			}
+		case !first:
+			return nil, p.errorf("expected '/'")
+		}
+		next, stepErr := p.step()
+		if stepErr != nil {
+			return nil, stepErr
+		}
+		next.Descendant = descendant
+		steps = append(steps, next)
+	}
+	return steps, nil
+}
+
+func (p *pathParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid path '%s' at position %d: %s", p.path, p.pos+1, fmt.Sprintf(format, args...))
+}
+
+// step reads a node test and its predicates.
+func (p *pathParser) step() (step, error) {
+	var s step
+	switch {
+	case strings.HasPrefix(p.path[p.pos:], ".."):
+		s.Kind = parentStep
+		p.pos += 2
+	case strings.HasPrefix(p.path[p.pos:], "."):
+		s.Kind = selfStep
+		p.pos++
+	case strings.HasPrefix(p.path[p.pos:], "text()"):
+		s.Kind = textStep
+		p.pos += len("text()")
+	case strings.HasPrefix(p.path[p.pos:], "@"):
+		s.Kind = attributeStep
+		p.pos++
+		s.Name = p.name()
+	default:
+		s.Kind = childStep
+		s.Name = p.name()
+	}
+	if (s.Kind == childStep || s.Kind == attributeStep) && len(s.Name) == 0 {
+		return s, p.errorf("expected a name")
+	}
+	for p.pos < len(p.path) && p.path[p.pos] == '[' {
+		p.pos++
+		pred, predErr := p.predicate()
+		if predErr != nil {
+			return s, predErr
+		}
+		if p.pos >= len(p.path) || p.path[p.pos] != ']' {
+			return s, p.errorf("expected ']'")
+		}
+		p.pos++
+		s.Predicates = append(s.Predicates, pred)
+	}
+	return s, nil
+}
+
+// name reads an element or attribute name, or "*". A namespace prefix is dropped, as names are matched
+// by their local part.
+func (p *pathParser) name() string {
+	if strings.HasPrefix(p.path[p.pos:], "*") {
+		p.pos++
+		return "*"
+	}
+	start := p.pos
+	for p.pos < len(p.path) {
+		r := rune(p.path[p.pos])
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("_-.:", r) && r < 0x80 {
+			break
+		}
+		p.pos++
+	}
+	name := p.path[start:p.pos]
+	if colon := strings.LastIndexByte(name, ':'); colon >= 0 {
+		name = name[colon+1:]
+	}
+	return name
+}
+
+// predicate reads the expression between the brackets of a predicate.
+func (p *pathParser) predicate() (predicate, error) {
+	var pred predicate
+	p.skipSpace()
+	rest := p.path[p.pos:]
+	if strings.HasPrefix(rest, "last()") {
+		pred.Last = true
+		p.pos += len("last()")
+		p.skipSpace()
+		return pred, nil
+	}
+	if len(rest) > 0 && rest[0] >= '0' && rest[0] <= '9' {
+		end := strings.IndexFunc(rest, func(r rune) bool { return r < '0' || r > '9' })
+		if end < 0 {
+			end = len(rest)
+		}
+		pred.Position, _ = strconv.Atoi(rest[:end])
+		if pred.Position < 1 {
+			return pred, p.errorf("positions start at 1")
+		}
+		p.pos += end
+		p.skipSpace()
+		return pred, nil
+	}
+	operand, operandErr := p.step()
+	if operandErr != nil {
+		return pred, operandErr
+	}
+	if operand.Kind == parentStep || len(operand.Predicates) > 0 {
+		return pred, p.errorf("a predicate compares an attribute, a child element, text() or '.'")
+	}
+	pred.Operand = &operand
+	p.skipSpace()
+	for _, operator := range comparisonOperators {
+		if strings.HasPrefix(p.path[p.pos:], operator) {
+			pred.Operator = operator
+			p.pos += len(operator)
+			break
+		}
+	}
+	if len(pred.Operator) == 0 {
+		return pred, nil
+	}
+	p.skipSpace()
+	literal, literalErr := p.literal()
+	if literalErr != nil {
+		return pred, literalErr
+	}
+	pred.Literal = literal
+	p.skipSpace()
+	return pred, nil
+}
+
+// literal reads a quoted string or a number.
+func (p *pathParser) literal() (string, error) {
+	if p.pos >= len(p.path) {
+		return "", p.errorf("expected a value")
+	}
+	if quote := p.path[p.pos]; quote == '\'' || quote == '"' {
+		end := strings.IndexByte(p.path[p.pos+1:], quote)
+		if end < 0 {
+			return "", p.errorf("unterminated string")
+		}
+		literal := p.path[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return literal, nil
+	}
+	end := strings.IndexAny(p.path[p.pos:], " ]")
+	if end < 0 {
+		end = len(p.path) - p.pos
+	}
+	literal := p.path[p.pos : p.pos+end]
+	if _, err := strconv.ParseFloat(literal, 64); err != nil {
+		return "", p.errorf("expected a quoted string or a number")
+	}
+	p.pos += end
+	return literal, nil
+}
+
+func (p *pathParser) skipSpace() {
+	for p.pos < len(p.path) && p.path[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// evaluate returns the nodes selected by steps from the document, in document order and without duplicates.
+func evaluate(document *node, steps []step) []*node {
+	context := []*node{document}
+	for _, s := range steps {
+		var selected []*node
+		seen := make(map[*node]bool)
+		for _, origin := range context {
+			origins := []*node{origin}
+			if s.Descendant {
+				origins = descendantsOrSelf(origin, nil)
+			}
+			for _, from := range origins {
+				for _, match := range s.filter(s.candidates(from)) {
+					if !seen[match] {
+						seen[match] = true
+						selected = append(selected, match)
+					}
+				}
+			}
+		}
+		context = selected
+	}
+	return context
+}
+
+// descendantsOrSelf appends n and every element below it to nodes, in document order.
+func descendantsOrSelf(n *node, nodes []*node) []*node {
+	nodes = append(nodes, n)
+	for _, child := range n.Children {
+		if child.Kind == elementNode {
+			nodes = descendantsOrSelf(child, nodes)
+		}
+	}
+	return nodes
+}
+
+// candidates returns the nodes the step's node test selects from n, before any predicates.
+func (s step) candidates(n *node) []*node {
+	var nodes []*node
+	switch s.Kind {
+	case selfStep:
+		nodes = append(nodes, n)
+	case parentStep:
+		if n.Parent != nil {
+			nodes = append(nodes, n.Parent)
+		}
+	case attributeStep:
+		for _, attr := range n.Attrs {
+			if s.Name == "*" || attr.Name.Local == s.Name {
+				nodes = append(nodes, attr)
+			}
+		}
+	case textStep:
+		for _, child := range n.Children {
+			if child.Kind == textNode {
+				nodes = append(nodes, child)
+			}
+		}
+	case childStep:
+		for _, child := range n.Children {
+			if child.Kind == elementNode && (s.Name == "*" || child.Name.Local == s.Name) {
+				nodes = append(nodes, child)
+			}
+		}
+	}
+	return nodes
+}
+
+// filter applies each predicate in turn, positions counting among the nodes left by the previous predicate.
+func (s step) filter(nodes []*node) []*node {
+	for _, pred := range s.Predicates {
+		var kept []*node
+		for i, n := range nodes {
+			if pred.matches(n, i+1, len(nodes)) {
+				kept = append(kept, n)
+			}
+		}
+		nodes = kept
+	}
+	return nodes
+}
+
+// matches reports whether n, at the given position among size nodes, satisfies the predicate.
+// A comparison holds if any value of the operand satisfies it; values are compared as numbers
+// when both sides are numbers, and ordering operators never hold for other values.
+func (pred predicate) matches(n *node, position, size int) bool {
+	switch {
+	case pred.Last:
+		return position == size
+	case pred.Position > 0:
+		return position == pred.Position
+	}
+	values := pred.Operand.candidates(n)
+	if len(pred.Operator) == 0 {
+		return len(values) > 0
+	}
+	for _, value := range values {
+		if compare(value.stringValue(), pred.Operator, pred.Literal) {
+			return true
+		}
+	}
+	return false
+}
+
+func compare(value, operator, literal string) bool {
+	number, valueErr := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	target, literalErr := strconv.ParseFloat(literal, 64)
+	if valueErr != nil || literalErr != nil {
+		switch operator {
+		case "=":
+			return value == literal
+		case "!=":
+			return value != literal
+		}
+		return false
+	}
+	switch operator {
+	case "=":
+		return number == target
+	case "!=":
+		return number != target
+	case "<":
+		return number < target
+	case "<=":
+		return number <= target
+	case ">":
+		return number > target
+	case ">=":
+		return number >= target
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// document is the document the path tests are evaluated against.
+const document = `<DataTable>
+	<Row id="1"><Name>Ann</Name><Age>42</Age></Row>
+	<Row id="2"><Name>Bob</Name><Age>7</Age></Row>
+	<Row id="3"><Name>Cy</Name><Age>30</Age><Note>new</Note></Row>
+</DataTable>`
+
+func TestEvaluate(t *testing.T) {
+	root, readErr := readDocument([]byte(document))
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"/DataTable/Row/Name", []string{"Ann", "Bob", "Cy"}},
+		{"Row/Name", nil},
+		{"DataTable/Row/Name/text()", []string{"Ann", "Bob", "Cy"}},
+		{"//Row[@id='2']/Name", []string{"Bob"}},
+		{"//Row[2]/Name", []string{"Bob"}},
+		{"//Row[last()]/@id", []string{"3"}},
+		{"//Row[Note]/Name", []string{"Cy"}},
+		{"//Row[Age>10]/Name", []string{"Ann", "Cy"}},
+		{"//Row[Age<=7]/Name", []string{"Bob"}},
+		{"//Row[Name!='Ann']/@id", []string{"2", "3"}},
+		{"//Age/../Name", []string{"Ann", "Bob", "Cy"}},
+		{"//Row/*[1]", []string{"Ann", "Bob", "Cy"}},
+		{"//Missing", nil},
+	}
+	for _, test := range tests {
+		steps, parseErr := parsePath(test.path)
+		if parseErr != nil {
+			t.Errorf("parsePath(%q): %v", test.path, parseErr)
+			continue
+		}
+		var got []string
+		for _, n := range evaluate(root, steps) {
+			got = append(got, n.stringValue())
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("evaluate(%q) = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
+
+func TestLocation(t *testing.T) {
+	root, readErr := readDocument([]byte(document))
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"//Note", []string{"/DataTable/Row[3]/Note"}},
+		{"//Row[1]/@id", []string{"/DataTable/Row[1]/@id"}},
+		{"/DataTable", []string{"/DataTable"}},
+	}
+	for _, test := range tests {
+		steps, parseErr := parsePath(test.path)
+		if parseErr != nil {
+			t.Fatal(parseErr)
+		}
+		var got []string
+		for _, n := range evaluate(root, steps) {
+			got = append(got, n.location())
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("location of %q = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
+
+func TestParsePathErrors(t *testing.T) {
+	for _, path := range []string{"", "//Row[", "//Row[@id=7", "//Row[@id='7]", "//Row[0]", "Row/@"} {
+		if _, err := parsePath(path); err == nil {
+			t.Errorf("parsePath(%q) succeeded, want an error", path)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// This program evaluates a path, a subset of XPath, against XML documents and prints what it selects,
+// so values can be pulled out of documents without searching their text.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
+	"github.com/charmbracelet/log"
+)
+
+// options holds the command line settings for a query.
+type options struct {
+	Query string
+	Files []Input
+	Raw   bool
+	JSON  bool
+	Count bool
+}
+
+// match is a node selected by the query, as written by --json.
+type match struct {
+	File  string `json:"file"`
+	Path  string `json:"path"`
+	Value string `json:"value"`
+	XML   string `json:"xml,omitempty"`
+}
+
+func main() {
+	startTime := time.Now()
+
+	processingErr := ErrMsg{Code: Success}
+	defer func() {
+		log.Debug(
+			"DONE!",
+			"time", time.Since(startTime),
+		)
+		processingErr.Exit()
+	}()
+	opts, argErr := getInput()
+	if argErr != nil {
+		processingErr = *argErr
+		return
+	}
+	processingErr = runQuery(opts)
+}
+
+// getInput parses the command line. Files are given by --path as a comma-separated list and as arguments;
+// "-", or no file at all while standard input is not a terminal, reads the document from standard input.
+func getInput() (options, *ErrMsg) {
+	var opts options
+	var paths string
+	flag.StringVar(&opts.Query, "query", "", "The path to evaluate, e.g. //Row[@id='7']/Name, /DataTable/Row[last()] or //Row/@id")
+	flag.StringVar(&opts.Query, "q", "", "Shorthand for --query")
+	flag.StringVar(&paths, "path", "", "Comma-separated XML files to query, '-' for standard input; files may also be given as arguments")
+	flag.BoolVar(&opts.Raw, "raw", false, "Print only the text of each match, without file names or markup")
+	flag.BoolVar(&opts.JSON, "json", false, "Print the matches as a JSON array of objects with their file, path, text and, for elements, XML")
+	flag.BoolVar(&opts.Count, "count", false, "Print the number of matches instead of the matches")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if logErr := logging.Setup(); logErr != nil {
+		return opts, &ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+	}
+
+	if len(strings.TrimSpace(opts.Query)) == 0 {
+		return opts, &ErrMsg{Err: errors.New("no query provided, use --query"), Code: ErrInvalidArgs}
+	}
+	// A document is piped in far more often than a list of files, so standard input is read as one
+	if len(strings.Trim(paths, ", ")) == 0 && flag.NArg() == 0 && StdinRedirected() {
+		paths = StdinPath
+	}
+	files, inputErr := ResolveInputs(paths, flag.Args())
+	if inputErr != nil {
+		errMsg := ToErrMsg(inputErr, ErrNoInput)
+		return opts, &errMsg
+	}
+	opts.Files = files
+	modes := 0
+	for _, set := range []bool{opts.Raw, opts.JSON, opts.Count} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return opts, &ErrMsg{Err: errors.New("--raw, --json and --count cannot be combined"), Code: ErrInvalidArgs}
+	}
+	return opts, nil
+}
+
+// runQuery evaluates the query against each file in turn and prints the matches.
+// Matches are prefixed with their file name when several files are queried, as grep does.
+func runQuery(opts options) ErrMsg {
+	steps, parseErr := parsePath(opts.Query)
+	if parseErr != nil {
+		return ErrMsg{Err: parseErr, Code: ErrInvalidArgs}
+	}
+	var matches []match
+	multiple := len(opts.Files) > 1
+	for _, file := range opts.Files {
+		name := file.String()
+		data, readErr := readInput(file)
+		if readErr != nil {
+			return *readErr
+		}
+		document, docErr := readDocument(data)
+		if docErr != nil {
+			return ErrMsg{Err: fmt.Errorf("%s: %w", name, docErr), Code: ErrParse}
+		}
+		nodes := evaluate(document, steps)
+		log.Debug("Evaluated query", "file", name, "matches", len(nodes))
+
+		var lines []string
+		switch {
+		case opts.Count:
+			lines = append(lines, fmt.Sprint(len(nodes)))
+		case opts.JSON:
+			for _, n := range nodes {
+				m := match{File: name, Path: n.location(), Value: n.stringValue()}
+				if n.Kind == elementNode {
+					m.XML = n.outerXML()
+				}
+				matches = append(matches, m)
+			}
+		case opts.Raw:
+			for _, n := range nodes {
+				lines = append(lines, n.stringValue())
+			}
+		default:
+			for _, n := range nodes {
+				lines = append(lines, n.outerXML())
+			}
+		}
+		for _, line := range lines {
+			if multiple && !opts.Raw {
+				line = name + ": " + line
+			}
+			if _, writeErr := fmt.Println(line); writeErr != nil {
+				return ErrMsg{Err: writeErr, Code: ErrStdout}
+			}
+		}
+	}
+	if opts.JSON {
+		if matches == nil {
+			matches = []match{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if encodeErr := encoder.Encode(matches); encodeErr != nil {
+			return ErrMsg{Err: encodeErr, Code: ErrStdout}
+		}
+	}
+	return ErrMsg{Code: Success}
+}
+
+// readInput reads a whole document from a file, or from standard input.
+func readInput(file Input) ([]byte, *ErrMsg) {
+	if file.IsStdin() {
+		data, readErr := io.ReadAll(os.Stdin)
+		if readErr != nil {
+			return nil, &ErrMsg{Err: readErr, Code: ErrStdin}
+		}
+		return data, nil
+	}
+	if exists, _ := PathExists(file.Path); !exists {
+		return nil, &ErrMsg{Err: fmt.Errorf("file '%s' does not exist", file), Code: ErrNoFile}
+	}
+	data, readErr := os.ReadFile(file.Path)
+	if readErr != nil {
+		return nil, &ErrMsg{Err: readErr, Code: ErrReadFile}
+	}
+	return data, nil
+}
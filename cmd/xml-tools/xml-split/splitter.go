@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	. "GoTools/pkg/helpers"
+	"github.com/charmbracelet/log"
+)
+
+// existsError reports a part that would overwrite an existing file without --force.
+type existsError struct {
+	path string
+}
+
+func (e *existsError) Error() string {
+	return fmt.Sprintf("output file '%s' already exists, use --force to overwrite it", e.path)
+}
+
+// ancestor is an element enclosing records, which is repeated around the records of every part.
+type ancestor struct {
+	id    int
+	start xml.StartElement
+	// records is the number of records read before the element opened, to tell whether it enclosed any.
+	records int
+	// skipped names what the element held outside the records, which no part receives.
+	skipped []string
+}
+
+// part is one output file, holding the ancestors it has opened so far.
+type part struct {
+	path    string
+	file    *os.File
+	writer  *bufio.Writer
+	encoder *xml.Encoder
+	open    []ancestor
+	records int
+}
+
+// splitter streams records from a document into parts of at most opts.Chunk records.
+// Tokens are read with RawToken and written with namespace prefixes folded into the names, so every part
+// keeps the prefixes and namespace declarations as they were written. Only the records and the elements
+// enclosing them are copied; anything else outside the records, such as an inline schema, is skipped with a warning.
+type splitter struct {
+	opts        options
+	base        string
+	declaration *xml.ProcInst
+	// hadBOM records that the document started with a UTF-8 byte order mark, for --bom preserve.
+	hadBOM    bool
+	ancestors []ancestor
+	nextID    int
+	current   *part
+	parts     []string
+	records   int
+}
+
+// split reads the document from r and writes its parts.
+func (s *splitter) split(r io.Reader) error {
+	r, hadBOM, bomErr := SkipBOM(r)
+	if bomErr != nil {
+		return bomErr
+	}
+	s.hadBOM = hadBOM
+	decoder := xml.NewDecoder(r)
+	var indent xml.CharData
+	for {
+		token, tokenErr := decoder.RawToken()
+		if errors.Is(tokenErr, io.EOF) {
+			break
+		} else if tokenErr != nil {
+			return positionError(decoder, tokenErr)
+		}
+		switch t := token.(type) {
+		case xml.ProcInst:
+			if t.Target == "xml" && len(s.ancestors) == 0 && s.declaration == nil {
+				declaration := t.Copy()
+				s.declaration = &declaration
+			}
+		case xml.CharData:
+			// The whitespace ending the text indents the record that may follow it
+			content := strings.TrimRightFunc(string(t), unicode.IsSpace)
+			indent = xml.CharData(string(t[len(content):]))
+			if len(strings.TrimSpace(content)) > 0 {
+				s.skip("text")
+			}
+		case xml.Comment:
+			s.skip("comment")
+		case xml.StartElement:
+			if t.Name.Local != s.opts.Record && qualifiedName(t.Name).Local != s.opts.Record {
+				s.ancestors = append(s.ancestors, ancestor{id: s.nextID, start: qualifyStart(t.Copy()), records: s.records})
+				s.nextID++
+				continue
+			}
+			if err := s.copyRecord(decoder, t, indent); err != nil {
+				var existsErr *existsError
+				if errors.As(err, &existsErr) {
+					return err
+				}
+				return positionError(decoder, err)
+			}
+		case xml.EndElement:
+			if len(s.ancestors) == 0 || qualifiedName(t.Name) != s.ancestors[len(s.ancestors)-1].start.Name {
+				return positionError(decoder, fmt.Errorf("unexpected end element </%s>", qualifiedName(t.Name).Local))
+			}
+			s.leave()
+		}
+	}
+	if len(s.ancestors) > 0 {
+		return fmt.Errorf("unexpected end of document, <%s> is not closed", s.ancestors[len(s.ancestors)-1].start.Name.Local)
+	}
+	return s.closePart()
+}
+
+// skip notes that the innermost open element held content outside the records, described by what.
+func (s *splitter) skip(what string) {
+	if len(s.ancestors) == 0 {
+		return
+	}
+	a := &s.ancestors[len(s.ancestors)-1]
+	if !slices.Contains(a.skipped, what) {
+		a.skipped = append(a.skipped, what)
+	}
+}
+
+// leave closes the innermost open element. An element that enclosed no records is itself skipped content of
+// its parent, while one that did warns of the content it held outside them.
+func (s *splitter) leave() {
+	a := s.ancestors[len(s.ancestors)-1]
+	s.ancestors = s.ancestors[:len(s.ancestors)-1]
+	if s.records == a.records {
+		s.skip("<" + a.start.Name.Local + ">")
+		return
+	}
+	if len(a.skipped) > 0 {
+		log.Warn("Skipped content outside the records", "element", a.start.Name.Local, "skipped", strings.Join(a.skipped, ", "))
+	}
+}
+
+// copyRecord writes the record opened by start, and everything up to its end, to the current part,
+// starting a new part when the current one is full.
+func (s *splitter) copyRecord(decoder *xml.Decoder, start xml.StartElement, indent xml.CharData) error {
+	if s.current != nil && s.current.records >= s.opts.Chunk {
+		if err := s.closePart(); err != nil {
+			return err
+		}
+	}
+	if s.current == nil {
+		if err := s.openPart(); err != nil {
+			return err
+		}
+	}
+	if err := s.enterAncestors(); err != nil {
+		return err
+	}
+	p := s.current
+	if len(indent) > 0 {
+		if err := p.encoder.EncodeToken(indent); err != nil {
+			return err
+		}
+	}
+	if err := p.encoder.EncodeToken(qualifyStart(start)); err != nil {
+		return err
+	}
+	for depth := 1; depth > 0; {
+		token, tokenErr := decoder.RawToken()
+		if tokenErr != nil {
+			if errors.Is(tokenErr, io.EOF) {
+				return fmt.Errorf("unexpected end of document, <%s> is not closed", s.opts.Record)
+			}
+			return tokenErr
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			token = qualifyStart(t)
+		case xml.EndElement:
+			depth--
+			token = xml.EndElement{Name: qualifiedName(t.Name)}
+		case xml.ProcInst, xml.Directive:
+			continue
+		}
+		if err := p.encoder.EncodeToken(token); err != nil {
+			return err
+		}
+	}
+	p.records++
+	s.records++
+	return nil
+}
+
+// enterAncestors closes the elements of the current part that no longer enclose the records being read,
+// such as the Table of the previous sheet in a DataSet, and opens those that do.
+func (s *splitter) enterAncestors() error {
+	p := s.current
+	shared := 0
+	for shared < len(p.open) && shared < len(s.ancestors) && p.open[shared].id == s.ancestors[shared].id {
+		shared++
+	}
+	if err := s.closeAncestors(shared); err != nil {
+		return err
+	}
+	for _, a := range s.ancestors[shared:] {
+		// The root element starts the part unless it follows the XML declaration
+		if len(p.open) > 0 || s.declaration != nil {
+			if err := p.encoder.EncodeToken(xml.CharData("\n" + strings.Repeat("  ", len(p.open)))); err != nil {
+				return err
+			}
+		}
+		if err := p.encoder.EncodeToken(a.start); err != nil {
+			return err
+		}
+		p.open = append(p.open, a)
+	}
+	return nil
+}
+
+// closeAncestors closes the elements the current part has open, down to the given depth.
+func (s *splitter) closeAncestors(depth int) error {
+	p := s.current
+	for len(p.open) > depth {
+		last := p.open[len(p.open)-1]
+		p.open = p.open[:len(p.open)-1]
+		if err := p.encoder.EncodeToken(xml.CharData("\n" + strings.Repeat("  ", len(p.open)))); err != nil {
+			return err
+		}
+		if err := p.encoder.EncodeToken(last.start.End()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openPart creates the next part, numbered from 1, and writes the byte order mark --bom asks for and the
+// XML declaration of the source, if any.
+func (s *splitter) openPart() error {
+	path := filepath.Join(s.opts.OutputDir, fmt.Sprintf("%s_%d.xml", s.base, len(s.parts)+1))
+	if exists, _ := PathExists(path); exists && !s.opts.Force {
+		return &existsError{path: path}
+	}
+	file, createErr := os.Create(path)
+	if createErr != nil {
+		return createErr
+	}
+	writer := bufio.NewWriter(file)
+	p := &part{path: path, file: file, writer: writer, encoder: xml.NewEncoder(writer)}
+	s.current = p
+	s.parts = append(s.parts, path)
+	// The encoder writes into writer directly and only accepts a declaration while writer is empty,
+	// so the byte order mark is flushed to the file first
+	if err := WriteBOM(writer, s.opts.BOM, s.hadBOM); err != nil {
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if s.declaration != nil {
+		if err := p.encoder.EncodeToken(*s.declaration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closePart closes the open elements of the current part and finishes its file.
+func (s *splitter) closePart() error {
+	p := s.current
+	if p == nil {
+		return nil
+	}
+	closeErr := s.closeAncestors(0)
+	s.current = nil
+	if closeErr == nil {
+		closeErr = p.encoder.Flush()
+	}
+	if closeErr == nil {
+		_, closeErr = p.writer.WriteString("\n")
+	}
+	if closeErr == nil {
+		closeErr = p.writer.Flush()
+	}
+	if fileErr := p.file.Close(); closeErr == nil {
+		closeErr = fileErr
+	}
+	if closeErr != nil {
+		return fmt.Errorf("'%s': %w", p.path, closeErr)
+	}
+	return nil
+}
+
+// qualifiedName folds a raw namespace prefix into the local name, so the encoder writes the name as it was read
+// rather than inventing a prefix of its own.
+func qualifiedName(name xml.Name) xml.Name {
+	if len(name.Space) > 0 {
+		return xml.Name{Local: name.Space + ":" + name.Local}
+	}
+	return name
+}
+
+// qualifyStart folds the prefixes of a start element and its attributes into their names.
+func qualifyStart(start xml.StartElement) xml.StartElement {
+	start.Name = qualifiedName(start.Name)
+	attrs := make([]xml.Attr, len(start.Attr))
+	for i, attr := range start.Attr {
+		attrs[i] = xml.Attr{Name: qualifiedName(attr.Name), Value: attr.Value}
+	}
+	start.Attr = attrs
+	return start
+}
+
+// positionError adds the line and column the decoder had reached to err.
+func positionError(decoder *xml.Decoder, err error) error {
+	line, column := decoder.InputPos()
+	return fmt.Errorf("line %d, column %d: %w", line, column, err)
+}
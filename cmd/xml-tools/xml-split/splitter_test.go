@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	. "GoTools/pkg/helpers"
+	"github.com/charmbracelet/log"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		record   string
+		chunk    int
+		want     []string
+		wantWarn string
+		wantErr  bool
+	}{
+		{
+			name:   "Chunks",
+			input:  "<Root>\n  <Row>1</Row>\n  <Row>2</Row>\n  <Row>3</Row>\n</Root>\n",
+			record: "Row",
+			chunk:  2,
+			want: []string{
+				"<Root>\n  <Row>1</Row>\n  <Row>2</Row>\n</Root>\n",
+				"<Root>\n  <Row>3</Row>\n</Root>\n",
+			},
+		},
+		{
+			name:   "Declaration",
+			input:  "<?xml version=\"1.0\"?>\n<Root>\n  <Row/>\n</Root>\n",
+			record: "Row",
+			chunk:  10,
+			want:   []string{"<?xml version=\"1.0\"?>\n<Root>\n  <Row></Row>\n</Root>\n"},
+		},
+		{
+			name:   "NestedAncestors",
+			input:  "<DataSet>\n  <Table>\n    <Row>1</Row>\n  </Table>\n  <Table>\n    <Row>2</Row>\n  </Table>\n</DataSet>\n",
+			record: "Row",
+			chunk:  10,
+			want: []string{
+				"<DataSet>\n  <Table>\n    <Row>1</Row>\n  </Table>\n  <Table>\n    <Row>2</Row>\n  </Table>\n</DataSet>\n",
+			},
+		},
+		{
+			name:   "Prefixes",
+			input:  "<a:Root xmlns:a=\"urn:a\">\n  <a:Row a:id=\"1\"/>\n</a:Root>\n",
+			record: "a:Row",
+			chunk:  10,
+			want:   []string{"<a:Root xmlns:a=\"urn:a\">\n  <a:Row a:id=\"1\"></a:Row>\n</a:Root>\n"},
+		},
+		{
+			name:     "SkippedSiblings",
+			input:    "<Root>\n  <Schema><Field/></Schema>\n  <Row>1</Row>\n  note\n  <Row>2</Row>\n  <!-- end -->\n</Root>\n",
+			record:   "Row",
+			chunk:    10,
+			want:     []string{"<Root>\n  <Row>1</Row>\n  <Row>2</Row>\n</Root>\n"},
+			wantWarn: "skipped=\"<Schema>, text, comment\"",
+		},
+		{
+			name:    "Unclosed",
+			input:   "<Root>\n  <Row>1</Row>\n",
+			record:  "Row",
+			chunk:   10,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			defer log.SetOutput(os.Stderr)
+			dir := t.TempDir()
+			s := &splitter{opts: options{Record: tt.record, Chunk: tt.chunk, OutputDir: dir, BOM: BOMPreserve}, base: "part"}
+			err := s.split(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("split() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(s.parts) != len(tt.want) {
+				t.Fatalf("split() wrote %d parts, want %d", len(s.parts), len(tt.want))
+			}
+			for i, path := range s.parts {
+				got, readErr := os.ReadFile(path)
+				if readErr != nil {
+					t.Fatal(readErr)
+				}
+				if string(got) != tt.want[i] {
+					t.Errorf("part %d = %q, want %q", i+1, got, tt.want[i])
+				}
+			}
+			if tt.wantWarn == "" && logs.Len() > 0 {
+				t.Errorf("split() logged %q, want nothing", logs.String())
+			} else if !strings.Contains(logs.String(), tt.wantWarn) {
+				t.Errorf("split() logged %q, want %q", logs.String(), tt.wantWarn)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+// This program splits a large XML file into several files of at most --chunk records each, where a record is
+// an element such as the Row elements written by parse-xml. The document is streamed, so memory use stays flat
+// however large it is, and the elements enclosing the records are repeated in every part.
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
+	"github.com/charmbracelet/log"
+)
+
+// options holds the command line settings controlling the split.
+type options struct {
+	FilePath  string
+	Record    string
+	Chunk     int
+	OutputDir string
+	Prefix    string
+	Force     bool
+	BOM       string
+}
+
+func main() {
+	startTime := time.Now()
+
+	processingErr := ErrMsg{Code: Success}
+	defer func() {
+		log.Debug(
+			"DONE!",
+			"time", time.Since(startTime),
+		)
+		processingErr.Exit()
+	}()
+	opts, argErr := getInput()
+	if argErr != nil {
+		processingErr = *argErr
+		return
+	}
+	processingErr = splitDocument(opts)
+}
+
+// getInput parses the command line flags. The file is given with --path or as an argument, falling back to
+// a path piped through standard input.
+func getInput() (options, *ErrMsg) {
+	var opts options
+	flag.StringVar(&opts.FilePath, "path", "", "The path to the XML file to split")
+	flag.StringVar(&opts.Record, "record", "Row", "The name of the record element, with its prefix if it has one")
+	flag.IntVar(&opts.Chunk, "chunk", 10000, "The most records written to each part")
+	flag.StringVar(&opts.OutputDir, "output-dir", "", "Directory for the parts (defaults to the XML file's directory)")
+	flag.StringVar(&opts.Prefix, "prefix", "", "The start of each part's file name, followed by _1.xml, _2.xml and so on (defaults to the XML file's name)")
+	flag.BoolVar(&opts.Force, "force", false, "Overwrite parts that already exist")
+	flag.StringVar(&opts.BOM, "bom", BOMPreserve, "Whether each part starts with a UTF-8 byte order mark: 'preserve' if the XML file does, 'strip' never, 'add' always")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if logErr := logging.Setup(); logErr != nil {
+		return opts, &ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+	}
+
+	path, inputErr := ResolveFile(opts.FilePath, flag.Args())
+	if inputErr != nil {
+		errMsg := ToErrMsg(inputErr, ErrNoInput)
+		return opts, &errMsg
+	}
+	opts.FilePath = path
+	if opts.Chunk < 1 {
+		return opts, &ErrMsg{Err: fmt.Errorf("--chunk must be at least 1, got %d", opts.Chunk), Code: ErrInvalidArgs}
+	}
+	if opts.Record = strings.TrimSpace(opts.Record); len(opts.Record) == 0 {
+		return opts, &ErrMsg{Err: errors.New("--record must not be empty"), Code: ErrInvalidArgs}
+	}
+	if !ValidBOMMode(opts.BOM) {
+		return opts, &ErrMsg{Err: fmt.Errorf("unknown --bom '%s', expected one of %s", opts.BOM, strings.Join(BOMModes, ", ")), Code: ErrInvalidArgs}
+	}
+	if len(opts.OutputDir) == 0 {
+		opts.OutputDir = filepath.Dir(opts.FilePath)
+	}
+	if len(opts.Prefix) == 0 {
+		opts.Prefix = strings.TrimSuffix(filepath.Base(opts.FilePath), filepath.Ext(opts.FilePath))
+	}
+	return opts, nil
+}
+
+func splitDocument(opts options) ErrMsg {
+	if exists, _ := PathExists(opts.FilePath); !exists {
+		return ErrMsg{Err: fmt.Errorf("file '%s' does not exist", opts.FilePath), Code: ErrNoFile}
+	}
+	if mkdirErr := os.MkdirAll(opts.OutputDir, 0o755); mkdirErr != nil {
+		return ErrMsg{Err: mkdirErr, Code: ErrWriteFile}
+	}
+	input, openErr := os.Open(opts.FilePath)
+	if openErr != nil {
+		return ErrMsg{Err: openErr, Code: ErrReadFile}
+	}
+	defer func(input *os.File) {
+		_ = input.Close()
+	}(input)
+
+	s := &splitter{opts: opts, base: opts.Prefix}
+	defer func(s *splitter) {
+		if s.current != nil {
+			_ = s.current.file.Close()
+		}
+	}(s)
+	if splitErr := s.split(bufio.NewReader(input)); splitErr != nil {
+		var existsErr *existsError
+		if errors.As(splitErr, &existsErr) {
+			return ErrMsg{Err: splitErr, Code: ErrWriteFile}
+		}
+		return ErrMsg{Err: fmt.Errorf("'%s': %w", opts.FilePath, splitErr), Code: ErrParse}
+	}
+	if s.records == 0 {
+		return ErrMsg{Err: fmt.Errorf("no <%s> records found in '%s'", opts.Record, opts.FilePath), Code: ErrInvalidArgs}
+	}
+	for _, path := range s.parts {
+		log.Info("Wrote part", "output", path)
+	}
+	log.Info("Split document", "records", s.records, "parts", len(s.parts))
+	return ErrMsg{Code: Success}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	. "GoTools/pkg/helpers"
+)
+
+const xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+
+// element is an element of the input document with its attributes, children and text.
+type element struct {
+	Name     string
+	Attrs    []xml.Attr
+	Children []*element
+	Text     strings.Builder
+	// Type and Nil hold the element's xsi:type and xsi:nil annotations, which are applied to its value
+	// rather than written as attributes.
+	Type string
+	Nil  bool
+}
+
+// readDocument decodes the XML read from r into a tree of elements, returning its root.
+func readDocument(r io.Reader) (*element, error) {
+	decoder := xml.NewDecoder(r)
+	var root *element
+	var stack []*element
+	for {
+		token, tokenErr := decoder.Token()
+		if errors.Is(tokenErr, io.EOF) {
+			break
+		} else if tokenErr != nil {
+			return nil, tokenErr
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &element{Name: t.Name.Local}
+			for _, attr := range t.Attr {
+				switch {
+				case attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns"):
+					continue
+				case attr.Name.Space == xsiNamespace && attr.Name.Local == "type":
+					node.Type = attr.Value
+				case attr.Name.Space == xsiNamespace && attr.Name.Local == "nil":
+					node.Nil = attr.Value == "true"
+				case attr.Name.Space == xsiNamespace:
+					continue
+				default:
+					node.Attrs = append(node.Attrs, attr)
+				}
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, node)
+			} else {
+				root = node
+			}
+			stack = append(stack, node)
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].Text.Write(t)
+			}
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if root == nil {
+		return nil, errors.New("the document has no root element")
+	}
+	return root, nil
+}
+
+// field is a key and value of a JSON object.
+type field struct {
+	Key   string
+	Value interface{}
+}
+
+// object is a JSON object that keeps its keys in document order, which a map would not.
+type object []field
+
+// MarshalJSON writes the fields of o in order.
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, keyErr := json.Marshal(f.Key)
+		if keyErr != nil {
+			return nil, keyErr
+		}
+		value, valueErr := json.Marshal(f.Value)
+		if valueErr != nil {
+			return nil, valueErr
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// converter turns elements into JSON values following the conversion options.
+type converter struct {
+	opts options
+}
+
+// name returns the JSON key for an element or attribute name, decoding escaped characters if requested.
+func (c converter) name(name string) string {
+	if c.opts.DecodeNames {
+		return DecodeXMLName(name)
+	}
+	return name
+}
+
+// value converts an element into a JSON value.
+// An element marked xsi:nil becomes null, and one with neither attributes nor children becomes a scalar.
+// Otherwise it becomes an object holding its attributes, under the attribute prefix, then its children,
+// where a name that repeats, or that is listed for array coercion, becomes an array,
+// and finally any text under the text key.
+func (c converter) value(node *element) interface{} {
+	if node.Nil {
+		return nil
+	}
+	text := node.Text.String()
+	if len(node.Attrs) == 0 && len(node.Children) == 0 {
+		return c.scalar(text, node.Type)
+	}
+	var result object
+	for _, attr := range node.Attrs {
+		result = append(result, field{Key: c.opts.AttrPrefix + c.name(attr.Name.Local), Value: c.scalar(attr.Value, "")})
+	}
+	var names []string
+	groups := make(map[string][]interface{})
+	for _, child := range node.Children {
+		if _, seen := groups[child.Name]; !seen {
+			names = append(names, child.Name)
+		}
+		groups[child.Name] = append(groups[child.Name], c.value(child))
+	}
+	for _, name := range names {
+		values := groups[name]
+		if len(values) > 1 || c.opts.ForceArrays || slices.Contains(c.opts.Arrays, name) {
+			result = append(result, field{Key: c.name(name), Value: values})
+		} else {
+			result = append(result, field{Key: c.name(name), Value: values[0]})
+		}
+	}
+	if trimmed := strings.TrimSpace(text); len(trimmed) > 0 {
+		result = append(result, field{Key: c.opts.TextKey, Value: c.scalar(trimmed, node.Type)})
+	}
+	if result == nil {
+		return object{}
+	}
+	return result
+}
+
+// scalar converts text to a JSON number or boolean when its xsi:type says so, or when types are inferred,
+// leaving it as a string otherwise.
+func (c converter) scalar(text, xsiType string) interface{} {
+	switch xsiType {
+	case "xs:double", "xs:decimal", "xs:int", "xs:integer", "xs:long", "xs:float", "xs:short":
+		if _, err := strconv.ParseFloat(strings.TrimSpace(text), 64); err == nil {
+			return json.Number(strings.TrimSpace(text))
+		}
+	case "xs:boolean":
+		if boolean, err := strconv.ParseBool(strings.TrimSpace(text)); err == nil {
+			return boolean
+		}
+	case "":
+		if c.opts.InferTypes {
+			return TypedValue(text)
+		}
+	}
+	return text
+}
+
+// convert returns the JSON value for a document, an object keyed by the root element's name
+// or, with StripRoot, the root element's own value.
+func (c converter) convert(root *element) interface{} {
+	if c.opts.StripRoot {
+		return c.value(root)
+	}
+	return object{{Key: c.name(root.Name), Value: c.value(root)}}
+}
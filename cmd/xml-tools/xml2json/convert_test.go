@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		options func(*options)
+		want    string
+	}{
+		{
+			name:  "Scalars",
+			input: "<DataTable><Row><Name>Ann</Name><Age>42</Age></Row></DataTable>",
+			want:  `{"DataTable":{"Row":{"Name":"Ann","Age":"42"}}}`,
+		},
+		{
+			name:  "RepeatedNames",
+			input: "<DataTable><Row><Id>1</Id></Row><Row><Id>2</Id></Row></DataTable>",
+			want:  `{"DataTable":{"Row":[{"Id":"1"},{"Id":"2"}]}}`,
+		},
+		{
+			name:    "Arrays",
+			input:   "<DataTable><Row><Id>1</Id></Row></DataTable>",
+			options: func(opts *options) { opts.Arrays = []string{"Row"} },
+			want:    `{"DataTable":{"Row":[{"Id":"1"}]}}`,
+		},
+		{
+			name:    "ForceArrays",
+			input:   "<DataTable><Row><Id>1</Id></Row></DataTable>",
+			options: func(opts *options) { opts.ForceArrays = true },
+			want:    `{"DataTable":{"Row":[{"Id":["1"]}]}}`,
+		},
+		{
+			name:  "AttributesAndText",
+			input: `<Cell ref="A1" hidden="true">  Total  </Cell>`,
+			want:  `{"Cell":{"@ref":"A1","@hidden":"true","#text":"Total"}}`,
+		},
+		{
+			name:    "OwnPrefixAndTextKey",
+			input:   `<Cell ref="A1">Total</Cell>`,
+			options: func(opts *options) { opts.AttrPrefix, opts.TextKey = "_", "value" },
+			want:    `{"Cell":{"_ref":"A1","value":"Total"}}`,
+		},
+		{
+			name:  "XSITypes",
+			input: `<Row xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"><A xsi:type="xs:double">1.5</A><B xsi:type="xs:boolean">true</B><C xsi:nil="true"/><D xsi:type="xs:double">abc</D></Row>`,
+			want:  `{"Row":{"A":1.5,"B":true,"C":null,"D":"abc"}}`,
+		},
+		{
+			name:    "InferTypes",
+			input:   "<Row><A>1.5</A><B>false</B><C>007</C></Row>",
+			options: func(opts *options) { opts.InferTypes = true },
+			want:    `{"Row":{"A":1.5,"B":false,"C":"007"}}`,
+		},
+		{
+			name:    "StripRoot",
+			input:   "<DataTable><Row>1</Row></DataTable>",
+			options: func(opts *options) { opts.StripRoot = true },
+			want:    `{"Row":"1"}`,
+		},
+		{
+			name:    "DecodeNames",
+			input:   "<Row><Order_x0020_Date>2024-01-31</Order_x0020_Date></Row>",
+			options: func(opts *options) { opts.DecodeNames = true },
+			want:    `{"Row":{"Order Date":"2024-01-31"}}`,
+		},
+		{
+			name:  "EmptyElement",
+			input: "<Row><A/></Row>",
+			want:  `{"Row":{"A":""}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := options{AttrPrefix: "@", TextKey: "#text"}
+			if test.options != nil {
+				test.options(&opts)
+			}
+			root, readErr := readDocument(strings.NewReader(test.input))
+			if readErr != nil {
+				t.Fatal(readErr)
+			}
+			got, marshalErr := json.Marshal(converter{opts: opts}.convert(root))
+			if marshalErr != nil {
+				t.Fatal(marshalErr)
+			}
+			if string(got) != test.want {
+				t.Errorf("convert() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReadDocumentErrors(t *testing.T) {
+	for _, input := range []string{"", "<a><b></a>", "text"} {
+		if _, err := readDocument(strings.NewReader(input)); err == nil {
+			t.Errorf("readDocument(%q) succeeded, want an error", input)
+		}
+	}
+}
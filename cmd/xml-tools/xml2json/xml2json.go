@@ -0,0 +1,148 @@
+// This program converts XML documents, such as the DataTable documents written by parse-xml, into JSON,
+// so they can be consumed without an XML parser.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	. "GoTools/pkg/helpers"
+	"GoTools/pkg/logging"
+	"github.com/charmbracelet/log"
+)
+
+// options holds the command line settings controlling the conversion.
+type options struct {
+	Input       Input
+	Output      string
+	Force       bool
+	AttrPrefix  string
+	TextKey     string
+	Arrays      []string
+	ForceArrays bool
+	StripRoot   bool
+	InferTypes  bool
+	DecodeNames bool
+	Compact     bool
+}
+
+func main() {
+	startTime := time.Now()
+
+	processingErr := ErrMsg{Code: Success}
+	defer func() {
+		log.Debug(
+			"DONE!",
+			"time", time.Since(startTime),
+		)
+		processingErr.Exit()
+	}()
+	opts, argErr := getInput()
+	if argErr != nil {
+		processingErr = *argErr
+		return
+	}
+	processingErr = processDocument(opts)
+}
+
+// getInput parses the command line flags. The file is given with --path or as an argument, falling back to
+// a path piped through standard input.
+func getInput() (options, *ErrMsg) {
+	var opts options
+	var path, arrays string
+	flag.StringVar(&path, "path", "", "The path to the XML file to convert, or - to read the XML from standard input")
+	flag.StringVar(&opts.Output, "output", "", "Write the JSON to this file instead of stdout")
+	flag.StringVar(&opts.Output, "o", "", "Shorthand for --output")
+	flag.BoolVar(&opts.Force, "force", false, "Overwrite the --output file if it already exists")
+	flag.StringVar(&opts.AttrPrefix, "attr-prefix", "@", "The prefix added to the keys of attributes, to tell them apart from child elements")
+	flag.StringVar(&opts.TextKey, "text-key", "#text", "The key holding the text of an element that also has attributes or children")
+	flag.StringVar(&arrays, "array", "", "Comma-separated element names that always become arrays, even when they appear once (e.g. Row)")
+	flag.BoolVar(&opts.ForceArrays, "force-arrays", false, "Make every child element an array, so the shape of the JSON does not depend on the data")
+	flag.BoolVar(&opts.StripRoot, "strip-root", false, "Write the root element's value on its own rather than as an object keyed by its name")
+	flag.BoolVar(&opts.InferTypes, "infer-types", false, "Write numbers and booleans without an xsi:type as JSON numbers and booleans rather than strings")
+	flag.BoolVar(&opts.DecodeNames, "decode-names", false, "Decode escaped characters in names, such as Order_x0020_Date, back into the original header")
+	flag.BoolVar(&opts.Compact, "compact", false, "Write the JSON on a single line instead of indenting it")
+	RegisterErrorFormatFlag(flag.CommandLine)
+	logging.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if logErr := logging.Setup(); logErr != nil {
+		return opts, &ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+	}
+
+	opts.Arrays = splitList(arrays)
+	input, inputErr := ResolveInput(path, flag.Args())
+	if inputErr != nil {
+		errMsg := ToErrMsg(inputErr, ErrNoInput)
+		return opts, &errMsg
+	}
+	opts.Input = input
+	if len(opts.TextKey) == 0 {
+		return opts, &ErrMsg{Err: errors.New("--text-key must not be empty"), Code: ErrInvalidArgs}
+	}
+	return opts, nil
+}
+
+// splitList splits a comma-separated flag value, discarding surrounding whitespace and blanks.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func processDocument(opts options) ErrMsg {
+	if exists, _ := PathExists(opts.Input.Path); !exists && !opts.Input.IsStdin() {
+		return ErrMsg{Err: fmt.Errorf("file '%s' does not exist", opts.Input), Code: ErrNoFile}
+	}
+	if len(opts.Output) > 0 {
+		if exists, _ := PathExists(opts.Output); exists && !opts.Force {
+			return ErrMsg{Err: fmt.Errorf("output file '%s' already exists, use --force to overwrite it", opts.Output), Code: ErrWriteFile}
+		}
+	}
+	input, openErr := opts.Input.Open()
+	if openErr != nil {
+		return ErrMsg{Err: openErr, Code: ErrReadFile}
+	}
+	defer func(input io.ReadCloser) {
+		_ = input.Close()
+	}(input)
+	root, readErr := readDocument(bufio.NewReader(input))
+	if readErr != nil {
+		return ErrMsg{Err: readErr, Code: ErrParse}
+	}
+
+	value := converter{opts: opts}.convert(root)
+	var marshalled []byte
+	var marshalErr error
+	if opts.Compact {
+		marshalled, marshalErr = json.Marshal(value)
+	} else {
+		marshalled, marshalErr = json.MarshalIndent(value, "", "  ")
+	}
+	if marshalErr != nil {
+		return ErrMsg{Err: marshalErr, Code: ErrParse}
+	}
+	marshalled = append(marshalled, '\n')
+
+	if len(opts.Output) == 0 {
+		if _, writeErr := os.Stdout.Write(marshalled); writeErr != nil {
+			return ErrMsg{Err: writeErr, Code: ErrStdout}
+		}
+		return ErrMsg{Code: Success}
+	}
+	if writeErr := AtomicWriteFile(opts.Output, marshalled); writeErr != nil {
+		return ErrMsg{Err: writeErr, Code: ErrWriteFile}
+	}
+	log.Info("Wrote JSON", "output", opts.Output)
+	return ErrMsg{Code: Success}
+}
@@ -5,6 +5,7 @@ go 1.22.0
 require (
 	github.com/charmbracelet/log v0.4.0
 	github.com/xuri/excelize/v2 v2.8.1
+	golang.org/x/text v0.14.0
 )
 
 require (
@@ -26,5 +27,4 @@ require (
 	golang.org/x/exp v0.0.0-20231006140011-7918f672742d // indirect
 	golang.org/x/net v0.22.0 // indirect
 	golang.org/x/sys v0.18.0 // indirect
-	golang.org/x/text v0.14.0 // indirect
 )
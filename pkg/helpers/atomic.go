@@ -0,0 +1,96 @@
+package helpers
+
+import (
+	"bufio"
+	"os"
+	"time"
+)
+
+// AtomicFile is a temporary file beside the file it replaces once committed, so a failed or interrupted write
+// never leaves that file truncated. Once committed, the file keeps the permissions it had, or 0644 if it is new.
+// Example usage:
+//
+//	output, createErr := CreateAtomic(path)
+//	if createErr != nil {
+//		return createErr
+//	}
+//	if writeErr := write(output); writeErr != nil {
+//		output.Abort()
+//		return writeErr
+//	}
+//	return output.Commit()
+type AtomicFile struct {
+	*bufio.Writer
+	path string
+	mode os.FileMode
+	file *os.File
+	// ModTime, if set, is given to the file before it replaces the original.
+	ModTime time.Time
+}
+
+// CreateAtomic starts writing a replacement for the file at path, which need not exist yet.
+func CreateAtomic(path string) (*AtomicFile, error) {
+	mode := os.FileMode(0o644)
+	if info, statErr := os.Stat(path); statErr == nil {
+		mode = info.Mode().Perm()
+	}
+	tempFile, err := TempFileSibling(path, "")
+	if err != nil {
+		return nil, err
+	}
+	return &AtomicFile{Writer: bufio.NewWriter(tempFile), path: path, mode: mode, file: tempFile}, nil
+}
+
+// Name returns the path of the file being replaced.
+func (a *AtomicFile) Name() string {
+	return a.path
+}
+
+// Commit flushes and syncs what has been written and renames it over the file being replaced.
+// The temporary file is removed if any step fails.
+func (a *AtomicFile) Commit() (err error) {
+	defer func() {
+		if err != nil {
+			a.Abort()
+		}
+	}()
+	if err = a.Flush(); err != nil {
+		return err
+	}
+	if err = a.file.Sync(); err != nil {
+		return err
+	}
+	if err = a.file.Chmod(a.mode); err != nil {
+		return err
+	}
+	if err = a.file.Close(); err != nil {
+		return err
+	}
+	if !a.ModTime.IsZero() {
+		// A zero access time is left as it is
+		if err = os.Chtimes(a.file.Name(), time.Time{}, a.ModTime); err != nil {
+			return err
+		}
+	}
+	return os.Rename(a.file.Name(), a.path)
+}
+
+// Abort discards what has been written, leaving the file being replaced as it was.
+func (a *AtomicFile) Abort() {
+	_ = a.file.Close()
+	_ = os.Remove(a.file.Name())
+}
+
+// AtomicWriteFile writes data to the file at path as os.WriteFile does, but through an AtomicFile, so the file
+// is either replaced whole or left as it was.
+func AtomicWriteFile(path string, data []byte) error {
+	output, createErr := CreateAtomic(path)
+	if createErr != nil {
+		return createErr
+	}
+	if _, writeErr := output.Write(data); writeErr != nil {
+		output.Abort()
+		return writeErr
+	}
+	return output.Commit()
+}
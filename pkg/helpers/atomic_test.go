@@ -0,0 +1,58 @@
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateAtomic(t *testing.T) {
+	const oldContent, newContent = "old", "new"
+	tests := []struct {
+		name     string
+		existing bool
+		abort    bool
+		want     string
+		wantMode os.FileMode
+	}{
+		{name: "Existing", existing: true, want: newContent, wantMode: 0o600},
+		{name: "New", want: newContent, wantMode: 0o644},
+		{name: "Abort", existing: true, abort: true, want: oldContent, wantMode: 0o600},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "doc.xml")
+			if test.existing {
+				if err := os.WriteFile(path, []byte(oldContent), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			file, err := CreateAtomic(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = file.WriteString(newContent); err != nil {
+				t.Fatal(err)
+			}
+			if test.abort {
+				file.Abort()
+			} else if err = file.Commit(); err != nil {
+				t.Fatal(err)
+			}
+			content, readErr := os.ReadFile(path)
+			if readErr != nil {
+				t.Fatal(readErr)
+			}
+			if string(content) != test.want {
+				t.Errorf("content = %q, want %q", content, test.want)
+			}
+			if info, _ := os.Stat(path); info.Mode().Perm() != test.wantMode {
+				t.Errorf("mode = %v, want %v", info.Mode().Perm(), test.wantMode)
+			}
+			if temps, _ := filepath.Glob(filepath.Join(dir, ".*.tmp")); len(temps) > 0 {
+				t.Errorf("left temporary files %q", temps)
+			}
+		})
+	}
+}
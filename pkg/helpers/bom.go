@@ -0,0 +1,53 @@
+package helpers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+)
+
+// The values accepted by the --bom flag of the tools writing XML and CSV.
+const (
+	BOMPreserve = "preserve"
+	BOMStrip    = "strip"
+	BOMAdd      = "add"
+)
+
+// BOMModes lists the --bom values, with the default first.
+var BOMModes = []string{BOMPreserve, BOMStrip, BOMAdd}
+
+// UTF8BOM is the byte order mark that may start a UTF-8 file. Excel needs it to read a CSV file as UTF-8,
+// while some XML parsers, notably older Java ones, fail on it.
+var UTF8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// ValidBOMMode checks if mode is one of BOMModes.
+func ValidBOMMode(mode string) bool {
+	return slices.Contains(BOMModes, mode)
+}
+
+// SkipBOM returns a reader of r that leaves out a leading UTF-8 byte order mark, and whether r started with one,
+// so the mark never ends up in the first header or before the XML declaration.
+func SkipBOM(r io.Reader) (io.Reader, bool, error) {
+	buffered := bufio.NewReader(r)
+	head, err := buffered.Peek(len(UTF8BOM))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, false, err
+	}
+	if !bytes.Equal(head, UTF8BOM) {
+		return buffered, false, nil
+	}
+	_, err = buffered.Discard(len(UTF8BOM))
+	return buffered, true, err
+}
+
+// WriteBOM writes a UTF-8 byte order mark to w if mode calls for one: always for BOMAdd, never for BOMStrip,
+// and for BOMPreserve only if hadBOM reports that the input started with one.
+func WriteBOM(w io.Writer, mode string, hadBOM bool) error {
+	if mode == BOMAdd || (mode == BOMPreserve && hadBOM) {
+		_, err := w.Write(UTF8BOM)
+		return err
+	}
+	return nil
+}
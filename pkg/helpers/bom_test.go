@@ -0,0 +1,53 @@
+package helpers
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSkipBOM(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantBOM bool
+	}{
+		{input: "\ufeffName,Age\n", want: "Name,Age\n", wantBOM: true},
+		{input: "Name,Age\n", want: "Name,Age\n"},
+		{input: "\xef\xbb", want: "\xef\xbb"},
+		{input: "", want: ""},
+	}
+	for _, test := range tests {
+		reader, hadBOM, err := SkipBOM(strings.NewReader(test.input))
+		if err != nil {
+			t.Errorf("SkipBOM(%q): %v", test.input, err)
+			continue
+		}
+		got, _ := io.ReadAll(reader)
+		if string(got) != test.want || hadBOM != test.wantBOM {
+			t.Errorf("SkipBOM(%q) = %q, %v, want %q, %v", test.input, got, hadBOM, test.want, test.wantBOM)
+		}
+	}
+}
+
+func TestWriteBOM(t *testing.T) {
+	tests := []struct {
+		mode   string
+		hadBOM bool
+		want   string
+	}{
+		{mode: BOMPreserve, hadBOM: true, want: "\ufeff"},
+		{mode: BOMPreserve, want: ""},
+		{mode: BOMStrip, hadBOM: true, want: ""},
+		{mode: BOMAdd, want: "\ufeff"},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := WriteBOM(&buf, test.mode, test.hadBOM); err != nil {
+			t.Errorf("WriteBOM(%q, %v): %v", test.mode, test.hadBOM, err)
+		} else if buf.String() != test.want {
+			t.Errorf("WriteBOM(%q, %v) wrote %q, want %q", test.mode, test.hadBOM, buf.String(), test.want)
+		}
+	}
+}
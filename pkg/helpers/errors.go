@@ -1,8 +1,14 @@
 package helpers
 
 import (
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"slices"
+	"strings"
 )
 
 const (
@@ -17,11 +23,74 @@ const (
 	ErrNoFile
 	ErrInvalidFileType
 	ErrParse
+	ErrInvalidArgs
+	ErrDifferences
+	ErrValidation
+	ErrPartialFailure
+	ErrAllFailed
 )
 
+// ErrorCode describes an exit code with a stable symbolic name, for scripts to branch on instead of the number.
+type ErrorCode struct {
+	Code        int
+	Name        string
+	Description string
+}
+
+// ErrorCodes is the registry of the exit codes above, in order.
+var ErrorCodes = []ErrorCode{
+	{Success, "SUCCESS", "The run completed without errors"},
+	{ErrReadFile, "ERR_READ_FILE", "An input file could not be read"},
+	{ErrWriteFile, "ERR_WRITE_FILE", "An output file could not be written"},
+	{ErrReadWrite, "ERR_READ_WRITE", "Processing failed while reading input or writing output"},
+	{ErrMoveFile, "ERR_MOVE_FILE", "A file could not be moved into place"},
+	{ErrStdin, "ERR_STDIN", "Standard input could not be read"},
+	{ErrStdout, "ERR_STDOUT", "Standard output could not be written"},
+	{ErrNoInput, "ERR_NO_INPUT", "No input was given by argument, flag or pipe"},
+	{ErrNoFile, "ERR_NO_FILE", "The input file or directory does not exist or holds no files to process"},
+	{ErrInvalidFileType, "ERR_INVALID_FILE_TYPE", "The input is not of a type the tool accepts"},
+	{ErrParse, "ERR_PARSE", "The input could not be parsed"},
+	{ErrInvalidArgs, "ERR_INVALID_ARGS", "The command line arguments are invalid"},
+	{ErrDifferences, "ERR_DIFFERENCES", "A check or comparison found differences"},
+	{ErrValidation, "ERR_VALIDATION", "The input failed validation"},
+	{ErrPartialFailure, "ERR_PARTIAL_FAILURE", "Some of the files could not be processed"},
+	{ErrAllFailed, "ERR_ALL_FAILED", "None of the files could be processed"},
+}
+
+// LookupErrorCode returns the registry entry for code, or one named ERR_UNKNOWN if there is none.
+func LookupErrorCode(code int) ErrorCode {
+	for _, entry := range ErrorCodes {
+		if entry.Code == code {
+			return entry
+		}
+	}
+	return ErrorCode{Code: code, Name: "ERR_UNKNOWN", Description: "An error without an entry in the registry"}
+}
+
+// ErrorFormats lists the values accepted by the --error-format flag, with the default first.
+var ErrorFormats = []string{"text", "json"}
+
+// errorFormat is how Exit writes an error, as set by --error-format.
+var errorFormat = ErrorFormats[0]
+
+// RegisterErrorFormatFlag adds the --error-format flag, which sets how Exit writes an error, to fs.
+// Each command calls it before parsing its flags, so the tools share the flag without importing helpers
+// registering anything.
+func RegisterErrorFormatFlag(fs *flag.FlagSet) {
+	fs.Func("error-format", "How to write an error on exit: 'text', or 'json' as one line of {\"code\", \"exit_code\", \"description\", \"error\"} for orchestration tooling", func(value string) error {
+		if !slices.Contains(ErrorFormats, value) {
+			return fmt.Errorf("expected one of %s", strings.Join(ErrorFormats, ", "))
+		}
+		errorFormat = value
+		return nil
+	})
+}
+
 // ErrMsg is a custom error type that represents an error and its corresponding Code.
 // Err is the error that occurred.
 // Code is the Code associated with the error.
+// ErrMsg is itself an error that unwraps to Err, so errors.Is and errors.As see through it,
+// and errors.Is(err, ErrMsg{Code: ErrParse}) checks its Code; see Wrap.
 // Example usage:
 //
 //	var processingErr ErrMsg = ErrMsg{nil, Success}
@@ -35,8 +104,9 @@ type ErrMsg struct {
 	Code int
 }
 
-// Exit terminates the program with the provided exit Code and prints an error message if there is an error.
-// If e.Err is not nil, it prints "An error occurred: <error message>" before exiting.
+// Exit terminates the program with the provided exit Code and prints an error message if there is an error,
+// as Handle does. Only a tool's main should call it; code meant to be used from tests or other programs
+// returns its ErrMsg instead, leaving the decision to exit to the top level.
 // It uses defer to ensure that os.Exit is always called, even if an error occurs.
 // Example usage:
 //
@@ -48,7 +118,94 @@ type ErrMsg struct {
 //	processingErr = processCSV(...)
 func (e *ErrMsg) Exit() {
 	defer os.Exit(e.Code)
+	e.Handle(os.Stderr)
+}
+
+// Handle prints the error message to w if there is an error, and returns the exit Code without exiting.
+// If e.Err is not nil, it prints "An error occurred: <error message>", or with --error-format json the JSON
+// of MarshalJSON. Tools write it to stderr, so the message never ends up mixed into output written to stdout.
+// It is the non-exiting counterpart of Exit, for tests and programs that run a tool's logic in-process.
+// Example usage:
+//
+//	if code := processCSV(...).Handle(os.Stderr); code != Success {
+//		return fmt.Errorf("processing failed with code %d", code)
+//	}
+func (e ErrMsg) Handle(w io.Writer) int {
+	if e.Err == nil {
+		return e.Code
+	}
+	if errorFormat == "json" {
+		if data, err := json.Marshal(e); err == nil {
+			_, _ = fmt.Fprintln(w, string(data))
+			return e.Code
+		}
+	}
+	_, _ = fmt.Fprintf(w, "An error occured!\nError Code: %d\nDetail: %v\n", e.Code, e.Err)
+	return e.Code
+}
+
+// MarshalJSON writes e as its Code's symbolic name, number and description, along with the message of Err
+// if there is one, as in {"code":"ERR_PARSE","exit_code":10,"description":"...","error":"..."}.
+func (e ErrMsg) MarshalJSON() ([]byte, error) {
+	entry := LookupErrorCode(e.Code)
+	message := ""
 	if e.Err != nil {
-		fmt.Printf("An error occured!\nError Code: %d\nDetail: %v\n", e.Code, e.Err)
+		message = e.Err.Error()
 	}
+	return json.Marshal(struct {
+		Code        string `json:"code"`
+		ExitCode    int    `json:"exit_code"`
+		Description string `json:"description"`
+		Error       string `json:"error,omitempty"`
+	}{entry.Name, entry.Code, entry.Description, message})
+}
+
+// Wrap returns an ErrMsg with the given Code for err, prefixing its message with context if that is not empty,
+// as in "reading input.xml: ...". A nil err is wrapped as Success, so a result can be wrapped unconditionally.
+// Example usage:
+//
+//	processingErr = Wrap(readErr, ErrReadFile, "reading "+path)
+//	if errors.Is(processingErr, fs.ErrNotExist) { ... }
+func Wrap(err error, code int, context string) ErrMsg {
+	if err == nil {
+		return ErrMsg{Code: Success}
+	}
+	if len(context) > 0 {
+		err = fmt.Errorf("%s: %w", context, err)
+	}
+	return ErrMsg{Err: err, Code: code}
+}
+
+// ToErrMsg returns err as an ErrMsg: the ErrMsg it is or wraps, if any, or else one with the given Code.
+func ToErrMsg(err error, code int) ErrMsg {
+	var msg ErrMsg
+	if errors.As(err, &msg) {
+		return msg
+	}
+	return ErrMsg{Err: err, Code: code}
+}
+
+// Errorf returns an ErrMsg with the given Code for an error formatted as fmt.Errorf does, so %w wraps.
+func Errorf(code int, format string, args ...any) ErrMsg {
+	return ErrMsg{Err: fmt.Errorf(format, args...), Code: code}
+}
+
+// Error returns the message of Err, or a description of the Code if there is no Err.
+func (e ErrMsg) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit code %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns Err, so errors.Is and errors.As look at the error behind the Code.
+func (e ErrMsg) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is an ErrMsg without an Err and with the same Code, so an error can be checked
+// for a Code with errors.Is(err, ErrMsg{Code: ErrParse}).
+func (e ErrMsg) Is(target error) bool {
+	t, ok := target.(ErrMsg)
+	return ok && t.Err == nil && t.Code == e.Code
 }
@@ -0,0 +1,45 @@
+package helpers
+
+import (
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestRegisterErrorFormatFlag(t *testing.T) {
+	if flag.CommandLine.Lookup("error-format") != nil {
+		t.Fatal("importing helpers registered --error-format on the default flag set")
+	}
+	defer func(saved string) { errorFormat = saved }(errorFormat)
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{"Default", nil, "text", false},
+		{"JSON", []string{"--error-format", "json"}, "json", false},
+		{"Unknown", []string{"--error-format", "xml"}, "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			errorFormat = ErrorFormats[0]
+			fs := flag.NewFlagSet(test.name, flag.ContinueOnError)
+			fs.SetOutput(io.Discard)
+			RegisterErrorFormatFlag(fs)
+			err := fs.Parse(test.args)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("Parse(%q) succeeded, want an error", test.args)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", test.args, err)
+			}
+			if errorFormat != test.want {
+				t.Errorf("errorFormat = %q, want %q", errorFormat, test.want)
+			}
+		})
+	}
+}
@@ -2,10 +2,12 @@ package helpers
 
 import (
 	"io"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
 )
 
 // PathExists checks if a path exists or not.
@@ -28,53 +30,125 @@ func CheckExtension(path string, extension string) bool {
 	return filepath.Ext(path) == strings.ToLower(extension)
 }
 
-func MoveFile(src, dst string) (err error) {
-	// Open original file.
-	originalFile, err := os.Open(src)
-	if err != nil {
-		return err
+// TempFileSibling creates a temporary file in the directory of target, opened for reading and writing with
+// mode 0600, as os.CreateTemp does with pattern. An empty pattern names it after target, as in .data.csv.*.tmp,
+// with the leading dot hiding it from directory listings while it exists.
+// Being on the same volume as target, the file can replace it with a rename, rather than a slow copy or a
+// failure with EXDEV as a file in the OS temp directory risks.
+func TempFileSibling(target string, pattern string) (*os.File, error) {
+	if len(pattern) == 0 {
+		pattern = "." + filepath.Base(target) + ".*.tmp"
 	}
+	return os.CreateTemp(filepath.Dir(target), pattern)
+}
 
-	// Create new file.
-	newFile, err := os.Create(dst)
-	if err != nil {
-		err := originalFile.Close()
-		if err != nil {
-			return err
-		}
-		return err
+// MoveOptions controls how MoveFileWith moves a file.
+type MoveOptions struct {
+	// PreserveModTime gives a file copied across devices the modification time of the source, as a rename does.
+	PreserveModTime bool
+}
+
+// MoveFile moves the file at src to dst, replacing any file already there, as MoveFileWith does with the
+// default MoveOptions.
+func MoveFile(src, dst string) error {
+	return MoveFileWith(src, dst, MoveOptions{})
+}
+
+// MoveFileWith moves the file at src to dst, replacing any file already there. It renames the file when it can,
+// which keeps its mode and modification time; when it cannot, such as across devices, it copies the file
+// into a temporary file beside dst, syncs it to disk, renames it over dst and only then removes src.
+// The copy is given the mode of src, and its modification time with opts.PreserveModTime. If copying fails,
+// the temporary file is removed, leaving both src and any file already at dst as they were.
+func MoveFileWith(src, dst string, opts MoveOptions) error {
+	renameErr := os.Rename(src, dst)
+	if renameErr == nil {
+		return nil
+	}
+	log.Debug("Falling back to copying the file", "source", src, "destination", dst, "reason", renameErr)
+	if copyErr := copyFile(src, dst, opts); copyErr != nil {
+		return copyErr
 	}
+	return os.Remove(src)
+}
 
-	// Copy the bytes to destination from source.
-	_, err = io.Copy(newFile, originalFile)
-	if err != nil {
-		err := newFile.Close()
-		if err != nil {
-			return err
+// copyFile copies the file at src over dst for MoveFileWith, through a synced temporary file beside dst,
+// so dst is only replaced by a complete copy.
+func copyFile(src, dst string, opts MoveOptions) (err error) {
+	original, openErr := os.Open(src)
+	if openErr != nil {
+		return openErr
+	}
+	defer func(original *os.File) {
+		if err := original.Close(); err != nil {
+			log.Warn("Failed to close original file", "error", err)
 		}
-		err = originalFile.Close()
+	}(original)
+	info, statErr := original.Stat()
+	if statErr != nil {
+		return statErr
+	}
+	copied, createErr := TempFileSibling(dst, "")
+	if createErr != nil {
+		return createErr
+	}
+	defer func(copied *os.File) {
 		if err != nil {
-			return err
+			_ = copied.Close()
+			_ = os.Remove(copied.Name())
 		}
+	}(copied)
+	if _, err = io.Copy(copied, original); err != nil {
 		return err
 	}
-
-	// Closes files
-	err = newFile.Close()
-	if err != nil {
-		log.Printf("Failed to close new file: %v", err)
+	// The temporary file is created with mode 0600
+	if err = copied.Chmod(info.Mode().Perm()); err != nil {
+		return err
 	}
-
-	err = originalFile.Close()
-	if err != nil {
-		log.Printf("Failed to close original file: %v", err)
+	if err = copied.Sync(); err != nil {
+		return err
 	}
-
-	// Remove original file.
-	err = os.Remove(src)
-	if err != nil {
+	if err = copied.Close(); err != nil {
 		return err
 	}
+	if opts.PreserveModTime {
+		if err = os.Chtimes(copied.Name(), time.Time{}, info.ModTime()); err != nil {
+			return err
+		}
+	}
+	return os.Rename(copied.Name(), dst)
+}
+
+// WorkbookExtensions lists the spreadsheet file extensions whose data can be read like a .xlsx workbook.
+// Macro-enabled and template workbooks share the .xlsx layout; their macros are simply ignored.
+var WorkbookExtensions = []string{".xlsx", ".xlsm", ".xltx", ".xltm"}
 
-	return nil
+// IsWorkbookFile checks if the given file path is an Excel workbook that can be parsed.
+// In strict mode only the .xlsx extension is accepted. Otherwise any of WorkbookExtensions is accepted,
+// as is a file with another extension whose content starts with the ZIP signature used by all of them.
+func IsWorkbookFile(path string, strict bool) bool {
+	if strict {
+		return CheckExtension(path, ".xlsx")
+	}
+	for _, extension := range WorkbookExtensions {
+		if CheckExtension(path, extension) {
+			return true
+		}
+	}
+	return HasZipSignature(path)
+}
+
+// HasZipSignature checks if the file at path starts with the local file header signature of a ZIP archive.
+func HasZipSignature(path string) bool {
+	file, openErr := os.Open(path)
+	if openErr != nil {
+		return false
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	signature := make([]byte, 4)
+	if _, readErr := io.ReadFull(file, signature); readErr != nil {
+		return false
+	}
+	return string(signature) == "PK\x03\x04"
 }
@@ -0,0 +1,75 @@
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMoveFileWith(t *testing.T) {
+	modTime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name string
+		// move moves src to dst, either with MoveFileWith or with the copy it falls back to across devices
+		move       func(src, dst string) error
+		missingSrc bool
+		// wantFiles is the number of files left in the directory, as copyFile leaves src for its caller to remove
+		wantFiles int
+		wantErr   bool
+	}{
+		{name: "Rename", move: MoveFile, wantFiles: 1},
+		{name: "Copy", move: func(src, dst string) error {
+			return copyFile(src, dst, MoveOptions{PreserveModTime: true})
+		}, wantFiles: 2},
+		{name: "MissingSource", move: MoveFile, missingSrc: true, wantFiles: 1, wantErr: true},
+		{name: "CopyMissingSource", move: func(src, dst string) error {
+			return copyFile(src, dst, MoveOptions{})
+		}, missingSrc: true, wantFiles: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := filepath.Join(dir, "src.txt")
+			dst := filepath.Join(dir, "dst.txt")
+			if err := os.WriteFile(dst, []byte("old"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if !tt.missingSrc {
+				if err := os.WriteFile(src, []byte("new"), 0o640); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chtimes(src, modTime, modTime); err != nil {
+					t.Fatal(err)
+				}
+			}
+			err := tt.move(src, dst)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("move error = %v, wantErr %v", err, tt.wantErr)
+			}
+			want := "new"
+			if tt.wantErr {
+				want = "old"
+			}
+			if got, readErr := os.ReadFile(dst); readErr != nil || string(got) != want {
+				t.Errorf("dst = %q (%v), want %q", got, readErr, want)
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != tt.wantFiles {
+				t.Errorf("move left %d files, want %d", len(entries), tt.wantFiles)
+			}
+			if tt.wantErr {
+				return
+			}
+			info, statErr := os.Stat(dst)
+			if statErr != nil {
+				t.Fatal(statErr)
+			}
+			if info.Mode().Perm() != 0o640 {
+				t.Errorf("dst mode = %v, want %v", info.Mode().Perm(), os.FileMode(0o640))
+			}
+			if !info.ModTime().Equal(modTime) {
+				t.Errorf("dst modification time = %v, want %v", info.ModTime(), modTime)
+			}
+		})
+	}
+}
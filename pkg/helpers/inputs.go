@@ -0,0 +1,129 @@
+package helpers
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// StdinPath stands for the data on standard input wherever a command takes a path.
+const StdinPath = "-"
+
+// ErrNoInputs is the error ResolveInputs wraps when nothing names an input.
+var ErrNoInputs = errors.New("no input provided by --path, arguments nor standard input")
+
+// Input is a file a command reads, or the data on standard input if Path is StdinPath.
+type Input struct {
+	Path string
+}
+
+// IsStdin reports whether the input is the data on standard input.
+func (in Input) IsStdin() bool {
+	return in.Path == StdinPath
+}
+
+// String returns the path of the input, or "<standard input>" for standard input, for messages.
+func (in Input) String() string {
+	if in.IsStdin() {
+		return "<standard input>"
+	}
+	return in.Path
+}
+
+// Open opens the input for reading. Closing standard input's reader leaves standard input open.
+func (in Input) Open() (io.ReadCloser, error) {
+	if in.IsStdin() {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(in.Path)
+}
+
+// StdinRedirected reports whether standard input is a pipe or a redirected file rather than a terminal.
+func StdinRedirected() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice == 0
+}
+
+// ResolveInputs returns the inputs named by flagVal, a comma-separated --path value, followed by args,
+// the positional arguments left after the flags. When neither names any, and standard input is a pipe or
+// a redirected file, standard input is read as a list of paths, one per line, as in `ls *.csv | tool`.
+// StdinPath names the data on standard input, and may only be given once.
+// Errors are ErrMsg values, with ErrStdin if standard input cannot be read, ErrInvalidArgs for a repeated
+// StdinPath, and ErrNoInput wrapping ErrNoInputs if nothing names an input.
+// Example usage:
+//
+//	inputs, inputErr := ResolveInputs(*filePathPtr, flag.Args())
+//	if inputErr != nil {
+//		processingErr = ToErrMsg(inputErr, ErrNoInput)
+//		return
+//	}
+func ResolveInputs(flagVal string, args []string) ([]Input, error) {
+	var inputs []Input
+	stdin := false
+	add := func(path string) error {
+		if path = strings.TrimSpace(path); len(path) == 0 {
+			return nil
+		}
+		if path == StdinPath {
+			if stdin {
+				return ErrMsg{Err: errors.New("standard input can only be given once"), Code: ErrInvalidArgs}
+			}
+			stdin = true
+		}
+		inputs = append(inputs, Input{Path: path})
+		return nil
+	}
+	for _, path := range append(strings.Split(flagVal, ","), args...) {
+		if err := add(path); err != nil {
+			return nil, err
+		}
+	}
+	if len(inputs) == 0 && StdinRedirected() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			// The paths are read from standard input, so it cannot also hold the data
+			if path := strings.TrimSpace(scanner.Text()); path == StdinPath {
+				return nil, ErrMsg{Err: errors.New("standard input lists the paths, so it cannot also be read as data"), Code: ErrInvalidArgs}
+			} else if err := add(path); err != nil {
+				return nil, err
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, Wrap(err, ErrStdin, "reading paths from standard input")
+		}
+	}
+	if len(inputs) == 0 {
+		return nil, ErrMsg{Err: ErrNoInputs, Code: ErrNoInput}
+	}
+	return inputs, nil
+}
+
+// ResolveInput returns the single input a command reads, resolved as ResolveInputs does.
+// Giving more than one fails with ErrInvalidArgs.
+func ResolveInput(flagVal string, args []string) (Input, error) {
+	inputs, err := ResolveInputs(flagVal, args)
+	if err != nil {
+		return Input{}, err
+	}
+	if len(inputs) > 1 {
+		return Input{}, ErrMsg{Err: fmt.Errorf("expected a single input, got %d", len(inputs)), Code: ErrInvalidArgs}
+	}
+	return inputs[0], nil
+}
+
+// ResolveFile returns the path of the single file a command works on, resolved as ResolveInput does.
+// StdinPath fails with ErrInvalidArgs, as the command needs a file it can open by name, such as a workbook
+// or a file rewritten in place.
+func ResolveFile(flagVal string, args []string) (string, error) {
+	input, err := ResolveInput(flagVal, args)
+	if err != nil {
+		return "", err
+	}
+	if input.IsStdin() {
+		return "", ErrMsg{Err: errors.New("the data on standard input cannot be used here, give the path of a file"), Code: ErrInvalidArgs}
+	}
+	return input.Path, nil
+}
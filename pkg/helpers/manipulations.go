@@ -2,9 +2,17 @@ package helpers
 
 import (
 	"fmt"
-	"log"
+	"math"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
+
+	"github.com/charmbracelet/log"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
 )
 
 // RenameDuplicates takes an input slice of strings and renames any duplicate headers
@@ -43,26 +51,41 @@ func RenameDuplicates(input []string, printOffending bool) []string {
 	if printOffending {
 		for header, count := range counts {
 			if count > 1 {
-				log.Printf("Header '%s' was present %d times\n", header, count)
+				log.Info("Header was present more than once", "header", header, "count", count)
 			}
 		}
 	}
 	return input
 }
 
+// invalidXMLTagChars lists the characters removed or replaced when cleaning a string for use as an XML tag.
+var invalidXMLTagChars = []rune{
+	'(', ')', '<', '>', '/', '\\',
+	'?', '!', '"', '\'', '@', '#', '$',
+	'%', '^', '&', '*', '+', '=', '~',
+	'`', '|', '[', ']', '{', '}', ';',
+	':', ',', '.',
+}
+
+// encodedXMLChar matches a character escaped in an XML name as "_xHHHH_" or "_xHHHHHHHH_".
+var encodedXMLChar = regexp.MustCompile(`_x([0-9A-Fa-f]{4}|[0-9A-Fa-f]{8})_`)
+
+// symbolNames holds the words TransliterateXMLTag uses in place of common symbols.
+var symbolNames = map[rune]string{
+	'$': "Dollar", '€': "Euro", '£': "Pound", '¥': "Yen", '%': "Percent",
+	'&': "And", '#': "Number", '@': "At", '+': "Plus", '=': "Equals",
+	'<': "LessThan", '>': "GreaterThan", '°': "Degrees",
+}
+
 // FixXMLTags takes a string `tag` as input and removes any invalid XML characters from it.
 // It returns the modified string with the cleaned tag.
-// The function first initializes a slice `invalidXmlChars` with a list of invalid XML characters.
+// The invalid XML characters are listed in `invalidXMLTagChars`.
 // These characters are identified as parentheses, angle brackets, slashes, backslashes,
 // question marks, exclamation marks, double and single quotation marks, at signs, hash signs, dollar signs,
 // percent signs, caret symbols, ampersands, asterisks, plus signs, equal signs, tilde, backticks,
 // vertical bars, square brackets, curly braces, semicolons, colons, commas, and periods.
-// The function then iterates over each character in the `invalidXmlChars` slice.
-// For each character, it removes all occurrences of that character in the `tag` string
-// using the `ReplaceAll` function from the `strings` package,
-// and assigns the result back to the `cleanTag` variable.
-// Finally, the function returns the `cleanTag` string, which contains the modified tag
-// with all invalid XML characters removed.
+// All occurrences of each character are removed from the `tag` string using ReplaceXMLTagChars,
+// and spaces are encoded as "_x0020_".
 // Example usage:
 //
 //	tag := "<Hello World!>"
@@ -70,22 +93,69 @@ func RenameDuplicates(input []string, printOffending bool) []string {
 //	fmt.Println(cleanTag)
 //	// Output: "Hello World"
 func FixXMLTags(tag string) string {
-	invalidXmlChars := []rune{
-		'(', ')', '<', '>', '/', '\\',
-		'?', '!', '"', '\'', '@', '#', '$',
-		'%', '^', '&', '*', '+', '=', '~',
-		'`', '|', '[', ']', '{', '}', ';',
-		':', ',', '.',
-	}
+	return ReplaceXMLTagChars(tag, "")
+}
+
+// ReplaceXMLTagChars works like FixXMLTags, but replaces each invalid XML character with `replacement`
+// rather than removing it, so that the positions of removed characters still show in the tag.
+// Every character gets the same replacement, so headers such as "Amount ($)" and "Amount (%)" still end up
+// equal; TransliterateXMLTag spells such symbols out first to keep them apart.
+// Example usage:
+//
+//	cleanTag := ReplaceXMLTagChars("Amount ($)", "_")
+//	fmt.Println(cleanTag)
+//	// Output: "Amount_x0020____"
+func ReplaceXMLTagChars(tag, replacement string) string {
 	// Replace invalid characters
 	cleanTag := tag
-	for _, char := range invalidXmlChars {
-		cleanTag = strings.ReplaceAll(cleanTag, string(char), "")
+	for _, char := range invalidXMLTagChars {
+		cleanTag = strings.ReplaceAll(cleanTag, string(char), replacement)
 	}
 	cleanTag = strings.ReplaceAll(cleanTag, " ", "_x0020_")
 	return cleanTag
 }
 
+// DecodeXMLName reverses the escaping applied to XML names, turning sequences such as "_x0020_" written by
+// FixXMLTags or .NET's XmlConvert.EncodeName back into the characters they stand for.
+// Example usage:
+//
+//	header := DecodeXMLName("Order_x0020_Date")
+//	fmt.Println(header)
+//	// Output: "Order Date"
+func DecodeXMLName(name string) string {
+	return encodedXMLChar.ReplaceAllStringFunc(name, func(match string) string {
+		code, err := strconv.ParseUint(match[2:len(match)-1], 16, 32)
+		if err != nil {
+			return match
+		}
+		return string(rune(code))
+	})
+}
+
+// TransliterateXMLTag spells out currency signs and other common symbols as words, and strips accents
+// from letters, so that the information they carry survives cleaning with FixXMLTags.
+// Example usage:
+//
+//	tag := TransliterateXMLTag("Café (€)")
+//	fmt.Println(tag)
+//	// Output: "Cafe (Euro)"
+func TransliterateXMLTag(tag string) string {
+	var builder strings.Builder
+	for _, char := range tag {
+		if name, ok := symbolNames[char]; ok {
+			builder.WriteString(name)
+		} else {
+			builder.WriteRune(char)
+		}
+	}
+	stripAccents := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	stripped, _, err := transform.String(stripAccents, builder.String())
+	if err != nil {
+		return builder.String()
+	}
+	return stripped
+}
+
 // ConvertToISO8601 converts a given string value representing a date or time to ISO-8601 format.
 // It supports various date and time formats such as "MM-DD-YY", "MM-DD-YY HH:mm:ss", "1/02/06", etc.
 // The function iterates through the array of supported formats and attempts to parse the value using each format.
@@ -104,6 +174,18 @@ func FixXMLTags(tag string) string {
 //	fmt.Println(result)
 //	// Output: "invalid date"
 func ConvertToISO8601(value string) string {
+	return ConvertDateFormat(value, time.DateTime)
+}
+
+// ConvertDateFormat converts a given string value representing a date or time to the given Go time layout.
+// It recognises the same input formats as ConvertToISO8601, and returns the original value if none match.
+//
+// Example usage:
+//
+//	result := ConvertDateFormat("12-25-20", "2006-01-02")
+//	fmt.Println(result)
+//	// Output: "2020-12-25"
+func ConvertDateFormat(value, layout string) string {
 	formats := [9]string{
 		"01-02-06",
 		"01-02-06 15:04",
@@ -118,8 +200,214 @@ func ConvertToISO8601(value string) string {
 	for _, format := range formats {
 		parsedDate, parseErr := time.Parse(format, value)
 		if parseErr == nil {
-			return parsedDate.Format(time.DateTime)
+			return parsedDate.Format(layout)
+		}
+	}
+	return value
+}
+
+// SanitizeFormula protects a value written to a CSV file against formula injection: values beginning with
+// =, +, -, @, a tab or a carriage return are prefixed with a single quote, so spreadsheet programs show them as
+// text instead of evaluating them. Plain numbers such as "-42" or "+1.5" are returned unchanged.
+//
+// Example usage:
+//
+//	fmt.Println(SanitizeFormula("=HYPERLINK(\"http://evil\")"), SanitizeFormula("-42"))
+//	// Output: '=HYPERLINK("http://evil") -42
+func SanitizeFormula(value string) string {
+	if len(value) == 0 || !strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return value
+	}
+	switch InferValueType(value) {
+	case "integer", "number":
+		return value
+	}
+	return "'" + value
+}
+
+// ParseByteSize converts a human-readable size such as "512MB", "1.5GiB", "64k" or "1048576" into a number of bytes.
+// Units are case-insensitive and use powers of 1024; a bare number is taken as bytes.
+//
+// Example usage:
+//
+//	limit, err := ParseByteSize("256MB")
+//	fmt.Println(limit)
+//	// Output: 268435456
+func ParseByteSize(size string) (int64, error) {
+	units := []struct {
+		suffix     string
+		multiplier float64
+	}{
+		{"tib", 1 << 40}, {"tb", 1 << 40}, {"t", 1 << 40},
+		{"gib", 1 << 30}, {"gb", 1 << 30}, {"g", 1 << 30},
+		{"mib", 1 << 20}, {"mb", 1 << 20}, {"m", 1 << 20},
+		{"kib", 1 << 10}, {"kb", 1 << 10}, {"k", 1 << 10},
+		{"b", 1},
+	}
+	value := strings.ToLower(strings.TrimSpace(size))
+	multiplier := 1.0
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+	number, parseErr := strconv.ParseFloat(value, 64)
+	if parseErr != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size '%s'", size)
+	}
+	return int64(number * multiplier), nil
+}
+
+// SplitWords breaks value into words at spaces, punctuation and changes of case, so that
+// "Customer ID", "customer_id", "customerId" and "CustomerID" all become ["Customer", "ID"] or similar.
+// A run of capitals is kept together as an acronym, ending before a capital that starts a lowercase word.
+//
+// Example usage:
+//
+//	words := SplitWords("XMLHttpRequest id")
+//	fmt.Println(words)
+//	// Output: [XML Http Request id]
+func SplitWords(value string) []string {
+	var words []string
+	var word []rune
+	runes := []rune(value)
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			flush()
+			continue
+		}
+		if len(word) > 0 && unicode.IsUpper(r) {
+			previous := word[len(word)-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !unicode.IsUpper(previous) || nextIsLower {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+	return words
+}
+
+// ToPascalCase joins the words of value with each word capitalised, e.g. "customer id" becomes "CustomerId".
+func ToPascalCase(value string) string {
+	var builder strings.Builder
+	for _, word := range SplitWords(value) {
+		builder.WriteString(capitalise(word))
+	}
+	return builder.String()
+}
+
+// ToCamelCase joins the words of value as ToPascalCase does, but with the first word in lowercase,
+// e.g. "Customer ID" becomes "customerId".
+func ToCamelCase(value string) string {
+	var builder strings.Builder
+	for i, word := range SplitWords(value) {
+		if i == 0 {
+			builder.WriteString(strings.ToLower(word))
+		} else {
+			builder.WriteString(capitalise(word))
+		}
+	}
+	return builder.String()
+}
+
+// ToSnakeCase joins the lowercase words of value with underscores, e.g. "CustomerID" becomes "customer_id".
+func ToSnakeCase(value string) string {
+	words := SplitWords(value)
+	for i, word := range words {
+		words[i] = strings.ToLower(word)
+	}
+	return strings.Join(words, "_")
+}
+
+// capitalise returns word with its first letter in uppercase and the rest in lowercase.
+func capitalise(word string) string {
+	runes := []rune(strings.ToLower(word))
+	if len(runes) > 0 {
+		runes[0] = unicode.ToUpper(runes[0])
+	}
+	return string(runes)
+}
+
+// InferValueType guesses the type of a cell or field value from its text, returning one of
+// "empty", "integer", "number", "boolean", "date" or "string".
+// Numbers with leading zeros, such as postcodes and account codes, are treated as strings, as are "NaN", "Inf"
+// and "Infinity".
+//
+// Example usage:
+//
+//	fmt.Println(InferValueType("3.14"), InferValueType("007"), InferValueType("2024-01-31"))
+//	// Output: number string date
+func InferValueType(value string) string {
+	trimmed := strings.TrimSpace(value)
+	if len(trimmed) == 0 {
+		return "empty"
+	}
+	leadingZero := len(trimmed) > 1 && trimmed[0] == '0' && trimmed[1] != '.'
+	if _, err := strconv.ParseInt(trimmed, 10, 64); err == nil && !leadingZero {
+		return "integer"
+	}
+	// ParseFloat also accepts NaN and infinities, which no spreadsheet, database or JSON number can hold
+	if number, err := strconv.ParseFloat(trimmed, 64); err == nil && !leadingZero && !math.IsNaN(number) && !math.IsInf(number, 0) {
+		return "number"
+	}
+	switch strings.ToLower(trimmed) {
+	case "true", "false":
+		return "boolean"
+	}
+	if _, isDate := ParseDate(trimmed); isDate {
+		return "date"
+	}
+	return "string"
+}
+
+// ParseDate parses a date or date and time written in one of the formats recognised by ConvertToISO8601,
+// or in ISO 8601 or m/d/yyyy form, reporting whether any of them matched.
+//
+// Example usage:
+//
+//	date, ok := ParseDate("2024-01-31")
+//	fmt.Println(date.Month(), ok)
+//	// Output: January true
+func ParseDate(value string) (time.Time, bool) {
+	trimmed := strings.TrimSpace(value)
+	if converted := ConvertToISO8601(trimmed); converted != trimmed {
+		date, err := time.Parse(time.DateTime, converted)
+		return date, err == nil
+	}
+	for _, layout := range []string{time.DateOnly, time.DateTime, "2006-01-02T15:04:05", time.RFC3339, "1/2/2006"} {
+		if date, err := time.Parse(layout, trimmed); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// TypedValue converts a value to the Go type suggested by InferValueType, for writing to a spreadsheet cell:
+// integers and numbers become float64 and booleans become bool, while everything else stays a string.
+//
+// Example usage:
+//
+//	fmt.Printf("%T %T\n", TypedValue("42"), TypedValue("007"))
+//	// Output: float64 string
+func TypedValue(value string) interface{} {
+	trimmed := strings.TrimSpace(value)
+	switch InferValueType(value) {
+	case "integer", "number":
+		if number, err := strconv.ParseFloat(trimmed, 64); err == nil {
+			return number
 		}
+	case "boolean":
+		return strings.EqualFold(trimmed, "true")
 	}
 	return value
 }
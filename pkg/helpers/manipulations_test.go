@@ -0,0 +1,33 @@
+package helpers
+
+import "testing"
+
+func TestInferValueType(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", "empty"},
+		{"   ", "empty"},
+		{"42", "integer"},
+		{"-7", "integer"},
+		{"3.14", "number"},
+		{"0.5", "number"},
+		{"1e3", "number"},
+		{"007", "string"},
+		{"NaN", "string"},
+		{"nan", "string"},
+		{"Inf", "string"},
+		{"-Inf", "string"},
+		{"+Infinity", "string"},
+		{"1e400", "string"},
+		{"TRUE", "boolean"},
+		{"2024-01-31", "date"},
+		{"Amount", "string"},
+	}
+	for _, test := range tests {
+		if got := InferValueType(test.value); got != test.want {
+			t.Errorf("InferValueType(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ReportFormats lists the values accepted by the --report flag of the CLI tools.
+var ReportFormats = []string{"json"}
+
+// RunReport is a structured summary of what a single tool invocation did.
+// Fields that do not apply to a given tool are left at their zero value; the Files and Bytes fields
+// are filled in by tools that process several files in one run, with Failures in path order.
+// RowsRead and RowsWritten count data rows, leaving out the header row of the tools that read one.
+// Example usage:
+//
+//	report := RunReport{File: path}
+//	startTime := time.Now()
+//	defer func() {
+//		report.Finish(startTime, processingErr.Code)
+//		_ = WriteReport(report, "json", "")
+//	}()
+type RunReport struct {
+	File           string        `json:"file"`
+	RowsRead       int           `json:"rows_read"`
+	RowsWritten    int           `json:"rows_written"`
+	HeadersRenamed int           `json:"headers_renamed"`
+	FieldsTrimmed  int           `json:"fields_trimmed"`
+	FilesProcessed int           `json:"files_processed"`
+	FilesSucceeded int           `json:"files_succeeded"`
+	FilesFailed    int           `json:"files_failed"`
+	FilesSkipped   int           `json:"files_skipped"`
+	Failures       []FileFailure `json:"failures,omitempty"`
+	BytesBefore    int64         `json:"bytes_before"`
+	BytesAfter     int64         `json:"bytes_after"`
+	Duration       string        `json:"duration"`
+	DurationMs     int64         `json:"duration_ms"`
+	ExitCode       int           `json:"exit_code"`
+}
+
+// FileFailure is a file that a run could not process, and why.
+type FileFailure struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+}
+
+// Finish records the elapsed time since startTime and the exit code of the run.
+func (r *RunReport) Finish(startTime time.Time, exitCode int) {
+	elapsed := time.Since(startTime)
+	r.Duration = elapsed.String()
+	r.DurationMs = elapsed.Milliseconds()
+	r.ExitCode = exitCode
+}
+
+// ValidReportFormat checks if the given --report value is supported.
+// An empty format is valid and means no report is requested.
+func ValidReportFormat(format string) bool {
+	if format == "" {
+		return true
+	}
+	for _, supported := range ReportFormats {
+		if format == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteReport writes the report in the given format to dest, or to stdout if dest is empty or "-".
+// Nothing is written if format is empty.
+func WriteReport(report RunReport, format, dest string) error {
+	if format == "" {
+		return nil
+	}
+	if !ValidReportFormat(format) {
+		return fmt.Errorf("unsupported report format '%s'", format)
+	}
+	data, marshalErr := json.MarshalIndent(report, "", "  ")
+	if marshalErr != nil {
+		return marshalErr
+	}
+	data = append(data, '\n')
+	if dest == "" || dest == "-" {
+		_, writeErr := os.Stdout.Write(data)
+		return writeErr
+	}
+	return os.WriteFile(dest, data, 0644)
+}
@@ -0,0 +1,129 @@
+// Package logging sets up the charmbracelet/log default logger that every command logs through, so they all
+// take the same --verbose, --quiet and --log-format flags and the same environment variables.
+// A command registers the flags with RegisterFlags before parsing them, and calls Setup straight after
+// flag.Parse to apply them.
+// Example usage:
+//
+//	logging.RegisterFlags(flag.CommandLine)
+//	flag.Parse()
+//	if logErr := logging.Setup(); logErr != nil {
+//		processingErr = ErrMsg{Err: logErr, Code: ErrInvalidArgs}
+//		return
+//	}
+package logging
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// The values accepted by --log-format and FormatEnv.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Formats lists the log formats, with the default first.
+var Formats = []string{FormatText, FormatJSON}
+
+// LevelEnv and FormatEnv name the environment variables giving the log level (debug, info, warn or error)
+// and format when no flag does, such as in a scheduled job whose command line is hard to change.
+const (
+	LevelEnv  = "GOTOOLS_LOG_LEVEL"
+	FormatEnv = "GOTOOLS_LOG_FORMAT"
+)
+
+var (
+	verbose bool
+	quiet   bool
+	// format is the log format given by --log-format, empty if the flag is not set.
+	format string
+	// level and resolvedFormat are what Setup settled on from the flags and environment.
+	level          = log.InfoLevel
+	resolvedFormat = FormatText
+)
+
+// RegisterFlags adds the --verbose, --quiet and --log-format flags that Setup applies to fs.
+func RegisterFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&verbose, "verbose", false, "Enable verbose logging, including debug messages and where each message is logged from")
+	fs.BoolVar(&quiet, "quiet", false, "Only log errors, suppressing informational messages and warnings")
+	fs.Func("log-format", "The format of log messages: 'text', or 'json' with one object per line for log collectors (default "+FormatEnv+" or text)", func(value string) error {
+		if !slices.Contains(Formats, value) {
+			return fmt.Errorf("expected one of %s", strings.Join(Formats, ", "))
+		}
+		format = value
+		return nil
+	})
+}
+
+// Setup configures the default logger from the flags, falling back on LevelEnv and FormatEnv for those not set.
+// The level is debug with --verbose, error with --quiet, which cannot be used together, and info otherwise.
+// An environment variable with an unknown value is ignored with a warning.
+// Messages are written to stderr, so they never end up mixed into output written to stdout.
+func Setup() error {
+	if verbose && quiet {
+		return errors.New("--quiet and --verbose cannot be used together")
+	}
+	var ignored []string
+	level, resolvedFormat = log.InfoLevel, FormatText
+	if value := os.Getenv(LevelEnv); len(value) > 0 {
+		if parsed, err := log.ParseLevel(value); err == nil {
+			level = parsed
+		} else {
+			ignored = append(ignored, LevelEnv)
+		}
+	}
+	switch {
+	case verbose:
+		level = log.DebugLevel
+	case quiet:
+		level = log.ErrorLevel
+	}
+	if value := os.Getenv(FormatEnv); len(value) > 0 {
+		if slices.Contains(Formats, strings.ToLower(value)) {
+			resolvedFormat = strings.ToLower(value)
+		} else {
+			ignored = append(ignored, FormatEnv)
+		}
+	}
+	if len(format) > 0 {
+		resolvedFormat = format
+	}
+
+	log.SetOutput(os.Stderr)
+	log.SetLevel(level)
+	if resolvedFormat == FormatJSON {
+		log.SetFormatter(log.JSONFormatter)
+	} else {
+		log.SetFormatter(log.TextFormatter)
+	}
+	if level == log.DebugLevel {
+		log.SetCallerFormatter(log.LongCallerFormatter)
+		log.SetReportCaller(true)
+	}
+	for _, name := range ignored {
+		log.Warn("Ignoring environment variable with an unknown value", "name", name, "value", os.Getenv(name))
+	}
+	return nil
+}
+
+// Verbose reports whether Setup enabled debug logging, through --verbose or LevelEnv.
+func Verbose() bool {
+	return level == log.DebugLevel
+}
+
+// Quiet reports whether Setup limited logging to errors or less, through --quiet or LevelEnv.
+func Quiet() bool {
+	return level >= log.ErrorLevel
+}
+
+// Format returns the log format Setup chose, one of Formats.
+func Format() string {
+	return resolvedFormat
+}
@@ -0,0 +1,249 @@
+// Package xlsxwriter writes tabular data to worksheets laid out for people rather than programs:
+// a bold, frozen header row, columns sized to their contents, numbers and dates stored as real cells,
+// and optionally an Excel table with filter buttons and banded rows.
+package xlsxwriter
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	. "GoTools/pkg/helpers"
+	"github.com/xuri/excelize/v2"
+)
+
+// Column widths chosen by AutoWidth, in characters.
+const (
+	minColumnWidth = 8
+	maxColumnWidth = 60
+)
+
+// Options controls how a SheetWriter formats its worksheet.
+type Options struct {
+	// BoldHeader writes the header row in bold.
+	BoldHeader bool
+	// FreezeHeader freezes the header row so it stays visible while scrolling.
+	FreezeHeader bool
+	// AutoWidth sizes each column to its longest value, within minColumnWidth and maxColumnWidth.
+	AutoWidth bool
+	// InferTypes writes values that read as numbers, booleans or dates as typed cells instead of text.
+	InferTypes bool
+	// Table formats the data as an Excel table with the given TableStyle, e.g. "TableStyleMedium2".
+	Table      bool
+	TableStyle string
+	// DateFormat and DateTimeFormat are the number formats of inferred dates with and without a time of day.
+	DateFormat     string
+	DateTimeFormat string
+}
+
+// DefaultOptions returns the options used when a tool does not override them: every feature but Table
+// is enabled, and dates are shown in ISO 8601 form.
+func DefaultOptions() Options {
+	return Options{
+		BoldHeader:     true,
+		FreezeHeader:   true,
+		AutoWidth:      true,
+		InferTypes:     true,
+		TableStyle:     "TableStyleMedium2",
+		DateFormat:     "yyyy-mm-dd",
+		DateTimeFormat: "yyyy-mm-dd hh:mm:ss",
+	}
+}
+
+// SheetWriter writes a header row and records to a worksheet, then applies the formatting in its Options
+// when closed. The worksheet must already exist in the workbook.
+// Example usage:
+//
+//	writer, err := NewSheetWriter(file, "Sheet1", DefaultOptions())
+//	err = writer.WriteHeader([]string{"Name", "Joined"})
+//	err = writer.WriteRow([]string{"Ann", "2024-01-31"})
+//	err = writer.Close()
+type SheetWriter struct {
+	file          *excelize.File
+	sheet         string
+	opts          Options
+	headers       []string
+	widths        []int
+	rows          int
+	dateStyle     int
+	dateTimeStyle int
+}
+
+// NewSheetWriter returns a SheetWriter for the named worksheet of file.
+func NewSheetWriter(file *excelize.File, sheet string, opts Options) (*SheetWriter, error) {
+	if index, _ := file.GetSheetIndex(sheet); index < 0 {
+		return nil, fmt.Errorf("sheet '%s' does not exist", sheet)
+	}
+	w := &SheetWriter{file: file, sheet: sheet, opts: opts}
+	if opts.InferTypes {
+		var styleErr error
+		if w.dateStyle, styleErr = file.NewStyle(&excelize.Style{CustomNumFmt: &opts.DateFormat}); styleErr != nil {
+			return nil, styleErr
+		}
+		if w.dateTimeStyle, styleErr = file.NewStyle(&excelize.Style{CustomNumFmt: &opts.DateTimeFormat}); styleErr != nil {
+			return nil, styleErr
+		}
+	}
+	return w, nil
+}
+
+// WriteHeader writes the header row. It must be called before any WriteRow.
+// With Options.Table, blank headers are named after their column number and duplicates are renamed.
+func (w *SheetWriter) WriteHeader(headers []string) error {
+	if w.rows > 0 {
+		return fmt.Errorf("sheet '%s': header written after %d rows", w.sheet, w.rows)
+	}
+	if w.opts.Table {
+		// Excel refuses to open a table with blank or repeated column names.
+		headers = slices.Clone(headers)
+		for i, header := range headers {
+			if len(strings.TrimSpace(header)) == 0 {
+				headers[i] = fmt.Sprintf("Column%d", i+1)
+			}
+		}
+		headers = RenameDuplicates(headers, false)
+	}
+	w.headers = headers
+	values := make([]interface{}, len(headers))
+	for i, header := range headers {
+		values[i] = header
+		w.measure(i, header)
+	}
+	if err := w.file.SetSheetRow(w.sheet, "A1", &values); err != nil {
+		return err
+	}
+	if w.opts.BoldHeader && len(headers) > 0 {
+		bold, styleErr := w.file.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+		if styleErr != nil {
+			return styleErr
+		}
+		lastCell, _ := excelize.CoordinatesToCellName(len(headers), 1)
+		if err := w.file.SetCellStyle(w.sheet, "A1", lastCell, bold); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteRow writes a record below the header and any previous records.
+func (w *SheetWriter) WriteRow(values []string) error {
+	w.rows++
+	rowNum := w.rows + 1
+	for i, value := range values {
+		if len(value) == 0 {
+			continue
+		}
+		cellName, _ := excelize.CoordinatesToCellName(i+1, rowNum)
+		if !w.opts.InferTypes {
+			if err := w.file.SetCellStr(w.sheet, cellName, value); err != nil {
+				return err
+			}
+			w.measure(i, value)
+			continue
+		}
+		if date, isDate := ParseDate(value); isDate {
+			style, format := w.dateStyle, w.opts.DateFormat
+			if date.Hour() != 0 || date.Minute() != 0 || date.Second() != 0 {
+				style, format = w.dateTimeStyle, w.opts.DateTimeFormat
+			}
+			if err := w.file.SetCellValue(w.sheet, cellName, date); err != nil {
+				return err
+			}
+			if err := w.file.SetCellStyle(w.sheet, cellName, cellName, style); err != nil {
+				return err
+			}
+			w.measure(i, format)
+			continue
+		}
+		if err := w.file.SetCellValue(w.sheet, cellName, TypedValue(value)); err != nil {
+			return err
+		}
+		w.measure(i, value)
+	}
+	return nil
+}
+
+// Close applies the column widths, frozen header and table formatting once all rows are written.
+func (w *SheetWriter) Close() error {
+	if w.opts.AutoWidth {
+		for i, width := range w.widths {
+			name, _ := excelize.ColumnNumberToName(i + 1)
+			if err := w.file.SetColWidth(w.sheet, name, name, float64(min(max(width+2, minColumnWidth), maxColumnWidth))); err != nil {
+				return err
+			}
+		}
+	}
+	if w.opts.FreezeHeader && len(w.headers) > 0 {
+		panes := &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"}
+		if err := w.file.SetPanes(w.sheet, panes); err != nil {
+			return err
+		}
+	}
+	if w.opts.Table && len(w.headers) > 0 {
+		lastCell, _ := excelize.CoordinatesToCellName(len(w.headers), w.rows+1)
+		table := &excelize.Table{
+			Range:          "A1:" + lastCell,
+			Name:           tableName(w.sheet),
+			StyleName:      w.opts.TableStyle,
+			ShowRowStripes: boolPtr(true),
+		}
+		if err := w.file.AddTable(w.sheet, table); err != nil {
+			return fmt.Errorf("adding table: %w", err)
+		}
+	}
+	return nil
+}
+
+// measure records the displayed length of a value written to the given column.
+func (w *SheetWriter) measure(column int, value string) {
+	for len(w.widths) <= column {
+		w.widths = append(w.widths, 0)
+	}
+	w.widths[column] = max(w.widths[column], utf8.RuneCountInString(value))
+}
+
+// tableName derives a table name from a sheet name. Table names must start with a letter or underscore
+// and hold only letters, digits, underscores and periods.
+func tableName(sheet string) string {
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, sheet)
+	return "Table_" + name
+}
+
+// SheetName makes name usable as a worksheet name: at most 31 characters, without []:*?/\ characters,
+// and different from every name in used.
+func SheetName(name string, used []string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if len(name) == 0 {
+		name = "Sheet"
+	}
+	truncate := func(value string, limit int) string {
+		runes := []rune(value)
+		if len(runes) > limit {
+			return string(runes[:limit])
+		}
+		return value
+	}
+	candidate := truncate(name, 31)
+	for count := 2; slices.ContainsFunc(used, func(other string) bool { return strings.EqualFold(other, candidate) }); count++ {
+		suffix := "_" + strconv.Itoa(count)
+		candidate = truncate(name, 31-len(suffix)) + suffix
+	}
+	return candidate
+}
+
+func boolPtr(value bool) *bool {
+	return &value
+}
@@ -0,0 +1,23 @@
+package xmlfmt
+
+import (
+	"cmp"
+	"encoding/xml"
+	"slices"
+)
+
+// sortAttributes orders the attributes of start alphabetically, keeping namespace declarations first,
+// so that documents generated with attributes in varying order format identically.
+func sortAttributes(start *xml.StartElement) {
+	slices.SortStableFunc(start.Attr, func(a, b xml.Attr) int {
+		_, aDecl := declaredPrefix(a)
+		_, bDecl := declaredPrefix(b)
+		if aDecl != bDecl {
+			if aDecl {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(cmp.Compare(a.Name.Space, b.Name.Space), cmp.Compare(a.Name.Local, b.Name.Local))
+	})
+}
@@ -0,0 +1,171 @@
+package xmlfmt
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/xml"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+)
+
+// canonicalAttrEscaper and canonicalTextEscaper escape attribute values and text as Canonical XML requires,
+// which differs from the encoder's escaping: quotes are written as &quot; and whitespace characters in
+// attribute values as character references.
+var (
+	canonicalAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+	canonicalTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+)
+
+// nsScope is the set of namespace declarations made by one element, mapping each prefix to its namespace.
+type nsScope map[string]string
+
+// resolvePrefix finds the namespace bound to prefix by the innermost scope declaring it.
+func resolvePrefix(scopes []nsScope, prefix string) (string, bool) {
+	if prefix == "xml" {
+		return "http://www.w3.org/XML/1998/namespace", true
+	}
+	for depth := len(scopes) - 1; depth >= 0; depth-- {
+		if uri, found := scopes[depth][prefix]; found {
+			return uri, true
+		}
+	}
+	return "", false
+}
+
+// canonicalize writes the document read from r to w in the form given by Exclusive XML Canonicalization 1.0,
+// without comments: the XML declaration, DTD and comments are removed, empty elements are written as a start
+// and end tag, whitespace inside the document element is kept exactly, and each element only declares the
+// namespaces it or its attributes use and that are not already declared by an element above it in the output.
+// Namespace declarations are written sorted by prefix, before the attributes sorted by namespace and local name.
+// Attribute values are taken as the decoder reads them, without the whitespace normalization a validating
+// parser applies, so values containing literal line breaks keep them.
+func canonicalize(r io.Reader, w io.Writer, opts Options) error {
+	decoder := newDecoder(bufio.NewReader(r), opts.Lenient)
+	buf := bufio.NewWriter(w)
+	var open []xml.Name
+	var declared, rendered []nsScope
+	seenRoot := false
+
+	for {
+		t, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return positionError(decoder, err)
+		}
+		switch token := t.(type) {
+		case xml.StartElement:
+			scope := make(nsScope)
+			var attrs []xml.Attr
+			for _, attr := range token.Attr {
+				if prefix, isDecl := declaredPrefix(attr); isDecl {
+					scope[prefix] = attr.Value
+				} else {
+					attrs = append(attrs, attr)
+				}
+			}
+			declared = append(declared, scope)
+
+			// Declare the namespaces the element visibly uses that its output ancestors have not
+			output := make(nsScope)
+			utilized := []string{token.Name.Space}
+			for _, attr := range attrs {
+				if len(attr.Name.Space) > 0 {
+					utilized = append(utilized, attr.Name.Space)
+				}
+			}
+			for _, prefix := range utilized {
+				if prefix == "xml" {
+					continue
+				}
+				uri, found := resolvePrefix(declared, prefix)
+				if !found && len(prefix) > 0 {
+					return positionError(decoder, errors.New("the prefix '"+prefix+"' is not declared"))
+				}
+				if current, _ := resolvePrefix(rendered, prefix); current != uri {
+					output[prefix] = uri
+				}
+			}
+			rendered = append(rendered, output)
+
+			slices.SortFunc(attrs, func(a, b xml.Attr) int {
+				aURI, _ := resolvePrefix(declared, a.Name.Space)
+				bURI, _ := resolvePrefix(declared, b.Name.Space)
+				if len(a.Name.Space) == 0 {
+					aURI = ""
+				}
+				if len(b.Name.Space) == 0 {
+					bURI = ""
+				}
+				return cmp.Or(cmp.Compare(aURI, bURI), cmp.Compare(a.Name.Local, b.Name.Local))
+			})
+			name := qualifiedName(token.Name).Local
+			buf.WriteString("<" + name)
+			prefixes := make([]string, 0, len(output))
+			for prefix := range output {
+				prefixes = append(prefixes, prefix)
+			}
+			slices.Sort(prefixes)
+			for _, prefix := range prefixes {
+				if len(prefix) == 0 {
+					buf.WriteString(` xmlns="`)
+				} else {
+					buf.WriteString(` xmlns:` + prefix + `="`)
+				}
+				buf.WriteString(canonicalAttrEscaper.Replace(output[prefix]) + `"`)
+			}
+			for _, attr := range attrs {
+				buf.WriteString(" " + qualifiedName(attr.Name).Local + `="` + canonicalAttrEscaper.Replace(attr.Value) + `"`)
+			}
+			buf.WriteString(">")
+			open = append(open, token.Name)
+			seenRoot = true
+		case xml.EndElement:
+			if len(open) == 0 || open[len(open)-1] != token.Name {
+				return positionError(decoder, unexpectedEndError(qualifiedNames(open), qualifiedName(token.Name)))
+			}
+			buf.WriteString("</" + qualifiedName(token.Name).Local + ">")
+			open = open[:len(open)-1]
+			declared = declared[:len(declared)-1]
+			rendered = rendered[:len(rendered)-1]
+		case xml.CharData:
+			if len(open) > 0 {
+				buf.WriteString(canonicalTextEscaper.Replace(string(token)))
+			}
+		case xml.ProcInst:
+			if token.Target == "xml" {
+				continue
+			}
+			if len(open) == 0 && seenRoot {
+				buf.WriteByte('\n')
+			}
+			buf.WriteString("<?" + token.Target)
+			if len(token.Inst) > 0 {
+				buf.WriteString(" " + string(token.Inst))
+			}
+			buf.WriteString("?>")
+			if len(open) == 0 && !seenRoot {
+				buf.WriteByte('\n')
+			}
+		case xml.Directive:
+			if _, doctypeErr := handleDoctype(decoder, token, opts.Doctype); doctypeErr != nil {
+				return positionError(decoder, doctypeErr)
+			}
+		}
+	}
+	if len(open) > 0 {
+		return positionError(decoder, errors.New("unexpected end of document, <"+qualifiedName(open[len(open)-1]).Local+"> is not closed"))
+	}
+	return buf.Flush()
+}
+
+// qualifiedNames applies qualifiedName to each of names.
+func qualifiedNames(names []xml.Name) []xml.Name {
+	qualified := make([]xml.Name, len(names))
+	for i, name := range names {
+		qualified[i] = qualifiedName(name)
+	}
+	return qualified
+}
@@ -0,0 +1,47 @@
+package xmlfmt
+
+import (
+	"bufio"
+	"bytes"
+)
+
+// tokenRecorder feeds the decoder one byte at a time while keeping the raw bytes read since the last reset,
+// so the source text of a token can be told apart from what the decoder makes of it, such as whether
+// character data came from a CDATA section. Bytes are only kept when record is set.
+type tokenRecorder struct {
+	reader *bufio.Reader
+	raw    []byte
+	record bool
+}
+
+// Read reads from the underlying reader without recording, as the decoder only reads through ReadByte.
+func (r *tokenRecorder) Read(p []byte) (int, error) {
+	return r.reader.Read(p)
+}
+
+// ReadByte reads the next byte, recording it if required.
+func (r *tokenRecorder) ReadByte() (byte, error) {
+	b, err := r.reader.ReadByte()
+	if err == nil && r.record {
+		r.raw = append(r.raw, b)
+	}
+	return b, err
+}
+
+// reset forgets the bytes recorded so far, ahead of reading the next token.
+func (r *tokenRecorder) reset() {
+	r.raw = r.raw[:0]
+}
+
+// isCDATA checks if the bytes recorded since the last reset are a CDATA section.
+// The decoder may already have read the section's opening '<' while looking ahead from the previous token.
+func (r *tokenRecorder) isCDATA() bool {
+	return bytes.HasPrefix(bytes.TrimPrefix(r.raw, []byte("<")), []byte("![CDATA["))
+}
+
+// writeCDATA writes data to buf as a CDATA section, exactly as it appeared in the source.
+func writeCDATA(buf *bytes.Buffer, data []byte) {
+	buf.WriteString("<![CDATA[")
+	buf.Write(data)
+	buf.WriteString("]]>")
+}
@@ -0,0 +1,132 @@
+package xmlfmt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"strings"
+
+	. "GoTools/pkg/helpers"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// SniffSize is how much of the start of a document DecodeCharset reads to find its character encoding.
+const SniffSize = 1024
+
+// declaredEncoding matches the encoding pseudo-attribute of an XML declaration, capturing its value.
+var declaredEncoding = regexp.MustCompile(`^<\?xml\s[^>]*?\bencoding\s*=\s*["']([A-Za-z][A-Za-z0-9._-]*)["']`)
+
+// encodingAttr matches the encoding pseudo-attribute in the body of an XML declaration, capturing what precedes
+// the value and its quote.
+var encodingAttr = regexp.MustCompile(`(\bencoding\s*=\s*)(["'])[^"']*["']`)
+
+// newDecoder returns a decoder for the UTF-8 document read from r. Documents in other encodings are decoded
+// to UTF-8 by DecodeCharset before they get here, so the encoding their declaration names is accepted as read.
+// A lenient decoder is not strict, so it accepts the likes of unescaped ampersands and HTML entities.
+func newDecoder(r io.Reader, lenient bool) *xml.Decoder {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	if lenient {
+		decoder.Strict = false
+		// Cloned, as a DOCTYPE's internal entities are added to the map
+		decoder.Entity = maps.Clone(xml.HTMLEntity)
+	}
+	return decoder
+}
+
+// DecodeCharset returns a reader of the document in r as UTF-8 without any byte order mark, along with the
+// encoding it was read from, or nil if it was UTF-8 already, and whether it started with a byte order mark.
+// UTF-16 is recognised by its byte order mark, or without one by how the document starts; other encodings,
+// such as ISO-8859-1 and windows-1252, by the XML declaration. A UTF-8 byte order mark takes precedence over
+// the declaration, as the XML specification has it. A reader that can seek is rewound to just after any
+// byte order mark once its start is read, and returned as it is when it needs no decoding.
+func DecodeCharset(r io.Reader) (io.Reader, encoding.Encoding, bool, error) {
+	var head []byte
+	if seeker, ok := r.(io.ReadSeeker); ok {
+		head = make([]byte, SniffSize)
+		n, readErr := io.ReadFull(seeker, head)
+		if readErr != nil && !errors.Is(readErr, io.EOF) && !errors.Is(readErr, io.ErrUnexpectedEOF) {
+			return nil, nil, false, readErr
+		}
+		head = head[:n]
+		if _, seekErr := seeker.Seek(int64(utf8BOMLength(head)), io.SeekStart); seekErr != nil {
+			return nil, nil, false, seekErr
+		}
+	} else {
+		buffered := bufio.NewReaderSize(r, SniffSize)
+		var peekErr error
+		if head, peekErr = buffered.Peek(SniffSize); peekErr != nil && !errors.Is(peekErr, io.EOF) {
+			return nil, nil, false, peekErr
+		}
+		if _, discardErr := buffered.Discard(utf8BOMLength(head)); discardErr != nil {
+			return nil, nil, false, discardErr
+		}
+		r = buffered
+	}
+	if skipped := utf8BOMLength(head); skipped > 0 {
+		return r, nil, true, nil
+	}
+	enc, err := SniffCharset(head)
+	if err != nil || enc == nil {
+		return r, nil, false, err
+	}
+	hadBOM := bytes.HasPrefix(head, []byte{0xFF, 0xFE}) || bytes.HasPrefix(head, []byte{0xFE, 0xFF})
+	return transform.NewReader(r, enc.NewDecoder()), enc, hadBOM, nil
+}
+
+// utf8BOMLength returns the length of the UTF-8 byte order mark that head starts with, or 0 if it has none.
+func utf8BOMLength(head []byte) int {
+	if bytes.HasPrefix(head, UTF8BOM) {
+		return len(UTF8BOM)
+	}
+	return 0
+}
+
+// SniffCharset finds the encoding of a document from its first bytes, returning nil for UTF-8 and US-ASCII.
+// UTF-16 read with a byte order mark is written back with one, and without one if it had none.
+func SniffCharset(head []byte) (encoding.Encoding, error) {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		return unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM), nil
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		return unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM), nil
+	case bytes.HasPrefix(head, []byte{'<', 0, '?', 0}):
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), nil
+	case bytes.HasPrefix(head, []byte{0, '<', 0, '?'}):
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), nil
+	}
+	match := declaredEncoding.FindSubmatch(head)
+	if match == nil {
+		return nil, nil
+	}
+	label := strings.ToLower(string(match[1]))
+	switch label {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		return nil, nil
+	case "utf-16", "utf-16le", "utf-16be":
+		return nil, fmt.Errorf("the XML declaration names encoding '%s', but the document is not UTF-16", match[1])
+	}
+	enc, err := ianaindex.IANA.Encoding(label)
+	if err != nil || enc == nil {
+		return nil, fmt.Errorf("unsupported encoding '%s' in the XML declaration", match[1])
+	}
+	return enc, nil
+}
+
+// declareUTF8 changes the encoding named by an XML declaration to UTF-8, for documents converted by Options.ToUTF8.
+func declareUTF8(inst xml.ProcInst) xml.ProcInst {
+	if inst.Target != "xml" {
+		return inst
+	}
+	return xml.ProcInst{Target: inst.Target, Inst: encodingAttr.ReplaceAll(inst.Inst, []byte(`${1}${2}UTF-8${2}`))}
+}
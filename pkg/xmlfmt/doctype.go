@@ -0,0 +1,48 @@
+package xmlfmt
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"regexp"
+)
+
+// The policies for DOCTYPE declarations that Options.Doctype accepts.
+const (
+	DoctypePreserve = "preserve"
+	DoctypeStrip    = "strip"
+	DoctypeReject   = "reject"
+)
+
+// DoctypeModes lists the Options.Doctype values, with the default first.
+var DoctypeModes = []string{DoctypePreserve, DoctypeStrip, DoctypeReject}
+
+// internalEntity matches the declaration of an internal general entity in a DTD's internal subset.
+// Parameter entities and external entities, declared with SYSTEM or PUBLIC, do not match.
+var internalEntity = regexp.MustCompile(`<!ENTITY\s+([A-Za-z_:][\w.:-]*)\s+(?:"([^"]*)"|'([^']*)')\s*>`)
+
+// isDoctype checks if a directive is a DOCTYPE declaration.
+func isDoctype(directive xml.Directive) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(directive), []byte("DOCTYPE"))
+}
+
+// handleDoctype applies the Options.Doctype policy to a directive read by decoder, reporting whether it should be
+// left out of the output. A preserved or stripped DOCTYPE still declares its internal entities to the decoder,
+// so references to them are read as their replacement text.
+// External entities are never loaded: encoding/xml has no means to fetch them, so references to one fail as
+// undefined rather than reaching out to the file system or network.
+func handleDoctype(decoder *xml.Decoder, directive xml.Directive, mode string) (skip bool, err error) {
+	if !isDoctype(directive) {
+		return false, nil
+	}
+	if mode == DoctypeReject {
+		return true, errors.New("the document has a DOCTYPE declaration")
+	}
+	for _, match := range internalEntity.FindAllSubmatch(directive, -1) {
+		if decoder.Entity == nil {
+			decoder.Entity = make(map[string]string)
+		}
+		decoder.Entity[string(match[1])] = string(match[2]) + string(match[3])
+	}
+	return mode == DoctypeStrip, nil
+}
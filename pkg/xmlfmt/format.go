@@ -0,0 +1,441 @@
+// Package xmlfmt formats XML documents: indented or minified, in Exclusive Canonical XML, or repaired from HTML,
+// streaming from a reader to a writer so memory use does not grow with the size of the document.
+// Documents keep the character encoding they were read in, names keep the prefixes they were written with,
+// and namespace declarations stay where they were.
+package xmlfmt
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	. "GoTools/pkg/helpers"
+	"golang.org/x/text/transform"
+)
+
+// Options controls how Format writes a document. Empty mode strings stand for the first of their modes.
+type Options struct {
+	// Indent and Prefix are written before each line after the first, Prefix first and Indent once per level.
+	Indent string
+	Prefix string
+	// Minify writes each document on a single line rather than indenting it.
+	Minify bool
+	// Doctype is the policy for DOCTYPE declarations, one of DoctypeModes.
+	Doctype string
+	// PreserveCDATA writes CDATA sections verbatim instead of as escaped character data.
+	PreserveCDATA bool
+	// SelfClose controls how empty elements are written, one of SelfCloseModes.
+	SelfClose string
+	// SortAttributes writes the attributes of each element in alphabetical order, after namespace declarations.
+	SortAttributes bool
+	// SortElements reorders the elements it names among their siblings by their SortKey; see ParseSortKeys.
+	SortElements map[string]SortKey
+	// MaxLineWidth, if positive, is the width above which a start tag's attributes are written one per line.
+	MaxLineWidth int
+	// PruneNamespaces removes namespace declarations that nothing uses or that repeat one already in scope.
+	PruneNamespaces bool
+	// Canonical writes Exclusive Canonical XML, ignoring the options that affect layout.
+	Canonical bool
+	// Lenient formats documents that are not well-formed, such as HTML, repairing them instead of failing;
+	// each repair is passed to Warn, if set, along with the element concerned and where it was found.
+	Lenient bool
+	Warn    func(repair, element string, line, column int)
+	// BOM controls the UTF-8 byte order mark at the start of the output, one of BOMModes.
+	BOM string
+	// ToUTF8 writes documents read in another encoding, such as UTF-16 or ISO-8859-1, as UTF-8 instead of in
+	// the encoding they were read in.
+	ToUTF8 bool
+}
+
+// DefaultOptions returns the options used when a tool does not override them: tab indentation,
+// with every other option at its zero value.
+func DefaultOptions() Options {
+	return Options{
+		Indent:    "\t",
+		Doctype:   DoctypePreserve,
+		SelfClose: SelfCloseExpand,
+		BOM:       BOMPreserve,
+	}
+}
+
+// check reports options that are unknown or that cannot be applied together.
+func (opts Options) check() error {
+	switch {
+	case len(opts.Doctype) > 0 && !slices.Contains(DoctypeModes, opts.Doctype):
+		return fmt.Errorf("unknown Doctype '%s', expected one of %s", opts.Doctype, strings.Join(DoctypeModes, ", "))
+	case len(opts.SelfClose) > 0 && !slices.Contains(SelfCloseModes, opts.SelfClose):
+		return fmt.Errorf("unknown SelfClose '%s', expected one of %s", opts.SelfClose, strings.Join(SelfCloseModes, ", "))
+	case len(opts.BOM) > 0 && !ValidBOMMode(opts.BOM):
+		return fmt.Errorf("unknown BOM '%s', expected one of %s", opts.BOM, strings.Join(BOMModes, ", "))
+	case opts.MaxLineWidth < 0:
+		return fmt.Errorf("MaxLineWidth must not be negative, got %d", opts.MaxLineWidth)
+	case opts.MaxLineWidth > 0 && opts.Minify:
+		return errors.New("MaxLineWidth cannot be used with Minify, which writes each document on one line")
+	case opts.Lenient && (opts.Canonical || opts.PruneNamespaces):
+		return errors.New("Lenient cannot be used with Canonical or PruneNamespaces, which need well-formed XML")
+	}
+	return nil
+}
+
+// newEncoderDecoder returns a decoder reading r through a tokenRecorder, and an encoder writing to the returned
+// buffer with the indentation of opts.
+func newEncoderDecoder(r io.Reader, opts Options) (*xml.Encoder, *xml.Decoder, *bytes.Buffer, *tokenRecorder) {
+	// Create a new buffered reader from the file
+	reader := &tokenRecorder{reader: bufio.NewReader(r), record: opts.PreserveCDATA || opts.SelfClose == SelfClosePreserve}
+
+	var buf bytes.Buffer
+	encoder := xml.NewEncoder(&buf)
+	if !opts.Minify {
+		encoder.Indent(opts.Prefix, opts.Indent)
+	}
+
+	decoder := newDecoder(reader, opts.Lenient)
+
+	return encoder, decoder, &buf, reader
+}
+
+// drainSize is how much formatted output Format holds before passing it on to its writer.
+const drainSize = 64 * 1024
+
+// Format re-encodes the XML read from r to w with the indentation of opts, or on one line with Minify.
+// Whitespace-only text between elements is dropped, since the encoder adds its own,
+// so formatting a document that is already formatted leaves it unchanged. An element holding nothing but
+// whitespace keeps it, as that whitespace is the element's value.
+// Once an element is found to hold text other than whitespace, the rest of its content is mixed content and is
+// written as it was, without indentation and keeping its whitespace, as indenting it would change the text.
+// The content of an element with xml:space="preserve" is written as it was in the same way.
+// When indenting, the XML declaration, DOCTYPE and comments before the root element are each followed by a
+// line break, as the encoder adds none.
+// With PreserveCDATA, CDATA sections are written as they were rather than as escaped text.
+// Each start tag is held back until the next token shows whether the element is empty,
+// so empty elements can be written as SelfClose requires; otherwise the output is passed on to w as it goes.
+// Tokens are read raw, so names keep the prefixes they were written with and namespace declarations stay
+// where they were, rather than being rewritten by the encoder; nesting is checked here instead.
+// With PruneNamespaces the document is read twice, first to find the declarations it can do without:
+// r is rewound if it can seek, and read into memory otherwise.
+// With SortElements the elements it names are reordered among their siblings, see elementSorter.
+// With MaxLineWidth, start tags on lines that are too wide have their attributes wrapped, see wrapAttributes.
+// With Canonical the document is written by canonicalize instead.
+// With Lenient, documents that are not well-formed are repaired, see tokenRepairer.
+// Documents in another encoding than UTF-8 are written back in that encoding, unless ToUTF8 or Canonical
+// asks for UTF-8, see DecodeCharset. UTF-8 output starts with a byte order mark as BOM says.
+func Format(r io.Reader, w io.Writer, opts Options) error {
+	if err := opts.check(); err != nil {
+		return err
+	}
+	r, enc, hadBOM, charsetErr := DecodeCharset(r)
+	if charsetErr != nil {
+		return charsetErr
+	}
+	if enc == nil || opts.ToUTF8 || opts.Canonical {
+		if !opts.Canonical {
+			if err := WriteBOM(w, cmp.Or(opts.BOM, BOMPreserve), hadBOM); err != nil {
+				return err
+			}
+		}
+		return formatDecoded(r, w, opts, enc != nil)
+	}
+	encoded := transform.NewWriter(w, enc.NewEncoder())
+	if err := formatDecoded(r, encoded, opts, false); err != nil {
+		return err
+	}
+	return encoded.Close()
+}
+
+// formatDecoded formats the UTF-8 document read from r to w, as Format describes. With converted set,
+// the document was read in another encoding and is being written as UTF-8, so its declaration is updated to match.
+func formatDecoded(r io.Reader, w io.Writer, opts Options, converted bool) error {
+	if opts.Canonical {
+		return canonicalize(r, w, opts)
+	}
+	var unused map[int]map[string]bool
+	if opts.PruneNamespaces {
+		var usageErr error
+		if seeker, ok := r.(io.ReadSeeker); ok {
+			if unused, usageErr = unusedNamespaces(bufio.NewReader(seeker), opts); usageErr != nil {
+				return usageErr
+			}
+			if _, seekErr := seeker.Seek(0, io.SeekStart); seekErr != nil {
+				return seekErr
+			}
+		} else {
+			data, readErr := io.ReadAll(r)
+			if readErr != nil {
+				return readErr
+			}
+			if unused, usageErr = unusedNamespaces(bytes.NewReader(data), opts); usageErr != nil {
+				return usageErr
+			}
+			r = bytes.NewReader(data)
+		}
+	}
+	encoder, decoder, buf, recorder := newEncoderDecoder(r, opts)
+	drain := func() error {
+		if err := encoder.Flush(); err != nil {
+			return err
+		}
+		_, err := buf.WriteTo(w)
+		return err
+	}
+
+	var pending *xml.StartElement
+	// pendingSpace holds whitespace read straight after the pending start tag, which is kept if the element
+	// turns out to hold nothing else
+	var pendingSpace xml.CharData
+	pendingSelfClosed := false
+	pendingDepth := 0
+	pendingVerbatim := false
+	// The output is flushed before and after a tag that needs rewriting: start tags to be wrapped with
+	// MaxLineWidth, and tags in mixed content to have the encoder's indentation taken out again
+	markTag := func(verbatim bool) (int, error) {
+		if opts.MaxLineWidth == 0 && !verbatim {
+			return 0, nil
+		}
+		err := encoder.Flush()
+		return buf.Len(), err
+	}
+	fixTag := func(mark int, verbatim, start bool) error {
+		if opts.MaxLineWidth == 0 && !verbatim {
+			return nil
+		}
+		if err := encoder.Flush(); err != nil {
+			return err
+		}
+		if verbatim {
+			unindent(buf, mark)
+		}
+		if start && opts.MaxLineWidth > 0 {
+			wrapAttributes(buf, mark, pendingDepth, opts)
+		}
+		return nil
+	}
+	flushPending := func() error {
+		if pending == nil {
+			return nil
+		}
+		start := *pending
+		pending, pendingSpace = nil, nil
+		mark, err := markTag(pendingVerbatim)
+		if err != nil {
+			return err
+		}
+		if err = encoder.EncodeToken(start); err != nil {
+			return err
+		}
+		return fixTag(mark, pendingVerbatim, true)
+	}
+
+	element := 0
+	next := func() (sourceToken, error) {
+		recorder.reset()
+		t, err := decoder.RawToken()
+		if err == io.EOF || (err == nil && t == nil) {
+			return sourceToken{}, io.EOF
+		} else if err != nil {
+			return sourceToken{}, positionError(decoder, err)
+		}
+		read := sourceToken{token: qualifyToken(t)}
+		switch t.(type) {
+		case xml.CharData:
+			read.cdata = opts.PreserveCDATA && recorder.isCDATA()
+		case xml.StartElement:
+			read.selfClosed = bytes.HasSuffix(recorder.raw, []byte("/>"))
+			read.index = element
+			element++
+		}
+		return read, nil
+	}
+	if opts.Lenient {
+		repairer := &tokenRepairer{read: next, warn: func(repair, element string) {
+			if opts.Warn != nil {
+				line, column := decoder.InputPos()
+				opts.Warn(repair, element, line, column)
+			}
+		}}
+		next = repairer.next
+	}
+	if len(opts.SortElements) > 0 {
+		sorter := &elementSorter{specs: opts.SortElements, read: next, position: func(err error) error {
+			return positionError(decoder, err)
+		}}
+		next = sorter.next
+	}
+
+	var open []xml.Name
+	// mixedDepth is the depth of the content of the outermost open element holding text, or 0 if there is none;
+	// everything from that depth down is written as it was
+	mixedDepth := 0
+	// rootSeen is set once the root element starts, ending the prolog
+	rootSeen := false
+	for {
+		read, err := next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		depth := len(open)
+		verbatim := mixedDepth > 0 && depth >= mixedDepth
+		t := read.token
+		prolog := false
+		switch token := t.(type) {
+		case xml.CharData:
+			if read.cdata {
+				// The encoder cannot write CDATA, so the section goes straight after what it has written so far
+				if err := flushPending(); err != nil {
+					return err
+				}
+				if err := encoder.Flush(); err != nil {
+					return err
+				}
+				writeCDATA(buf, token)
+				continue
+			}
+			if len(bytes.TrimSpace(token)) == 0 {
+				if !verbatim {
+					if pending != nil {
+						pendingSpace = append(pendingSpace, token...)
+					}
+					continue
+				}
+			} else if mixedDepth == 0 && depth > 0 {
+				mixedDepth = depth
+			}
+		case xml.Directive:
+			skip, doctypeErr := handleDoctype(decoder, token, opts.Doctype)
+			if doctypeErr != nil {
+				return positionError(decoder, doctypeErr)
+			} else if skip {
+				continue
+			}
+			prolog = !rootSeen
+		case xml.Comment:
+			prolog = !rootSeen
+		case xml.ProcInst:
+			prolog = !rootSeen
+			if converted {
+				t = declareUTF8(token)
+			}
+		case xml.StartElement:
+			if err := flushPending(); err != nil {
+				return err
+			}
+			open = append(open, token.Name)
+			rootSeen = true
+			if mixedDepth == 0 && preservesSpace(token) {
+				mixedDepth = depth + 1
+			}
+			start := token.Copy()
+			if prefixes := unused[read.index]; prefixes != nil {
+				pruneDeclarations(&start, prefixes)
+			}
+			if opts.SortAttributes {
+				sortAttributes(&start)
+			}
+			pending = &start
+			pendingSelfClosed = read.selfClosed
+			pendingDepth = depth
+			pendingVerbatim = verbatim
+			continue
+		case xml.EndElement:
+			if depth == 0 || open[depth-1] != token.Name {
+				return positionError(decoder, unexpectedEndError(open, token.Name))
+			}
+			open = open[:depth-1]
+			if len(open) < mixedDepth {
+				mixedDepth = 0
+			}
+			if space := pendingSpace; len(space) > 0 {
+				// The element holds only whitespace, which is written as its text
+				if err := flushPending(); err != nil {
+					return err
+				}
+				if err := encoder.EncodeToken(space); err != nil {
+					return err
+				}
+			}
+			if pending != nil {
+				start := *pending
+				pending = nil
+				selfClosing := opts.SelfClose == SelfCloseEmpty || (opts.SelfClose == SelfClosePreserve && pendingSelfClosed)
+				// HTML reads </br> as another <br>, so void elements are always written as <br/>
+				if opts.Lenient && voidElements[strings.ToLower(start.Name.Local)] {
+					selfClosing = true
+				}
+				mark, err := markTag(pendingVerbatim)
+				if err != nil {
+					return err
+				}
+				if err = encodeEmpty(encoder, buf, start, selfClosing); err != nil {
+					return err
+				}
+				if err = fixTag(mark, pendingVerbatim, true); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if err := flushPending(); err != nil {
+			return err
+		}
+		_, text := t.(xml.CharData)
+		mark, err := markTag(verbatim && !text)
+		if err != nil {
+			return err
+		}
+		if err = encoder.EncodeToken(t); err != nil {
+			return err
+		}
+		if err = fixTag(mark, verbatim && !text, false); err != nil {
+			return err
+		}
+		if prolog && !opts.Minify {
+			if err := encoder.EncodeToken(xml.CharData("\n")); err != nil {
+				return err
+			}
+		}
+		if pending == nil && buf.Len() >= drainSize {
+			if err := drain(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(open) > 0 {
+		return positionError(decoder, fmt.Errorf("unexpected end of document, <%s> is not closed", open[len(open)-1].Local))
+	}
+	return drain()
+}
+
+// preservesSpace reports whether start has xml:space="preserve", asking for the whitespace in its content to be kept.
+func preservesSpace(start xml.StartElement) bool {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == "xml:space" {
+			return attr.Value == "preserve"
+		}
+	}
+	return false
+}
+
+// unindent removes the line break and indentation the encoder wrote at mark ahead of a tag.
+func unindent(buf *bytes.Buffer, mark int) {
+	data := buf.Bytes()
+	if tagStart := bytes.IndexByte(data[mark:], '<'); tagStart > 0 {
+		copy(data[mark:], data[mark+tagStart:])
+		buf.Truncate(len(data) - tagStart)
+	}
+}
+
+// unexpectedEndError describes an end tag that does not close the innermost open element.
+func unexpectedEndError(open []xml.Name, end xml.Name) error {
+	if len(open) == 0 {
+		return fmt.Errorf("unexpected end element </%s>", end.Local)
+	}
+	return fmt.Errorf("element <%s> closed by </%s>", open[len(open)-1].Local, end.Local)
+}
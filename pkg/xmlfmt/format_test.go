@@ -0,0 +1,332 @@
+package xmlfmt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	. "GoTools/pkg/helpers"
+)
+
+// format formats input with opts, failing the test on error.
+func format(t *testing.T, input string, opts Options) string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := Format(strings.NewReader(input), &out, opts); err != nil {
+		t.Fatalf("Format(%q): %v", input, err)
+	}
+	return out.String()
+}
+
+func TestFormat(t *testing.T) {
+	sortKeys, sortErr := ParseSortKeys("row:@id,item:name")
+	if sortErr != nil {
+		t.Fatal(sortErr)
+	}
+	tests := []struct {
+		name    string
+		input   string
+		options func(*Options)
+		want    string
+	}{
+		{
+			name:  "Indent",
+			input: "<a>\n  <b x=\"1\">t</b>\n  <c/>\n</a>",
+			want:  "<a>\n\t<b x=\"1\">t</b>\n\t<c></c>\n</a>",
+		},
+		{
+			name:    "IndentSpaces",
+			input:   "<a><b/></a>",
+			options: func(opts *Options) { opts.Indent = "  " },
+			want:    "<a>\n  <b></b>\n</a>",
+		},
+		{
+			name:    "Minify",
+			input:   "<a>\n  <b x=\"1\">t</b>\n  <c/>\n</a>",
+			options: func(opts *Options) { opts.Minify = true },
+			want:    "<a><b x=\"1\">t</b><c></c></a>",
+		},
+		{
+			name:  "Prolog",
+			input: "<?xml version=\"1.0\"?><!DOCTYPE a><a/>",
+			want:  "<?xml version=\"1.0\"?>\n<!DOCTYPE a>\n<a></a>",
+		},
+		{
+			name:    "DoctypeStrip",
+			input:   "<?xml version=\"1.0\"?><!DOCTYPE a><a/>",
+			options: func(opts *Options) { opts.Doctype = DoctypeStrip },
+			want:    "<?xml version=\"1.0\"?>\n<a></a>",
+		},
+		{
+			name:    "SelfCloseEmpty",
+			input:   "<a><c/><d></d></a>",
+			options: func(opts *Options) { opts.SelfClose = SelfCloseEmpty },
+			want:    "<a>\n\t<c/>\n\t<d/>\n</a>",
+		},
+		{
+			name:    "SelfCloseWhitespace",
+			input:   "<a><b> </b></a>",
+			options: func(opts *Options) { opts.SelfClose = SelfCloseEmpty },
+			want:    "<a>\n\t<b> </b>\n</a>",
+		},
+		{
+			name:    "SelfClosePreserve",
+			input:   "<a><c/><d></d></a>",
+			options: func(opts *Options) { opts.SelfClose = SelfClosePreserve },
+			want:    "<a>\n\t<c/>\n\t<d></d>\n</a>",
+		},
+		{
+			name:  "CDATA",
+			input: "<a><![CDATA[<x>]]></a>",
+			want:  "<a>&lt;x&gt;</a>",
+		},
+		{
+			name:    "PreserveCDATA",
+			input:   "<a><![CDATA[<x>]]></a>",
+			options: func(opts *Options) { opts.PreserveCDATA = true },
+			want:    "<a><![CDATA[<x>]]></a>",
+		},
+		{
+			name:    "SortAttributes",
+			input:   "<a z=\"1\" b=\"2\" xmlns:q=\"u\"><c/></a>",
+			options: func(opts *Options) { opts.SortAttributes = true },
+			want:    "<a xmlns:q=\"u\" b=\"2\" z=\"1\">\n\t<c></c>\n</a>",
+		},
+		{
+			name:    "SortElementsByAttribute",
+			input:   "<a><row id=\"2\"/><row id=\"10\"/><row id=\"1\"/></a>",
+			options: func(opts *Options) { opts.SortElements = sortKeys },
+			want:    "<a>\n\t<row id=\"1\"></row>\n\t<row id=\"2\"></row>\n\t<row id=\"10\"></row>\n</a>",
+		},
+		{
+			name:    "SortElementsByChild",
+			input:   "<a><item><name>b</name></item><item><name>a</name></item></a>",
+			options: func(opts *Options) { opts.SortElements = sortKeys },
+			want:    "<a>\n\t<item>\n\t\t<name>a</name>\n\t</item>\n\t<item>\n\t\t<name>b</name>\n\t</item>\n</a>",
+		},
+		{
+			name:    "PruneNamespaces",
+			input:   "<a xmlns:x=\"u\" xmlns:y=\"v\"><y:b xmlns:y=\"v\"/></a>",
+			options: func(opts *Options) { opts.PruneNamespaces = true },
+			want:    "<a xmlns:y=\"v\">\n\t<y:b></y:b>\n</a>",
+		},
+		{
+			name:    "MaxLineWidth",
+			input:   "<a><b first=\"aaaaaaaaaa\" second=\"bbbbbbbbbb\"/><c d=\"1\" e=\"2\"/></a>",
+			options: func(opts *Options) { opts.MaxLineWidth = 30 },
+			want:    "<a>\n\t<b\n\t\tfirst=\"aaaaaaaaaa\"\n\t\tsecond=\"bbbbbbbbbb\"></b>\n\t<c d=\"1\" e=\"2\"></c>\n</a>",
+		},
+		{
+			name:    "Canonical",
+			input:   "<?xml version=\"1.0\"?>\n<a b=\"2\" a=\"1\"><!-- c --><x/></a>",
+			options: func(opts *Options) { opts.Canonical = true },
+			want:    "<a a=\"1\" b=\"2\"><x></x></a>",
+		},
+		{
+			name:    "Lenient",
+			input:   "<html><body><br><p>one</p><p>two</body></html>",
+			options: func(opts *Options) { opts.Lenient = true },
+			want:    "<html>\n\t<body>\n\t\t<br/>\n\t\t<p>one</p>\n\t\t<p>two</p>\n\t</body>\n</html>",
+		},
+		{
+			name:  "BOMPreserve",
+			input: "\ufeff<a/>",
+			want:  "\ufeff<a></a>",
+		},
+		{
+			name:    "BOMStrip",
+			input:   "\ufeff<a/>",
+			options: func(opts *Options) { opts.BOM = BOMStrip },
+			want:    "<a></a>",
+		},
+		{
+			name:    "BOMAdd",
+			input:   "<a/>",
+			options: func(opts *Options) { opts.BOM = BOMAdd },
+			want:    "\ufeff<a></a>",
+		},
+		{
+			name:  "KeepEncoding",
+			input: "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><a>caf\xe9</a>",
+			want:  "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<a>caf\xe9</a>",
+		},
+		{
+			name:    "ToUTF8",
+			input:   "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><a>caf\xe9</a>",
+			options: func(opts *Options) { opts.ToUTF8 = true },
+			want:    "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<a>café</a>",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			if test.options != nil {
+				test.options(&opts)
+			}
+			if got := format(t, test.input, opts); got != test.want {
+				t.Errorf("Format() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		options func(*Options)
+		wantErr string
+	}{
+		{
+			name:    "MismatchedEndTag",
+			input:   "<a><b></a>",
+			wantErr: "element <b> closed by </a>",
+		},
+		{
+			name:    "Unclosed",
+			input:   "<a><b></b>",
+			wantErr: "<a> is not closed",
+		},
+		{
+			name:    "DoctypeReject",
+			input:   "<!DOCTYPE a><a/>",
+			options: func(opts *Options) { opts.Doctype = DoctypeReject },
+			wantErr: "DOCTYPE",
+		},
+		{
+			name:    "UnknownSelfClose",
+			input:   "<a/>",
+			options: func(opts *Options) { opts.SelfClose = "always" },
+			wantErr: "unknown SelfClose",
+		},
+		{
+			name:    "MinifyWithMaxLineWidth",
+			input:   "<a/>",
+			options: func(opts *Options) { opts.Minify, opts.MaxLineWidth = true, 80 },
+			wantErr: "MaxLineWidth cannot be used with Minify",
+		},
+		{
+			name:    "LenientWithCanonical",
+			input:   "<a/>",
+			options: func(opts *Options) { opts.Lenient, opts.Canonical = true, true },
+			wantErr: "Lenient cannot be used",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			if test.options != nil {
+				test.options(&opts)
+			}
+			err := Format(strings.NewReader(test.input), &bytes.Buffer{}, opts)
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("Format() error = %v, want one containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestFormatUTF16(t *testing.T) {
+	utf16 := func(s string) []byte {
+		encoded := []byte{0xff, 0xfe}
+		for _, r := range s {
+			encoded = append(encoded, byte(r), 0)
+		}
+		return encoded
+	}
+	var out bytes.Buffer
+	if err := Format(bytes.NewReader(utf16("<a><b/></a>")), &out, DefaultOptions()); err != nil {
+		t.Fatal(err)
+	}
+	if want := utf16("<a>\n\t<b></b>\n</a>"); !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("Format() = %q, want %q", out.Bytes(), want)
+	}
+}
+
+func TestFormatIdempotent(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "ElementContent",
+			input: "<a><b>1</b>\n    <c/></a>",
+			want:  "<a>\n\t<b>1</b>\n\t<c></c>\n</a>",
+		},
+		{
+			name:  "MixedContent",
+			input: "<p>Some <b>bold</b> and <i>italic <u>text</u></i> here\n</p>",
+			want:  "<p>Some <b>bold</b> and <i>italic <u>text</u></i> here\n</p>",
+		},
+		{
+			name:  "TextAfterChildren",
+			input: "<doc><p><b>bold</b> text <br/>more</p><q><r>1</r></q></doc>",
+			want:  "<doc>\n\t<p>\n\t\t<b>bold</b> text <br></br>more</p>\n\t<q>\n\t\t<r>1</r>\n\t</q>\n</doc>",
+		},
+		{
+			name:  "WhitespaceOnlyElement",
+			input: "<a><b> </b><c>\n</c><d/></a>",
+			want:  "<a>\n\t<b> </b>\n\t<c>\n</c>\n\t<d></d>\n</a>",
+		},
+		{
+			name:  "PreserveSpace",
+			input: "<a><pre xml:space=\"preserve\">\n  <b> x </b>\n  <c/>\n</pre><d/></a>",
+			want:  "<a>\n\t<pre xml:space=\"preserve\">\n  <b> x </b>\n  <c></c>\n</pre>\n\t<d></d>\n</a>",
+		},
+		{
+			name:  "PrologComment",
+			input: "<!-- c --><?pi x?><a/><!-- end -->",
+			want:  "<!-- c -->\n<?pi x?>\n<a></a><!-- end -->",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			once := format(t, test.input, DefaultOptions())
+			if once != test.want {
+				t.Errorf("Format() = %q, want %q", once, test.want)
+			}
+			if twice := format(t, once, DefaultOptions()); twice != once {
+				t.Errorf("formatting again changed the output:\n%q\nbecame\n%q", once, twice)
+			}
+		})
+	}
+}
+
+func TestParseSortKeys(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    map[string]SortKey
+		wantErr bool
+	}{
+		{value: "", want: map[string]SortKey{}},
+		{value: "Row:@Id, Item:Name", want: map[string]SortKey{"Row": {Key: "Id", Attr: true}, "Item": {Key: "Name"}}},
+		{value: "ns:Row:@Id", want: map[string]SortKey{"ns:Row": {Key: "Id", Attr: true}}},
+		{value: "ns:Row:Name", want: map[string]SortKey{"ns:Row": {Key: "Name"}}},
+		{value: "Row", wantErr: true},
+		{value: "Row:@", wantErr: true},
+		{value: "Row:@Id,Row:@Key", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := ParseSortKeys(test.value)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("ParseSortKeys(%q) = %v, want an error", test.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSortKeys(%q): %v", test.value, err)
+			continue
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("ParseSortKeys(%q) = %v, want %v", test.value, got, test.want)
+			continue
+		}
+		for element, key := range test.want {
+			if got[element] != key {
+				t.Errorf("ParseSortKeys(%q)[%q] = %v, want %v", test.value, element, got[element], key)
+			}
+		}
+	}
+}
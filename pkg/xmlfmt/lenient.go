@@ -0,0 +1,94 @@
+package xmlfmt
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// voidElements are the HTML elements that never have content, and so are written without an end tag.
+var voidElements = make(map[string]bool)
+
+func init() {
+	for _, name := range xml.HTMLAutoClose {
+		voidElements[name] = true
+	}
+}
+
+// tokenRepairer passes on the tokens of a document that is not well-formed, for Options.Lenient, repairing its
+// nesting so the formatter can write it out: HTML void elements such as <br> are closed straight away,
+// an end tag closes any elements left open inside the element it ends, end tags that match no open
+// element are dropped, and elements still open at the end of the document are closed there.
+// Each repair is passed to warn, along with the name of the element concerned.
+type tokenRepairer struct {
+	read  func() (sourceToken, error)
+	warn  func(repair, element string)
+	open  []xml.Name
+	queue []sourceToken
+}
+
+// next returns the next token of the repaired document, or io.EOF at its end.
+func (r *tokenRepairer) next() (sourceToken, error) {
+	for len(r.queue) == 0 {
+		if err := r.repair(); err != nil {
+			return sourceToken{}, err
+		}
+	}
+	read := r.queue[0]
+	r.queue = r.queue[1:]
+	return read, nil
+}
+
+// repair reads the next token and queues it, along with any tokens needed to keep the nesting valid.
+// The queue is left empty when the token is dropped.
+func (r *tokenRepairer) repair() error {
+	read, err := r.read()
+	if errors.Is(err, io.EOF) {
+		if len(r.open) == 0 {
+			return io.EOF
+		}
+		r.closeTo(0, "Closing element left open at the end of the document")
+		return nil
+	} else if err != nil {
+		return err
+	}
+	switch token := read.token.(type) {
+	case xml.StartElement:
+		r.queue = append(r.queue, read)
+		if voidElements[strings.ToLower(token.Name.Local)] && !read.selfClosed {
+			// The decoder reads <br> as a start tag only, so its end is supplied here
+			r.queue = append(r.queue, sourceToken{token: token.End()})
+			return nil
+		}
+		r.open = append(r.open, token.Name)
+	case xml.EndElement:
+		depth := len(r.open) - 1
+		for depth >= 0 && r.open[depth] != token.Name {
+			depth--
+		}
+		if depth < 0 {
+			// An explicit end tag of a void element, as in <br></br>, is expected, so it is dropped silently
+			if !voidElements[strings.ToLower(token.Name.Local)] {
+				r.warn("Dropping end tag that closes no open element", token.Name.Local)
+			}
+			return nil
+		}
+		r.closeTo(depth+1, "Closing element left open")
+		r.queue = append(r.queue, read)
+		r.open = r.open[:depth]
+	default:
+		r.queue = append(r.queue, read)
+	}
+	return nil
+}
+
+// closeTo queues end tags for the open elements deeper than depth, innermost first, warning about each.
+func (r *tokenRepairer) closeTo(depth int, repair string) {
+	for len(r.open) > depth {
+		name := r.open[len(r.open)-1]
+		r.open = r.open[:len(r.open)-1]
+		r.warn(repair, name.Local)
+		r.queue = append(r.queue, sourceToken{token: xml.EndElement{Name: name}})
+	}
+}
@@ -0,0 +1,89 @@
+package xmlfmt
+
+import (
+	"cmp"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// DefaultRefAttributes are the attributes Lint takes as references to IDs when a tool does not name its own.
+var DefaultRefAttributes = []string{"ref", "idref", "idrefs"}
+
+// LintIssue is a problem Lint found in a document, and the line and column where the tag holding it ends.
+type LintIssue struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+// idUse is an ID or a reference to one, and where it was found.
+type idUse struct {
+	value        string
+	line, column int
+}
+
+// Lint reads the XML from r and reports the IDs that are given to more than one element, and the references
+// to IDs that no element has. IDs are the values of attributes named id in any case, such as Id or xml:id.
+// References are the values of the attributes named by refAttrs, compared without regard to case and prefix,
+// each of which may hold several IDs separated by whitespace, and of href attributes starting with '#'.
+// The Doctype and Lenient options apply as they do to Validate. Issues are returned in document order.
+func Lint(r io.Reader, opts Options, refAttrs []string) ([]LintIssue, error) {
+	r, _, _, charsetErr := DecodeCharset(r)
+	if charsetErr != nil {
+		return nil, charsetErr
+	}
+	decoder := newDecoder(r, opts.Lenient)
+	var issues []LintIssue
+	var refs []idUse
+	ids := make(map[string]idUse)
+	for {
+		t, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, positionError(decoder, err)
+		}
+		switch token := t.(type) {
+		case xml.Directive:
+			if _, doctypeErr := handleDoctype(decoder, token, opts.Doctype); doctypeErr != nil {
+				return nil, positionError(decoder, doctypeErr)
+			}
+		case xml.StartElement:
+			line, column := decoder.InputPos()
+			for _, attr := range token.Attr {
+				name := strings.ToLower(attr.Name.Local)
+				switch {
+				case name == "id":
+					id := strings.TrimSpace(attr.Value)
+					if first, seen := ids[id]; seen {
+						issues = append(issues, LintIssue{Line: line, Column: column,
+							Message: fmt.Sprintf("Duplicate ID '%s', first given on line %d", id, first.line)})
+					} else {
+						ids[id] = idUse{value: id, line: line, column: column}
+					}
+				case name == "href" && strings.HasPrefix(attr.Value, "#"):
+					refs = append(refs, idUse{value: attr.Value[1:], line: line, column: column})
+				case slices.ContainsFunc(refAttrs, func(ref string) bool { return strings.EqualFold(ref, name) }):
+					for _, id := range strings.Fields(attr.Value) {
+						refs = append(refs, idUse{value: id, line: line, column: column})
+					}
+				}
+			}
+		}
+	}
+	// References may come before the element they refer to, so they are only checked once every ID is known
+	for _, ref := range refs {
+		if _, found := ids[ref.value]; !found {
+			issues = append(issues, LintIssue{Line: ref.line, Column: ref.column,
+				Message: fmt.Sprintf("Reference to undefined ID '%s'", ref.value)})
+		}
+	}
+	slices.SortStableFunc(issues, func(a, b LintIssue) int {
+		return cmp.Or(cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column))
+	})
+	return issues, nil
+}
@@ -0,0 +1,56 @@
+package xmlfmt
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		refAttrs []string
+		want     []string
+	}{
+		{
+			name:  "Clean",
+			input: "<a><b id=\"x\"/><c ref=\"x\"/><d href=\"#x\"/></a>",
+		},
+		{
+			name:  "DuplicateID",
+			input: "<a><b id=\"x\"/><c Id=\"x\"/></a>",
+			want:  []string{"Duplicate ID 'x', first given on line 1"},
+		},
+		{
+			name:  "UndefinedReferences",
+			input: "<a><b id=\"x\"/><c idrefs=\"x y\"/><d href=\"#z\"/></a>",
+			want:  []string{"Reference to undefined ID 'y'", "Reference to undefined ID 'z'"},
+		},
+		{
+			name:     "OwnReferenceAttributes",
+			input:    "<a><b id=\"x\"/><c ref=\"y\" target=\"w\"/></a>",
+			refAttrs: []string{"target"},
+			want:     []string{"Reference to undefined ID 'w'"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			refAttrs := test.refAttrs
+			if refAttrs == nil {
+				refAttrs = DefaultRefAttributes
+			}
+			issues, err := Lint(strings.NewReader(test.input), DefaultOptions(), refAttrs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, issue := range issues {
+				got = append(got, issue.Message)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("Lint() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package xmlfmt
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// qualifiedName folds the prefix of a name read with RawToken into its local part, as in "xs:element",
+// so the encoder writes the name as it appeared rather than inventing prefixes of its own.
+func qualifiedName(name xml.Name) xml.Name {
+	if len(name.Space) > 0 {
+		return xml.Name{Local: name.Space + ":" + name.Local}
+	}
+	return name
+}
+
+// qualifyToken applies qualifiedName to the names of an element and its attributes.
+func qualifyToken(t xml.Token) xml.Token {
+	switch token := t.(type) {
+	case xml.StartElement:
+		token.Name = qualifiedName(token.Name)
+		attrs := make([]xml.Attr, len(token.Attr))
+		for i, attr := range token.Attr {
+			attrs[i] = xml.Attr{Name: qualifiedName(attr.Name), Value: attr.Value}
+		}
+		token.Attr = attrs
+		return token
+	case xml.EndElement:
+		token.Name = qualifiedName(token.Name)
+		return token
+	}
+	return t
+}
+
+// declaredPrefix returns the prefix declared by a namespace declaration, which is empty for a default
+// namespace declaration. The attribute may be as read with RawToken or folded by qualifiedName.
+func declaredPrefix(attr xml.Attr) (prefix string, ok bool) {
+	if attr.Name.Space == "xmlns" {
+		return attr.Name.Local, true
+	}
+	if attr.Name.Space == "" {
+		if attr.Name.Local == "xmlns" {
+			return "", true
+		}
+		return strings.CutPrefix(attr.Name.Local, "xmlns:")
+	}
+	return "", false
+}
+
+// qnamePrefix matches a value that starts with a prefix, such as the "xs" of xsi:type="xs:string".
+var qnamePrefix = regexp.MustCompile(`^\s*([A-Za-z_][\w.-]*):[A-Za-z_]`)
+
+// namespaceBinding is a namespace declaration in scope, and whether anything in its scope uses it.
+type namespaceBinding struct {
+	element int
+	prefix  string
+	uri     string
+	used    bool
+}
+
+// unusedNamespaces reads the document from r and returns, for the index of each element in document order,
+// the prefixes whose declarations on that element can be removed: those that nothing in their scope uses,
+// and those that repeat a declaration of the same prefix and namespace already in scope.
+// A prefix counts as used by an element or attribute name carrying it, and by an attribute value or text
+// that starts with it, since values such as xsi:type="xs:string" refer to namespaces by prefix.
+func unusedNamespaces(r io.Reader, opts Options) (map[int]map[string]bool, error) {
+	decoder := newDecoder(r, opts.Lenient)
+	unused := make(map[int]map[string]bool)
+	drop := func(element int, prefix string) {
+		if unused[element] == nil {
+			unused[element] = make(map[string]bool)
+		}
+		unused[element][prefix] = true
+	}
+	var scopes [][]*namespaceBinding
+	lookup := func(prefix string) *namespaceBinding {
+		for depth := len(scopes) - 1; depth >= 0; depth-- {
+			for _, binding := range scopes[depth] {
+				if binding.prefix == prefix {
+					return binding
+				}
+			}
+		}
+		return nil
+	}
+	use := func(prefix string) {
+		if binding := lookup(prefix); binding != nil {
+			binding.used = true
+		}
+	}
+	useValue := func(value []byte) {
+		if match := qnamePrefix.FindSubmatch(value); match != nil {
+			use(string(match[1]))
+		}
+	}
+
+	element := 0
+	for {
+		t, err := decoder.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, positionError(decoder, err)
+		}
+		switch token := t.(type) {
+		case xml.StartElement:
+			var scope []*namespaceBinding
+			for _, attr := range token.Attr {
+				prefix, isDecl := declaredPrefix(attr)
+				if !isDecl {
+					continue
+				}
+				if existing := lookup(prefix); existing != nil && existing.uri == attr.Value {
+					drop(element, prefix)
+					continue
+				}
+				scope = append(scope, &namespaceBinding{element: element, prefix: prefix, uri: attr.Value})
+			}
+			scopes = append(scopes, scope)
+			use(token.Name.Space)
+			for _, attr := range token.Attr {
+				if _, isDecl := declaredPrefix(attr); isDecl {
+					continue
+				}
+				if len(attr.Name.Space) > 0 {
+					use(attr.Name.Space)
+				}
+				useValue([]byte(attr.Value))
+			}
+			element++
+		case xml.CharData:
+			useValue(token)
+		case xml.EndElement:
+			if len(scopes) == 0 {
+				continue
+			}
+			for _, binding := range scopes[len(scopes)-1] {
+				if !binding.used {
+					drop(binding.element, binding.prefix)
+				}
+			}
+			scopes = scopes[:len(scopes)-1]
+		}
+	}
+	return unused, nil
+}
+
+// pruneDeclarations removes the namespace declarations of the given prefixes from start.
+func pruneDeclarations(start *xml.StartElement, prefixes map[string]bool) {
+	attrs := start.Attr[:0]
+	for _, attr := range start.Attr {
+		if prefix, isDecl := declaredPrefix(attr); isDecl && prefixes[prefix] {
+			continue
+		}
+		attrs = append(attrs, attr)
+	}
+	start.Attr = attrs
+}
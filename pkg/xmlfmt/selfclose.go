@@ -0,0 +1,38 @@
+package xmlfmt
+
+import (
+	"bytes"
+	"encoding/xml"
+)
+
+// The ways of writing empty elements that Options.SelfClose accepts.
+const (
+	SelfCloseEmpty    = "empty"
+	SelfCloseExpand   = "expand"
+	SelfClosePreserve = "preserve"
+)
+
+// SelfCloseModes lists the Options.SelfClose values.
+var SelfCloseModes = []string{SelfCloseEmpty, SelfCloseExpand, SelfClosePreserve}
+
+// encodeEmpty writes an element without content, as <a/> if selfClosing is set or <a></a> otherwise.
+// The encoder only writes the expanded form, so the end tag it writes is replaced once it is flushed to buf.
+func encodeEmpty(encoder *xml.Encoder, buf *bytes.Buffer, start xml.StartElement, selfClosing bool) error {
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := encoder.EncodeToken(start.End()); err != nil {
+		return err
+	}
+	if !selfClosing {
+		return nil
+	}
+	if err := encoder.Flush(); err != nil {
+		return err
+	}
+	if endTag := bytes.LastIndex(buf.Bytes(), []byte("></")); endTag >= 0 {
+		buf.Truncate(endTag)
+		buf.WriteString("/>")
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package xmlfmt
+
+import (
+	"cmp"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// SortKey is what the elements named by an Options.SortElements entry are ordered by among their siblings:
+// the value of the attribute named Key if Attr is set, or else the text of their first child element named Key.
+type SortKey struct {
+	Key  string
+	Attr bool
+}
+
+// ParseSortKeys parses a comma-separated list such as "Row:@Id,Setting:Name" into the key of each element
+// name, for Options.SortElements. Names are matched as written, with their prefix if they have one, so an attribute key
+// is taken after ":@" and a child key after the last colon, as in "ns:Row:@Id" or "ns:Row:Name".
+func ParseSortKeys(value string) (map[string]SortKey, error) {
+	specs := make(map[string]SortKey)
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); len(entry) == 0 {
+			continue
+		}
+		element, key, attr := strings.Cut(entry, ":@")
+		if !attr {
+			separator := strings.LastIndex(entry, ":")
+			if separator < 0 {
+				separator = len(entry)
+			}
+			element, key = entry[:separator], strings.TrimPrefix(entry[separator:], ":")
+		}
+		element, key = strings.TrimSpace(element), strings.TrimSpace(key)
+		if len(element) == 0 || len(key) == 0 {
+			return nil, fmt.Errorf("invalid entry '%s', expected an element and the key to sort it by, as in Row:@Id or Row:Name", entry)
+		}
+		if _, repeated := specs[element]; repeated {
+			return nil, fmt.Errorf("element '%s' is given more than once", element)
+		}
+		specs[element] = SortKey{Key: key, Attr: attr}
+	}
+	return specs, nil
+}
+
+// sourceToken is a token as read from the document, along with what formatDecoded needs to know about how it
+// was written: whether character data was a CDATA section, whether a start tag closed itself as in <a/>,
+// and the position of a start tag among the document's elements, which Options.PruneNamespaces refers to.
+type sourceToken struct {
+	token      xml.Token
+	cdata      bool
+	selfClosed bool
+	index      int
+}
+
+// sortItem is a sibling buffered by elementSorter: a whole element, or a single token between elements.
+type sortItem struct {
+	tokens []sourceToken
+	// name is the element's name, empty for other tokens.
+	name  string
+	start xml.StartElement
+	// fields holds the text of the first child element with each name, for sorting by a child's value.
+	fields map[string]string
+}
+
+// key returns the value item is sorted by under spec, which is empty if the attribute or child is missing.
+func (item sortItem) key(spec SortKey) string {
+	if !spec.Attr {
+		return item.fields[spec.Key]
+	}
+	for _, attr := range item.start.Attr {
+		if attr.Name.Local == spec.Key {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// elementSorter passes on the tokens of a document, reordering the elements named by Options.SortElements among
+// their siblings. Tokens stream through until the first such element, from which the rest of its parent's
+// content is held in memory, sorted and passed on. Only the positions those elements occupy are reordered,
+// so any other siblings between them stay where they were.
+type elementSorter struct {
+	specs map[string]SortKey
+	read  func() (sourceToken, error)
+	// position adds the decoder's position to an error found while buffering.
+	position func(error) error
+	open     int
+	queue    []sourceToken
+}
+
+// next returns the next token of the sorted document, or io.EOF at its end.
+func (s *elementSorter) next() (sourceToken, error) {
+	if len(s.queue) > 0 {
+		read := s.queue[0]
+		s.queue = s.queue[1:]
+		return read, nil
+	}
+	read, err := s.read()
+	if err != nil {
+		return read, err
+	}
+	switch token := read.token.(type) {
+	case xml.StartElement:
+		if _, sorted := s.specs[token.Name.Local]; sorted && s.open > 0 {
+			if bufferErr := s.bufferSiblings(read); bufferErr != nil {
+				return sourceToken{}, bufferErr
+			}
+			return s.next()
+		}
+		s.open++
+	case xml.EndElement:
+		s.open--
+	}
+	return read, nil
+}
+
+// bufferSiblings reads the element starting with first and the siblings that follow it up to their parent's
+// end tag, and queues them in sorted order followed by that end tag.
+func (s *elementSorter) bufferSiblings(first sourceToken) error {
+	item, err := s.readElement(first)
+	if err != nil {
+		return err
+	}
+	siblings := []sortItem{item}
+	for {
+		read, readErr := s.read()
+		if errors.Is(readErr, io.EOF) {
+			return s.position(errors.New("unexpected end of document, an element is not closed"))
+		} else if readErr != nil {
+			return readErr
+		}
+		switch read.token.(type) {
+		case xml.StartElement:
+			if item, err = s.readElement(read); err != nil {
+				return err
+			}
+			siblings = append(siblings, item)
+			continue
+		case xml.EndElement:
+			s.sortSiblings(siblings)
+			for _, sibling := range siblings {
+				s.queue = append(s.queue, sibling.tokens...)
+			}
+			s.queue = append(s.queue, read)
+			s.open--
+			return nil
+		}
+		siblings = append(siblings, sortItem{tokens: []sourceToken{copySourceToken(read)}})
+	}
+}
+
+// readElement reads the element starting with start up to its end tag, sorting its own children as it goes.
+func (s *elementSorter) readElement(start sourceToken) (sortItem, error) {
+	startElement := start.token.(xml.StartElement).Copy()
+	start.token = startElement
+	item := sortItem{name: startElement.Name.Local, start: startElement, fields: make(map[string]string)}
+	var children []sortItem
+	for {
+		read, err := s.read()
+		if errors.Is(err, io.EOF) {
+			return item, s.position(fmt.Errorf("unexpected end of document, <%s> is not closed", item.name))
+		} else if err != nil {
+			return item, err
+		}
+		switch token := read.token.(type) {
+		case xml.StartElement:
+			child, childErr := s.readElement(read)
+			if childErr != nil {
+				return item, childErr
+			}
+			if _, seen := item.fields[child.name]; !seen {
+				item.fields[child.name] = childText(child)
+			}
+			children = append(children, child)
+			continue
+		case xml.EndElement:
+			if token.Name != startElement.Name {
+				return item, s.position(unexpectedEndError([]xml.Name{startElement.Name}, token.Name))
+			}
+			s.sortSiblings(children)
+			item.tokens = append(item.tokens, start)
+			for _, child := range children {
+				item.tokens = append(item.tokens, child.tokens...)
+			}
+			item.tokens = append(item.tokens, read)
+			return item, nil
+		}
+		children = append(children, sortItem{tokens: []sourceToken{copySourceToken(read)}})
+	}
+}
+
+// childText returns the text directly inside an element, without the text of its own children.
+func childText(item sortItem) string {
+	var text strings.Builder
+	depth := 0
+	for _, read := range item.tokens {
+		switch token := read.token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 1 {
+				text.Write(token)
+			}
+		}
+	}
+	return strings.TrimSpace(text.String())
+}
+
+// sortSiblings reorders the siblings named by a spec among the positions they occupy, leaving the rest in place.
+// The sort is stable, so siblings with equal keys keep their order.
+func (s *elementSorter) sortSiblings(siblings []sortItem) {
+	for name, spec := range s.specs {
+		var slots []int
+		for i, sibling := range siblings {
+			if sibling.name == name {
+				slots = append(slots, i)
+			}
+		}
+		if len(slots) < 2 {
+			continue
+		}
+		group := make([]sortItem, len(slots))
+		for i, slot := range slots {
+			group[i] = siblings[slot]
+		}
+		slices.SortStableFunc(group, func(a, b sortItem) int {
+			return compareKeys(a.key(spec), b.key(spec))
+		})
+		for i, slot := range slots {
+			siblings[slot] = group[i]
+		}
+	}
+}
+
+// compareKeys compares two sort keys numerically if both are numbers, and as strings otherwise.
+func compareKeys(a, b string) int {
+	aNum, aErr := strconv.ParseFloat(strings.TrimSpace(a), 64)
+	bNum, bErr := strconv.ParseFloat(strings.TrimSpace(b), 64)
+	if aErr == nil && bErr == nil {
+		return cmp.Compare(aNum, bNum)
+	}
+	return strings.Compare(a, b)
+}
+
+// copySourceToken copies the token in read, as the decoder reuses the memory of the tokens it returns.
+func copySourceToken(read sourceToken) sourceToken {
+	read.token = xml.CopyToken(read.token)
+	return read
+}
@@ -0,0 +1,58 @@
+package xmlfmt
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// positionError adds the line and column the decoder had reached to err,
+// so a syntax error can be found in the file. A syntax error's own message is used, since it only has the line.
+func positionError(decoder *xml.Decoder, err error) error {
+	line, column := decoder.InputPos()
+	var syntaxErr *xml.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return fmt.Errorf("XML syntax error on line %d, column %d: %s", line, column, syntaxErr.Msg)
+	}
+	return fmt.Errorf("line %d, column %d: %w", line, column, err)
+}
+
+// Validate checks that the XML read from r is well-formed, with exactly one root element,
+// without writing anything. The Doctype policy of opts applies, so DoctypeReject fails any document with a DOCTYPE;
+// the other options only affect formatting.
+func Validate(r io.Reader, opts Options) error {
+	r, _, _, charsetErr := DecodeCharset(r)
+	if charsetErr != nil {
+		return charsetErr
+	}
+	decoder := newDecoder(r, opts.Lenient)
+	depth, roots := 0, 0
+	for {
+		t, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return positionError(decoder, err)
+		}
+		switch token := t.(type) {
+		case xml.Directive:
+			if _, doctypeErr := handleDoctype(decoder, token, opts.Doctype); doctypeErr != nil {
+				return positionError(decoder, doctypeErr)
+			}
+		case xml.StartElement:
+			if depth == 0 {
+				if roots++; roots > 1 {
+					return positionError(decoder, errors.New("the document has more than one root element"))
+				}
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	if roots == 0 {
+		return errors.New("the document has no root element")
+	}
+	return nil
+}
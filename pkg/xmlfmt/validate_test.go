@@ -0,0 +1,39 @@
+package xmlfmt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		doctype string
+		wantErr string
+	}{
+		{name: "WellFormed", input: "<?xml version=\"1.0\"?>\n<a><b/></a>"},
+		{name: "Doctype", input: "<!DOCTYPE a><a/>"},
+		{name: "DoctypeRejected", input: "<!DOCTYPE a><a/>", doctype: DoctypeReject, wantErr: "DOCTYPE"},
+		{name: "Empty", input: "", wantErr: "no root element"},
+		{name: "TwoRoots", input: "<a/><b/>", wantErr: "more than one root element"},
+		{name: "Unclosed", input: "<a>", wantErr: "unexpected EOF"},
+		{name: "Mismatched", input: "<a>\n<b></a>", wantErr: "line 2, column"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			if len(test.doctype) > 0 {
+				opts.Doctype = test.doctype
+			}
+			err := Validate(strings.NewReader(test.input), opts)
+			if len(test.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("Validate() = %v, want an error containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package xmlfmt
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+)
+
+// tabWidth is the number of columns a tab counts for when measuring a line against Options.MaxLineWidth.
+const tabWidth = 4
+
+// wrapAttributes rewrites the start tag that the encoder has written to buf after offset mark, putting each
+// attribute on a line of its own, indented one level deeper than the element at depth, if the line holding
+// the tag is wider than opts.MaxLineWidth. Tags with fewer than two attributes are left as they are, as wrapping
+// would not make them narrower. The encoder escapes '<', '>' and '"' in attribute values, so the tag ends
+// at the first '>' after it starts and each value at the next '"'.
+func wrapAttributes(buf *bytes.Buffer, mark, depth int, opts Options) {
+	data := buf.Bytes()
+	tagStart := bytes.IndexByte(data[mark:], '<')
+	if tagStart < 0 {
+		return
+	}
+	tagStart += mark
+	tagEnd := bytes.IndexByte(data[tagStart:], '>')
+	if tagEnd < 0 {
+		return
+	}
+	tagEnd += tagStart + 1
+	lineStart := bytes.LastIndexByte(data[:tagStart], '\n') + 1
+	if lineWidth(data[lineStart:tagEnd]) <= opts.MaxLineWidth {
+		return
+	}
+	name, attrs, closing := splitStartTag(data[tagStart:tagEnd])
+	if len(attrs) < 2 {
+		return
+	}
+	var wrapped bytes.Buffer
+	wrapped.Write(name)
+	continuation := "\n" + opts.Prefix + strings.Repeat(opts.Indent, depth+1)
+	for _, attr := range attrs {
+		wrapped.WriteString(continuation)
+		wrapped.Write(attr)
+	}
+	wrapped.Write(closing)
+	wrapped.Write(data[tagEnd:])
+	buf.Truncate(tagStart)
+	_, _ = buf.Write(wrapped.Bytes())
+}
+
+// splitStartTag splits a start tag as written by the encoder, such as <a x="1" y="2"/>,
+// into the '<' and name, each attribute, and the closing '>' or '/>'.
+func splitStartTag(tag []byte) (name []byte, attrs [][]byte, closing []byte) {
+	closing = []byte(">")
+	if bytes.HasSuffix(tag, []byte("/>")) {
+		closing = []byte("/>")
+	}
+	body := tag[:len(tag)-len(closing)]
+	space := bytes.IndexByte(body, ' ')
+	if space < 0 {
+		return body, nil, closing
+	}
+	name, rest := body[:space], bytes.TrimLeft(body[space:], " ")
+	for len(rest) > 0 {
+		valueStart := bytes.Index(rest, []byte(`="`))
+		if valueStart < 0 {
+			break
+		}
+		valueEnd := bytes.IndexByte(rest[valueStart+2:], '"')
+		if valueEnd < 0 {
+			break
+		}
+		attrEnd := valueStart + 2 + valueEnd + 1
+		attrs = append(attrs, rest[:attrEnd])
+		rest = bytes.TrimLeft(rest[attrEnd:], " ")
+	}
+	return name, attrs, closing
+}
+
+// lineWidth returns the number of columns line takes up, counting each tab as tabWidth.
+func lineWidth(line []byte) int {
+	return utf8.RuneCount(line) + bytes.Count(line, []byte("\t"))*(tabWidth-1)
+}